@@ -4,8 +4,12 @@ import (
 	"errors"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/rs/zerolog/log"
 	"os"
 	"wekactl/internal/aws/lambdas"
+	"wekactl/internal/aws/lambdas/interruption"
+	"wekactl/internal/aws/lambdas/lifecycle"
+	"wekactl/internal/aws/lambdas/metrics"
 	"wekactl/internal/aws/lambdas/protocol"
 	"wekactl/internal/aws/lambdas/scale"
 	"wekactl/internal/aws/lambdas/terminate"
@@ -13,15 +17,17 @@ import (
 	"wekactl/internal/env"
 )
 
-func joinHandler() (events.APIGatewayProxyResponse, error) {
+func joinHandler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	result, err := lambdas.GetJoinParams(
 		os.Getenv("CLUSTER_NAME"),
 		os.Getenv("ASG_NAME"),
 		os.Getenv("TABLE_NAME"),
 		os.Getenv("ROLE"),
+		request.QueryStringParameters["instance_type"],
 	)
 	if err != nil {
-		result = err.Error()
+		log.Error().Err(err).Msg("join failed")
+		return events.APIGatewayProxyResponse{Body: lambdas.GetJoinErrorScript(err), StatusCode: 500}, nil
 	}
 	return events.APIGatewayProxyResponse{Body: result, StatusCode: 200}, nil
 }
@@ -46,6 +52,12 @@ func main() {
 		lambda.Start(joinHandler)
 	case "fetch":
 		lambda.Start(fetchHandler)
+	case "interruption":
+		lambda.Start(interruption.Handler)
+	case "lifecycle":
+		lambda.Start(lifecycle.Handler)
+	case "metrics":
+		lambda.Start(metrics.Handler)
 	case "scale":
 		lambda.Start(scale.Handler)
 	case "terminate":
@@ -8,6 +8,8 @@ import (
 	"os"
 	"strings"
 	"unicode"
+	// registers the aws provider
+	_ "wekactl/internal/aws/cluster"
 	"wekactl/internal/cli/aws"
 	"wekactl/internal/cli/cluster"
 	"wekactl/internal/cli/debug"
@@ -12,6 +12,7 @@ import (
 	"wekactl/internal/cluster"
 	"wekactl/internal/connectors"
 	"wekactl/internal/env"
+	"wekactl/internal/lib/strings"
 )
 
 func getAccountId() (string, error) {
@@ -100,6 +101,7 @@ func createRestApiGateway(tags cluster.TagsRefsValues, lambdaUri string, apiGate
 				Stage: aws.String("default"),
 			},
 		},
+		Tags: tags,
 	})
 	if err != nil {
 		return
@@ -288,3 +290,111 @@ func GetRestApiGateway(resourceName string) (restApiGateway RestApiGateway, err
 	restApiGateway.Name = resourceName
 	return
 }
+
+func hasClusterTag(tags map[string]*string, clusterName cluster.ClusterName) bool {
+	value, ok := tags[cluster.ClusterNameTagKey]
+	return ok && *value == string(clusterName)
+}
+
+// GetClusterRestApis returns all the rest apis tagged with the cluster name
+func GetClusterRestApis(clusterName cluster.ClusterName) (restApis []*apigateway.RestApi, err error) {
+	svc := connectors.GetAWSSession().ApiGateway
+	err = svc.GetRestApisPages(&apigateway.GetRestApisInput{}, func(page *apigateway.GetRestApisOutput, lastPage bool) bool {
+		for _, restApi := range page.Items {
+			if hasClusterTag(restApi.Tags, clusterName) {
+				restApis = append(restApis, restApi)
+			}
+		}
+		return true
+	})
+	return
+}
+
+// GetClusterUsagePlans returns all the usage plans tagged with the cluster name, serving one of the given rest apis,
+// or named like one of the cluster join apis, usage plans created before they were tagged have no tags
+func GetClusterUsagePlans(clusterName cluster.ClusterName, restApiIds, names []string) (usagePlans []*apigateway.UsagePlan, err error) {
+	svc := connectors.GetAWSSession().ApiGateway
+	err = svc.GetUsagePlansPages(&apigateway.GetUsagePlansInput{}, func(page *apigateway.GetUsagePlansOutput, lastPage bool) bool {
+		for _, usagePlan := range page.Items {
+			isClusterUsagePlan := hasClusterTag(usagePlan.Tags, clusterName) || strings.AnyOf(*usagePlan.Name, names...)
+			for _, apiStage := range usagePlan.ApiStages {
+				if strings.AnyOf(*apiStage.ApiId, restApiIds...) {
+					isClusterUsagePlan = true
+				}
+			}
+			if isClusterUsagePlan {
+				usagePlans = append(usagePlans, usagePlan)
+			}
+		}
+		return true
+	})
+	return
+}
+
+// GetClusterApiKeys returns all the api keys tagged with the cluster name, or named like one of the cluster join
+// apis, api keys created before they were tagged have no tags
+func GetClusterApiKeys(clusterName cluster.ClusterName, names []string) (apiKeys []*apigateway.ApiKey, err error) {
+	svc := connectors.GetAWSSession().ApiGateway
+	err = svc.GetApiKeysPages(&apigateway.GetApiKeysInput{}, func(page *apigateway.GetApiKeysOutput, lastPage bool) bool {
+		for _, apiKey := range page.Items {
+			if hasClusterTag(apiKey.Tags, clusterName) || strings.AnyOf(*apiKey.Name, names...) {
+				apiKeys = append(apiKeys, apiKey)
+			}
+		}
+		return true
+	})
+	return
+}
+
+func DeleteRestApi(restApiId string) error {
+	svc := connectors.GetAWSSession().ApiGateway
+	_, err := svc.DeleteRestApi(&apigateway.DeleteRestApiInput{RestApiId: &restApiId})
+	if err != nil {
+		if _, ok := err.(*apigateway.NotFoundException); !ok {
+			return err
+		}
+	}
+	return nil
+}
+
+func DeleteUsagePlan(usagePlan *apigateway.UsagePlan) error {
+	svc := connectors.GetAWSSession().ApiGateway
+	var patchOperations []*apigateway.PatchOperation
+	for _, apiStage := range usagePlan.ApiStages {
+		patchOperations = append(patchOperations, &apigateway.PatchOperation{
+			Op:    aws.String(apigateway.OpRemove),
+			Path:  aws.String("/apiStages"),
+			Value: aws.String(fmt.Sprintf("%s:%s", *apiStage.ApiId, *apiStage.Stage)),
+		})
+	}
+	if len(patchOperations) > 0 {
+		_, err := svc.UpdateUsagePlan(&apigateway.UpdateUsagePlanInput{
+			UsagePlanId:     usagePlan.Id,
+			PatchOperations: patchOperations,
+		})
+		if err != nil {
+			if _, ok := err.(*apigateway.NotFoundException); !ok {
+				log.Debug().Msgf("failed removing usage plan %s stages: %v", *usagePlan.Id, err)
+			}
+		}
+	}
+
+	_, err := svc.DeleteUsagePlan(&apigateway.DeleteUsagePlanInput{UsagePlanId: usagePlan.Id})
+	if err != nil {
+		if _, ok := err.(*apigateway.NotFoundException); !ok {
+			return err
+		}
+	}
+	return nil
+}
+
+func DeleteApiKey(apiKeyId string) error {
+	svc := connectors.GetAWSSession().ApiGateway
+	_, err := svc.DeleteApiKey(&apigateway.DeleteApiKeyInput{ApiKey: &apiKeyId})
+	if err != nil {
+		if _, ok := err.(*apigateway.NotFoundException); !ok {
+			return err
+		}
+	}
+	return nil
+}
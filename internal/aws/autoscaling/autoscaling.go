@@ -1,32 +1,147 @@
 package autoscaling
 
 import (
+	"errors"
+	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/elb"
 	"github.com/rs/zerolog/log"
+	"strconv"
 	"time"
 	"wekactl/internal/aws/common"
 	"wekactl/internal/cluster"
 	"wekactl/internal/connectors"
+	"wekactl/internal/lib/strings"
 	"wekactl/internal/logging"
 )
 
 var KeepInstances bool
 
-func CreateAutoScalingGroup(tags []*autoscaling.Tag, launchTemplateName string, maxSize int64, autoScalingGroupName string) (err error) {
+const DefaultLaunchTemplateVersion = "$Default"
+
+// SuspendedProcesses are suspended on every wekactl auto scaling group, weka decides which instances to replace
+var SuspendedProcesses = []string{"ReplaceUnhealthy"}
+
+// TerminationHookName is the lifecycle hook that holds terminating instances until the lifecycle lambda removed
+// their weka hosts
+const TerminationHookName = "wekactl-terminate"
+
+// TerminationHookHeartbeatTimeout is how long an instance waits for the lifecycle lambda, which records a heartbeat
+// on every run while the weka host is deactivating
+const TerminationHookHeartbeatTimeout = 15 * time.Minute
+
+const lifecycleActionContinue = "CONTINUE"
+
+// TargetTrackingPolicyName is the scaling policy that keeps an autoscaled host group metric at its target
+const TargetTrackingPolicyName = "wekactl-target-tracking"
+
+// targetTrackingWarmup is how long a new client takes to join the cluster, its frontend doesn't count towards the
+// host group metrics until then
+const targetTrackingWarmup = 5 * time.Minute
+
+// SpotAllocationStrategy launches spot instances from the pools least likely to be interrupted
+const SpotAllocationStrategy = "capacity-optimized"
+
+func launchTemplateSpecification(launchTemplateName string) *autoscaling.LaunchTemplateSpecification {
+	return &autoscaling.LaunchTemplateSpecification{
+		LaunchTemplateName: aws.String(launchTemplateName),
+		Version:            aws.String(DefaultLaunchTemplateVersion),
+	}
+}
+
+func launchTemplateOverrides(instanceTypes common.InstanceTypesParams) (overrides []*autoscaling.LaunchTemplateOverrides) {
+	for _, instanceType := range instanceTypes.Types {
+		overrides = append(overrides, &autoscaling.LaunchTemplateOverrides{
+			InstanceType:     aws.String(instanceType.InstanceType),
+			WeightedCapacity: aws.String(strconv.FormatInt(instanceType.Weight, 10)),
+		})
+	}
+	return
+}
+
+func onDemandAllocationStrategy(instanceTypes common.InstanceTypesParams) string {
+	if instanceTypes.AllocationStrategy == "" {
+		return common.AllocationStrategyPrioritized
+	}
+	return instanceTypes.AllocationStrategy
+}
+
+// launchTemplate returns the launch template the auto scaling group launches on-demand instances of its instance type
+// with, or the mixed instances policy over it when part of the instances are spot or the group has several instance
+// types. Exactly one of them is not nil
+func launchTemplate(launchTemplateName string, spot common.SpotParams, instanceTypes common.InstanceTypesParams) (*autoscaling.LaunchTemplateSpecification, *autoscaling.MixedInstancesPolicy) {
+	if !spot.Enabled() && !instanceTypes.Enabled() {
+		return launchTemplateSpecification(launchTemplateName), nil
+	}
+	return nil, &autoscaling.MixedInstancesPolicy{
+		LaunchTemplate: &autoscaling.LaunchTemplate{
+			LaunchTemplateSpecification: launchTemplateSpecification(launchTemplateName),
+			Overrides:                   launchTemplateOverrides(instanceTypes),
+		},
+		InstancesDistribution: &autoscaling.InstancesDistribution{
+			OnDemandAllocationStrategy:          aws.String(onDemandAllocationStrategy(instanceTypes)),
+			OnDemandBaseCapacity:                aws.Int64(spot.OnDemandBase),
+			OnDemandPercentageAboveBaseCapacity: aws.Int64(100 - spot.Percentage),
+			SpotAllocationStrategy:              aws.String(SpotAllocationStrategy),
+		},
+	}
+}
+
+// GroupInstanceTypes returns the instance types the auto scaling group launches besides its launch template one
+func GroupInstanceTypes(asg *autoscaling.Group) (instanceTypes common.InstanceTypesParams) {
+	if asg.MixedInstancesPolicy == nil || asg.MixedInstancesPolicy.LaunchTemplate == nil {
+		return
+	}
+	for _, override := range asg.MixedInstancesPolicy.LaunchTemplate.Overrides {
+		weight := int64(1)
+		if override.WeightedCapacity != nil {
+			weight, _ = strconv.ParseInt(*override.WeightedCapacity, 10, 64)
+		}
+		instanceTypes.Types = append(instanceTypes.Types, common.InstanceTypeWeight{
+			InstanceType: aws.StringValue(override.InstanceType),
+			Weight:       weight,
+		})
+	}
+	if len(instanceTypes.Types) > 0 && asg.MixedInstancesPolicy.InstancesDistribution != nil {
+		instanceTypes.AllocationStrategy = aws.StringValue(asg.MixedInstancesPolicy.InstancesDistribution.OnDemandAllocationStrategy)
+	}
+	return
+}
+
+// GroupSpotParams returns the spot share the auto scaling group launches instances with
+func GroupSpotParams(asg *autoscaling.Group) (spot common.SpotParams) {
+	if asg.MixedInstancesPolicy == nil || asg.MixedInstancesPolicy.InstancesDistribution == nil {
+		return
+	}
+	distribution := asg.MixedInstancesPolicy.InstancesDistribution
+	spot.OnDemandBase = aws.Int64Value(distribution.OnDemandBaseCapacity)
+	if distribution.OnDemandPercentageAboveBaseCapacity != nil {
+		spot.Percentage = 100 - *distribution.OnDemandPercentageAboveBaseCapacity
+	}
+	return
+}
+
+// groupLaunchTemplate returns the launch template of the auto scaling group, whether it is mixed or not
+func groupLaunchTemplate(asg *autoscaling.Group) *autoscaling.LaunchTemplateSpecification {
+	if asg.MixedInstancesPolicy != nil && asg.MixedInstancesPolicy.LaunchTemplate != nil {
+		return asg.MixedInstancesPolicy.LaunchTemplate.LaunchTemplateSpecification
+	}
+	return asg.LaunchTemplate
+}
+
+func CreateAutoScalingGroup(tags []*autoscaling.Tag, launchTemplateName string, spot common.SpotParams, instanceTypes common.InstanceTypesParams, maxSize int64, autoScalingGroupName string) (err error) {
 	svc := connectors.GetAWSSession().ASG
+	launchTemplateSpec, mixedInstancesPolicy := launchTemplate(launchTemplateName, spot, instanceTypes)
 	input := &autoscaling.CreateAutoScalingGroupInput{
 		AutoScalingGroupName:             &autoScalingGroupName,
 		NewInstancesProtectedFromScaleIn: aws.Bool(true),
-		LaunchTemplate: &autoscaling.LaunchTemplateSpecification{
-			LaunchTemplateName: aws.String(launchTemplateName),
-			Version:            aws.String("1"),
-		},
-		MinSize: aws.Int64(0),
-		MaxSize: aws.Int64(maxSize),
-		Tags:    tags,
+		LaunchTemplate:                   launchTemplateSpec,
+		MixedInstancesPolicy:             mixedInstancesPolicy,
+		MinSize:                          aws.Int64(0),
+		MaxSize:                          aws.Int64(maxSize),
+		Tags:                             tags,
 	}
 	_, err = svc.CreateAutoScalingGroup(input)
 	if err != nil {
@@ -34,49 +149,233 @@ func CreateAutoScalingGroup(tags []*autoscaling.Tag, launchTemplateName string,
 	}
 	log.Debug().Msgf("AutoScalingGroup: \"%s\" was created successfully!", autoScalingGroupName)
 
-	log.Debug().Msgf("AutoScalingGroup: \"%s\" suspending ReplaceUnhealthy...", autoScalingGroupName)
+	err = putTerminationLifecycleHook(autoScalingGroupName)
+	if err != nil {
+		return
+	}
+	return suspendProcesses(autoScalingGroupName)
+}
+
+// putTerminationLifecycleHook makes terminations wait in Terminating:Wait, whether wekactl, an operator or the
+// auto scaling group itself started them, so the weka host is removed before its instance goes away
+func putTerminationLifecycleHook(autoScalingGroupName string) (err error) {
+	svc := connectors.GetAWSSession().ASG
+	_, err = svc.PutLifecycleHook(&autoscaling.PutLifecycleHookInput{
+		AutoScalingGroupName: &autoScalingGroupName,
+		LifecycleHookName:    aws.String(TerminationHookName),
+		LifecycleTransition:  aws.String("autoscaling:EC2_INSTANCE_TERMINATING"),
+		HeartbeatTimeout:     aws.Int64(int64(TerminationHookHeartbeatTimeout / time.Second)),
+		DefaultResult:        aws.String(lifecycleActionContinue),
+	})
+	if err != nil {
+		return
+	}
+	log.Debug().Msgf("AutoScalingGroup: \"%s\" termination lifecycle hook was set", autoScalingGroupName)
+	return
+}
+
+// CompleteTermination lets the termination of the instance continue, once its weka host was removed
+func CompleteTermination(autoScalingGroupName, instanceId string) error {
+	svc := connectors.GetAWSSession().ASG
+	_, err := svc.CompleteLifecycleAction(&autoscaling.CompleteLifecycleActionInput{
+		AutoScalingGroupName:  &autoScalingGroupName,
+		LifecycleHookName:     aws.String(TerminationHookName),
+		InstanceId:            &instanceId,
+		LifecycleActionResult: aws.String(lifecycleActionContinue),
+	})
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("instance %s termination completed", instanceId)
+	return nil
+}
+
+// ExtendTermination keeps the instance in Terminating:Wait for another heartbeat timeout
+func ExtendTermination(autoScalingGroupName, instanceId string) error {
+	svc := connectors.GetAWSSession().ASG
+	_, err := svc.RecordLifecycleActionHeartbeat(&autoscaling.RecordLifecycleActionHeartbeatInput{
+		AutoScalingGroupName: &autoScalingGroupName,
+		LifecycleHookName:    aws.String(TerminationHookName),
+		InstanceId:           &instanceId,
+	})
+	return err
+}
+
+// TerminateInstances starts terminating the auto scaling group instances, scale in protection doesn't apply to
+// them and the termination lifecycle hook holds them until their weka hosts are removed. The desired capacity is
+// kept, so they are replaced unless the group is scaling in
+func TerminateInstances(autoScalingGroupName string, instanceIds []string) (terminating []string, errs []error) {
+	svc := connectors.GetAWSSession().ASG
+	for _, instanceId := range instanceIds {
+		_, err := svc.TerminateInstanceInAutoScalingGroup(&autoscaling.TerminateInstanceInAutoScalingGroupInput{
+			InstanceId:                     aws.String(instanceId),
+			ShouldDecrementDesiredCapacity: aws.Bool(false),
+		})
+		if err != nil {
+			log.Error().Msgf("error terminating instance %s: %s", instanceId, err)
+			errs = append(errs, err)
+			continue
+		}
+		terminating = append(terminating, instanceId)
+	}
+	log.Info().Msgf("Terminating instances %s", terminating)
+	return
+}
+
+// ProtectFromScaleIn keeps the auto scaling group from picking the instances when its desired capacity is lowered,
+// the scale lambda picks them instead
+func ProtectFromScaleIn(autoScalingGroupName string, instanceIds []string) error {
+	svc := connectors.GetAWSSession().ASG
+	limit := 50
+	for i := 0; i < len(instanceIds); i += limit {
+		batch := instanceIds[i:common.Min(i+limit, len(instanceIds))]
+		_, err := svc.SetInstanceProtection(&autoscaling.SetInstanceProtectionInput{
+			AutoScalingGroupName: &autoScalingGroupName,
+			InstanceIds:          strings.ListToRefList(batch),
+			ProtectedFromScaleIn: aws.Bool(true),
+		})
+		if err != nil {
+			return err
+		}
+		log.Info().Msgf("protected %d instances of %s from scale in", len(batch), autoScalingGroupName)
+	}
+	return nil
+}
+
+func suspendProcesses(autoScalingGroupName string) (err error) {
+	svc := connectors.GetAWSSession().ASG
+	log.Debug().Msgf("AutoScalingGroup: \"%s\" suspending %s...", autoScalingGroupName, SuspendedProcesses)
 	_, err = svc.SuspendProcesses(&autoscaling.ScalingProcessQuery{
 		AutoScalingGroupName: &autoScalingGroupName,
-		ScalingProcesses : []*string{
-			aws.String("ReplaceUnhealthy"),
-		},
+		ScalingProcesses:     strings.ListToRefList(SuspendedProcesses),
 	})
+	return
+}
 
+// getAutoScalingGroup returns nil if the auto scaling group doesn't exist
+func getAutoScalingGroup(autoScalingGroupName string) (*autoscaling.Group, error) {
+	svc := connectors.GetAWSSession().ASG
+	asgOutput, err := svc.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{&autoScalingGroupName},
+	})
+	if err != nil || len(asgOutput.AutoScalingGroups) == 0 {
+		return nil, err
+	}
+	return asgOutput.AutoScalingGroups[0], nil
+}
+
+// UseDefaultLaunchTemplateVersion makes the auto scaling group follow the launch template default version,
+// it is a no-op if the auto scaling group doesn't exist yet
+func UseDefaultLaunchTemplateVersion(launchTemplateName, autoScalingGroupName string) error {
+	asg, err := getAutoScalingGroup(autoScalingGroupName)
+	if err != nil || asg == nil {
+		return err
+	}
+
+	svc := connectors.GetAWSSession().ASG
+	input := &autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName: &autoScalingGroupName,
+	}
+	// setting the launch template of a mixed group would drop its instances distribution and instance types
+	if asg.MixedInstancesPolicy != nil {
+		var overrides []*autoscaling.LaunchTemplateOverrides
+		if asg.MixedInstancesPolicy.LaunchTemplate != nil {
+			overrides = asg.MixedInstancesPolicy.LaunchTemplate.Overrides
+		}
+		input.MixedInstancesPolicy = &autoscaling.MixedInstancesPolicy{
+			LaunchTemplate: &autoscaling.LaunchTemplate{
+				LaunchTemplateSpecification: launchTemplateSpecification(launchTemplateName),
+				Overrides:                   overrides,
+			},
+		}
+	} else {
+		input.LaunchTemplate = launchTemplateSpecification(launchTemplateName)
+	}
+	_, err = svc.UpdateAutoScalingGroup(input)
+	if err != nil {
+		return err
+	}
+	log.Debug().Msgf("AutoScalingGroup: \"%s\" now uses %s default version", autoScalingGroupName, launchTemplateName)
+	return nil
+}
+
+func UpdateAutoScalingGroup(tags []*autoscaling.Tag, launchTemplateName string, spot common.SpotParams, instanceTypes common.InstanceTypesParams, maxSize int64, autoScalingGroupName string) (err error) {
+	svc := connectors.GetAWSSession().ASG
+	launchTemplateSpec, mixedInstancesPolicy := launchTemplate(launchTemplateName, spot, instanceTypes)
+	_, err = svc.UpdateAutoScalingGroup(&autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName:             &autoScalingGroupName,
+		NewInstancesProtectedFromScaleIn: aws.Bool(true),
+		LaunchTemplate:                   launchTemplateSpec,
+		MixedInstancesPolicy:             mixedInstancesPolicy,
+		MaxSize:                          aws.Int64(maxSize),
+	})
+	if err != nil {
+		return
+	}
+	log.Debug().Msgf("AutoScalingGroup: \"%s\" was updated successfully!", autoScalingGroupName)
+
+	err = putTerminationLifecycleHook(autoScalingGroupName)
+	if err != nil {
+		return
+	}
+
+	err = suspendProcesses(autoScalingGroupName)
+	if err != nil {
+		return
+	}
+
+	for _, tag := range tags {
+		tag.ResourceId = aws.String(autoScalingGroupName)
+		tag.ResourceType = aws.String("auto-scaling-group")
+	}
+	_, err = svc.CreateOrUpdateTags(&autoscaling.CreateOrUpdateTagsInput{
+		Tags: tags,
+	})
 	return
 }
 
-func AttachInstancesToASG(instancesIds []*string, autoScalingGroupsName string) error {
+// AttachInstancesToASG attaches the instances that are not attached yet, and returns the ones it attached
+func AttachInstancesToASG(instancesIds []*string, autoScalingGroupsName string) (attached []*string, err error) {
 	asgInstanceIds, err := common.GetAutoScalingGroupInstanceIds(autoScalingGroupsName)
 	if err != nil {
-		return err
+		return
 	}
 	instancesIds = common.GetDeltaInstancesIds(asgInstanceIds, instancesIds)
 	svc := connectors.GetAWSSession().ASG
 	limit := 20
 	for i := 0; i < len(instancesIds); i += limit {
 		batch := instancesIds[i:common.Min(i+limit, len(instancesIds))]
-		_, err := svc.AttachInstances(&autoscaling.AttachInstancesInput{
+		_, err = svc.AttachInstances(&autoscaling.AttachInstancesInput{
 			AutoScalingGroupName: &autoScalingGroupsName,
 			InstanceIds:          batch,
 		})
 		if err != nil {
-			return err
+			return
 		}
+		attached = append(attached, batch...)
 		log.Debug().Msgf("Attached %d instances to %s successfully!", len(batch), autoScalingGroupsName)
 	}
-	return nil
+	return
 }
 
-
 func DetachInstancesFromASG(instancesIds []*string, autoScalingGroupsName string) error {
+	return detachInstancesFromASG(instancesIds, autoScalingGroupsName, false)
+}
+
+// ReleaseInstancesFromASG detaches instances and decrements the desired capacity, so no replacements are launched
+func ReleaseInstancesFromASG(instancesIds []*string, autoScalingGroupsName string) error {
+	return detachInstancesFromASG(instancesIds, autoScalingGroupsName, true)
+}
+
+func detachInstancesFromASG(instancesIds []*string, autoScalingGroupsName string, decrementDesiredCapacity bool) error {
 	svc := connectors.GetAWSSession().ASG
 	limit := 20
 	for i := 0; i < len(instancesIds); i += limit {
 		batch := instancesIds[i:common.Min(i+limit, len(instancesIds))]
 		_, err := svc.DetachInstances(&autoscaling.DetachInstancesInput{
-			AutoScalingGroupName: &autoScalingGroupsName,
-			InstanceIds:          batch,
-			ShouldDecrementDesiredCapacity: aws.Bool(false),
+			AutoScalingGroupName:           &autoScalingGroupsName,
+			InstanceIds:                    batch,
+			ShouldDecrementDesiredCapacity: aws.Bool(decrementDesiredCapacity),
 		})
 		if err != nil {
 			return err
@@ -247,3 +546,315 @@ func GetAutoScalingGroupVersion(autoScalingGroupName string) (version string, er
 	}
 	return
 }
+
+// DiffAutoScalingGroup compares the auto scaling group with the attributes CreateAutoScalingGroup would set
+func DiffAutoScalingGroup(launchTemplateName string, spot common.SpotParams, instanceTypes common.InstanceTypesParams, autoscale common.AutoscaleParams, maxSize int64, autoScalingGroupName string) (drifts cluster.Drifts, err error) {
+	svc := connectors.GetAWSSession().ASG
+	asgOutput, err := svc.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{&autoScalingGroupName},
+	})
+	if err != nil || len(asgOutput.AutoScalingGroups) == 0 {
+		return
+	}
+	asg := asgOutput.AutoScalingGroups[0]
+
+	drifts.Compare("max_size", strconv.FormatInt(maxSize, 10), strconv.FormatInt(aws.Int64Value(asg.MaxSize), 10))
+	drifts.Compare("new_instances_protected_from_scale_in", "true", strconv.FormatBool(aws.BoolValue(asg.NewInstancesProtectedFromScaleIn)))
+
+	hooksOutput, err := svc.DescribeLifecycleHooks(&autoscaling.DescribeLifecycleHooksInput{
+		AutoScalingGroupName: &autoScalingGroupName,
+		LifecycleHookNames:   []*string{aws.String(TerminationHookName)},
+	})
+	if err != nil {
+		return
+	}
+	actualHookTransition := ""
+	for _, hook := range hooksOutput.LifecycleHooks {
+		actualHookTransition = aws.StringValue(hook.LifecycleTransition)
+	}
+	drifts.Compare("termination_lifecycle_hook", "autoscaling:EC2_INSTANCE_TERMINATING", actualHookTransition)
+
+	actualLaunchTemplateName, actualLaunchTemplateVersion := "", ""
+	if actualLaunchTemplate := groupLaunchTemplate(asg); actualLaunchTemplate != nil {
+		actualLaunchTemplateName = aws.StringValue(actualLaunchTemplate.LaunchTemplateName)
+		actualLaunchTemplateVersion = aws.StringValue(actualLaunchTemplate.Version)
+	}
+	drifts.Compare("launch_template", launchTemplateName, actualLaunchTemplateName)
+	drifts.Compare("launch_template_version", DefaultLaunchTemplateVersion, actualLaunchTemplateVersion)
+
+	actualSpot := GroupSpotParams(asg)
+	drifts.Compare("on_demand_base_capacity", strconv.FormatInt(spot.OnDemandBase, 10), strconv.FormatInt(actualSpot.OnDemandBase, 10))
+	drifts.Compare("spot_percentage", strconv.FormatInt(spot.Percentage, 10), strconv.FormatInt(actualSpot.Percentage, 10))
+
+	actualInstanceTypes := GroupInstanceTypes(asg)
+	drifts.Compare("instance_types", instanceTypeWeights(instanceTypes), instanceTypeWeights(actualInstanceTypes))
+	if instanceTypes.Enabled() {
+		drifts.Compare("on_demand_allocation_strategy", onDemandAllocationStrategy(instanceTypes), actualInstanceTypes.AllocationStrategy)
+	}
+
+	actualAutoscale, err := GroupAutoscale(asg)
+	if err != nil {
+		return
+	}
+	drifts.Compare("min_size", strconv.FormatInt(autoscale.MinSize, 10), strconv.FormatInt(aws.Int64Value(asg.MinSize), 10))
+	drifts.Compare("target_tracking_metric", autoscale.Metric, actualAutoscale.Metric)
+	drifts.Compare("target_tracking_value", strconv.FormatFloat(autoscale.Target, 'g', -1, 64), strconv.FormatFloat(actualAutoscale.Target, 'g', -1, 64))
+
+	var suspendedProcesses []string
+	for _, process := range asg.SuspendedProcesses {
+		suspendedProcesses = append(suspendedProcesses, aws.StringValue(process.ProcessName))
+	}
+	drifts.CompareList("suspended_processes", SuspendedProcesses, suspendedProcesses)
+	return
+}
+
+// instanceTypeWeights formats the instance types as type:weight, in their priority order
+func instanceTypeWeights(instanceTypes common.InstanceTypesParams) (weights string) {
+	for i, instanceType := range instanceTypes.Types {
+		if i > 0 {
+			weights += ","
+		}
+		weights += fmt.Sprintf("%s:%d", instanceType.InstanceType, instanceType.Weight)
+	}
+	return
+}
+
+// GetClusterAutoScalingGroups returns the names of all the auto scaling groups tagged with the cluster name
+func GetClusterAutoScalingGroups(clusterName cluster.ClusterName) (asgNames []string, err error) {
+	svc := connectors.GetAWSSession().ASG
+	err = svc.DescribeTagsPages(&autoscaling.DescribeTagsInput{
+		Filters: []*autoscaling.Filter{
+			{
+				Name:   aws.String("key"),
+				Values: []*string{aws.String(cluster.ClusterNameTagKey)},
+			},
+			{
+				Name:   aws.String("value"),
+				Values: []*string{aws.String(string(clusterName))},
+			},
+		},
+	}, func(page *autoscaling.DescribeTagsOutput, lastPage bool) bool {
+		for _, tag := range page.Tags {
+			asgNames = append(asgNames, *tag.ResourceId)
+		}
+		return true
+	})
+	return
+}
+
+// InstanceWeight returns the capacity units the instance counts for in the desired capacity, instances of groups
+// without weighted instance types count for one
+func InstanceWeight(instance *autoscaling.Instance) int64 {
+	if instance.WeightedCapacity == nil {
+		return 1
+	}
+	weight, err := strconv.ParseInt(*instance.WeightedCapacity, 10, 64)
+	if err != nil {
+		return 1
+	}
+	return weight
+}
+
+// GetAutoScalingGroupCapacity returns the auto scaling group desired capacity and the number of its instances
+func GetAutoScalingGroupCapacity(autoScalingGroupName string) (desired, actual int64, err error) {
+	svc := connectors.GetAWSSession().ASG
+	asgOutput, err := svc.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{&autoScalingGroupName},
+	})
+	if err != nil || len(asgOutput.AutoScalingGroups) == 0 {
+		return
+	}
+	asg := asgOutput.AutoScalingGroups[0]
+	return *asg.DesiredCapacity, int64(len(asg.Instances)), nil
+}
+
+// GetAutoScalingGroupMaxSize returns the auto scaling group max size
+func GetAutoScalingGroupMaxSize(autoScalingGroupName string) (maxSize int64, err error) {
+	svc := connectors.GetAWSSession().ASG
+	asgOutput, err := svc.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{&autoScalingGroupName},
+	})
+	if err != nil {
+		return
+	}
+	if len(asgOutput.AutoScalingGroups) == 0 {
+		err = errors.New(fmt.Sprintf("auto scaling group %s not found", autoScalingGroupName))
+		return
+	}
+	return *asgOutput.AutoScalingGroups[0].MaxSize, nil
+}
+
+// GetAutoScalingGroupMaxWeight returns the most capacity units a single instance of the auto scaling group counts
+// for in its desired capacity
+func GetAutoScalingGroupMaxWeight(autoScalingGroupName string) (maxWeight int64, err error) {
+	asg, err := getAutoScalingGroup(autoScalingGroupName)
+	if err != nil {
+		return
+	}
+	if asg == nil {
+		err = errors.New(fmt.Sprintf("auto scaling group %s not found", autoScalingGroupName))
+		return
+	}
+	maxWeight = 1
+	for _, instanceType := range GroupInstanceTypes(asg).Types {
+		if instanceType.Weight > maxWeight {
+			maxWeight = instanceType.Weight
+		}
+	}
+	return
+}
+
+// SetAutoScalingGroupSpot sets the spot share of the instances the auto scaling group launches from now on,
+// running instances are not replaced
+func SetAutoScalingGroupSpot(autoScalingGroupName string, spot common.SpotParams) error {
+	asg, err := getAutoScalingGroup(autoScalingGroupName)
+	if err != nil {
+		return err
+	}
+	if asg == nil || groupLaunchTemplate(asg) == nil {
+		return errors.New(fmt.Sprintf("auto scaling group %s not found", autoScalingGroupName))
+	}
+	return setLaunchTemplate(asg, spot, GroupInstanceTypes(asg))
+}
+
+// SetAutoScalingGroupInstanceTypes sets the instance types the auto scaling group launches from now on, running
+// instances are not replaced
+func SetAutoScalingGroupInstanceTypes(autoScalingGroupName string, instanceTypes common.InstanceTypesParams) error {
+	asg, err := getAutoScalingGroup(autoScalingGroupName)
+	if err != nil {
+		return err
+	}
+	if asg == nil || groupLaunchTemplate(asg) == nil {
+		return errors.New(fmt.Sprintf("auto scaling group %s not found", autoScalingGroupName))
+	}
+	return setLaunchTemplate(asg, GroupSpotParams(asg), instanceTypes)
+}
+
+func setLaunchTemplate(asg *autoscaling.Group, spot common.SpotParams, instanceTypes common.InstanceTypesParams) error {
+	launchTemplateSpec, mixedInstancesPolicy := launchTemplate(aws.StringValue(groupLaunchTemplate(asg).LaunchTemplateName), spot, instanceTypes)
+	svc := connectors.GetAWSSession().ASG
+	_, err := svc.UpdateAutoScalingGroup(&autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName: asg.AutoScalingGroupName,
+		LaunchTemplate:       launchTemplateSpec,
+		MixedInstancesPolicy: mixedInstancesPolicy,
+	})
+	return err
+}
+
+// SetAutoScalingGroupCapacity sets the auto scaling group desired capacity, and its max size unless it is 0
+func SetAutoScalingGroupCapacity(autoScalingGroupName string, desired, maxSize int64) error {
+	svc := connectors.GetAWSSession().ASG
+	input := &autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName: &autoScalingGroupName,
+		DesiredCapacity:      aws.Int64(desired),
+	}
+	if maxSize != 0 {
+		input.MaxSize = aws.Int64(maxSize)
+	}
+	_, err := svc.UpdateAutoScalingGroup(input)
+	if err != nil {
+		return err
+	}
+	log.Debug().Msgf("AutoScalingGroup: \"%s\" desired capacity was set to %d", autoScalingGroupName, desired)
+	return nil
+}
+
+// getTargetTrackingPolicy returns nil if the auto scaling group has no target tracking policy
+func getTargetTrackingPolicy(autoScalingGroupName string) (*autoscaling.ScalingPolicy, error) {
+	svc := connectors.GetAWSSession().ASG
+	policiesOutput, err := svc.DescribePolicies(&autoscaling.DescribePoliciesInput{
+		AutoScalingGroupName: &autoScalingGroupName,
+		PolicyNames:          []*string{aws.String(TargetTrackingPolicyName)},
+	})
+	if err != nil || len(policiesOutput.ScalingPolicies) == 0 {
+		return nil, err
+	}
+	return policiesOutput.ScalingPolicies[0], nil
+}
+
+// GroupAutoscale returns the metric target the auto scaling group tracks, and its min size
+func GroupAutoscale(asg *autoscaling.Group) (autoscale common.AutoscaleParams, err error) {
+	policy, err := getTargetTrackingPolicy(aws.StringValue(asg.AutoScalingGroupName))
+	if err != nil || policy == nil || policy.TargetTrackingConfiguration == nil {
+		return
+	}
+	configuration := policy.TargetTrackingConfiguration
+	if configuration.CustomizedMetricSpecification == nil {
+		return
+	}
+	autoscale.Metric = aws.StringValue(configuration.CustomizedMetricSpecification.MetricName)
+	autoscale.Target = aws.Float64Value(configuration.TargetValue)
+	autoscale.MinSize = aws.Int64Value(asg.MinSize)
+	return
+}
+
+// AutoscaleEnabled tells whether the auto scaling group tracks a metric target
+func AutoscaleEnabled(autoScalingGroupName string) (bool, error) {
+	policy, err := getTargetTrackingPolicy(autoScalingGroupName)
+	return policy != nil, err
+}
+
+// SetAutoScalingGroupAutoscale makes the auto scaling group track the metric target between its min size and the
+// given max size. Scale in terminates instances through the termination lifecycle hook, like any other termination
+func SetAutoScalingGroupAutoscale(autoScalingGroupName string, autoscale common.AutoscaleParams, maxSize int64) error {
+	svc := connectors.GetAWSSession().ASG
+	_, err := svc.UpdateAutoScalingGroup(&autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName: &autoScalingGroupName,
+		MinSize:              aws.Int64(autoscale.MinSize),
+		MaxSize:              aws.Int64(maxSize),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.PutScalingPolicy(&autoscaling.PutScalingPolicyInput{
+		AutoScalingGroupName:    &autoScalingGroupName,
+		PolicyName:              aws.String(TargetTrackingPolicyName),
+		PolicyType:              aws.String("TargetTrackingScaling"),
+		EstimatedInstanceWarmup: aws.Int64(int64(targetTrackingWarmup / time.Second)),
+		TargetTrackingConfiguration: &autoscaling.TargetTrackingConfiguration{
+			CustomizedMetricSpecification: &autoscaling.CustomizedMetricSpecification{
+				Namespace:  aws.String(common.MetricsNamespace),
+				MetricName: aws.String(autoscale.Metric),
+				Dimensions: []*autoscaling.MetricDimension{
+					{
+						Name:  aws.String(common.MetricsDimension),
+						Value: aws.String(autoScalingGroupName),
+					},
+				},
+				Statistic: aws.String(autoscaling.MetricStatisticAverage),
+				Unit:      aws.String(common.MetricUnits[autoscale.Metric]),
+			},
+			TargetValue: aws.Float64(autoscale.Target),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	log.Debug().Msgf("AutoScalingGroup: \"%s\" tracks %s target %g", autoScalingGroupName, autoscale.Metric, autoscale.Target)
+	return nil
+}
+
+// DeleteAutoScalingGroupAutoscale stops the auto scaling group from tracking a metric target, and sets its min size
+// back to 0. The desired capacity is left as is
+func DeleteAutoScalingGroupAutoscale(autoScalingGroupName string) error {
+	policy, err := getTargetTrackingPolicy(autoScalingGroupName)
+	if err != nil {
+		return err
+	}
+	svc := connectors.GetAWSSession().ASG
+	if policy != nil {
+		_, err = svc.DeletePolicy(&autoscaling.DeletePolicyInput{
+			AutoScalingGroupName: &autoScalingGroupName,
+			PolicyName:           aws.String(TargetTrackingPolicyName),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	_, err = svc.UpdateAutoScalingGroup(&autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName: &autoScalingGroupName,
+		MinSize:              aws.Int64(0),
+	})
+	return err
+}
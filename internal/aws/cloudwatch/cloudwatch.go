@@ -3,17 +3,23 @@ package cloudwatch
 import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/lambda"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"wekactl/internal/cluster"
 	"wekactl/internal/connectors"
 )
 
+const ScheduleExpression = "rate(1 minute)"
+
+// SpotInterruptionEventPattern matches the two minute warnings ec2 sends before it reclaims a spot instance
+const SpotInterruptionEventPattern = `{"source":["aws.ec2"],"detail-type":["EC2 Spot Instance Interruption Warning"]}`
+
 func CreateCloudWatchEventRule(tags []*cloudwatchevents.Tag, arn *string, roleArn, ruleName string) error {
 	svc := connectors.GetAWSSession().CloudWatchEvents
 	_, err := svc.PutRule(&cloudwatchevents.PutRuleInput{
 		Name:               &ruleName,
-		ScheduleExpression: aws.String("rate(1 minute)"),
+		ScheduleExpression: aws.String(ScheduleExpression),
 		State:              aws.String("ENABLED"),
 		Tags:               tags,
 	})
@@ -22,7 +28,12 @@ func CreateCloudWatchEventRule(tags []*cloudwatchevents.Tag, arn *string, roleAr
 	}
 	log.Debug().Msgf("cloudwatch rule %s was created successfully!", ruleName)
 
-	_, err = svc.PutTargets(&cloudwatchevents.PutTargetsInput{
+	return putTarget(arn, roleArn, ruleName)
+}
+
+func putTarget(arn *string, roleArn, ruleName string) error {
+	svc := connectors.GetAWSSession().CloudWatchEvents
+	_, err := svc.PutTargets(&cloudwatchevents.PutTargetsInput{
 		Rule: &ruleName,
 		Targets: []*cloudwatchevents.Target{
 			{
@@ -40,14 +51,32 @@ func CreateCloudWatchEventRule(tags []*cloudwatchevents.Tag, arn *string, roleAr
 	return nil
 }
 
-func DeleteCloudWatchEventRule(ruleName string) error {
+// putLambdaTarget sets the lambda as the rule target, lambdas are invoked through their resource policy rather than
+// a role
+func putLambdaTarget(lambdaArn, ruleName string) error {
+	svc := connectors.GetAWSSession().CloudWatchEvents
+	_, err := svc.PutTargets(&cloudwatchevents.PutTargetsInput{
+		Rule: &ruleName,
+		Targets: []*cloudwatchevents.Target{
+			{
+				Arn: aws.String(lambdaArn),
+				Id:  aws.String(uuid.New().String()),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	log.Debug().Msgf("cloudwatch lambda target was set successfully!")
+
+	return nil
+}
+
+func removeTargets(ruleName string) error {
 	svc := connectors.GetAWSSession().CloudWatchEvents
 
 	targetsOutput, err := svc.ListTargetsByRule(&cloudwatchevents.ListTargetsByRuleInput{Rule: &ruleName})
 	if err != nil {
-		if _, ok := err.(*cloudwatchevents.ResourceNotFoundException); ok {
-			return nil
-		}
 		return err
 	}
 
@@ -55,11 +84,52 @@ func DeleteCloudWatchEventRule(ruleName string) error {
 	for _, target := range targetsOutput.Targets {
 		targetIds = append(targetIds, target.Id)
 	}
+	if len(targetIds) == 0 {
+		return nil
+	}
 	_, err = svc.RemoveTargets(&cloudwatchevents.RemoveTargetsInput{Rule: &ruleName, Ids: targetIds})
+	return err
+}
+
+func UpdateCloudWatchEventRule(versionTag []*cloudwatchevents.Tag, arn *string, roleArn, ruleName string) error {
+	svc := connectors.GetAWSSession().CloudWatchEvents
+	ruleOutput, err := svc.PutRule(&cloudwatchevents.PutRuleInput{
+		Name:               &ruleName,
+		ScheduleExpression: aws.String(ScheduleExpression),
+		State:              aws.String("ENABLED"),
+	})
+	if err != nil {
+		return err
+	}
+	log.Debug().Msgf("cloudwatch rule %s was updated successfully!", ruleName)
+
+	err = removeTargets(ruleName)
+	if err != nil {
+		return err
+	}
+	err = putTarget(arn, roleArn, ruleName)
 	if err != nil {
 		return err
 	}
 
+	_, err = svc.TagResource(&cloudwatchevents.TagResourceInput{
+		ResourceARN: ruleOutput.RuleArn,
+		Tags:        versionTag,
+	})
+	return err
+}
+
+func DeleteCloudWatchEventRule(ruleName string) error {
+	svc := connectors.GetAWSSession().CloudWatchEvents
+
+	err := removeTargets(ruleName)
+	if err != nil {
+		if _, ok := err.(*cloudwatchevents.ResourceNotFoundException); ok {
+			return nil
+		}
+		return err
+	}
+
 	_, err = svc.DeleteRule(&cloudwatchevents.DeleteRuleInput{
 		Name: &ruleName,
 	})
@@ -99,3 +169,134 @@ func GetCloudWatchEventRuleVersion(ruleName string) (version string, err error)
 
 	return
 }
+
+// DiffCloudWatchEventRule compares the rule and its target with the ones CreateCloudWatchEventRule would set
+func DiffCloudWatchEventRule(arn *string, roleArn, ruleName string) (drifts cluster.Drifts, err error) {
+	svc := connectors.GetAWSSession().CloudWatchEvents
+	ruleOutput, err := svc.DescribeRule(&cloudwatchevents.DescribeRuleInput{Name: &ruleName})
+	if err != nil {
+		return
+	}
+	drifts.Compare("schedule_expression", ScheduleExpression, aws.StringValue(ruleOutput.ScheduleExpression))
+	drifts.Compare("state", cloudwatchevents.RuleStateEnabled, aws.StringValue(ruleOutput.State))
+
+	targetsOutput, err := svc.ListTargetsByRule(&cloudwatchevents.ListTargetsByRuleInput{Rule: &ruleName})
+	if err != nil {
+		return
+	}
+	var targetArns, targetRoleArns []string
+	for _, target := range targetsOutput.Targets {
+		targetArns = append(targetArns, aws.StringValue(target.Arn))
+		targetRoleArns = append(targetRoleArns, aws.StringValue(target.RoleArn))
+	}
+	drifts.CompareList("targets", []string{aws.StringValue(arn)}, targetArns)
+	drifts.CompareList("targets_role_arn", []string{roleArn}, targetRoleArns)
+	return
+}
+
+// CreateSpotInterruptionRule creates a rule that invokes the lambda on every spot instance interruption warning
+func CreateSpotInterruptionRule(tags []*cloudwatchevents.Tag, lambdaArn, lambdaName, ruleName string) error {
+	svc := connectors.GetAWSSession().CloudWatchEvents
+	ruleOutput, err := svc.PutRule(&cloudwatchevents.PutRuleInput{
+		Name:         &ruleName,
+		EventPattern: aws.String(SpotInterruptionEventPattern),
+		State:        aws.String("ENABLED"),
+		Tags:         tags,
+	})
+	if err != nil {
+		return err
+	}
+	log.Debug().Msgf("cloudwatch rule %s was created successfully!", ruleName)
+
+	err = putLambdaTarget(lambdaArn, ruleName)
+	if err != nil {
+		return err
+	}
+
+	_, err = connectors.GetAWSSession().Lambda.AddPermission(&lambda.AddPermissionInput{
+		FunctionName: aws.String(lambdaName),
+		StatementId:  aws.String(lambdaName + "-" + uuid.New().String()),
+		Action:       aws.String("lambda:InvokeFunction"),
+		Principal:    aws.String("events.amazonaws.com"),
+		SourceArn:    ruleOutput.RuleArn,
+	})
+	return err
+}
+
+func UpdateSpotInterruptionRule(versionTag []*cloudwatchevents.Tag, lambdaArn, ruleName string) error {
+	svc := connectors.GetAWSSession().CloudWatchEvents
+	ruleOutput, err := svc.PutRule(&cloudwatchevents.PutRuleInput{
+		Name:         &ruleName,
+		EventPattern: aws.String(SpotInterruptionEventPattern),
+		State:        aws.String("ENABLED"),
+	})
+	if err != nil {
+		return err
+	}
+	log.Debug().Msgf("cloudwatch rule %s was updated successfully!", ruleName)
+
+	err = removeTargets(ruleName)
+	if err != nil {
+		return err
+	}
+	err = putLambdaTarget(lambdaArn, ruleName)
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.TagResource(&cloudwatchevents.TagResourceInput{
+		ResourceARN: ruleOutput.RuleArn,
+		Tags:        versionTag,
+	})
+	return err
+}
+
+// DiffSpotInterruptionRule compares the rule and its target with the ones CreateSpotInterruptionRule would set
+func DiffSpotInterruptionRule(lambdaArn, ruleName string) (drifts cluster.Drifts, err error) {
+	svc := connectors.GetAWSSession().CloudWatchEvents
+	ruleOutput, err := svc.DescribeRule(&cloudwatchevents.DescribeRuleInput{Name: &ruleName})
+	if err != nil {
+		return
+	}
+	drifts.Compare("event_pattern", SpotInterruptionEventPattern, aws.StringValue(ruleOutput.EventPattern))
+	drifts.Compare("state", cloudwatchevents.RuleStateEnabled, aws.StringValue(ruleOutput.State))
+
+	targetsOutput, err := svc.ListTargetsByRule(&cloudwatchevents.ListTargetsByRuleInput{Rule: &ruleName})
+	if err != nil {
+		return
+	}
+	var targetArns []string
+	for _, target := range targetsOutput.Targets {
+		targetArns = append(targetArns, aws.StringValue(target.Arn))
+	}
+	drifts.CompareList("targets", []string{lambdaArn}, targetArns)
+	return
+}
+
+// GetClusterEventRules returns the names of all the rules tagged with the cluster name
+func GetClusterEventRules(clusterName cluster.ClusterName) (ruleNames []string, err error) {
+	svc := connectors.GetAWSSession().CloudWatchEvents
+	input := &cloudwatchevents.ListRulesInput{NamePrefix: aws.String("wekactl-")}
+	for {
+		rulesOutput, err := svc.ListRules(input)
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range rulesOutput.Rules {
+			tagsOutput, err := svc.ListTagsForResource(&cloudwatchevents.ListTagsForResourceInput{ResourceARN: rule.Arn})
+			if err != nil {
+				return nil, err
+			}
+			for _, tag := range tagsOutput.Tags {
+				if *tag.Key == cluster.ClusterNameTagKey && *tag.Value == string(clusterName) {
+					ruleNames = append(ruleNames, *rule.Name)
+					break
+				}
+			}
+		}
+		if rulesOutput.NextToken == nil {
+			return ruleNames, nil
+		}
+		input.NextToken = rulesOutput.NextToken
+	}
+}
@@ -18,6 +18,7 @@ type ApiGateway struct {
 	TableName      string
 	Version        string
 	ASGName        string
+	Inventory      *Inventory
 }
 
 func (a *ApiGateway) Tags() cluster.Tags {
@@ -35,6 +36,7 @@ func (a *ApiGateway) Init() {
 	a.Backend.Permissions = iam.GetJoinAndFetchLambdaPolicy()
 	a.Backend.Type = lambdas.LambdaJoin
 	a.Backend.ASGName = a.ASGName
+	a.Backend.Inventory = a.Inventory
 	a.Backend.Init()
 }
 
@@ -42,14 +44,29 @@ func (a *ApiGateway) ResourceName() string {
 	return common.GenerateResourceName(a.HostGroupInfo.ClusterName, a.HostGroupInfo.Name)
 }
 
+func (a *ApiGateway) inventoryName() string {
+	return a.ResourceName()
+}
+
+func (a *ApiGateway) inventoryId() string {
+	if a.RestApiGateway.Id == "" {
+		return a.ResourceName()
+	}
+	return a.RestApiGateway.Id
+}
+
 func (a *ApiGateway) Fetch() error {
-	version, err := apigateway.GetRestApiGatewayVersion(a.ResourceName())
-	if err != nil {
-		return err
+	if item, ok := a.Inventory.Get(a); ok {
+		a.Version = item.Version
+	} else {
+		version, err := apigateway.GetRestApiGatewayVersion(a.ResourceName())
+		if err != nil {
+			return err
+		}
+		a.Version = version
 	}
-	a.Version = version
 
-	if version != "" && !lambdas.InvokePolicyExists(a.Backend.ResourceName()) {
+	if a.Version != "" && !lambdas.InvokePolicyExists(a.Backend.ResourceName()) {
 		a.Version = "re-create"
 	}
 
@@ -63,6 +80,10 @@ func (a *ApiGateway) Fetch() error {
 	return nil
 }
 
+func (a *ApiGateway) Status() (cluster.ResourceStatus, error) {
+	return cluster.ResourceStatus{Id: a.inventoryId()}, nil
+}
+
 func (a *ApiGateway) DeployedVersion() string {
 	return a.Version
 }
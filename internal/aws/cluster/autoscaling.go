@@ -3,20 +3,23 @@ package cluster
 import (
 	"fmt"
 	"github.com/rs/zerolog/log"
+	"strconv"
 	"wekactl/internal/aws/autoscaling"
 	"wekactl/internal/aws/common"
 	"wekactl/internal/cluster"
 )
 
-const autoscalingVersion = "v1"
+const autoscalingVersion = "v3"
 
 type AutoscalingGroup struct {
 	HostGroupInfo          common.HostGroupInfo
 	HostGroupParams        common.HostGroupParams
 	LaunchTemplate         LaunchTemplate
 	ScaleMachineCloudWatch CloudWatch
+	SpotInterruption       SpotInterruption
 	TableName              string
 	Version                string
+	Inventory              *Inventory
 }
 
 func (a *AutoscalingGroup) Tags() cluster.Tags {
@@ -25,6 +28,9 @@ func (a *AutoscalingGroup) Tags() cluster.Tags {
 }
 
 func (a *AutoscalingGroup) SubResources() []cluster.Resource {
+	if a.HostGroupInfo.Role == common.RoleClient {
+		return []cluster.Resource{&a.LaunchTemplate, &a.ScaleMachineCloudWatch, &a.SpotInterruption}
+	}
 	return []cluster.Resource{&a.LaunchTemplate, &a.ScaleMachineCloudWatch}
 }
 
@@ -32,7 +38,20 @@ func (a *AutoscalingGroup) ResourceName() string {
 	return common.GenerateResourceName(a.HostGroupInfo.ClusterName, a.HostGroupInfo.Name)
 }
 
+func (a *AutoscalingGroup) inventoryName() string {
+	return a.ResourceName()
+}
+
+func (a *AutoscalingGroup) inventoryId() string {
+	return a.ResourceName()
+}
+
 func (a *AutoscalingGroup) Fetch() error {
+	if item, ok := a.Inventory.Get(a); ok {
+		a.Version = item.Version
+		return nil
+	}
+
 	version, err := autoscaling.GetAutoScalingGroupVersion(a.ResourceName())
 	if err != nil {
 		return err
@@ -41,6 +60,17 @@ func (a *AutoscalingGroup) Fetch() error {
 	return nil
 }
 
+func (a *AutoscalingGroup) Status() (status cluster.ResourceStatus, err error) {
+	status.Id = a.inventoryId()
+	desired, actual, err := autoscaling.GetAutoScalingGroupCapacity(a.ResourceName())
+	if err != nil {
+		return
+	}
+	status.AddDetail("desired", strconv.FormatInt(desired, 10))
+	status.AddDetail("instances", strconv.FormatInt(actual, 10))
+	return
+}
+
 func (a *AutoscalingGroup) DeployedVersion() string {
 	return a.Version
 }
@@ -55,11 +85,20 @@ func (a *AutoscalingGroup) Delete() error {
 
 func (a *AutoscalingGroup) Create() error {
 	return autoscaling.CreateAutoScalingGroup(
-		a.Tags().AsAsg(), a.LaunchTemplate.ResourceName(), a.HostGroupParams.MaxSize, a.ResourceName())
+		a.Tags().AsAsg(), a.LaunchTemplate.ResourceName(), a.HostGroupParams.Spot, a.HostGroupParams.InstanceTypes, a.HostGroupParams.MaxSize, a.ResourceName())
 }
 
 func (a *AutoscalingGroup) Update() error {
-	panic("update not supported")
+	err := autoscaling.UpdateAutoScalingGroup(
+		cluster.GetResourceVersionTag(a.TargetVersion()).AsAsg(), a.LaunchTemplate.ResourceName(), a.HostGroupParams.Spot, a.HostGroupParams.InstanceTypes, a.HostGroupParams.MaxSize, a.ResourceName())
+	if err != nil || !a.HostGroupParams.Autoscale.Enabled() {
+		return err
+	}
+	return autoscaling.SetAutoScalingGroupAutoscale(a.ResourceName(), a.HostGroupParams.Autoscale, a.HostGroupParams.MaxSize)
+}
+
+func (a *AutoscalingGroup) Diff() (cluster.Drifts, error) {
+	return autoscaling.DiffAutoScalingGroup(a.LaunchTemplate.ResourceName(), a.HostGroupParams.Spot, a.HostGroupParams.InstanceTypes, a.HostGroupParams.Autoscale, a.HostGroupParams.MaxSize, a.ResourceName())
 }
 
 func (a *AutoscalingGroup) Init() {
@@ -68,10 +107,18 @@ func (a *AutoscalingGroup) Init() {
 	a.LaunchTemplate.HostGroupParams = a.HostGroupParams
 	a.LaunchTemplate.TableName = a.TableName
 	a.LaunchTemplate.ASGName = a.ResourceName()
+	a.LaunchTemplate.Inventory = a.Inventory
 	a.LaunchTemplate.Init()
 	a.ScaleMachineCloudWatch.HostGroupInfo = a.HostGroupInfo
 	a.ScaleMachineCloudWatch.HostGroupParams = a.HostGroupParams
 	a.ScaleMachineCloudWatch.TableName = a.TableName
 	a.ScaleMachineCloudWatch.ASGName = a.ResourceName()
+	a.ScaleMachineCloudWatch.Inventory = a.Inventory
 	a.ScaleMachineCloudWatch.Init()
+	a.SpotInterruption.HostGroupInfo = a.HostGroupInfo
+	a.SpotInterruption.HostGroupParams = a.HostGroupParams
+	a.SpotInterruption.TableName = a.TableName
+	a.SpotInterruption.ASGName = a.ResourceName()
+	a.SpotInterruption.Inventory = a.Inventory
+	a.SpotInterruption.Init()
 }
@@ -20,6 +20,7 @@ type CloudWatch struct {
 	TableName       string
 	Version         string
 	ASGName         string
+	Inventory       *Inventory
 }
 
 func (c *CloudWatch) Tags() cluster.Tags {
@@ -34,12 +35,24 @@ func (c *CloudWatch) ResourceName() string {
 	return common.GenerateResourceName(c.HostGroupInfo.ClusterName, c.HostGroupInfo.Name)
 }
 
+func (c *CloudWatch) inventoryName() string {
+	return c.ResourceName()
+}
+
+func (c *CloudWatch) inventoryId() string {
+	return c.ResourceName()
+}
+
 func (c *CloudWatch) Fetch() error {
-	version, err := cloudwatch.GetCloudWatchEventRuleVersion(c.ResourceName())
-	if err != nil {
-		return err
+	if item, ok := c.Inventory.Get(c); ok {
+		c.Version = item.Version
+	} else {
+		version, err := cloudwatch.GetCloudWatchEventRuleVersion(c.ResourceName())
+		if err != nil {
+			return err
+		}
+		c.Version = version
 	}
-	c.Version = version
 
 	if c.ScaleMachine.Arn == "" {
 		scaleMachineArn, err := scalemachine.GetStateMachineArn(c.ScaleMachine.ResourceName())
@@ -49,17 +62,18 @@ func (c *CloudWatch) Fetch() error {
 		c.ScaleMachine.Arn = scaleMachineArn
 	}
 
-	if c.Profile.Arn == "" {
-		profileArn, err := iam.GetIamRoleArn(c.Profile.resourceNameBase())
-		if err != nil {
-			return err
-		}
-		c.Profile.Arn = profileArn
+	err := c.Profile.FetchArn()
+	if err != nil {
+		return err
 	}
 
 	return nil
 }
 
+func (c *CloudWatch) Status() (cluster.ResourceStatus, error) {
+	return cluster.ResourceStatus{Id: c.inventoryId()}, nil
+}
+
 func (c *CloudWatch) DeployedVersion() string {
 	return c.Version
 }
@@ -77,7 +91,12 @@ func (c *CloudWatch) Create() (err error) {
 }
 
 func (c *CloudWatch) Update() error {
-	panic("update not supported")
+	return cloudwatch.UpdateCloudWatchEventRule(
+		cluster.GetResourceVersionTag(c.TargetVersion()).AsCloudWatch(), &c.ScaleMachine.Arn, c.Profile.Arn, c.ResourceName())
+}
+
+func (c *CloudWatch) Diff() (cluster.Drifts, error) {
+	return cloudwatch.DiffCloudWatchEventRule(&c.ScaleMachine.Arn, c.Profile.Arn, c.ResourceName())
 }
 
 func (c *CloudWatch) Init() {
@@ -88,11 +107,13 @@ func (c *CloudWatch) Init() {
 	c.Profile.AssumeRolePolicy = iam.GetCloudWatchEventAssumeRolePolicy()
 	c.Profile.HostGroupInfo = c.HostGroupInfo
 	c.Profile.Policy = iam.GetCloudWatchEventRolePolicy()
+	c.Profile.Inventory = c.Inventory
 	c.Profile.Init()
 
 	c.ScaleMachine.TableName = c.TableName
 	c.ScaleMachine.HostGroupInfo = c.HostGroupInfo
 	c.ScaleMachine.HostGroupParams = c.HostGroupParams
 	c.ScaleMachine.ASGName = c.ASGName
+	c.ScaleMachine.Inventory = c.Inventory
 	c.ScaleMachine.Init()
 }
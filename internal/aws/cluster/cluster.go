@@ -1,8 +1,10 @@
 package cluster
 
 import (
+	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/rs/zerolog/log"
 	"strings"
+	"time"
 	"wekactl/internal/aws/common"
 	"wekactl/internal/aws/db"
 	"wekactl/internal/cluster"
@@ -15,7 +17,9 @@ type AWSCluster struct {
 	DefaultParams db.DefaultClusterParams
 	CFStack       Stack
 	DynamoDb      DynamoDb
+	HistoryDb     HistoryDb
 	HostGroups    []HostGroup
+	Inventory     *Inventory
 }
 
 func (c *AWSCluster) Tags() cluster.Tags {
@@ -23,7 +27,7 @@ func (c *AWSCluster) Tags() cluster.Tags {
 }
 
 func (c *AWSCluster) SubResources() []cluster.Resource {
-	resources := []cluster.Resource{&c.DynamoDb}
+	resources := []cluster.Resource{&c.DynamoDb, &c.HistoryDb}
 	for i := range c.HostGroups {
 		resources = append(resources, &c.HostGroups[i])
 	}
@@ -49,14 +53,37 @@ func (c *AWSCluster) Fetch() error {
 
 func (c *AWSCluster) Init() {
 	log.Debug().Msgf("Initializing cluster %s ...", string(c.Name))
+	c.DynamoDb.DefaultParams = c.DefaultParams
+	c.DynamoDb.Inventory = c.Inventory
 	c.DynamoDb.Init()
+	c.HistoryDb.ClusterName = c.Name
+	c.HistoryDb.Inventory = c.Inventory
+	c.HistoryDb.Init()
 	for i := range c.HostGroups {
 		c.HostGroups[i].TableName = c.DynamoDb.ResourceName()
+		c.HostGroups[i].Inventory = c.Inventory
 		c.HostGroups[i].Init()
 	}
 	return
 }
 
+func (c *AWSCluster) Status() (status cluster.ResourceStatus, err error) {
+	status.Id = c.CFStack.StackId
+	pause, err := db.GetScalingPause(c.DynamoDb.ResourceName())
+	if _, ok := err.(*dynamodb.ResourceNotFoundException); ok {
+		return status, nil
+	}
+	if err != nil {
+		return
+	}
+	if pause.Active(time.Now()) {
+		status.AddDetail("scaling", pause.String())
+	} else {
+		status.AddDetail("scaling", "active")
+	}
+	return
+}
+
 func (c *AWSCluster) DeployedVersion() string {
 	return ""
 }
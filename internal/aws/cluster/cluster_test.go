@@ -0,0 +1,709 @@
+package cluster
+
+import (
+	"encoding/json"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	autoscaling2 "wekactl/internal/aws/autoscaling"
+	"wekactl/internal/aws/common"
+	"wekactl/internal/aws/db"
+	"wekactl/internal/aws/dist"
+	"wekactl/internal/aws/fake"
+	"wekactl/internal/aws/lambdas"
+	"wekactl/internal/aws/scalemachine"
+	"wekactl/internal/cluster"
+	"wekactl/internal/connectors"
+	"wekactl/internal/env"
+	"wekactl/internal/provider"
+)
+
+const testStackName = "test-cluster"
+
+func setupFakeStack(t *testing.T) *fake.AWS {
+	t.Helper()
+	env.Config.Region = fake.Region
+	dist.LambdasSource[fake.Region] = "weka-lambdas"
+	dist.LambdasID = "v1"
+	a := fake.New().Install()
+	a.AddStack(testStackName, 3, 2)
+	return a
+}
+
+func getClusterGroups(t *testing.T, a *fake.AWS) []*autoscaling.Group {
+	t.Helper()
+	output, err := a.ASG.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output.AutoScalingGroups
+}
+
+func TestImportUpdateDestroyCluster(t *testing.T) {
+	a := setupFakeStack(t)
+
+	err := ImportCluster(testStackName, "admin", "password", nil)
+	if err != nil {
+		t.Fatalf("ImportCluster() error = %v", err)
+	}
+
+	groups := getClusterGroups(t, a)
+	if len(groups) != 2 {
+		t.Fatalf("ImportCluster() created %d auto scaling groups, want 2", len(groups))
+	}
+	var instanceIds []string
+	for _, group := range groups {
+		for _, instance := range group.Instances {
+			instanceIds = append(instanceIds, *instance.InstanceId)
+			if !a.EC2.DisableApiTermination(*instance.InstanceId) {
+				t.Errorf("instance %s api termination is not disabled", *instance.InstanceId)
+			}
+		}
+	}
+	if len(instanceIds) != 5 {
+		t.Errorf("ImportCluster() attached %d instances, want 5", len(instanceIds))
+	}
+
+	awsCluster := generateExistingAWSCluster(testStackName)
+	err = awsCluster.LoadInventory()
+	if err != nil {
+		t.Fatalf("LoadInventory() error = %v", err)
+	}
+	if len(awsCluster.Inventory.Items()) == 0 {
+		t.Error("ImportCluster() recorded an empty inventory")
+	}
+
+	dist.LambdasID = "v2"
+	err = UpdateCluster(testStackName)
+	if err != nil {
+		t.Fatalf("UpdateCluster() error = %v", err)
+	}
+	functions := 0
+	err = a.Lambda.ListFunctionsPages(&lambda.ListFunctionsInput{}, func(page *lambda.ListFunctionsOutput, lastPage bool) bool {
+		for _, function := range page.Functions {
+			functions++
+			tags, err := a.Lambda.ListTags(&lambda.ListTagsInput{Resource: function.FunctionArn})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version := aws.StringValue(tags.Tags[cluster.VersionTagKey]); !strings.HasPrefix(version, "v2") {
+				t.Errorf("lambda %s version = %s, want v2 prefix", *function.FunctionName, version)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if functions == 0 {
+		t.Error("ImportCluster() created no lambdas")
+	}
+
+	err = DestroyCluster(testStackName, true)
+	if err != nil {
+		t.Fatalf("DestroyCluster() error = %v", err)
+	}
+	if groups := getClusterGroups(t, a); len(groups) != 0 {
+		t.Errorf("DestroyCluster() left %d auto scaling groups", len(groups))
+	}
+	resources, err := DiscoverTaggedResources(cluster.ClusterName(testStackName))
+	if err != nil {
+		t.Fatalf("DiscoverTaggedResources() error = %v", err)
+	}
+	for _, resource := range resources {
+		t.Errorf("DestroyCluster() left %s %s", resource.Type, resource.Name)
+	}
+	for _, instanceId := range instanceIds {
+		if state := *a.EC2.Instance(instanceId).State.Name; state != ec2.InstanceStateNameRunning {
+			t.Errorf("DestroyCluster() changed kept instance %s state to %s", instanceId, state)
+		}
+	}
+}
+
+func TestUpdateSavesClusterParams(t *testing.T) {
+	a := setupFakeStack(t)
+	err := ImportCluster(testStackName, "admin", "password", nil)
+	if err != nil {
+		t.Fatalf("ImportCluster() error = %v", err)
+	}
+	// clusters imported before the params were saved have none
+	tableName := common.GenerateResourceName(cluster.ClusterName(testStackName), "")
+	_, err = a.DynamoDB.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(tableName),
+		Key:       map[string]*dynamodb.AttributeValue{"Key": {S: aws.String(db.ModelDefaultClusterParams)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	awsCluster, err := generateUpdateAWSCluster(testStackName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = awsCluster.LoadInventory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	awsCluster.Init()
+	// the credentials change along with the params
+	awsCluster.DynamoDb.Username = "admin2"
+	awsCluster.DynamoDb.Password = "password2"
+	err = awsCluster.DynamoDb.Update()
+	if err != nil {
+		t.Fatalf("DynamoDb.Update() error = %v", err)
+	}
+	var params db.DefaultClusterParams
+	err = db.GetItem(tableName, db.ModelDefaultClusterParams, &params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Key == "" || params.Backends.InstanceType != "i3en.2xlarge" || params.Clients.InstanceType != "r5.large" {
+		t.Errorf("DynamoDb.Update() saved params %+v, want the deployed host groups params", params)
+	}
+	var creds db.ClusterCreds
+	err = db.GetItem(tableName, db.ModelClusterCreds, &creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.Username != "admin2" || creds.Password != "password2" {
+		t.Errorf("DynamoDb.Update() saved credentials %s/%s, want admin2/password2", creds.Username, creds.Password)
+	}
+}
+
+// countingIAM counts role listings, the prefix scans that find roles without the inventory
+type countingIAM struct {
+	*fake.IAM
+	listRoles int
+}
+
+func (c *countingIAM) ListRoles(input *iam.ListRolesInput) (*iam.ListRolesOutput, error) {
+	c.listRoles++
+	return c.IAM.ListRoles(input)
+}
+
+func TestFetchProfilesFromInventory(t *testing.T) {
+	a := setupFakeStack(t)
+	err := ImportCluster(testStackName, "admin", "password", nil)
+	if err != nil {
+		t.Fatalf("ImportCluster() error = %v", err)
+	}
+
+	awsCluster := generateExistingAWSCluster(testStackName)
+	err = awsCluster.LoadInventory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	awsCluster.Init()
+	cloudWatch := &awsCluster.HostGroups[0].AutoscalingGroup.ScaleMachineCloudWatch
+	scaleMachine := &cloudWatch.ScaleMachine
+
+	roles := &countingIAM{IAM: a.IAM}
+	connectors.GetAWSSession().IAM = roles
+	for _, r := range []cluster.Resource{&scaleMachine.scale, scaleMachine, cloudWatch} {
+		err = r.Fetch()
+		if err != nil {
+			t.Fatalf("%s Fetch() error = %v", cluster.ResourceType(r), err)
+		}
+	}
+	if roles.listRoles != 0 {
+		t.Errorf("Fetch() listed roles %d times, want the role arns from the inventory", roles.listRoles)
+	}
+	for _, profile := range []IamProfile{scaleMachine.scale.Profile, scaleMachine.Profile, cloudWatch.Profile} {
+		if !strings.HasPrefix(profile.Arn, "arn:") {
+			t.Errorf("Fetch() profile %s arn = %q", profile.Name, profile.Arn)
+		}
+	}
+}
+
+func TestScalingPolicy(t *testing.T) {
+	setupFakeStack(t)
+	err := ImportCluster(testStackName, "admin", "password", nil)
+	if err != nil {
+		t.Fatalf("ImportCluster() error = %v", err)
+	}
+
+	p := Provider{}
+	policy, err := p.GetScalingPolicy(testStackName, "Backends")
+	if err != nil {
+		t.Fatalf("GetScalingPolicy() error = %v", err)
+	}
+	if policy != cluster.DefaultScalingPolicy() {
+		t.Errorf("GetScalingPolicy() = %+v, want the default policy", policy)
+	}
+
+	policy.MaxUnhealthyDeactivating = 4
+	err = p.SetScalingPolicy(testStackName, "Backends", policy)
+	if err != nil {
+		t.Fatalf("SetScalingPolicy() error = %v", err)
+	}
+	if got, _ := p.GetScalingPolicy(testStackName, "Backends"); got != policy {
+		t.Errorf("GetScalingPolicy() = %+v, want %+v", got, policy)
+	}
+	if got, _ := p.GetScalingPolicy(testStackName, "Clients"); got != cluster.DefaultScalingPolicy() {
+		t.Errorf("GetScalingPolicy() of another host group = %+v, want the default policy", got)
+	}
+
+	policy.MaxUnhealthyDeactivating = 0
+	if err = p.SetScalingPolicy(testStackName, "Backends", policy); err == nil {
+		t.Error("SetScalingPolicy() of an invalid policy succeeded")
+	}
+	if _, err = p.GetScalingPolicy(testStackName, "Unknown"); err == nil {
+		t.Error("GetScalingPolicy() of an unknown host group succeeded")
+	}
+}
+
+func scalingStatus(tree cluster.ResourceStatusTree) string {
+	for _, detail := range tree.Details {
+		if detail.Key == "scaling" {
+			return detail.Value
+		}
+	}
+	return ""
+}
+
+func TestScalingPause(t *testing.T) {
+	setupFakeStack(t)
+	err := ImportCluster(testStackName, "admin", "password", nil)
+	if err != nil {
+		t.Fatalf("ImportCluster() error = %v", err)
+	}
+
+	p := Provider{}
+	tableName := common.GenerateResourceName(cluster.ClusterName(testStackName), "")
+	if err = p.PauseScaling(testStackName, time.Now().Add(-time.Minute), ""); err == nil {
+		t.Error("PauseScaling() until a past time succeeded")
+	}
+	until := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	if err = p.PauseScaling(testStackName, until, "maintenance"); err != nil {
+		t.Fatalf("PauseScaling() error = %v", err)
+	}
+	pause, err := db.GetScalingPause(tableName)
+	if err != nil {
+		t.Fatalf("GetScalingPause() error = %v", err)
+	}
+	if !pause.Active(time.Now()) || pause.Active(until) || !pause.Until.Equal(until) {
+		t.Errorf("GetScalingPause() = %+v, want a pause until %s", pause, until)
+	}
+	if !strings.HasSuffix(pause.PausedBy, ":user/wekactl") || pause.Reason != "maintenance" {
+		t.Errorf("GetScalingPause() = %+v, want a pause by the caller for maintenance", pause)
+	}
+
+	tree, err := p.StatusCluster(testStackName)
+	if err != nil {
+		t.Fatalf("StatusCluster() error = %v", err)
+	}
+	if status := scalingStatus(tree); status != pause.String() {
+		t.Errorf("StatusCluster() scaling = %q, want %q", status, pause.String())
+	}
+
+	if err = p.ResumeScaling(testStackName); err != nil {
+		t.Fatalf("ResumeScaling() error = %v", err)
+	}
+	if pause, _ = db.GetScalingPause(tableName); pause.Active(time.Now()) {
+		t.Errorf("GetScalingPause() after ResumeScaling() = %+v, want no pause", pause)
+	}
+	tree, err = p.StatusCluster(testStackName)
+	if err != nil {
+		t.Fatalf("StatusCluster() error = %v", err)
+	}
+	if status := scalingStatus(tree); status != "active" {
+		t.Errorf("StatusCluster() scaling = %q, want active", status)
+	}
+}
+
+func TestScaleHostGroup(t *testing.T) {
+	a := setupFakeStack(t)
+	err := ImportCluster(testStackName, "admin", "password", nil)
+	if err != nil {
+		t.Fatalf("ImportCluster() error = %v", err)
+	}
+
+	p := Provider{}
+	if err = p.ScaleHostGroup(testStackName, "Backends", 5, false); err == nil {
+		t.Error("ScaleHostGroup() below the minimum backends succeeded")
+	}
+	if err = p.ScaleHostGroup(testStackName, "Backends", 100, false); err == nil {
+		t.Error("ScaleHostGroup() above the max size succeeded")
+	}
+	if err = p.ScaleHostGroup(testStackName, "Missing", 10, false); err == nil {
+		t.Error("ScaleHostGroup() of a missing host group succeeded")
+	}
+	if err = p.ScaleHostGroup(testStackName, "Clients", 1, false); err != nil {
+		t.Errorf("ScaleHostGroup() error = %v", err)
+	}
+	if err = p.ScaleHostGroup(testStackName, "Backends", 100, true); err != nil {
+		t.Fatalf("ScaleHostGroup() error = %v", err)
+	}
+
+	capacity := map[string][2]int64{}
+	for _, group := range getClusterGroups(t, a) {
+		capacity[aws.StringValue(group.AutoScalingGroupName)] = [2]int64{aws.Int64Value(group.DesiredCapacity), aws.Int64Value(group.MaxSize)}
+	}
+	backends := common.GenerateResourceName(cluster.ClusterName(testStackName), "Backends")
+	clients := common.GenerateResourceName(cluster.ClusterName(testStackName), "Clients")
+	if capacity[backends] != [2]int64{100, 100} || capacity[clients][0] != 1 {
+		t.Errorf("ScaleHostGroup() capacity = %v, want backends desired and max size 100 and clients desired 1", capacity)
+	}
+	params, err := db.GetClusterParams(common.GenerateResourceName(cluster.ClusterName(testStackName), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Backends.MaxSize != 100 {
+		t.Errorf("saved backends max size = %d, want 100", params.Backends.MaxSize)
+	}
+}
+
+func findResourceDiffs(diff cluster.ResourceDiff, resourceType string) (diffs []cluster.ResourceDiff) {
+	if diff.Type == resourceType {
+		diffs = append(diffs, diff)
+	}
+	for _, subResource := range diff.SubResources {
+		diffs = append(diffs, findResourceDiffs(subResource, resourceType)...)
+	}
+	return
+}
+
+func TestSpotHostGroup(t *testing.T) {
+	a := setupFakeStack(t)
+	err := ImportCluster(testStackName, "admin", "password", nil)
+	if err != nil {
+		t.Fatalf("ImportCluster() error = %v", err)
+	}
+
+	p := Provider{}
+	if err = p.SetHostGroupSpot(testStackName, "Backends", 0, 50); err == nil {
+		t.Error("SetHostGroupSpot() of a backends host group succeeded")
+	}
+	if err = p.SetHostGroupSpot(testStackName, "Clients", 0, 150); err == nil {
+		t.Error("SetHostGroupSpot() above 100 percent succeeded")
+	}
+	if err = p.SetHostGroupSpot(testStackName, "Clients", 1, 70); err != nil {
+		t.Fatalf("SetHostGroupSpot() error = %v", err)
+	}
+
+	clients := common.GenerateResourceName(cluster.ClusterName(testStackName), "Clients")
+	for _, group := range getClusterGroups(t, a) {
+		if aws.StringValue(group.AutoScalingGroupName) != clients {
+			continue
+		}
+		if group.MixedInstancesPolicy == nil || group.LaunchTemplate != nil {
+			t.Fatalf("SetHostGroupSpot() didn't replace the launch template with a mixed instances policy")
+		}
+		distribution := group.MixedInstancesPolicy.InstancesDistribution
+		if aws.Int64Value(distribution.OnDemandBaseCapacity) != 1 || aws.Int64Value(distribution.OnDemandPercentageAboveBaseCapacity) != 30 {
+			t.Errorf("SetHostGroupSpot() instances distribution = %v, want 1 on-demand base and 30 percent on-demand", distribution)
+		}
+	}
+	params, err := db.GetClusterParams(common.GenerateResourceName(cluster.ClusterName(testStackName), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Clients.Spot != (common.SpotParams{OnDemandBase: 1, Percentage: 70}) {
+		t.Errorf("saved clients spot = %+v, want 1 on-demand base and 70 percent", params.Clients.Spot)
+	}
+
+	// the launch template update keeps the mixed instances policy, and the spot interruption rule is in place
+	dist.LambdasID = "v2"
+	err = UpdateCluster(testStackName)
+	if err != nil {
+		t.Fatalf("UpdateCluster() error = %v", err)
+	}
+	diff, err := DiffCluster(testStackName)
+	if err != nil {
+		t.Fatalf("DiffCluster() error = %v", err)
+	}
+	for _, resourceType := range []string{"AutoscalingGroup", "SpotInterruption"} {
+		diffs := findResourceDiffs(diff, resourceType)
+		if resourceType == "SpotInterruption" && len(diffs) != 1 {
+			t.Errorf("DiffCluster() has %d spot interruption rules, want 1 of the clients", len(diffs))
+		}
+		for _, resourceDiff := range diffs {
+			if resourceDiff.Missing || len(resourceDiff.Drifts) != 0 {
+				t.Errorf("%s %s drifted: missing %t, %+v", resourceType, resourceDiff.Name, resourceDiff.Missing, resourceDiff.Drifts)
+			}
+		}
+	}
+}
+
+func TestHostGroupInstanceTypes(t *testing.T) {
+	a := setupFakeStack(t)
+	err := ImportCluster(testStackName, "admin", "password", nil)
+	if err != nil {
+		t.Fatalf("ImportCluster() error = %v", err)
+	}
+
+	p := Provider{}
+	invalid := map[string][]provider.InstanceTypeWeight{
+		"zero weight": {{InstanceType: "i3en.2xlarge", Weight: 0}},
+		"duplicate":   {{InstanceType: "i3en.2xlarge", Weight: 1}, {InstanceType: "i3en.2xlarge", Weight: 2}},
+	}
+	for name, instanceTypes := range invalid {
+		if err = p.SetHostGroupInstanceTypes(testStackName, "Backends", instanceTypes, ""); err == nil {
+			t.Errorf("SetHostGroupInstanceTypes() with %s succeeded", name)
+		}
+	}
+	instanceTypes := []provider.InstanceTypeWeight{{InstanceType: "i3en.2xlarge", Weight: 1}, {InstanceType: "i3en.3xlarge", Weight: 2}}
+	if err = p.SetHostGroupInstanceTypes(testStackName, "Backends", instanceTypes, "cheapest"); err == nil {
+		t.Error("SetHostGroupInstanceTypes() with an unknown allocation strategy succeeded")
+	}
+	if err = p.SetHostGroupInstanceTypes(testStackName, "Backends", instanceTypes, common.AllocationStrategyLowestPrice); err != nil {
+		t.Fatalf("SetHostGroupInstanceTypes() error = %v", err)
+	}
+
+	backends := common.GenerateResourceName(cluster.ClusterName(testStackName), "Backends")
+	getBackends := func() *autoscaling.Group {
+		for _, group := range getClusterGroups(t, a) {
+			if aws.StringValue(group.AutoScalingGroupName) == backends {
+				return group
+			}
+		}
+		t.Fatalf("auto scaling group %s not found", backends)
+		return nil
+	}
+	want := common.InstanceTypesParams{
+		Types:              []common.InstanceTypeWeight{{InstanceType: "i3en.2xlarge", Weight: 1}, {InstanceType: "i3en.3xlarge", Weight: 2}},
+		AllocationStrategy: common.AllocationStrategyLowestPrice,
+	}
+	if actual := autoscaling2.GroupInstanceTypes(getBackends()); !reflect.DeepEqual(actual, want) {
+		t.Errorf("SetHostGroupInstanceTypes() auto scaling group instance types = %+v, want %+v", actual, want)
+	}
+	params, err := db.GetClusterParams(common.GenerateResourceName(cluster.ClusterName(testStackName), ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(params.Backends.InstanceTypes, want) {
+		t.Errorf("saved backends instance types = %+v, want %+v", params.Backends.InstanceTypes, want)
+	}
+	if err = p.ScaleHostGroup(testStackName, "Backends", 10, true); err == nil {
+		t.Error("ScaleHostGroup() below the minimum backends of weight 2 succeeded")
+	}
+	if err = p.ScaleHostGroup(testStackName, "Backends", 12, true); err != nil {
+		t.Errorf("ScaleHostGroup() error = %v", err)
+	}
+
+	// the launch template update keeps the instance types
+	dist.LambdasID = "v2"
+	err = UpdateCluster(testStackName)
+	if err != nil {
+		t.Fatalf("UpdateCluster() error = %v", err)
+	}
+	diff, err := DiffCluster(testStackName)
+	if err != nil {
+		t.Fatalf("DiffCluster() error = %v", err)
+	}
+	for _, resourceDiff := range findResourceDiffs(diff, "AutoscalingGroup") {
+		if resourceDiff.Missing || len(resourceDiff.Drifts) != 0 {
+			t.Errorf("AutoscalingGroup %s drifted: missing %t, %+v", resourceDiff.Name, resourceDiff.Missing, resourceDiff.Drifts)
+		}
+	}
+
+	if err = p.SetHostGroupInstanceTypes(testStackName, "Backends", nil, ""); err != nil {
+		t.Fatalf("SetHostGroupInstanceTypes() error = %v", err)
+	}
+	if group := getBackends(); group.MixedInstancesPolicy != nil || group.LaunchTemplate == nil {
+		t.Error("SetHostGroupInstanceTypes() without instance types didn't go back to the launch template")
+	}
+}
+
+func TestImportInstanceTypes(t *testing.T) {
+	instances := []*ec2.Instance{
+		{InstanceType: aws.String("r5.large")},
+		{InstanceType: aws.String("r5.xlarge")},
+		{InstanceType: aws.String("r5.large")},
+	}
+	want := common.InstanceTypesParams{
+		Types:              []common.InstanceTypeWeight{{InstanceType: "r5.large", Weight: 1}, {InstanceType: "r5.xlarge", Weight: 1}},
+		AllocationStrategy: common.AllocationStrategyPrioritized,
+	}
+	if actual := importInstanceTypes(instances); !reflect.DeepEqual(actual, want) {
+		t.Errorf("importInstanceTypes() = %+v, want %+v", actual, want)
+	}
+	if actual := importInstanceTypes(instances[:1]); actual.Enabled() {
+		t.Errorf("importInstanceTypes() of a single type = %+v, want none", actual)
+	}
+}
+
+func TestBackendCoreCounts(t *testing.T) {
+	setupFakeStack(t)
+	err := ImportCluster(testStackName, "admin", "password", nil)
+	if err != nil {
+		t.Fatalf("ImportCluster() error = %v", err)
+	}
+	tableName := common.GenerateResourceName(cluster.ClusterName(testStackName), "")
+
+	counts, err := lambdas.GetBackendCoreCounts(tableName, "i3en.2xlarge")
+	if err != nil {
+		t.Fatalf("GetBackendCoreCounts() error = %v", err)
+	}
+	if want := (cluster.CoreCounts{Total: 3, Frontend: 1, Drive: 1}); counts != want {
+		t.Errorf("GetBackendCoreCounts() = %+v, want %+v", counts, want)
+	}
+	for _, instanceType := range []string{"r5.large", "x9.unknown", ""} {
+		if _, err = lambdas.GetBackendCoreCounts(tableName, instanceType); err == nil {
+			t.Errorf("GetBackendCoreCounts(%q) succeeded", instanceType)
+		}
+	}
+	// the instance types of the former core counts table join with the same cores
+	table := map[string]cluster.CoreCounts{
+		"r3.large":      {Total: 1},
+		"r3.2xlarge":    {Total: 3, Frontend: 1, Drive: 1},
+		"r3.8xlarge":    {Total: 7, Frontend: 1, Drive: 2},
+		"i3.16xlarge":   {Total: 14, Frontend: 1, Drive: 4},
+		"i3en.3xlarge":  {Total: 3, Frontend: 1, Drive: 1},
+		"i3en.6xlarge":  {Total: 7, Frontend: 1, Drive: 2},
+		"i3en.12xlarge": {Total: 7, Frontend: 1, Drive: 2},
+		"i3en.24xlarge": {Total: 14, Frontend: 1, Drive: 4},
+	}
+	for instanceType, want := range table {
+		if counts, err = lambdas.GetBackendCoreCounts(tableName, instanceType); err != nil || counts != want {
+			t.Errorf("GetBackendCoreCounts(%q) = %+v, %v, want %+v", instanceType, counts, err, want)
+		}
+	}
+
+	p := Provider{}
+	override := cluster.CoreCounts{Total: 5, Frontend: 1, Drive: 2}
+	if err = p.SetBackendCoreCounts(testStackName, "i3en.2xlarge", cluster.CoreCounts{Total: 3, Frontend: 1, Drive: 2}); err == nil {
+		t.Error("SetBackendCoreCounts() without compute cores succeeded")
+	}
+	if err = p.SetBackendCoreCounts(testStackName, "x9.unknown", override); err == nil {
+		t.Error("SetBackendCoreCounts() of an unknown instance type succeeded")
+	}
+	if err = p.SetBackendCoreCounts(testStackName, "r5.large", override); err != nil {
+		t.Fatalf("SetBackendCoreCounts() error = %v", err)
+	}
+	if counts, err = lambdas.GetBackendCoreCounts(tableName, "r5.large"); err != nil || counts != override {
+		t.Errorf("GetBackendCoreCounts() = %+v, %v, want the %+v override", counts, err, override)
+	}
+	if err = p.ResetBackendCoreCounts(testStackName, "r5.large"); err != nil {
+		t.Fatalf("ResetBackendCoreCounts() error = %v", err)
+	}
+	if _, err = lambdas.GetBackendCoreCounts(tableName, "r5.large"); err == nil {
+		t.Error("GetBackendCoreCounts() succeeded after the override was reset")
+	}
+	if err = p.SetBackendCoreCounts(testStackName, "i3en.2xlarge", override); err != nil {
+		t.Fatalf("SetBackendCoreCounts() error = %v", err)
+	}
+	if counts, err = lambdas.GetBackendCoreCounts(tableName, "i3en.2xlarge"); err != nil || counts != override {
+		t.Errorf("GetBackendCoreCounts() = %+v, %v, want the %+v override of the built-in cores", counts, err, override)
+	}
+}
+
+func TestHostGroupAutoscale(t *testing.T) {
+	a := setupFakeStack(t)
+	err := ImportCluster(testStackName, "admin", "password", nil)
+	if err != nil {
+		t.Fatalf("ImportCluster() error = %v", err)
+	}
+
+	p := Provider{}
+	autoscale := provider.Autoscale{Metric: provider.MetricThroughput, Target: 500, MinSize: 1, MaxSize: 8}
+	if err = p.EnableHostGroupAutoscale(testStackName, "Backends", autoscale); err == nil {
+		t.Error("EnableHostGroupAutoscale() of a backends host group succeeded")
+	}
+	if err = p.EnableHostGroupAutoscale(testStackName, "Clients", provider.Autoscale{Metric: "latency", Target: 1, MaxSize: 8}); err == nil {
+		t.Error("EnableHostGroupAutoscale() of an unknown metric succeeded")
+	}
+	if err = p.EnableHostGroupAutoscale(testStackName, "Clients", provider.Autoscale{Metric: provider.MetricIops, Target: 1000, MinSize: 4, MaxSize: 2}); err == nil {
+		t.Error("EnableHostGroupAutoscale() with min size above max size succeeded")
+	}
+	if err = p.EnableHostGroupAutoscale(testStackName, "Clients", autoscale); err != nil {
+		t.Fatalf("EnableHostGroupAutoscale() error = %v", err)
+	}
+
+	clients := common.GenerateResourceName(cluster.ClusterName(testStackName), "Clients")
+	policies, err := a.ASG.DescribePolicies(&autoscaling.DescribePoliciesInput{AutoScalingGroupName: aws.String(clients)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policies.ScalingPolicies) != 1 {
+		t.Fatalf("EnableHostGroupAutoscale() put %d scaling policies, want 1", len(policies.ScalingPolicies))
+	}
+	configuration := policies.ScalingPolicies[0].TargetTrackingConfiguration
+	metric := configuration.CustomizedMetricSpecification
+	if aws.StringValue(metric.Namespace) != common.MetricsNamespace || aws.StringValue(metric.MetricName) != common.MetricFrontendThroughput || aws.Float64Value(configuration.TargetValue) != 500 {
+		t.Errorf("EnableHostGroupAutoscale() tracks %s/%s at %g, want %s/%s at 500", aws.StringValue(metric.Namespace),
+			aws.StringValue(metric.MetricName), aws.Float64Value(configuration.TargetValue), common.MetricsNamespace, common.MetricFrontendThroughput)
+	}
+	for _, group := range getClusterGroups(t, a) {
+		if aws.StringValue(group.AutoScalingGroupName) == clients && (aws.Int64Value(group.MinSize) != 1 || aws.Int64Value(group.MaxSize) != 8) {
+			t.Errorf("EnableHostGroupAutoscale() group size = %d to %d, want 1 to 8", aws.Int64Value(group.MinSize), aws.Int64Value(group.MaxSize))
+		}
+	}
+	if err = p.ScaleHostGroup(testStackName, "Clients", 3, false); err == nil {
+		t.Error("ScaleHostGroup() of a host group scaling by a metric target succeeded")
+	}
+	tableName := common.GenerateResourceName(cluster.ClusterName(testStackName), "")
+	params, err := db.GetClusterParams(tableName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := common.AutoscaleParams{Metric: common.MetricFrontendThroughput, Target: 500, MinSize: 1}
+	if params.Clients.Autoscale != want || params.Clients.MaxSize != 8 {
+		t.Errorf("saved clients autoscale = %+v, max size %d, want %+v, max size 8", params.Clients.Autoscale, params.Clients.MaxSize, want)
+	}
+
+	// the update keeps the policy, and the clients state machine publishes metrics before it scales
+	dist.LambdasID = "v2"
+	err = UpdateCluster(testStackName)
+	if err != nil {
+		t.Fatalf("UpdateCluster() error = %v", err)
+	}
+	diff, err := DiffCluster(testStackName)
+	if err != nil {
+		t.Fatalf("DiffCluster() error = %v", err)
+	}
+	for _, resourceType := range []string{"AutoscalingGroup", "ScaleMachine"} {
+		for _, resourceDiff := range findResourceDiffs(diff, resourceType) {
+			if resourceDiff.Missing || len(resourceDiff.Drifts) != 0 {
+				t.Errorf("%s %s drifted: missing %t, %+v", resourceType, resourceDiff.Name, resourceDiff.Missing, resourceDiff.Drifts)
+			}
+		}
+	}
+	for hostGroup, wantMetrics := range map[string]bool{"Backends": false, "Clients": true} {
+		arn, err := scalemachine.GetStateMachineArn(common.GenerateResourceName(cluster.ClusterName(testStackName), common.HostGroupName(hostGroup)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		output, err := a.SFN.DescribeStateMachine(&sfn.DescribeStateMachineInput{StateMachineArn: aws.String(arn)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var definition scalemachine.StateMachine
+		err = json.Unmarshal([]byte(aws.StringValue(output.Definition)), &definition)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := definition.States["Metrics"]; ok != wantMetrics {
+			t.Errorf("%s state machine has a metrics state: %t, want %t", hostGroup, ok, wantMetrics)
+		}
+	}
+
+	err = p.DisableHostGroupAutoscale(testStackName, "Clients")
+	if err != nil {
+		t.Fatalf("DisableHostGroupAutoscale() error = %v", err)
+	}
+	enabled, err := autoscaling2.AutoscaleEnabled(clients)
+	if err != nil || enabled {
+		t.Errorf("AutoscaleEnabled() after disable = %t, %v, want false", enabled, err)
+	}
+	if err = p.ScaleHostGroup(testStackName, "Clients", 3, false); err != nil {
+		t.Errorf("ScaleHostGroup() after disable error = %v", err)
+	}
+	diff, err = DiffCluster(testStackName)
+	if err != nil {
+		t.Fatalf("DiffCluster() error = %v", err)
+	}
+	for _, resourceDiff := range findResourceDiffs(diff, "AutoscalingGroup") {
+		if len(resourceDiff.Drifts) != 0 {
+			t.Errorf("AutoscalingGroup %s drifted after disable: %+v", resourceDiff.Name, resourceDiff.Drifts)
+		}
+	}
+}
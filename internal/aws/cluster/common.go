@@ -19,3 +19,22 @@ func GenerateHostGroup(clusterName cluster.ClusterName, hostGroupParams common.H
 
 	return hostGroup
 }
+
+// generateExistingAWSCluster generates the resource tree of an imported cluster from its name only,
+// for operations which fetch or delete its resources and don't need the cluster params
+func generateExistingAWSCluster(stackName string) AWSCluster {
+	clusterName := cluster.ClusterName(stackName)
+	return AWSCluster{
+		Name: clusterName,
+		CFStack: Stack{
+			StackName: stackName,
+		},
+		DynamoDb: DynamoDb{
+			ClusterName: clusterName,
+		},
+		HostGroups: []HostGroup{
+			GenerateHostGroup(clusterName, common.HostGroupParams{}, common.RoleBackend, "Backends"),
+			GenerateHostGroup(clusterName, common.HostGroupParams{}, common.RoleClient, "Clients"),
+		},
+	}
+}
@@ -8,15 +8,17 @@ import (
 	"wekactl/internal/cluster"
 )
 
-const dbVersion = "v1"
+const dbVersion = "v2"
 
 type DynamoDb struct {
-	ClusterName cluster.ClusterName
-	Username    string
-	Password    string
-	StackId     string
-	Version     string
-	KmsKey      KmsKey
+	ClusterName   cluster.ClusterName
+	Username      string
+	Password      string
+	StackId       string
+	DefaultParams db.DefaultClusterParams
+	Version       string
+	KmsKey        KmsKey
+	Inventory     *Inventory
 }
 
 func (d *DynamoDb) Tags() cluster.Tags {
@@ -32,12 +34,24 @@ func (d *DynamoDb) ResourceName() string {
 	return common.GenerateResourceName(d.ClusterName, "")
 }
 
+func (d *DynamoDb) inventoryName() string {
+	return d.ResourceName()
+}
+
+func (d *DynamoDb) inventoryId() string {
+	return d.ResourceName()
+}
+
 func (d *DynamoDb) Fetch() error {
-	version, err := db.GetDbVersion(d.ResourceName())
-	if err != nil {
-		return err
+	if item, ok := d.Inventory.Get(d); ok {
+		d.Version = item.Version
+	} else {
+		version, err := db.GetDbVersion(d.ResourceName())
+		if err != nil {
+			return err
+		}
+		d.Version = version
 	}
-	d.Version = version
 
 	if d.KmsKey.Key == "" {
 		kmsKeyId, err := kms.GetKMSKeyId(d.ClusterName)
@@ -52,6 +66,11 @@ func (d *DynamoDb) Fetch() error {
 func (d *DynamoDb) Init() {
 	log.Debug().Msgf("Initializing db ...")
 	d.KmsKey.ClusterName = d.ClusterName
+	d.KmsKey.Inventory = d.Inventory
+}
+
+func (d *DynamoDb) Status() (cluster.ResourceStatus, error) {
+	return cluster.ResourceStatus{Id: d.inventoryId()}, nil
 }
 
 func (d *DynamoDb) DeployedVersion() string {
@@ -71,9 +90,36 @@ func (d *DynamoDb) Create() error {
 	if err != nil {
 		return err
 	}
-	return db.SaveCredentials(d.ResourceName(), d.Username, d.Password)
+	err = db.SaveCredentials(d.ResourceName(), d.Username, d.Password)
+	if err != nil {
+		return err
+	}
+	return d.saveClusterParams()
+}
+
+// saveClusterParams saves the params diff compares the host groups with, update saves the ones it deployed so
+// clusters imported before the params were saved get them as well
+func (d *DynamoDb) saveClusterParams() error {
+	if d.DefaultParams.Backends.InstanceType == "" {
+		return nil
+	}
+	return db.SaveClusterParams(d.ResourceName(), d.DefaultParams)
+}
+
+func (d *DynamoDb) Diff() (cluster.Drifts, error) {
+	return db.DiffDb(d.ResourceName(), d.KmsKey.Key)
 }
 
 func (d *DynamoDb) Update() error {
-	panic("update not supported")
+	err := db.TagDb(d.ResourceName(), cluster.GetResourceVersionTag(d.TargetVersion()))
+	if err != nil {
+		return err
+	}
+	if d.Username != "" {
+		err = db.ChangeCredentials(d.ResourceName(), d.Username, d.Password)
+		if err != nil {
+			return err
+		}
+	}
+	return d.saveClusterParams()
 }
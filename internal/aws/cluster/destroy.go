@@ -0,0 +1,20 @@
+package cluster
+
+import (
+	"wekactl/internal/aws/autoscaling"
+	"wekactl/internal/cluster"
+)
+
+func DestroyCluster(stackName string, keepInstances bool) error {
+	if keepInstances {
+		autoscaling.KeepInstances = true
+	}
+
+	awsCluster := generateExistingAWSCluster(stackName)
+	err := awsCluster.LoadInventory()
+	if err != nil {
+		return err
+	}
+	awsCluster.Init()
+	return cluster.DestroyResource(&awsCluster)
+}
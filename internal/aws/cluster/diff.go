@@ -0,0 +1,57 @@
+package cluster
+
+import (
+	"github.com/rs/zerolog/log"
+	"wekactl/internal/aws/common"
+	"wekactl/internal/aws/db"
+	"wekactl/internal/cluster"
+	"wekactl/internal/logging"
+)
+
+// importedClusterParams returns the params import derives from the CloudFormation stack instances
+func importedClusterParams(stackName string) (defaultParams db.DefaultClusterParams, err error) {
+	stackInstances, err := GetStackInstancesInfo(stackName)
+	if err != nil {
+		return
+	}
+	return importClusterParamsFromCF(stackInstances)
+}
+
+func generateDiffAWSCluster(stackName string) (awsCluster AWSCluster, err error) {
+	clusterName := cluster.ClusterName(stackName)
+	defaultParams, err := db.GetClusterParams(common.GenerateResourceName(clusterName, ""))
+	if err != nil {
+		return
+	}
+
+	if defaultParams.Key == "" {
+		defaultParams, err = importedClusterParams(stackName)
+		if err != nil {
+			log.Debug().Msgf("getting cluster %s imported params failed: %s", stackName, err.Error())
+			logging.UserWarning("Cluster %s params weren't saved and its stack instances are gone, the launch templates and auto scaling groups are compared with themselves, run \"cluster update\" to save the params", stackName)
+			awsCluster, err = generateUpdateAWSCluster(stackName)
+			if err != nil {
+				return
+			}
+		} else {
+			logging.UserWarning("Cluster %s params weren't saved, comparing with the params of its stack instances, run \"cluster update\" to save the params", stackName)
+			awsCluster = generateAWSCluster("", stackName, "", "", defaultParams)
+		}
+	} else {
+		awsCluster = generateAWSCluster("", stackName, "", "", defaultParams)
+	}
+
+	// the inventory isn't loaded, so every resource is rediscovered and compared with its live state
+	awsCluster.Init()
+	return
+}
+
+// DiffCluster compares the live attributes of every cluster resource with the ones wekactl would create,
+// using the cluster params saved on import as the desired state
+func DiffCluster(stackName string) (diff cluster.ResourceDiff, err error) {
+	awsCluster, err := generateDiffAWSCluster(stackName)
+	if err != nil {
+		return
+	}
+	return cluster.DiffResource(&awsCluster)
+}
@@ -0,0 +1,51 @@
+package cluster
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"testing"
+	"wekactl/internal/aws/common"
+	"wekactl/internal/aws/db"
+	"wekactl/internal/cluster"
+)
+
+func TestDiffWithoutSavedParams(t *testing.T) {
+	a := setupFakeStack(t)
+	err := ImportCluster(testStackName, "admin", "password", nil)
+	if err != nil {
+		t.Fatalf("ImportCluster() error = %v", err)
+	}
+	// clusters imported before the params were saved have none
+	_, err = a.DynamoDB.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(common.GenerateResourceName(cluster.ClusterName(testStackName), "")),
+		Key:       map[string]*dynamodb.AttributeValue{"Key": {S: aws.String(db.ModelDefaultClusterParams)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	asgName := common.GenerateResourceName(cluster.ClusterName(testStackName), "Backends")
+	_, err = a.ASG.UpdateAutoScalingGroup(&autoscaling.UpdateAutoScalingGroupInput{
+		AutoScalingGroupName: aws.String(asgName),
+		MaxSize:              aws.Int64(7),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := DiffCluster(testStackName)
+	if err != nil {
+		t.Fatalf("DiffCluster() error = %v", err)
+	}
+	drifted := false
+	for _, resourceDiff := range findResourceDiffs(diff, "AutoscalingGroup") {
+		for _, drift := range resourceDiff.Drifts {
+			if resourceDiff.Name == asgName && drift.Attribute == "max_size" && drift.Actual == "7" {
+				drifted = true
+			}
+		}
+	}
+	if !drifted {
+		t.Errorf("DiffCluster() didn't find the %s max size drift from the stack instances params", asgName)
+	}
+}
@@ -0,0 +1,81 @@
+package cluster
+
+import (
+	"github.com/rs/zerolog/log"
+	"wekactl/internal/aws/db"
+	"wekactl/internal/cluster"
+)
+
+const historyDbVersion = "v2"
+
+// HistoryDb is the table the host groups state machines record their scale decisions in
+type HistoryDb struct {
+	ClusterName cluster.ClusterName
+	Version     string
+	Inventory   *Inventory
+}
+
+func (h *HistoryDb) Tags() cluster.Tags {
+	return cluster.GetCommonResourceTags(h.ClusterName, h.TargetVersion())
+}
+
+func (h *HistoryDb) SubResources() []cluster.Resource {
+	return []cluster.Resource{}
+}
+
+func (h *HistoryDb) ResourceName() string {
+	return db.HistoryTableName(h.ClusterName)
+}
+
+func (h *HistoryDb) inventoryName() string {
+	return h.ResourceName()
+}
+
+func (h *HistoryDb) inventoryId() string {
+	return h.ResourceName()
+}
+
+func (h *HistoryDb) Fetch() error {
+	if item, ok := h.Inventory.Get(h); ok {
+		h.Version = item.Version
+		return nil
+	}
+	version, err := db.GetDbVersion(h.ResourceName())
+	if err != nil {
+		return err
+	}
+	h.Version = version
+	return nil
+}
+
+func (h *HistoryDb) Init() {
+	log.Debug().Msgf("Initializing history db ...")
+}
+
+func (h *HistoryDb) Status() (cluster.ResourceStatus, error) {
+	return cluster.ResourceStatus{Id: h.inventoryId()}, nil
+}
+
+func (h *HistoryDb) DeployedVersion() string {
+	return h.Version
+}
+
+func (h *HistoryDb) TargetVersion() string {
+	return historyDbVersion
+}
+
+func (h *HistoryDb) Delete() error {
+	return db.DeleteDB(h.ResourceName())
+}
+
+func (h *HistoryDb) Create() error {
+	return db.CreateHistoryDb(h.ResourceName(), h.Tags())
+}
+
+func (h *HistoryDb) Diff() (cluster.Drifts, error) {
+	return db.DiffHistoryDb(h.ResourceName())
+}
+
+func (h *HistoryDb) Update() error {
+	return db.UpdateHistoryDb(h.ResourceName(), h.Tags())
+}
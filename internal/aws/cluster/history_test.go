@@ -0,0 +1,106 @@
+package cluster
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"testing"
+	"time"
+	"wekactl/internal/aws/db"
+	"wekactl/internal/cluster"
+)
+
+func TestScaleHistory(t *testing.T) {
+	a := setupFakeStack(t)
+	err := ImportCluster(testStackName, "admin", "password", nil)
+	if err != nil {
+		t.Fatalf("ImportCluster() error = %v", err)
+	}
+
+	tableName := db.HistoryTableName(cluster.ClusterName(testStackName))
+	ttl, err := a.DynamoDB.DescribeTimeToLive(&dynamodb.DescribeTimeToLiveInput{TableName: aws.String(tableName)})
+	if err != nil {
+		t.Fatalf("DescribeTimeToLive() error = %v", err)
+	}
+	if status := aws.StringValue(ttl.TimeToLiveDescription.TimeToLiveStatus); status != dynamodb.TimeToLiveStatusEnabled {
+		t.Errorf("history table time to live status = %s, want %s", status, dynamodb.TimeToLiveStatusEnabled)
+	}
+
+	p := Provider{}
+	asgName, err := hostGroupAutoScalingGroupName(testStackName, "Backends")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	records := []cluster.ScaleHistoryRecord{
+		{Time: now.Add(-db.HistoryRetention - time.Minute)},
+		{Time: now.Add(-time.Hour)},
+		{Time: now.Add(-time.Minute)},
+		{
+			Time:        now,
+			Hosts:       []cluster.ScaleHistoryHost{{HostId: "HostId<1>", InstanceId: "i-1", State: "ACTIVE", ScaleState: "HEALTHY"}},
+			Deactivated: []cluster.ScaleHistoryHost{{HostId: "HostId<1>", InstanceId: "i-1", Reason: "downscale"}},
+		},
+	}
+	for _, record := range records {
+		if err = db.SaveScaleHistory(tableName, asgName, record); err != nil {
+			t.Fatalf("SaveScaleHistory() error = %v", err)
+		}
+	}
+
+	history, err := p.ScaleHistory(testStackName, "Backends", now.Add(-30*24*time.Hour), 0)
+	if err != nil {
+		t.Fatalf("ScaleHistory() error = %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("ScaleHistory() returned %d records, want the 3 unexpired ones", len(history))
+	}
+	if !history[0].Time.Equal(now) || !history[0].Changed() || history[1].Changed() {
+		t.Errorf("ScaleHistory() = %+v, want the most recent change first", history)
+	}
+	if history[0].Deactivated[0].Reason != "downscale" {
+		t.Errorf("ScaleHistory() deactivation reason = %s, want downscale", history[0].Deactivated[0].Reason)
+	}
+	if history, _ = p.ScaleHistory(testStackName, "Backends", now.Add(-30*time.Minute), 0); len(history) != 2 {
+		t.Errorf("ScaleHistory() of the last 30 minutes returned %d records, want 2", len(history))
+	}
+	history, _ = p.ScaleHistory(testStackName, "Backends", now.Add(-24*time.Hour), 1)
+	if len(history) != 1 || !history[0].Time.Equal(now) {
+		t.Errorf("ScaleHistory() limited to 1 record = %+v, want the most recent one", history)
+	}
+	if history, _ = p.ScaleHistory(testStackName, "Clients", now.Add(-24*time.Hour), 0); len(history) != 0 {
+		t.Errorf("ScaleHistory() of another host group returned %d records, want 0", len(history))
+	}
+}
+
+func TestUpdateRekeysHistoryDb(t *testing.T) {
+	a := setupFakeStack(t)
+	historyDb := HistoryDb{ClusterName: testStackName}
+	// the v1 history table was keyed by a single "Key" attribute
+	_, err := a.DynamoDB.CreateTable(&dynamodb.CreateTableInput{
+		TableName:            aws.String(historyDb.ResourceName()),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{{AttributeName: aws.String("Key"), AttributeType: aws.String("S")}},
+		KeySchema:            []*dynamodb.KeySchemaElement{{AttributeName: aws.String("Key"), KeyType: aws.String(dynamodb.KeyTypeHash)}},
+		BillingMode:          aws.String(dynamodb.BillingModePayPerRequest),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	drifts, err := historyDb.Diff()
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if len(drifts) == 0 {
+		t.Error("Diff() of the v1 history table found no drift")
+	}
+
+	if err = historyDb.Update(); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if drifts, err = historyDb.Diff(); err != nil || len(drifts) != 0 {
+		t.Errorf("Diff() after Update() = %v, %v, want no drift", drifts, err)
+	}
+	version, err := db.GetDbVersion(historyDb.ResourceName())
+	if err != nil || version != historyDbVersion {
+		t.Errorf("history table version after Update() = %s, %v, want %s", version, err, historyDbVersion)
+	}
+}
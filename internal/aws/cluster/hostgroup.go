@@ -13,6 +13,7 @@ type HostGroup struct {
 	HostGroupParams  common.HostGroupParams
 	AutoscalingGroup AutoscalingGroup
 	TableName        string
+	Inventory        *Inventory
 }
 
 func (h *HostGroup) Tags() cluster.Tags {
@@ -31,8 +32,13 @@ func (h *HostGroup) Fetch() error {
 	return nil
 }
 
+func (h *HostGroup) Status() (cluster.ResourceStatus, error) {
+	return cluster.ResourceStatus{}, nil
+}
+
+// DeployedVersion is always the target one, a host group has no resource of its own
 func (h *HostGroup) DeployedVersion() string {
-	return ""
+	return h.TargetVersion()
 }
 
 func (h *HostGroup) TargetVersion() string {
@@ -56,6 +62,7 @@ func (h *HostGroup) Init() {
 	h.AutoscalingGroup.HostGroupInfo = h.HostGroupInfo
 	h.AutoscalingGroup.HostGroupParams = h.HostGroupParams
 	h.AutoscalingGroup.TableName = h.TableName
+	h.AutoscalingGroup.Inventory = h.Inventory
 	h.AutoscalingGroup.Init()
 }
 
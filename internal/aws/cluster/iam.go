@@ -11,6 +11,7 @@ import (
 
 type IamProfile struct {
 	Arn              string
+	RoleName         string
 	Name             string
 	PolicyName       string
 	TableName        string
@@ -18,6 +19,7 @@ type IamProfile struct {
 	AssumeRolePolicy iam.AssumeRolePolicyDocument
 	HostGroupInfo    common.HostGroupInfo
 	Policy           iam.PolicyDocument
+	Inventory        *Inventory
 }
 
 func (i *IamProfile) Tags() cluster.Tags {
@@ -34,17 +36,63 @@ func (i *IamProfile) resourceNameBase() string {
 }
 
 func (i *IamProfile) ResourceName() string {
+	if i.RoleName != "" {
+		return i.RoleName
+	}
 	//creating and deleting the same role name and use it for lambda caused problems, so we use unique uuid
 	return strings2.ElfHashSuffixed(fmt.Sprintf("%s-%s", i.resourceNameBase(), uuid.New().String()), 64)
 }
 
+func (i *IamProfile) inventoryName() string {
+	return i.resourceNameBase()
+}
+
+func (i *IamProfile) inventoryId() string {
+	if i.Arn == "" {
+		return i.resourceNameBase()
+	}
+	return i.Arn
+}
+
+// FetchArn sets the arn of the role from its inventory item, the role is looked up by name only when the inventory
+// has no item for it
+func (i *IamProfile) FetchArn() error {
+	if i.Arn != "" {
+		return nil
+	}
+	if item, ok := i.Inventory.Get(i); ok {
+		i.Arn = item.Id
+		return nil
+	}
+	arn, err := iam.GetIamRoleArn(i.resourceNameBase())
+	if err != nil {
+		return err
+	}
+	i.Arn = arn
+	return nil
+}
+
 func (i *IamProfile) Fetch() error {
+	if item, ok := i.Inventory.Get(i); ok {
+		i.Version = item.Version
+		i.Arn = item.Id
+		i.RoleName = iam.GetRoleNameFromArn(item.Id)
+		return nil
+	}
+
 	version, err := iam.GetIamRoleVersion(i.resourceNameBase())
 	if err != nil {
 		return err
 	}
-
 	i.Version = version
+
+	if version != "" && i.Arn == "" {
+		arn, err := iam.GetIamRoleArn(i.resourceNameBase())
+		if err != nil {
+			return err
+		}
+		i.Arn = arn
+	}
 	return nil
 }
 
@@ -52,6 +100,10 @@ func (i *IamProfile) Init() {
 	return
 }
 
+func (i *IamProfile) Status() (cluster.ResourceStatus, error) {
+	return cluster.ResourceStatus{Id: i.inventoryId()}, nil
+}
+
 func (i *IamProfile) DeployedVersion() string {
 	return i.Version
 }
@@ -65,11 +117,13 @@ func (i *IamProfile) Delete() error {
 }
 
 func (i *IamProfile) Create() error {
-	arn, err := iam.CreateIamRole(i.Tags().AsIam(), i.ResourceName(), i.PolicyName, i.AssumeRolePolicy, i.Policy)
+	roleName := i.ResourceName()
+	arn, err := iam.CreateIamRole(i.Tags().AsIam(), roleName, i.PolicyName, i.AssumeRolePolicy, i.Policy)
 	if err != nil {
 		return err
 	}
 	i.Arn = *arn
+	i.RoleName = roleName
 	return nil
 }
 
@@ -77,3 +131,7 @@ func (i *IamProfile) Update() error {
 	return iam.UpdateRolePolicy(
 		i.resourceNameBase(), i.PolicyName, i.Policy, cluster.GetResourceVersionTag(i.TargetVersion()).AsIam())
 }
+
+func (i *IamProfile) Diff() (cluster.Drifts, error) {
+	return iam.DiffIamRole(i.resourceNameBase(), i.AssumeRolePolicy, i.Policy)
+}
@@ -167,31 +167,84 @@ func generateAWSCluster(stackId, stackName, username, password string, defaultPa
 	}
 }
 
-func ImportCluster(stackName, username, password string) error {
+func generateImportAWSCluster(stackName, username, password string) (awsCluster AWSCluster, stackInstances StackInstances, err error) {
 	stackId, err := GetStackId(stackName)
+	if err != nil {
+		return
+	}
+
+	stackInstances, err = GetStackInstancesInfo(stackName)
+	if err != nil {
+		return
+	}
+
+	defaultParams, err := importClusterParamsFromCF(stackInstances)
+	if err != nil {
+		return
+	}
+
+	awsCluster = generateAWSCluster(stackId, stackName, username, password, defaultParams)
+	err = awsCluster.LoadInventory()
+	if err != nil {
+		return
+	}
+	awsCluster.Init()
+	return
+}
+
+func PlanImportCluster(stackName string) (plan cluster.ResourcePlan, err error) {
+	awsCluster, _, err := generateImportAWSCluster(stackName, "", "")
+	if err != nil {
+		return
+	}
+	return cluster.PlanResource(&awsCluster)
+}
+
+func recordInstancesApiTermination(instanceIds []string, journal *cluster.Journal) error {
+	originalValues, err := common.GetDisableInstancesApiTermination(instanceIds)
 	if err != nil {
 		return err
 	}
 
-	stackInstances, err := GetStackInstancesInfo(stackName)
+	var unprotected []string
+	for _, instanceId := range instanceIds {
+		if !originalValues[instanceId] {
+			unprotected = append(unprotected, instanceId)
+		}
+	}
+	if len(unprotected) == 0 {
+		return nil
+	}
+
+	journal.Record(fmt.Sprintf("disabled api termination of %d instances", len(unprotected)), func() error {
+		_, errs := common.SetDisableInstancesApiTermination(unprotected, false)
+		if len(errs) != 0 {
+			return errs[0]
+		}
+		return nil
+	})
+	return nil
+}
+
+// ImportCluster imports the stack, recording every change it makes in the journal (which may be nil),
+// so a failed import can be rolled back
+func ImportCluster(stackName, username, password string, journal *cluster.Journal) error {
+	awsCluster, stackInstances, err := generateImportAWSCluster(stackName, username, password)
 	if err != nil {
 		return err
 	}
 
 	instanceIds := common.GetInstancesIds(stackInstances.All())
+	err = recordInstancesApiTermination(instanceIds, journal)
+	if err != nil {
+		return err
+	}
 	_, errs := common.SetDisableInstancesApiTermination(instanceIds, true)
 	if len(errs) != 0 {
 		return errs[0]
 	}
 
-	defaultParams, err := importClusterParamsFromCF(stackInstances)
-	if err != nil {
-		return err
-	}
-
-	awsCluster := generateAWSCluster(stackId, stackName, username, password, defaultParams)
-	awsCluster.Init()
-	err = cluster.EnsureResource(&awsCluster)
+	err = cluster.EnsureResource(&awsCluster, journal)
 	if err != nil {
 		return err
 	}
@@ -201,7 +254,12 @@ func ImportCluster(stackName, username, password string) error {
 	roleInstanceIdsRefs[common.RoleClient] = common.GetInstancesIdsRefs(stackInstances.Clients)
 	for _, hostgroup := range awsCluster.HostGroups {
 		autoscalingGroupName := hostgroup.AutoscalingGroup.ResourceName()
-		err = autoscaling.AttachInstancesToASG(roleInstanceIdsRefs[hostgroup.HostGroupInfo.Role], autoscalingGroupName)
+		attached, err := autoscaling.AttachInstancesToASG(roleInstanceIdsRefs[hostgroup.HostGroupInfo.Role], autoscalingGroupName)
+		if len(attached) > 0 {
+			journal.Record(fmt.Sprintf("attached %d instances to %s", len(attached), autoscalingGroupName), func() error {
+				return autoscaling.ReleaseInstancesFromASG(attached, autoscalingGroupName)
+			})
+		}
 		if err != nil {
 			return err
 		}
@@ -213,6 +271,7 @@ func ImportCluster(stackName, username, password string) error {
 		}
 	}
 
+	awsCluster.recordInventory()
 	return nil
 }
 
@@ -257,5 +316,27 @@ func importRoleParams(hostGroupParams *common.HostGroupParams, instances []*ec2.
 	hostGroupParams.VolumeType = volumeInfo.Type
 	hostGroupParams.VolumeSize = volumeInfo.Size
 	hostGroupParams.MaxSize = common.GetMaxSize(role, len(instances))
+	hostGroupParams.InstanceTypes = importInstanceTypes(instances)
 	return nil
 }
+
+// importInstanceTypes returns the instance types of a stack that mixes them, in the order they first appear, so the
+// host group keeps launching all of them
+func importInstanceTypes(instances []*ec2.Instance) (instanceTypes common.InstanceTypesParams) {
+	seen := map[string]bool{}
+	for _, instance := range instances {
+		if seen[*instance.InstanceType] {
+			continue
+		}
+		seen[*instance.InstanceType] = true
+		instanceTypes.Types = append(instanceTypes.Types, common.InstanceTypeWeight{
+			InstanceType: *instance.InstanceType,
+			Weight:       1,
+		})
+	}
+	if len(instanceTypes.Types) < 2 {
+		return common.InstanceTypesParams{}
+	}
+	instanceTypes.AllocationStrategy = common.AllocationStrategyPrioritized
+	return
+}
@@ -0,0 +1,119 @@
+package cluster
+
+import (
+	"github.com/rs/zerolog/log"
+	"wekactl/internal/aws/cloudwatch"
+	"wekactl/internal/aws/common"
+	"wekactl/internal/aws/iam"
+	"wekactl/internal/aws/lambdas"
+	"wekactl/internal/cluster"
+)
+
+const spotInterruptionVersion = "v1"
+
+// SpotInterruption is the rule that invokes the interruption lambda on spot instance interruption warnings, client
+// host groups have one whether they run spot instances or not
+type SpotInterruption struct {
+	HostGroupInfo   common.HostGroupInfo
+	HostGroupParams common.HostGroupParams
+	TableName       string
+	Version         string
+	ASGName         string
+	interruption    Lambda
+	Inventory       *Inventory
+}
+
+func (s *SpotInterruption) Tags() cluster.Tags {
+	return GetHostGroupResourceTags(s.HostGroupInfo, s.TargetVersion())
+}
+
+func (s *SpotInterruption) SubResources() []cluster.Resource {
+	return []cluster.Resource{&s.interruption}
+}
+
+func (s *SpotInterruption) ResourceName() string {
+	return common.GenerateResourceName(s.HostGroupInfo.ClusterName, s.HostGroupInfo.Name) + "-spot"
+}
+
+func (s *SpotInterruption) inventoryName() string {
+	return s.ResourceName()
+}
+
+func (s *SpotInterruption) inventoryId() string {
+	return s.ResourceName()
+}
+
+func (s *SpotInterruption) Fetch() error {
+	if item, ok := s.Inventory.Get(s); ok {
+		s.Version = item.Version
+	} else {
+		version, err := cloudwatch.GetCloudWatchEventRuleVersion(s.ResourceName())
+		if err != nil {
+			return err
+		}
+		s.Version = version
+	}
+
+	// a re-created lambda loses the permission that lets the rule invoke it
+	if s.Version != "" && !lambdas.InvokePolicyExists(s.interruption.ResourceName()) {
+		s.Version = "re-create"
+	}
+
+	if s.interruption.Arn == "" {
+		lambdaArn, err := lambdas.GetLambdaArn(s.interruption.ResourceName())
+		if err != nil {
+			return err
+		}
+		s.interruption.Arn = lambdaArn
+	}
+	return nil
+}
+
+func (s *SpotInterruption) Status() (cluster.ResourceStatus, error) {
+	return cluster.ResourceStatus{Id: s.inventoryId()}, nil
+}
+
+func (s *SpotInterruption) DeployedVersion() string {
+	return s.Version
+}
+
+func (s *SpotInterruption) TargetVersion() string {
+	return spotInterruptionVersion
+}
+
+func (s *SpotInterruption) Delete() error {
+	return cloudwatch.DeleteCloudWatchEventRule(s.ResourceName())
+}
+
+func (s *SpotInterruption) Create() error {
+	return cloudwatch.CreateSpotInterruptionRule(
+		s.Tags().AsCloudWatch(), s.interruption.Arn, s.interruption.ResourceName(), s.ResourceName())
+}
+
+func (s *SpotInterruption) Update() error {
+	if s.Version == "re-create" {
+		err := s.Delete()
+		if err != nil {
+			return err
+		}
+		return s.Create()
+	}
+	return cloudwatch.UpdateSpotInterruptionRule(
+		cluster.GetResourceVersionTag(s.TargetVersion()).AsCloudWatch(), s.interruption.Arn, s.ResourceName())
+}
+
+func (s *SpotInterruption) Diff() (cluster.Drifts, error) {
+	return cloudwatch.DiffSpotInterruptionRule(s.interruption.Arn, s.ResourceName())
+}
+
+func (s *SpotInterruption) Init() {
+	log.Debug().Msgf("Initializing hostgroup %s spot interruption rule ...", string(s.HostGroupInfo.Name))
+	s.interruption.TableName = s.TableName
+	s.interruption.ASGName = s.ASGName
+	s.interruption.Inventory = s.Inventory
+	s.interruption.HostGroupInfo = s.HostGroupInfo
+	s.interruption.Type = lambdas.LambdaInterruption
+	s.interruption.VPCConfig = lambdas.GetLambdaVpcConfig(s.HostGroupParams.Subnet, s.HostGroupParams.SecurityGroupsIds)
+	s.interruption.Permissions = iam.GetInterruptionLambdaPolicy()
+	s.interruption.Init()
+}
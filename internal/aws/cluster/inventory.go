@@ -0,0 +1,170 @@
+package cluster
+
+import (
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+	"wekactl/internal/aws/db"
+	"wekactl/internal/cluster"
+	"wekactl/internal/logging"
+)
+
+// inventoryResource is implemented by the resources recorded in the cluster inventory,
+// inventoryName is the stable name the resource is looked up by, inventoryId its ARN or ID
+type inventoryResource interface {
+	cluster.Resource
+	inventoryName() string
+	inventoryId() string
+}
+
+// Inventory holds the deployed resources recorded in the cluster table, so Fetch doesn't
+// have to rediscover each of them with list/describe calls
+type Inventory struct {
+	lock      sync.Mutex
+	tableName string
+	items     map[string]db.InventoryItem
+}
+
+func LoadInventory(tableName string) (*Inventory, error) {
+	items, err := db.GetInventory(tableName)
+	if err != nil {
+		return nil, err
+	}
+	inventory := &Inventory{
+		tableName: tableName,
+		items:     map[string]db.InventoryItem{},
+	}
+	for _, item := range items {
+		inventory.items[item.Key] = item
+	}
+	log.Debug().Msgf("loaded %d inventory items from %s", len(items), tableName)
+	return inventory, nil
+}
+
+func (i *Inventory) Get(r inventoryResource) (item db.InventoryItem, ok bool) {
+	if i == nil {
+		return
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	item, ok = i.items[db.InventoryKey(cluster.ResourceType(r), r.inventoryName())]
+	return
+}
+
+func (i *Inventory) Items() (items []db.InventoryItem) {
+	if i == nil {
+		return
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	for _, item := range i.items {
+		items = append(items, item)
+	}
+	return
+}
+
+func (i *Inventory) save(r inventoryResource, version string) error {
+	item, ok := i.Get(r)
+	if ok && item.Version == version && item.Id == r.inventoryId() {
+		return nil
+	}
+	if !ok {
+		item.CreatedAt = time.Now().UTC()
+	}
+	item.Type = cluster.ResourceType(r)
+	item.Name = r.inventoryName()
+	item.Id = r.inventoryId()
+	item.Version = version
+
+	err := db.SaveInventoryItem(i.tableName, item)
+	if err != nil {
+		return err
+	}
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.items[db.InventoryKey(item.Type, item.Name)] = item
+	return nil
+}
+
+func walkInventoryResources(r cluster.Resource, visit func(r inventoryResource) error) error {
+	for _, subresource := range r.SubResources() {
+		err := walkInventoryResources(subresource, visit)
+		if err != nil {
+			return err
+		}
+	}
+	if ir, ok := r.(inventoryResource); ok {
+		return visit(ir)
+	}
+	return nil
+}
+
+// RecordEnsured records every resource of the tree at its target version, it should be called
+// once the tree was ensured successfully
+func (i *Inventory) RecordEnsured(r cluster.Resource) error {
+	return walkInventoryResources(r, func(r inventoryResource) error {
+		return i.save(r, r.TargetVersion())
+	})
+}
+
+// Rebuild rediscovers every resource of the tree from its tags and records the deployed ones,
+// the tree must be initialized without an inventory, so Fetch doesn't use the recorded one.
+// Items of missing resources or of resources which are no longer part of the tree are removed
+func (i *Inventory) Rebuild(r cluster.Resource) (recorded, removed int, err error) {
+	deployed := map[string]bool{}
+	err = walkInventoryResources(r, func(r inventoryResource) error {
+		err := r.Fetch()
+		if err != nil {
+			return err
+		}
+		if r.DeployedVersion() == "" {
+			return nil
+		}
+		deployed[db.InventoryKey(cluster.ResourceType(r), r.inventoryName())] = true
+		recorded++
+		return i.save(r, r.DeployedVersion())
+	})
+	if err != nil {
+		return
+	}
+
+	for _, item := range i.Items() {
+		if deployed[item.Key] {
+			continue
+		}
+		err = db.DeleteInventoryItem(i.tableName, item)
+		if err != nil {
+			return
+		}
+		i.lock.Lock()
+		delete(i.items, item.Key)
+		i.lock.Unlock()
+		removed++
+	}
+	return
+}
+
+// LoadInventory loads the cluster inventory, it must be called before Init
+func (c *AWSCluster) LoadInventory() (err error) {
+	c.Inventory, err = LoadInventory(c.DynamoDb.ResourceName())
+	return
+}
+
+func (c *AWSCluster) recordInventory() {
+	err := c.Inventory.RecordEnsured(c)
+	if err != nil {
+		logging.UserWarning("Failed recording cluster inventory, run \"cluster reconcile-inventory\" to rebuild it: %s", err.Error())
+	}
+}
+
+// ReconcileInventory rebuilds the cluster inventory from the resources tags
+func ReconcileInventory(stackName string) (recorded, removed int, err error) {
+	awsCluster := generateExistingAWSCluster(stackName)
+	awsCluster.Init()
+
+	inventory, err := LoadInventory(awsCluster.DynamoDb.ResourceName())
+	if err != nil {
+		return
+	}
+	return inventory.Rebuild(&awsCluster)
+}
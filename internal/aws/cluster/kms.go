@@ -12,6 +12,7 @@ type KmsKey struct {
 	Key         string
 	Version     string
 	ClusterName cluster.ClusterName
+	Inventory   *Inventory
 }
 
 func (k *KmsKey) Tags() cluster.Tags {
@@ -26,7 +27,24 @@ func (k *KmsKey) ResourceName() string {
 	return common.GenerateResourceName(k.ClusterName, "")
 }
 
+func (k *KmsKey) inventoryName() string {
+	return k.ResourceName()
+}
+
+func (k *KmsKey) inventoryId() string {
+	if k.Key == "" {
+		return k.ResourceName()
+	}
+	return k.Key
+}
+
 func (k *KmsKey) Fetch() error {
+	if item, ok := k.Inventory.Get(k); ok {
+		k.Version = item.Version
+		k.Key = item.Id
+		return nil
+	}
+
 	version, err := kms.GetKMSKeyVersion(k.ClusterName)
 	if err != nil {
 		return err
@@ -39,6 +57,10 @@ func (k *KmsKey) Init() {
 	return
 }
 
+func (k *KmsKey) Status() (cluster.ResourceStatus, error) {
+	return cluster.ResourceStatus{Id: k.inventoryId()}, nil
+}
+
 func (k *KmsKey) DeployedVersion() string {
 	return k.Version
 }
@@ -22,6 +22,7 @@ type Lambda struct {
 	VPCConfig     lambda.VpcConfig
 	HostGroupInfo common.HostGroupInfo
 	Permissions   iam.PolicyDocument
+	Inventory     *Inventory
 }
 
 func (l *Lambda) Tags() cluster.Tags {
@@ -37,19 +38,40 @@ func (l *Lambda) ResourceName() string {
 	return strings2.ElfHashSuffixed(n, 64)
 }
 
-func (l *Lambda) Fetch() error {
-	version, err := lambdas.GetLambdaVersion(l.ResourceName())
-	if err != nil {
-		return err
+func (l *Lambda) inventoryName() string {
+	return l.ResourceName()
+}
+
+func (l *Lambda) inventoryId() string {
+	if l.Arn == "" {
+		return l.ResourceName()
 	}
-	l.Version = version
+	return l.Arn
+}
 
-	if l.Profile.Arn == "" {
-		profileArn, err := iam.GetIamRoleArn(l.Profile.resourceNameBase())
+func (l *Lambda) Fetch() error {
+	if item, ok := l.Inventory.Get(l); ok {
+		l.Version = item.Version
+		l.Arn = item.Id
+	} else {
+		version, err := lambdas.GetLambdaVersion(l.ResourceName())
 		if err != nil {
 			return err
 		}
-		l.Profile.Arn = profileArn
+		l.Version = version
+
+		if version != "" && l.Arn == "" {
+			arn, err := lambdas.GetLambdaArn(l.ResourceName())
+			if err != nil {
+				return err
+			}
+			l.Arn = arn
+		}
+	}
+
+	err := l.Profile.FetchArn()
+	if err != nil {
+		return err
 	}
 
 	return nil
@@ -63,9 +85,14 @@ func (l *Lambda) Init() {
 	l.Profile.AssumeRolePolicy = iam.GetLambdaAssumeRolePolicy()
 	l.Profile.HostGroupInfo = l.HostGroupInfo
 	l.Profile.Policy = l.Permissions
+	l.Profile.Inventory = l.Inventory
 	l.Profile.Init()
 }
 
+func (l *Lambda) Status() (cluster.ResourceStatus, error) {
+	return cluster.ResourceStatus{Id: l.inventoryId()}, nil
+}
+
 func (l *Lambda) DeployedVersion() string {
 	return l.Version
 }
@@ -92,3 +119,7 @@ func (l *Lambda) Create() (err error) {
 func (l *Lambda) Update() error {
 	return lambdas.UpdateLambdaHandler(l.ResourceName(), cluster.GetResourceVersionTag(l.TargetVersion()).AsStringRefs())
 }
+
+func (l *Lambda) Diff() (cluster.Drifts, error) {
+	return lambdas.DiffLambda(l.Type, l.ResourceName(), l.Profile.Arn, l.ASGName, l.TableName, l.HostGroupInfo, l.VPCConfig)
+}
@@ -1,14 +1,17 @@
 package cluster
 
 import (
+	"errors"
+	"fmt"
 	"github.com/rs/zerolog/log"
 	"wekactl/internal/aws/apigateway"
+	"wekactl/internal/aws/autoscaling"
 	"wekactl/internal/aws/common"
 	"wekactl/internal/aws/launchtemplate"
 	"wekactl/internal/cluster"
 )
 
-const launchtemplateVersion = "v1"
+const launchtemplateVersion = "v2"
 
 type LaunchTemplate struct {
 	HostGroupInfo   common.HostGroupInfo
@@ -17,6 +20,7 @@ type LaunchTemplate struct {
 	TableName       string
 	Version         string
 	ASGName         string
+	Inventory       *Inventory
 }
 
 func (l *LaunchTemplate) Tags() cluster.Tags {
@@ -31,14 +35,27 @@ func (l *LaunchTemplate) ResourceName() string {
 	return common.GenerateResourceName(l.HostGroupInfo.ClusterName, l.HostGroupInfo.Name)
 }
 
+func (l *LaunchTemplate) inventoryName() string {
+	return l.ResourceName()
+}
+
+func (l *LaunchTemplate) inventoryId() string {
+	return l.ResourceName()
+}
+
 func (l *LaunchTemplate) Fetch() error {
-	version, err := launchtemplate.GetLaunchTemplateVersion(l.ResourceName())
-	if err != nil {
-		return err
+	if item, ok := l.Inventory.Get(l); ok {
+		l.Version = item.Version
+	} else {
+		version, err := launchtemplate.GetLaunchTemplateVersion(l.ResourceName())
+		if err != nil {
+			return err
+		}
+		l.Version = version
 	}
-	l.Version = version
 
-	if l.JoinApi.RestApiGateway.Id == "" {
+	// a join api that isn't deployed yet has nothing to look up, creating it fills its id in
+	if l.JoinApi.RestApiGateway.Id == "" && l.JoinApi.DeployedVersion() != "" {
 		restApiGateway, err := apigateway.GetRestApiGateway(l.JoinApi.ResourceName())
 		if err != nil {
 			return err
@@ -48,6 +65,10 @@ func (l *LaunchTemplate) Fetch() error {
 	return nil
 }
 
+func (l *LaunchTemplate) Status() (cluster.ResourceStatus, error) {
+	return cluster.ResourceStatus{Id: l.inventoryId()}, nil
+}
+
 func (l *LaunchTemplate) DeployedVersion() string {
 	return l.Version
 }
@@ -60,12 +81,36 @@ func (l *LaunchTemplate) Delete() error {
 	return launchtemplate.DeleteLaunchTemplate(l.ResourceName())
 }
 
+// joinApiFound fails when the join api id isn't known, the instances would join through an empty url and api key
+func (l *LaunchTemplate) joinApiFound() error {
+	if l.JoinApi.RestApiGateway.Id == "" {
+		return errors.New(fmt.Sprintf("join api %s wasn't found", l.JoinApi.ResourceName()))
+	}
+	return nil
+}
+
 func (l *LaunchTemplate) Create() error {
-	return launchtemplate.CreateLaunchTemplate(l.Tags().AsEc2(), l.HostGroupInfo.Name, l.HostGroupParams, l.JoinApi.RestApiGateway, l.ResourceName())
+	err := l.joinApiFound()
+	if err != nil {
+		return err
+	}
+	return launchtemplate.CreateLaunchTemplate(l.Tags().AsEc2(), l.HostGroupInfo.Name, l.HostGroupParams, l.JoinApi.RestApiGateway, l.ResourceName(), l.TargetVersion())
 }
 
 func (l *LaunchTemplate) Update() error {
-	panic("update not supported")
+	err := l.joinApiFound()
+	if err != nil {
+		return err
+	}
+	err = launchtemplate.UpdateLaunchTemplate(l.Tags().AsEc2(), l.HostGroupInfo.Name, l.HostGroupParams, l.JoinApi.RestApiGateway, l.ResourceName(), l.TargetVersion())
+	if err != nil {
+		return err
+	}
+	return autoscaling.UseDefaultLaunchTemplateVersion(l.ResourceName(), l.ASGName)
+}
+
+func (l *LaunchTemplate) Diff() (cluster.Drifts, error) {
+	return launchtemplate.DiffLaunchTemplate(l.Tags().AsEc2(), l.HostGroupInfo.Name, l.HostGroupParams, l.JoinApi.RestApiGateway, l.ResourceName())
 }
 
 func (l *LaunchTemplate) Init() {
@@ -73,5 +118,6 @@ func (l *LaunchTemplate) Init() {
 	l.JoinApi.HostGroupInfo = l.HostGroupInfo
 	l.JoinApi.TableName = l.TableName
 	l.JoinApi.ASGName = l.ASGName
+	l.JoinApi.Inventory = l.Inventory
 	l.JoinApi.Init()
 }
@@ -0,0 +1,31 @@
+package cluster
+
+import (
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"testing"
+	"wekactl/internal/aws/dist"
+)
+
+func TestUpdateWithoutJoinApi(t *testing.T) {
+	a := setupFakeStack(t)
+	err := ImportCluster(testStackName, "admin", "password", nil)
+	if err != nil {
+		t.Fatalf("ImportCluster() error = %v", err)
+	}
+	// the inventory still has the join apis after they were deleted behind wekactl's back
+	restApis, err := a.ApiGateway.GetRestApis(&apigateway.GetRestApisInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, restApi := range restApis.Items {
+		_, err = a.ApiGateway.DeleteRestApi(&apigateway.DeleteRestApiInput{RestApiId: restApi.Id})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dist.LambdasID = "v2"
+	if err = UpdateCluster(testStackName); err == nil {
+		t.Error("UpdateCluster() without the join apis succeeded")
+	}
+}
@@ -2,7 +2,6 @@ package cluster
 
 import (
 	"github.com/aws/aws-sdk-go/service/cloudformation"
-	"wekactl/internal/aws/common"
 	"wekactl/internal/connectors"
 )
 
@@ -35,25 +34,3 @@ func getStacks() ([]Cluster, error) {
 		return stacks, nil
 	}
 }
-
-func RenderStacksTable(region string) {
-
-	fields := []string{
-		"stackName",
-		"creationTime",
-	}
-
-	clusters, err := getStacks()
-	if err != nil {
-		println(err.Error())
-	} else {
-		var data [][]string
-		for _, stack := range clusters {
-			data = append(data, []string{
-				stack.stackName,
-				stack.creationTime,
-			})
-		}
-		common.RenderTable(fields, data)
-	}
-}
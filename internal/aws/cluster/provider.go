@@ -0,0 +1,478 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"time"
+	"wekactl/internal/aws/autoscaling"
+	"wekactl/internal/aws/common"
+	"wekactl/internal/aws/db"
+	"wekactl/internal/aws/lambdas"
+	"wekactl/internal/cluster"
+	"wekactl/internal/connectors"
+	"wekactl/internal/lib/jrpc"
+	"wekactl/internal/lib/weka"
+	"wekactl/internal/provider"
+)
+
+// Provider is the aws implementation of provider.Provider
+type Provider struct{}
+
+func init() {
+	provider.Register("aws", Provider{})
+}
+
+func (Provider) ListClusters() (clusters []provider.ClusterInfo, err error) {
+	stacks, err := getStacks()
+	if err != nil {
+		return
+	}
+	for _, stack := range stacks {
+		clusters = append(clusters, provider.ClusterInfo{
+			Name:         stack.stackName,
+			CreationTime: stack.creationTime,
+		})
+	}
+	return
+}
+
+func (Provider) ImportCluster(name, username, password string, journal *cluster.Journal) error {
+	return ImportCluster(name, username, password, journal)
+}
+
+func (Provider) PlanImportCluster(name string) (cluster.ResourcePlan, error) {
+	return PlanImportCluster(name)
+}
+
+func (Provider) RollbackImport(journal *cluster.Journal) []error {
+	// instances belong to the cloudformation stack, they must outlive the auto scaling groups
+	autoscaling.KeepInstances = true
+	return journal.Rollback()
+}
+
+func (Provider) UpdateCluster(name string) error {
+	return UpdateCluster(name)
+}
+
+func (Provider) PlanUpdateCluster(name string) (cluster.ResourcePlan, error) {
+	return PlanUpdateCluster(name)
+}
+
+func (Provider) DiffCluster(name string) (cluster.ResourceDiff, error) {
+	return DiffCluster(name)
+}
+
+func (Provider) StatusCluster(name string) (cluster.ResourceStatusTree, error) {
+	return StatusCluster(name)
+}
+
+func (Provider) ReconcileInventory(name string) (recorded, removed int, err error) {
+	return ReconcileInventory(name)
+}
+
+func (Provider) DestroyCluster(name string, keepInstances bool) error {
+	return DestroyCluster(name, keepInstances)
+}
+
+func (Provider) DiscoverTaggedResources(name string, keepInstances bool) ([]cluster.TaggedResource, error) {
+	if keepInstances {
+		autoscaling.KeepInstances = true
+	}
+	return DiscoverTaggedResources(cluster.ClusterName(name))
+}
+
+func (Provider) ChangeCredentials(name, username, password string) error {
+	tableName := common.GenerateResourceName(cluster.ClusterName(name), "")
+	return db.ChangeCredentials(tableName, username, password)
+}
+
+func (Provider) ListHostGroups(name string) (hostGroups []provider.HostGroupInfo, err error) {
+	awsCluster := generateExistingAWSCluster(name)
+	for _, hostGroup := range awsCluster.HostGroups {
+		asgName := common.GenerateResourceName(hostGroup.HostGroupInfo.ClusterName, hostGroup.HostGroupInfo.Name)
+		desired, instances, err := autoscaling.GetAutoScalingGroupCapacity(asgName)
+		if err != nil {
+			return nil, err
+		}
+		hostGroups = append(hostGroups, provider.HostGroupInfo{
+			Name:      string(hostGroup.HostGroupInfo.Name),
+			Role:      string(hostGroup.HostGroupInfo.Role),
+			Desired:   desired,
+			Instances: instances,
+		})
+	}
+	return
+}
+
+func findHostGroup(name, hostGroup string) (HostGroup, error) {
+	awsCluster := generateExistingAWSCluster(name)
+	for _, h := range awsCluster.HostGroups {
+		if string(h.HostGroupInfo.Name) == hostGroup {
+			return h, nil
+		}
+	}
+	return HostGroup{}, errors.New(fmt.Sprintf("host group %s not found", hostGroup))
+}
+
+func hostGroupAutoScalingGroupName(name, hostGroup string) (string, error) {
+	h, err := findHostGroup(name, hostGroup)
+	if err != nil {
+		return "", err
+	}
+	return common.GenerateResourceName(h.HostGroupInfo.ClusterName, h.HostGroupInfo.Name), nil
+}
+
+func (Provider) GetScalingPolicy(name, hostGroup string) (policy cluster.ScalingPolicy, err error) {
+	asgName, err := hostGroupAutoScalingGroupName(name, hostGroup)
+	if err != nil {
+		return
+	}
+	tableName := common.GenerateResourceName(cluster.ClusterName(name), "")
+	return db.GetScalingPolicy(tableName, asgName)
+}
+
+func (Provider) SetScalingPolicy(name, hostGroup string, policy cluster.ScalingPolicy) error {
+	err := policy.Validate()
+	if err != nil {
+		return err
+	}
+	asgName, err := hostGroupAutoScalingGroupName(name, hostGroup)
+	if err != nil {
+		return err
+	}
+	tableName := common.GenerateResourceName(cluster.ClusterName(name), "")
+	return db.SaveScalingPolicy(tableName, asgName, policy)
+}
+
+func (Provider) ScaleHistory(name, hostGroup string, since time.Time, limit int) (records []cluster.ScaleHistoryRecord, err error) {
+	asgName, err := hostGroupAutoScalingGroupName(name, hostGroup)
+	if err != nil {
+		return
+	}
+	records, err = db.GetScaleHistory(db.HistoryTableName(cluster.ClusterName(name)), asgName, since, int64(limit))
+	if _, ok := err.(*dynamodb.ResourceNotFoundException); ok {
+		err = errors.New(fmt.Sprintf("scale history table of cluster %s not found, run cluster update to create it", name))
+	}
+	return
+}
+
+func callerArn() (string, error) {
+	svc := connectors.GetAWSSession().STS
+	result, err := svc.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return *result.Arn, nil
+}
+
+func (Provider) PauseScaling(name string, until time.Time, reason string) error {
+	now := time.Now().UTC()
+	if !until.IsZero() && !until.After(now) {
+		return errors.New(fmt.Sprintf("pause end time %s has already passed", until.Format(time.RFC3339)))
+	}
+	pausedBy, err := callerArn()
+	if err != nil {
+		return err
+	}
+	tableName := common.GenerateResourceName(cluster.ClusterName(name), "")
+	return db.SaveScalingPause(tableName, cluster.ScalingPause{
+		PausedBy: pausedBy,
+		PausedAt: now,
+		Until:    until.UTC(),
+		Reason:   reason,
+	})
+}
+
+func (Provider) ResumeScaling(name string) error {
+	tableName := common.GenerateResourceName(cluster.ClusterName(name), "")
+	return db.DeleteScalingPause(tableName)
+}
+
+func (Provider) SetBackendCoreCounts(name, instanceType string, counts cluster.CoreCounts) error {
+	err := counts.Validate()
+	if err != nil {
+		return err
+	}
+	_, err = lambdas.GetInstanceTypeInfo(instanceType)
+	if err != nil {
+		return err
+	}
+	tableName := common.GenerateResourceName(cluster.ClusterName(name), "")
+	return db.SaveCoreCounts(tableName, instanceType, counts)
+}
+
+func (Provider) ResetBackendCoreCounts(name, instanceType string) error {
+	tableName := common.GenerateResourceName(cluster.ClusterName(name), "")
+	return db.DeleteCoreCounts(tableName, instanceType)
+}
+
+func (Provider) ScaleHostGroup(name, hostGroup string, desired int64, raiseMaxSize bool) error {
+	h, err := findHostGroup(name, hostGroup)
+	if err != nil {
+		return err
+	}
+	if desired < 0 {
+		return errors.New(fmt.Sprintf("desired capacity %d is negative", desired))
+	}
+	asgName := common.GenerateResourceName(h.HostGroupInfo.ClusterName, h.HostGroupInfo.Name)
+	// the target tracking policy would override the desired capacity on its next evaluation
+	enabled, err := autoscaling.AutoscaleEnabled(asgName)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		return errors.New(fmt.Sprintf("host group %s scales by a metric target, run \"hostgroup autoscale disable\" before scaling it", hostGroup))
+	}
+	if h.HostGroupInfo.Role == common.RoleBackend {
+		// the desired capacity counts capacity units, it must hold the minimum backends even of the heaviest type
+		maxWeight, err := autoscaling.GetAutoScalingGroupMaxWeight(asgName)
+		if err != nil {
+			return err
+		}
+		if desired < weka.MinBackends*maxWeight {
+			return errors.New(fmt.Sprintf("desired capacity %d is below the minimum of %d weka backends of weight %d", desired, weka.MinBackends, maxWeight))
+		}
+	}
+	maxSize, err := autoscaling.GetAutoScalingGroupMaxSize(asgName)
+	if err != nil {
+		return err
+	}
+	if desired <= maxSize {
+		return autoscaling.SetAutoScalingGroupCapacity(asgName, desired, 0)
+	}
+	if !raiseMaxSize {
+		return errors.New(fmt.Sprintf("desired capacity %d is above the host group max size %d", desired, maxSize))
+	}
+	err = autoscaling.SetAutoScalingGroupCapacity(asgName, desired, desired)
+	if err != nil {
+		return err
+	}
+	return saveHostGroupParams(cluster.ClusterName(name), h.HostGroupInfo.Role, func(params *common.HostGroupParams) {
+		params.MaxSize = desired
+	})
+}
+
+func (Provider) SetHostGroupSpot(name, hostGroup string, onDemandBase, spotPercentage int64) error {
+	h, err := findHostGroup(name, hostGroup)
+	if err != nil {
+		return err
+	}
+	if h.HostGroupInfo.Role != common.RoleClient {
+		return errors.New(fmt.Sprintf("host group %s is a %s host group, only client host groups can run spot instances", hostGroup, h.HostGroupInfo.Role))
+	}
+	if onDemandBase < 0 {
+		return errors.New(fmt.Sprintf("on-demand base %d is negative", onDemandBase))
+	}
+	if spotPercentage < 0 || spotPercentage > 100 {
+		return errors.New(fmt.Sprintf("spot percentage %d is not between 0 and 100", spotPercentage))
+	}
+	spot := common.SpotParams{Percentage: spotPercentage}
+	// the on-demand base only matters when some instances are spot, on-demand groups keep a single launch template
+	if spot.Enabled() {
+		spot.OnDemandBase = onDemandBase
+	}
+	asgName := common.GenerateResourceName(h.HostGroupInfo.ClusterName, h.HostGroupInfo.Name)
+	err = autoscaling.SetAutoScalingGroupSpot(asgName, spot)
+	if err != nil {
+		return err
+	}
+	return saveHostGroupParams(cluster.ClusterName(name), h.HostGroupInfo.Role, func(params *common.HostGroupParams) {
+		params.Spot = spot
+	})
+}
+
+// maxInstanceTypeWeight is the highest weighted capacity aws accepts
+const maxInstanceTypeWeight = 999
+
+func (Provider) SetHostGroupInstanceTypes(name, hostGroup string, instanceTypes []provider.InstanceTypeWeight, allocationStrategy string) error {
+	h, err := findHostGroup(name, hostGroup)
+	if err != nil {
+		return err
+	}
+	params := common.InstanceTypesParams{}
+	seen := map[string]bool{}
+	for _, instanceType := range instanceTypes {
+		if instanceType.InstanceType == "" {
+			return errors.New("instance type is empty")
+		}
+		if seen[instanceType.InstanceType] {
+			return errors.New(fmt.Sprintf("instance type %s is listed more than once", instanceType.InstanceType))
+		}
+		seen[instanceType.InstanceType] = true
+		if instanceType.Weight < 1 || instanceType.Weight > maxInstanceTypeWeight {
+			return errors.New(fmt.Sprintf("instance type %s weight %d is not between 1 and %d", instanceType.InstanceType, instanceType.Weight, maxInstanceTypeWeight))
+		}
+		params.Types = append(params.Types, common.InstanceTypeWeight{
+			InstanceType: instanceType.InstanceType,
+			Weight:       instanceType.Weight,
+		})
+	}
+	switch allocationStrategy {
+	case "", common.AllocationStrategyPrioritized, common.AllocationStrategyLowestPrice:
+	default:
+		return errors.New(fmt.Sprintf("allocation strategy %s is not one of %s, %s", allocationStrategy, common.AllocationStrategyPrioritized, common.AllocationStrategyLowestPrice))
+	}
+	if params.Enabled() {
+		params.AllocationStrategy = allocationStrategy
+		if params.AllocationStrategy == "" {
+			params.AllocationStrategy = common.AllocationStrategyPrioritized
+		}
+	}
+
+	asgName := common.GenerateResourceName(h.HostGroupInfo.ClusterName, h.HostGroupInfo.Name)
+	err = autoscaling.SetAutoScalingGroupInstanceTypes(asgName, params)
+	if err != nil {
+		return err
+	}
+	return saveHostGroupParams(cluster.ClusterName(name), h.HostGroupInfo.Role, func(hostGroupParams *common.HostGroupParams) {
+		hostGroupParams.InstanceTypes = params
+	})
+}
+
+// autoscaleMetrics are the cloudwatch metrics of the provider metrics
+var autoscaleMetrics = map[string]string{
+	provider.MetricThroughput: common.MetricFrontendThroughput,
+	provider.MetricIops:       common.MetricFrontendIops,
+	provider.MetricCpu:        common.MetricFrontendCpu,
+}
+
+func (Provider) EnableHostGroupAutoscale(name, hostGroup string, autoscale provider.Autoscale) error {
+	h, err := findHostGroup(name, hostGroup)
+	if err != nil {
+		return err
+	}
+	if h.HostGroupInfo.Role != common.RoleClient {
+		return errors.New(fmt.Sprintf("host group %s is a %s host group, only client host groups can be autoscaled", hostGroup, h.HostGroupInfo.Role))
+	}
+	metric, ok := autoscaleMetrics[autoscale.Metric]
+	if !ok {
+		return errors.New(fmt.Sprintf("metric %s is not one of %s, %s, %s", autoscale.Metric, provider.MetricThroughput, provider.MetricIops, provider.MetricCpu))
+	}
+	if autoscale.Target <= 0 {
+		return errors.New(fmt.Sprintf("target %g is not positive", autoscale.Target))
+	}
+	if autoscale.Metric == provider.MetricCpu && autoscale.Target > 100 {
+		return errors.New(fmt.Sprintf("cpu target %g is above 100%%", autoscale.Target))
+	}
+	if autoscale.MinSize < 0 {
+		return errors.New(fmt.Sprintf("min size %d is negative", autoscale.MinSize))
+	}
+	if autoscale.MaxSize < 1 || autoscale.MaxSize < autoscale.MinSize {
+		return errors.New(fmt.Sprintf("max size %d is below the min size %d or 1", autoscale.MaxSize, autoscale.MinSize))
+	}
+
+	// without the metrics lambda nothing publishes the metric, and the group would never scale
+	metrics := Lambda{HostGroupInfo: h.HostGroupInfo, Type: lambdas.LambdaMetrics}
+	metricsArn, err := lambdas.GetLambdaArn(metrics.ResourceName())
+	if err != nil {
+		return err
+	}
+	if metricsArn == "" {
+		return errors.New(fmt.Sprintf("host group %s has no metrics lambda, run cluster update first", hostGroup))
+	}
+
+	params := common.AutoscaleParams{
+		Metric:  metric,
+		Target:  autoscale.Target,
+		MinSize: autoscale.MinSize,
+	}
+	asgName := common.GenerateResourceName(h.HostGroupInfo.ClusterName, h.HostGroupInfo.Name)
+	err = autoscaling.SetAutoScalingGroupAutoscale(asgName, params, autoscale.MaxSize)
+	if err != nil {
+		return err
+	}
+	return saveHostGroupParams(cluster.ClusterName(name), h.HostGroupInfo.Role, func(hostGroupParams *common.HostGroupParams) {
+		hostGroupParams.Autoscale = params
+		hostGroupParams.MaxSize = autoscale.MaxSize
+	})
+}
+
+func (Provider) DisableHostGroupAutoscale(name, hostGroup string) error {
+	h, err := findHostGroup(name, hostGroup)
+	if err != nil {
+		return err
+	}
+	asgName := common.GenerateResourceName(h.HostGroupInfo.ClusterName, h.HostGroupInfo.Name)
+	err = autoscaling.DeleteAutoScalingGroupAutoscale(asgName)
+	if err != nil {
+		return err
+	}
+	return saveHostGroupParams(cluster.ClusterName(name), h.HostGroupInfo.Role, func(params *common.HostGroupParams) {
+		params.Autoscale = common.AutoscaleParams{}
+	})
+}
+
+// saveHostGroupParams updates the cluster params saved on import, so diff doesn't report host group changes made
+// through wekactl as drift
+func saveHostGroupParams(clusterName cluster.ClusterName, role common.InstanceRole, update func(params *common.HostGroupParams)) error {
+	tableName := common.GenerateResourceName(clusterName, "")
+	params, err := db.GetClusterParams(tableName)
+	if err != nil || params.Key == "" {
+		return err
+	}
+	if role == common.RoleBackend {
+		update(&params.Backends)
+	} else {
+		update(&params.Clients)
+	}
+	return db.SaveClusterParams(tableName, params)
+}
+
+func listWekaHosts(name string) (hosts weka.HostListResponse, err error) {
+	creds := db.ClusterCreds{}
+	err = db.GetItem(common.GenerateResourceName(cluster.ClusterName(name), ""), db.ModelClusterCreds, &creds)
+	if err != nil {
+		return
+	}
+	ips, err := common.GetBackendsPrivateIps(name)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	jpool := &jrpc.Pool{
+		Ips:     ips,
+		Clients: map[string]*jrpc.BaseClient{},
+		Builder: func(ip string) *jrpc.BaseClient {
+			return connectors.NewJrpcClient(ctx, ip, weka.ManagementJrpcPort, creds.Username, creds.Password)
+		},
+		Ctx: ctx,
+	}
+	err = jpool.Call(weka.JrpcHostList, struct{}{}, &hosts)
+	return
+}
+
+func (Provider) HostGroupProgress(name, hostGroup string) (progress provider.HostGroupProgress, err error) {
+	asgName, err := hostGroupAutoScalingGroupName(name, hostGroup)
+	if err != nil {
+		return
+	}
+	progress.Desired, progress.Instances, err = autoscaling.GetAutoScalingGroupCapacity(asgName)
+	if err != nil {
+		return
+	}
+	instances, err := common.GetASGInstances(asgName)
+	if err != nil {
+		return
+	}
+	// the desired capacity of a group with weighted instance types counts capacity units rather than instances
+	progress.Instances = 0
+	weights := map[string]int64{}
+	for _, instance := range instances {
+		weights[*instance.InstanceId] = autoscaling.InstanceWeight(instance)
+		progress.Instances += weights[*instance.InstanceId]
+	}
+	hosts, err := listWekaHosts(name)
+	if err != nil {
+		return
+	}
+	for _, host := range hosts {
+		if host.State == "ACTIVE" {
+			progress.Active += weights[host.Aws.InstanceId]
+		}
+	}
+	return
+}
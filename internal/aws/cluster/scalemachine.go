@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/aws/aws-sdk-go/service/lambda"
 	"github.com/rs/zerolog/log"
+	"time"
 	"wekactl/internal/aws/common"
 	"wekactl/internal/aws/iam"
 	"wekactl/internal/aws/lambdas"
@@ -11,7 +12,7 @@ import (
 	"wekactl/internal/cluster"
 )
 
-const scaleMachineVersion = "v1"
+const scaleMachineVersion = "v3"
 
 type ScaleMachine struct {
 	Arn             string
@@ -21,11 +22,14 @@ type ScaleMachine struct {
 	HostGroupInfo   common.HostGroupInfo
 	HostGroupParams common.HostGroupParams
 	fetch           Lambda
+	lifecycle       Lambda
+	metrics         Lambda
 	scale           Lambda
 	terminate       Lambda
 	transient       Lambda
 	StateMachine    scalemachine.StateMachine
 	Profile         IamProfile
+	Inventory       *Inventory
 }
 
 func (s *ScaleMachine) Tags() cluster.Tags {
@@ -33,26 +37,50 @@ func (s *ScaleMachine) Tags() cluster.Tags {
 }
 
 func (s *ScaleMachine) SubResources() []cluster.Resource {
-	return []cluster.Resource{&s.fetch, &s.scale, &s.terminate, &s.transient, &s.Profile}
+	if s.HostGroupInfo.Role == common.RoleClient {
+		return []cluster.Resource{&s.fetch, &s.lifecycle, &s.metrics, &s.scale, &s.terminate, &s.transient, &s.Profile}
+	}
+	return []cluster.Resource{&s.fetch, &s.lifecycle, &s.scale, &s.terminate, &s.transient, &s.Profile}
 }
 
 func (s *ScaleMachine) ResourceName() string {
 	return common.GenerateResourceName(s.HostGroupInfo.ClusterName, s.HostGroupInfo.Name)
 }
 
-func (s *ScaleMachine) Fetch() error {
-	version, err := scalemachine.GetStateMachineVersion(s.ResourceName())
-	if err != nil {
-		return err
+func (s *ScaleMachine) inventoryName() string {
+	return s.ResourceName()
+}
+
+func (s *ScaleMachine) inventoryId() string {
+	if s.Arn == "" {
+		return s.ResourceName()
 	}
-	s.Version = version
+	return s.Arn
+}
 
-	if s.Profile.Arn == "" {
-		profileArn, err := iam.GetIamRoleArn(s.Profile.resourceNameBase())
+func (s *ScaleMachine) Fetch() error {
+	if item, ok := s.Inventory.Get(s); ok {
+		s.Version = item.Version
+		s.Arn = item.Id
+	} else {
+		version, err := scalemachine.GetStateMachineVersion(s.ResourceName())
 		if err != nil {
 			return err
 		}
-		s.Profile.Arn = profileArn
+		s.Version = version
+
+		if version != "" && s.Arn == "" {
+			arn, err := scalemachine.GetStateMachineArn(s.ResourceName())
+			if err != nil {
+				return err
+			}
+			s.Arn = arn
+		}
+	}
+
+	err := s.Profile.FetchArn()
+	if err != nil {
+		return err
 	}
 
 	if s.fetch.Arn == "" {
@@ -63,6 +91,22 @@ func (s *ScaleMachine) Fetch() error {
 		s.fetch.Arn = backendArn
 	}
 
+	if s.lifecycle.Arn == "" {
+		backendArn, err := lambdas.GetLambdaArn(s.lifecycle.ResourceName())
+		if err != nil {
+			return err
+		}
+		s.lifecycle.Arn = backendArn
+	}
+
+	if s.HostGroupInfo.Role == common.RoleClient && s.metrics.Arn == "" {
+		backendArn, err := lambdas.GetLambdaArn(s.metrics.ResourceName())
+		if err != nil {
+			return err
+		}
+		s.metrics.Arn = backendArn
+	}
+
 	if s.scale.Arn == "" {
 		backendArn, err := lambdas.GetLambdaArn(s.scale.ResourceName())
 		if err != nil {
@@ -90,6 +134,24 @@ func (s *ScaleMachine) Fetch() error {
 	return nil
 }
 
+func (s *ScaleMachine) Status() (status cluster.ResourceStatus, err error) {
+	status.Id = s.inventoryId()
+	if s.Arn == "" {
+		return
+	}
+	execution, err := scalemachine.GetLastExecution(s.Arn)
+	if err != nil {
+		return
+	}
+	if execution == nil {
+		status.AddDetail("last execution", "none")
+		return
+	}
+	status.AddDetail("last execution", *execution.Status)
+	status.AddDetail("started", execution.StartDate.Format(time.RFC3339))
+	return
+}
+
 func (s *ScaleMachine) DeployedVersion() string {
 	return s.Version
 }
@@ -99,18 +161,25 @@ func (s *ScaleMachine) TargetVersion() string {
 }
 
 func (s *ScaleMachine) Delete() error {
+	if item, ok := s.Inventory.Get(s); ok {
+		return scalemachine.DeleteStateMachineArn(item.Id)
+	}
 	return scalemachine.DeleteStateMachine(s.ResourceName())
 }
 
-func (s *ScaleMachine) Create() (err error) {
-	stateMachineLambdasArn := scalemachine.StateMachineLambdasArn{
+func (s *ScaleMachine) lambdasArn() scalemachine.StateMachineLambdasArn {
+	return scalemachine.StateMachineLambdasArn{
 		Fetch:     s.fetch.Arn,
+		Lifecycle: s.lifecycle.Arn,
+		Metrics:   s.metrics.Arn,
 		Scale:     s.scale.Arn,
 		Terminate: s.terminate.Arn,
 		Transient: s.transient.Arn,
 	}
+}
 
-	arn, err := scalemachine.CreateStateMachine(s.Tags().AsSfn(), stateMachineLambdasArn, s.Profile.Arn, s.ResourceName())
+func (s *ScaleMachine) Create() (err error) {
+	arn, err := scalemachine.CreateStateMachine(s.Tags().AsSfn(), s.lambdasArn(), s.Profile.Arn, s.ResourceName())
 	if err != nil {
 		return
 	}
@@ -119,7 +188,17 @@ func (s *ScaleMachine) Create() (err error) {
 }
 
 func (s *ScaleMachine) Update() error {
-	panic("update not supported")
+	arn, err := scalemachine.UpdateStateMachine(
+		cluster.GetResourceVersionTag(s.TargetVersion()).AsSfn(), s.lambdasArn(), s.Profile.Arn, s.ResourceName())
+	if err != nil {
+		return err
+	}
+	s.Arn = *arn
+	return nil
+}
+
+func (s *ScaleMachine) Diff() (cluster.Drifts, error) {
+	return scalemachine.DiffStateMachine(s.lambdasArn(), s.Profile.Arn, s.ResourceName())
 }
 
 func (s *ScaleMachine) Init() {
@@ -130,20 +209,43 @@ func (s *ScaleMachine) Init() {
 	s.Profile.AssumeRolePolicy = iam.GetStateMachineAssumeRolePolicy()
 	s.Profile.HostGroupInfo = s.HostGroupInfo
 	s.Profile.Policy = iam.GetStateMachineRolePolicy()
+	s.Profile.Inventory = s.Inventory
 	s.Profile.Init()
 
 	vpcConfig := lambdas.GetLambdaVpcConfig(s.HostGroupParams.Subnet, s.HostGroupParams.SecurityGroupsIds)
 
 	s.fetch.TableName = s.TableName
 	s.fetch.ASGName = s.ASGName
+	s.fetch.Inventory = s.Inventory
 	s.fetch.HostGroupInfo = s.HostGroupInfo
 	s.fetch.Type = lambdas.LambdaFetchInfo
 	s.fetch.VPCConfig = lambda.VpcConfig{}
 	s.fetch.Permissions = iam.GetJoinAndFetchLambdaPolicy()
 	s.fetch.Init()
 
+	s.lifecycle.TableName = s.TableName
+	s.lifecycle.ASGName = s.ASGName
+	s.lifecycle.Inventory = s.Inventory
+	s.lifecycle.HostGroupInfo = s.HostGroupInfo
+	s.lifecycle.Type = lambdas.LambdaLifecycle
+	s.lifecycle.VPCConfig = vpcConfig
+	s.lifecycle.Permissions = iam.GetLifecycleLambdaPolicy()
+	s.lifecycle.Init()
+
+	if s.HostGroupInfo.Role == common.RoleClient {
+		s.metrics.TableName = s.TableName
+		s.metrics.ASGName = s.ASGName
+		s.metrics.Inventory = s.Inventory
+		s.metrics.HostGroupInfo = s.HostGroupInfo
+		s.metrics.Type = lambdas.LambdaMetrics
+		s.metrics.VPCConfig = vpcConfig
+		s.metrics.Permissions = iam.GetMetricsLambdaPolicy()
+		s.metrics.Init()
+	}
+
 	s.scale.TableName = s.TableName
 	s.scale.ASGName = s.ASGName
+	s.scale.Inventory = s.Inventory
 	s.scale.HostGroupInfo = s.HostGroupInfo
 	s.scale.Type = lambdas.LambdaScale
 	s.scale.VPCConfig = vpcConfig
@@ -152,6 +254,7 @@ func (s *ScaleMachine) Init() {
 
 	s.terminate.TableName = s.TableName
 	s.terminate.ASGName = s.ASGName
+	s.terminate.Inventory = s.Inventory
 	s.terminate.HostGroupInfo = s.HostGroupInfo
 	s.terminate.Type = lambdas.LambdaTerminate
 	s.terminate.VPCConfig = lambda.VpcConfig{}
@@ -160,6 +263,7 @@ func (s *ScaleMachine) Init() {
 
 	s.transient.TableName = s.TableName
 	s.transient.ASGName = s.ASGName
+	s.transient.Inventory = s.Inventory
 	s.transient.HostGroupInfo = s.HostGroupInfo
 	s.transient.Type = lambdas.LambdaTransient
 	s.transient.VPCConfig = lambda.VpcConfig{}
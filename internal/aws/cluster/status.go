@@ -0,0 +1,17 @@
+package cluster
+
+import (
+	"wekactl/internal/cluster"
+)
+
+// StatusCluster fetches every cluster resource, using the inventory when it was recorded,
+// and returns the cluster status tree
+func StatusCluster(stackName string) (tree cluster.ResourceStatusTree, err error) {
+	awsCluster := generateExistingAWSCluster(stackName)
+	err = awsCluster.LoadInventory()
+	if err != nil {
+		return
+	}
+	awsCluster.Init()
+	return cluster.StatusResource(&awsCluster)
+}
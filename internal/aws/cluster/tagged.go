@@ -0,0 +1,163 @@
+package cluster
+
+import (
+	"fmt"
+	"wekactl/internal/aws/apigateway"
+	"wekactl/internal/aws/autoscaling"
+	"wekactl/internal/aws/cloudwatch"
+	"wekactl/internal/aws/db"
+	"wekactl/internal/aws/iam"
+	"wekactl/internal/aws/kms"
+	"wekactl/internal/aws/lambdas"
+	"wekactl/internal/aws/launchtemplate"
+	"wekactl/internal/aws/scalemachine"
+	"wekactl/internal/cluster"
+)
+
+// DiscoverTaggedResources finds every resource of the cluster across all the services wekactl uses,
+// the resources are returned in the order they are safe to delete: triggers before what they invoke,
+// users before their roles, and the table before its kms key
+func DiscoverTaggedResources(clusterName cluster.ClusterName) (resources []cluster.TaggedResource, err error) {
+	add := func(resourceType, name string, delete func() error) {
+		resources = append(resources, cluster.NewTaggedResource(resourceType, name, delete))
+	}
+
+	ruleNames, err := cloudwatch.GetClusterEventRules(clusterName)
+	if err != nil {
+		return
+	}
+	for _, name := range ruleNames {
+		name := name
+		add("CloudWatch rule", name, func() error { return cloudwatch.DeleteCloudWatchEventRule(name) })
+	}
+
+	asgNames, err := autoscaling.GetClusterAutoScalingGroups(clusterName)
+	if err != nil {
+		return
+	}
+	for _, name := range asgNames {
+		name := name
+		add("Auto scaling group", name, func() error { return autoscaling.DeleteAutoScalingGroup(name) })
+	}
+
+	stateMachines, err := scalemachine.GetClusterStateMachines(clusterName)
+	if err != nil {
+		return
+	}
+	for _, stateMachine := range stateMachines {
+		arn := *stateMachine.StateMachineArn
+		add("State machine", *stateMachine.Name, func() error { return scalemachine.DeleteStateMachineArn(arn) })
+	}
+
+	restApis, err := apigateway.GetClusterRestApis(clusterName)
+	if err != nil {
+		return
+	}
+	var restApiIds []string
+	for _, restApi := range restApis {
+		id := *restApi.Id
+		restApiIds = append(restApiIds, id)
+		add("Rest api", fmt.Sprintf("%s (%s)", *restApi.Name, id), func() error { return apigateway.DeleteRestApi(id) })
+	}
+
+	var joinApiNames []string
+	for _, resource := range clusterResources(clusterName) {
+		if joinApi, ok := resource.(*ApiGateway); ok {
+			joinApiNames = append(joinApiNames, joinApi.ResourceName())
+		}
+	}
+	usagePlans, err := apigateway.GetClusterUsagePlans(clusterName, restApiIds, joinApiNames)
+	if err != nil {
+		return
+	}
+	for _, usagePlan := range usagePlans {
+		usagePlan := usagePlan
+		add("Usage plan", fmt.Sprintf("%s (%s)", *usagePlan.Name, *usagePlan.Id), func() error { return apigateway.DeleteUsagePlan(usagePlan) })
+	}
+
+	apiKeys, err := apigateway.GetClusterApiKeys(clusterName, joinApiNames)
+	if err != nil {
+		return
+	}
+	for _, apiKey := range apiKeys {
+		id := *apiKey.Id
+		add("Api key", fmt.Sprintf("%s (%s)", *apiKey.Name, id), func() error { return apigateway.DeleteApiKey(id) })
+	}
+
+	launchTemplateNames, err := launchtemplate.GetClusterLaunchTemplates(clusterName)
+	if err != nil {
+		return
+	}
+	for _, name := range launchTemplateNames {
+		name := name
+		add("Launch template", name, func() error { return launchtemplate.DeleteLaunchTemplate(name) })
+	}
+
+	lambdaNames, err := lambdas.GetClusterLambdas(clusterName)
+	if err != nil {
+		return
+	}
+	for _, name := range lambdaNames {
+		name := name
+		add("Lambda", name, func() error { return lambdas.DeleteLambda(name) })
+	}
+
+	roleNames, err := iam.GetClusterRoles(clusterName)
+	if err != nil {
+		return
+	}
+	for _, name := range roleNames {
+		name := name
+		add("IAM role", name, func() error { return iam.DeleteIamRoleByName(name) })
+	}
+
+	var policyNames []string
+	for _, resource := range clusterResources(clusterName) {
+		if profile, ok := resource.(*IamProfile); ok {
+			policyNames = append(policyNames, profile.PolicyName)
+		}
+	}
+	policies, err := iam.GetLeftoverPolicies(policyNames)
+	if err != nil {
+		return
+	}
+	for _, policy := range policies {
+		arn := *policy.Arn
+		add("IAM policy", *policy.PolicyName, func() error { return iam.DeletePolicy(arn) })
+	}
+
+	tableNames, err := db.GetClusterTables(clusterName)
+	if err != nil {
+		return
+	}
+	for _, name := range tableNames {
+		name := name
+		add("DynamoDB table", name, func() error { return db.DeleteDB(name) })
+	}
+
+	keyIds, err := kms.GetClusterKmsKeys(clusterName)
+	if err != nil {
+		return
+	}
+	for _, id := range keyIds {
+		id := id
+		add("KMS key", id, func() error { return kms.DeleteKMSKeyId(id) })
+	}
+
+	return
+}
+
+// clusterResources returns the resources the cluster is made of, with the names they are created with
+func clusterResources(clusterName cluster.ClusterName) (resources []cluster.Resource) {
+	awsCluster := generateExistingAWSCluster(string(clusterName))
+	awsCluster.Init()
+	var walk func(resource cluster.Resource)
+	walk = func(resource cluster.Resource) {
+		resources = append(resources, resource)
+		for _, subResource := range resource.SubResources() {
+			walk(subResource)
+		}
+	}
+	walk(&awsCluster)
+	return
+}
@@ -0,0 +1,66 @@
+package cluster
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"strings"
+	"testing"
+	"wekactl/internal/cluster"
+)
+
+func TestDiscoverLeftoverPolicies(t *testing.T) {
+	a := setupFakeStack(t)
+	document := `{"Version":"2012-10-17","Statement":[]}`
+	for _, name := range []string{"wekactl-test-cluster-cw-Clients", "wekactl-test-cluster-eu-cw-Clients", "wekactl-test-cluster-custom"} {
+		_, err := a.IAM.CreatePolicy(&iam.CreatePolicyInput{PolicyName: aws.String(name), PolicyDocument: aws.String(document)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resources, err := DiscoverTaggedResources(cluster.ClusterName(testStackName))
+	if err != nil {
+		t.Fatalf("DiscoverTaggedResources() error = %v", err)
+	}
+	var policies []string
+	for _, resource := range resources {
+		if resource.Type == "IAM policy" {
+			policies = append(policies, resource.Name)
+		}
+	}
+	if len(policies) != 1 || policies[0] != "wekactl-test-cluster-cw-Clients" {
+		t.Errorf("DiscoverTaggedResources() policies = %v, want only the leftover policy of the cluster", policies)
+	}
+}
+
+func TestDiscoverUntaggedJoinApiResources(t *testing.T) {
+	a := setupFakeStack(t)
+	// usage plans and api keys created before they were tagged
+	for _, name := range []string{"wekactl-test-cluster-Backends", "wekactl-test-cluster-eu-Backends"} {
+		_, err := a.ApiGateway.CreateUsagePlan(&apigateway.CreateUsagePlanInput{Name: aws.String(name)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = a.ApiGateway.CreateApiKey(&apigateway.CreateApiKeyInput{Name: aws.String(name)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resources, err := DiscoverTaggedResources(cluster.ClusterName(testStackName))
+	if err != nil {
+		t.Fatalf("DiscoverTaggedResources() error = %v", err)
+	}
+	found := map[string]int{}
+	for _, resource := range resources {
+		if strings.HasPrefix(resource.Name, "wekactl-test-cluster-Backends ") {
+			found[resource.Type]++
+		} else if strings.HasPrefix(resource.Name, "wekactl-test-cluster-eu-") {
+			t.Errorf("DiscoverTaggedResources() found %s %s of another cluster", resource.Type, resource.Name)
+		}
+	}
+	if found["Usage plan"] != 1 || found["Api key"] != 1 {
+		t.Errorf("DiscoverTaggedResources() found %v, want the untagged usage plan and api key of the cluster", found)
+	}
+}
@@ -2,28 +2,25 @@ package cluster
 
 import (
 	"github.com/aws/aws-sdk-go/service/autoscaling"
-	"github.com/aws/aws-sdk-go/service/ec2"
+	autoscaling2 "wekactl/internal/aws/autoscaling"
 	"wekactl/internal/aws/common"
 	"wekactl/internal/aws/db"
+	"wekactl/internal/aws/launchtemplate"
 	"wekactl/internal/cluster"
 	"wekactl/internal/connectors"
 )
 
 func fetchClusterLaunchTemplateParams(clusterName cluster.ClusterName, name common.HostGroupName) (hostGroupParams common.HostGroupParams, err error) {
 	resourceName := common.GenerateResourceName(clusterName, name)
-	svc := connectors.GetAWSSession().EC2
-
-	launchTemplateVersionsOutput, err := svc.DescribeLaunchTemplateVersions(&ec2.DescribeLaunchTemplateVersionsInput{
-		LaunchTemplateName: &resourceName,
-	})
-
+	launchTemplateData, err := launchtemplate.GetDefaultLaunchTemplateData(resourceName)
 	if err != nil {
 		return
 	}
 
-	launchTemplateData := launchTemplateVersionsOutput.LaunchTemplateVersions[0].LaunchTemplateData
-
 	maxSize := int64(1000)
+	var spot common.SpotParams
+	var instanceTypes common.InstanceTypesParams
+	var autoscale common.AutoscaleParams
 	svcAsg := connectors.GetAWSSession().ASG
 	asgOutput, err := svcAsg.DescribeAutoScalingGroups(
 		&autoscaling.DescribeAutoScalingGroupsInput{
@@ -32,10 +29,16 @@ func fetchClusterLaunchTemplateParams(clusterName cluster.ClusterName, name comm
 	)
 	if err == nil && len(asgOutput.AutoScalingGroups) > 0 {
 		maxSize = *asgOutput.AutoScalingGroups[0].MaxSize
+		spot = autoscaling2.GroupSpotParams(asgOutput.AutoScalingGroups[0])
+		instanceTypes = autoscaling2.GroupInstanceTypes(asgOutput.AutoScalingGroups[0])
+		autoscale, err = autoscaling2.GroupAutoscale(asgOutput.AutoScalingGroups[0])
+		if err != nil {
+			return
+		}
 	}
 
 	hostGroupParams = common.HostGroupParams{
-		SecurityGroupsIds: launchTemplateData.SecurityGroupIds,
+		SecurityGroupsIds: launchTemplateData.NetworkInterfaces[0].Groups,
 		ImageID:           *launchTemplateData.ImageId,
 		KeyName:           *launchTemplateData.KeyName,
 		IamArn:            *launchTemplateData.IamInstanceProfile.Arn,
@@ -45,6 +48,9 @@ func fetchClusterLaunchTemplateParams(clusterName cluster.ClusterName, name comm
 		VolumeType:        *launchTemplateData.BlockDeviceMappings[0].Ebs.VolumeType,
 		VolumeSize:        *launchTemplateData.BlockDeviceMappings[0].Ebs.VolumeSize,
 		MaxSize:           maxSize,
+		Spot:              spot,
+		InstanceTypes:     instanceTypes,
+		Autoscale:         autoscale,
 	}
 
 	return
@@ -79,8 +85,12 @@ func generateUpdateAWSCluster(stackName string) (awsCluster AWSCluster, err erro
 	}
 
 	awsCluster = AWSCluster{
-		Name:          clusterName,
-		DefaultParams: db.DefaultClusterParams{},
+		Name: clusterName,
+		DefaultParams: db.DefaultClusterParams{
+			Backends: backendsHostGroup.HostGroupParams,
+			Clients:  clientsHostGroup.HostGroupParams,
+			Subnet:   backendsHostGroup.HostGroupParams.Subnet,
+		},
 		CFStack: Stack{
 			StackName: stackName,
 		},
@@ -99,6 +109,30 @@ func UpdateCluster(stackName string) error {
 		return err
 	}
 
+	err = awsCluster.LoadInventory()
+	if err != nil {
+		return err
+	}
+	awsCluster.Init()
+	err = cluster.EnsureResource(&awsCluster, nil)
+	if err != nil {
+		return err
+	}
+
+	awsCluster.recordInventory()
+	return nil
+}
+
+func PlanUpdateCluster(stackName string) (plan cluster.ResourcePlan, err error) {
+	awsCluster, err := generateUpdateAWSCluster(stackName)
+	if err != nil {
+		return
+	}
+
+	err = awsCluster.LoadInventory()
+	if err != nil {
+		return
+	}
 	awsCluster.Init()
-	return cluster.EnsureResource(&awsCluster)
+	return cluster.PlanResource(&awsCluster)
 }
@@ -6,26 +6,33 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/ec2"
-	"github.com/olekukonko/tablewriter"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/sync/semaphore"
 	"math"
-	"os"
 	"sync"
 	"wekactl/internal/cluster"
 	"wekactl/internal/connectors"
 	strings2 "wekactl/internal/lib/strings"
+	"wekactl/internal/lib/table"
 	"wekactl/internal/lib/types"
 )
 
 type InstanceIdsSet map[string]types.Nilt
 
+// ProtectTagKey tagged "true" on an instance opts it out of scale in, e.g. a client running a critical workload
+const ProtectTagKey = "wekactl.io/protect"
+
+func IsInstanceProtected(instance *ec2.Instance) bool {
+	for _, tag := range instance.Tags {
+		if aws.StringValue(tag.Key) == ProtectTagKey && aws.StringValue(tag.Value) == "true" {
+			return true
+		}
+	}
+	return false
+}
+
 func RenderTable(fields []string, data [][]string) {
-	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader(fields)
-	table.SetRowLine(true)
-	table.AppendBulk(data)
-	table.Render()
+	table.Render(fields, data)
 }
 
 func setDisableInstanceApiTermination(instanceId string, value bool) (*ec2.ModifyInstanceAttributeOutput, error) {
@@ -72,6 +79,29 @@ func SetDisableInstancesApiTermination(instanceIds []string, value bool) (update
 	return
 }
 
+func getDisableInstanceApiTermination(instanceId string) (bool, error) {
+	svc := connectors.GetAWSSession().EC2
+	output, err := svc.DescribeInstanceAttribute(&ec2.DescribeInstanceAttributeInput{
+		Attribute:  aws.String(ec2.InstanceAttributeNameDisableApiTermination),
+		InstanceId: aws.String(instanceId),
+	})
+	if err != nil {
+		return false, err
+	}
+	return output.DisableApiTermination != nil && *output.DisableApiTermination.Value, nil
+}
+
+func GetDisableInstancesApiTermination(instanceIds []string) (values map[string]bool, err error) {
+	values = make(map[string]bool)
+	for _, instanceId := range instanceIds {
+		value, err := getDisableInstanceApiTermination(instanceId)
+		if err != nil {
+			return nil, err
+		}
+		values[instanceId] = value
+	}
+	return
+}
 
 func GetASGInstances(asgName string) ([]*autoscaling.Instance, error) {
 	svc := connectors.GetAWSSession().ASG
@@ -207,7 +237,7 @@ func GetBackendsPrivateIps(clusterName string) (ips []string, err error) {
 				},
 			},
 			{
-				Name: aws.String("tag:wekactl.io/cluster_name"),
+				Name: aws.String("tag:" + cluster.ClusterNameTagKey),
 				Values: []*string{
 					&clusterName,
 				},
@@ -20,6 +20,80 @@ type HostGroupParams struct {
 	VolumeType        string
 	VolumeSize        int64
 	MaxSize           int64
+	Spot              SpotParams
+	InstanceTypes     InstanceTypesParams
+	Autoscale         AutoscaleParams
+}
+
+// SpotParams is the spot share of a client host group, the zero value runs on-demand instances only
+type SpotParams struct {
+	// OnDemandBase instances are on-demand before any spot instance is launched
+	OnDemandBase int64
+	// Percentage of the instances above the on-demand base that are spot instances
+	Percentage int64
+}
+
+func (s SpotParams) Enabled() bool {
+	return s.Percentage > 0
+}
+
+const AllocationStrategyPrioritized = "prioritized"
+const AllocationStrategyLowestPrice = "lowest-price"
+
+// InstanceTypeWeight is an instance type a host group launches, Weight is the capacity units an instance of it
+// counts for in the auto scaling group desired capacity
+type InstanceTypeWeight struct {
+	InstanceType string
+	Weight       int64
+}
+
+// InstanceTypesParams are the instance types a host group launches, the zero value launches the launch template
+// instance type only
+type InstanceTypesParams struct {
+	Types []InstanceTypeWeight
+	// AllocationStrategy picks the type of on-demand instances, prioritized follows the Types order and lowest-price
+	// picks the cheapest of them
+	AllocationStrategy string
+}
+
+func (i InstanceTypesParams) Enabled() bool {
+	return len(i.Types) > 0
+}
+
+// MetricsNamespace is the cloudwatch namespace of the metrics the metrics lambda publishes
+const MetricsNamespace = "wekactl"
+
+// MetricsDimension is the dimension of every host group metric, its value is the host group auto scaling group name
+const MetricsDimension = "AutoScalingGroupName"
+
+// Host group metrics are averages over the active weka hosts of the host group instances
+const (
+	// MetricFrontendThroughput is how many megabytes per second a frontend reads and writes
+	MetricFrontendThroughput = "FrontendThroughput"
+	// MetricFrontendIops is how many operations per second a frontend serves
+	MetricFrontendIops = "FrontendIops"
+	// MetricFrontendCpu is the cpu utilization percentage of the frontend nodes
+	MetricFrontendCpu = "FrontendCpu"
+)
+
+// MetricUnits are the cloudwatch units of the host group metrics
+var MetricUnits = map[string]string{
+	MetricFrontendThroughput: "Megabytes/Second",
+	MetricFrontendIops:       "Count/Second",
+	MetricFrontendCpu:        "Percent",
+}
+
+// AutoscaleParams make a client host group track a target value of one of its metrics, the zero value leaves the
+// desired capacity to be set by hand
+type AutoscaleParams struct {
+	Metric string
+	Target float64
+	// MinSize is the auto scaling group min size, the max size is the host group MaxSize
+	MinSize int64
+}
+
+func (a AutoscaleParams) Enabled() bool {
+	return a.Metric != ""
 }
 
 type HostGroupInfo struct {
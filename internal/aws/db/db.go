@@ -47,30 +47,56 @@ func GetItem(tableName string, key string, item interface{}) error {
 	}
 }
 
+func DeleteItem(tableName string, key string) error {
+	svc := connectors.GetAWSSession().DynamoDB
+	_, err := svc.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName: aws.String(tableName),
+		Key: map[string]*dynamodb.AttributeValue{
+			"Key": {
+				S: aws.String(key),
+			},
+		},
+	})
+	return err
+}
+
 func CreateDb(tableName, kmsKey string, tags cluster.Tags) error {
+	return createTable(tableName, tags, &dynamodb.SSESpecification{
+		Enabled:        aws.Bool(true),
+		KMSMasterKeyId: &kmsKey,
+		SSEType:        aws.String("KMS"),
+	}, tableKey{name: "Key", attributeType: dynamodb.ScalarAttributeTypeS})
+}
+
+type tableKey struct {
+	name          string
+	attributeType string
+}
+
+// createTable creates a table keyed by the hash key and the optional range key that follows it,
+// sseSpecification may be nil for the default aws owned key encryption
+func createTable(tableName string, tags cluster.Tags, sseSpecification *dynamodb.SSESpecification, keys ...tableKey) error {
 	svc := connectors.GetAWSSession().DynamoDB
 
 	input := &dynamodb.CreateTableInput{
-		AttributeDefinitions: []*dynamodb.AttributeDefinition{
-			{
-				AttributeName: aws.String("Key"),
-				AttributeType: aws.String("S"),
-			},
-		},
-		KeySchema: []*dynamodb.KeySchemaElement{
-			{
-				AttributeName: aws.String("Key"),
-				KeyType:       aws.String("HASH"),
-			},
-		},
-		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
-		TableName:   aws.String(tableName),
-		Tags:        tags.ToDynamoDb(),
-		SSESpecification: &dynamodb.SSESpecification{
-			Enabled:        aws.Bool(true),
-			KMSMasterKeyId: &kmsKey,
-			SSEType:        aws.String("KMS"),
-		},
+		BillingMode:      aws.String(dynamodb.BillingModePayPerRequest),
+		TableName:        aws.String(tableName),
+		Tags:             tags.ToDynamoDb(),
+		SSESpecification: sseSpecification,
+	}
+	for i, key := range keys {
+		keyType := dynamodb.KeyTypeHash
+		if i > 0 {
+			keyType = dynamodb.KeyTypeRange
+		}
+		input.AttributeDefinitions = append(input.AttributeDefinitions, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(key.name),
+			AttributeType: aws.String(key.attributeType),
+		})
+		input.KeySchema = append(input.KeySchema, &dynamodb.KeySchemaElement{
+			AttributeName: aws.String(key.name),
+			KeyType:       aws.String(keyType),
+		})
 	}
 
 	_, err := svc.CreateTable(input)
@@ -134,7 +160,7 @@ func ChangeCredentials(tableName string, username, password string) error {
 	return nil
 }
 
-func saveClusterParams(tableName string, params DefaultClusterParams) error {
+func SaveClusterParams(tableName string, params DefaultClusterParams) error {
 	if params.Key == "" {
 		params.Key = ModelDefaultClusterParams
 	}
@@ -146,6 +172,94 @@ func saveClusterParams(tableName string, params DefaultClusterParams) error {
 	return nil
 }
 
+// GetClusterParams returns the cluster params saved on import, the returned Key is empty if none were saved
+func GetClusterParams(tableName string) (params DefaultClusterParams, err error) {
+	err = GetItem(tableName, ModelDefaultClusterParams, &params)
+	if err != nil {
+		if _, ok := err.(*dynamodb.ResourceNotFoundException); ok {
+			return params, nil
+		}
+	}
+	return
+}
+
+func SaveScalingPolicy(tableName, asgName string, policy cluster.ScalingPolicy) error {
+	err := PutItem(tableName, ScalingPolicy{
+		Key:           ModelScalingPolicyPrefix + asgName,
+		ScalingPolicy: policy,
+	})
+	if err != nil {
+		log.Debug().Msgf("error saving %s scaling policy to DB %v", asgName, err)
+		return err
+	}
+	return nil
+}
+
+// GetScalingPolicy returns the host group scaling policy, or the default one if none was set
+func GetScalingPolicy(tableName, asgName string) (policy cluster.ScalingPolicy, err error) {
+	item := ScalingPolicy{}
+	err = GetItem(tableName, ModelScalingPolicyPrefix+asgName, &item)
+	if err != nil {
+		return
+	}
+	if item.Key == "" {
+		return cluster.DefaultScalingPolicy(), nil
+	}
+	return item.ScalingPolicy, nil
+}
+
+func SaveScalingPause(tableName string, pause cluster.ScalingPause) error {
+	err := PutItem(tableName, ScalingPause{
+		Key:          ModelScalingPause,
+		ScalingPause: pause,
+	})
+	if err != nil {
+		log.Debug().Msgf("error saving scaling pause to DB %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetScalingPause returns the cluster scaling pause, a zero pause if scaling wasn't paused
+func GetScalingPause(tableName string) (pause cluster.ScalingPause, err error) {
+	item := ScalingPause{}
+	err = GetItem(tableName, ModelScalingPause, &item)
+	if err != nil {
+		return
+	}
+	return item.ScalingPause, nil
+}
+
+func DeleteScalingPause(tableName string) error {
+	return DeleteItem(tableName, ModelScalingPause)
+}
+
+func SaveCoreCounts(tableName, instanceType string, counts cluster.CoreCounts) error {
+	err := PutItem(tableName, CoreCounts{
+		Key:        ModelCoreCountsPrefix + instanceType,
+		CoreCounts: counts,
+	})
+	if err != nil {
+		log.Debug().Msgf("error saving %s core counts to DB %v", instanceType, err)
+		return err
+	}
+	return nil
+}
+
+// GetCoreCounts returns the core counts override of the instance type, found is false if there is none
+func GetCoreCounts(tableName, instanceType string) (counts cluster.CoreCounts, found bool, err error) {
+	item := CoreCounts{}
+	err = GetItem(tableName, ModelCoreCountsPrefix+instanceType, &item)
+	if err != nil {
+		return
+	}
+	return item.CoreCounts, item.Key != "", nil
+}
+
+func DeleteCoreCounts(tableName, instanceType string) error {
+	return DeleteItem(tableName, ModelCoreCountsPrefix+instanceType)
+}
+
 func DeleteDB(tableName string) error {
 	svc := connectors.GetAWSSession().DynamoDB
 	_, err := svc.DeleteTable(&dynamodb.DeleteTableInput{
@@ -188,3 +302,85 @@ func GetDbVersion(tableName string) (version string, err error) {
 
 	return
 }
+
+func TagDb(tableName string, tags cluster.Tags) error {
+	svc := connectors.GetAWSSession().DynamoDB
+	dbOutput, err := svc.DescribeTable(&dynamodb.DescribeTableInput{TableName: &tableName})
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.TagResource(&dynamodb.TagResourceInput{
+		ResourceArn: dbOutput.Table.TableArn,
+		Tags:        tags.ToDynamoDb(),
+	})
+	return err
+}
+
+// DiffDb compares the table billing mode and encryption with the ones CreateDb would set
+func DiffDb(tableName, kmsKey string) (drifts cluster.Drifts, err error) {
+	svc := connectors.GetAWSSession().DynamoDB
+	dbOutput, err := svc.DescribeTable(&dynamodb.DescribeTableInput{TableName: &tableName})
+	if err != nil {
+		return
+	}
+
+	drifts = diffBillingMode(dbOutput.Table)
+
+	sseStatus, sseKey := dynamodb.SSEStatusDisabled, ""
+	if dbOutput.Table.SSEDescription != nil {
+		sseStatus = aws.StringValue(dbOutput.Table.SSEDescription.Status)
+		sseKey = aws.StringValue(dbOutput.Table.SSEDescription.KMSMasterKeyArn)
+	}
+	drifts.Compare("sse_status", dynamodb.SSEStatusEnabled, sseStatus)
+	if kmsKey != "" && !strings.HasSuffix(sseKey, kmsKey) {
+		drifts.Compare("sse_kms_key", kmsKey, sseKey)
+	}
+	return
+}
+
+func diffBillingMode(table *dynamodb.TableDescription) (drifts cluster.Drifts) {
+	billingMode := dynamodb.BillingModeProvisioned
+	if table.BillingModeSummary != nil {
+		billingMode = aws.StringValue(table.BillingModeSummary.BillingMode)
+	}
+	drifts.Compare("billing_mode", dynamodb.BillingModePayPerRequest, billingMode)
+	return
+}
+
+// GetClusterTables returns the names of all the tables tagged with the cluster name
+func GetClusterTables(clusterName cluster.ClusterName) (tableNames []string, err error) {
+	svc := connectors.GetAWSSession().DynamoDB
+	var candidates []string
+	err = svc.ListTablesPages(&dynamodb.ListTablesInput{}, func(page *dynamodb.ListTablesOutput, lastPage bool) bool {
+		for _, tableName := range page.TableNames {
+			if strings.HasPrefix(*tableName, "wekactl-") {
+				candidates = append(candidates, *tableName)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return
+	}
+
+	for _, tableName := range candidates {
+		dbOutput, err := svc.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+		if err != nil {
+			return nil, err
+		}
+		tagsOutput, err := svc.ListTagsOfResource(&dynamodb.ListTagsOfResourceInput{
+			ResourceArn: dbOutput.Table.TableArn,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tagsOutput.Tags {
+			if *tag.Key == cluster.ClusterNameTagKey && *tag.Value == string(clusterName) {
+				tableNames = append(tableNames, tableName)
+				break
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/rs/zerolog/log"
+	"strconv"
+	"time"
+	"wekactl/internal/aws/common"
+	"wekactl/internal/cluster"
+	"wekactl/internal/connectors"
+	"wekactl/internal/logging"
+)
+
+const HistoryRetention = 7 * 24 * time.Hour
+const historyTtlAttribute = "ExpiresAt"
+
+func HistoryTableName(clusterName cluster.ClusterName) string {
+	return common.GenerateResourceName(clusterName, "") + "-history"
+}
+
+const historyHashKey = "AsgName"
+const historyRangeKey = "RunTime"
+
+// ScaleHistoryItem is keyed by the host group auto scaling group name and the run time in unix nanoseconds,
+// DynamoDB deletes it once ExpiresAt passes
+type ScaleHistoryItem struct {
+	AsgName   string
+	RunTime   int64
+	ExpiresAt int64
+	cluster.ScaleHistoryRecord
+}
+
+// CreateHistoryDb creates the scale history table, it holds no secrets so it is encrypted with the aws owned key
+func CreateHistoryDb(tableName string, tags cluster.Tags) error {
+	err := createTable(tableName, tags, nil,
+		tableKey{name: historyHashKey, attributeType: dynamodb.ScalarAttributeTypeS},
+		tableKey{name: historyRangeKey, attributeType: dynamodb.ScalarAttributeTypeN},
+	)
+	if err != nil {
+		return err
+	}
+	svc := connectors.GetAWSSession().DynamoDB
+	_, err = svc.UpdateTimeToLive(&dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(tableName),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String(historyTtlAttribute),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	return err
+}
+
+func historyKeySchema(table *dynamodb.TableDescription) (hashKey, rangeKey string) {
+	for _, key := range table.KeySchema {
+		if aws.StringValue(key.KeyType) == dynamodb.KeyTypeHash {
+			hashKey = aws.StringValue(key.AttributeName)
+		} else {
+			rangeKey = aws.StringValue(key.AttributeName)
+		}
+	}
+	return
+}
+
+// UpdateHistoryDb tags the scale history table, a table keyed otherwise than CreateHistoryDb keys it is recreated
+// since the key schema of a table can't be changed, the history it held is dropped
+func UpdateHistoryDb(tableName string, tags cluster.Tags) error {
+	svc := connectors.GetAWSSession().DynamoDB
+	dbOutput, err := svc.DescribeTable(&dynamodb.DescribeTableInput{TableName: &tableName})
+	if err != nil {
+		return err
+	}
+	if hashKey, rangeKey := historyKeySchema(dbOutput.Table); hashKey == historyHashKey && rangeKey == historyRangeKey {
+		return TagDb(tableName, tags)
+	}
+
+	logging.UserProgress("Recreating table %s with its new keys, the scale history it held is dropped", tableName)
+	err = DeleteDB(tableName)
+	if err != nil {
+		return err
+	}
+	err = svc.WaitUntilTableNotExists(&dynamodb.DescribeTableInput{TableName: &tableName})
+	if err != nil {
+		return err
+	}
+	return CreateHistoryDb(tableName, tags)
+}
+
+// DiffHistoryDb compares the table keys, billing mode and time to live with the ones CreateHistoryDb sets
+func DiffHistoryDb(tableName string) (drifts cluster.Drifts, err error) {
+	svc := connectors.GetAWSSession().DynamoDB
+	dbOutput, err := svc.DescribeTable(&dynamodb.DescribeTableInput{TableName: &tableName})
+	if err != nil {
+		return
+	}
+	drifts = diffBillingMode(dbOutput.Table)
+	hashKey, rangeKey := historyKeySchema(dbOutput.Table)
+	drifts.Compare("hash_key", historyHashKey, hashKey)
+	drifts.Compare("range_key", historyRangeKey, rangeKey)
+
+	output, err := svc.DescribeTimeToLive(&dynamodb.DescribeTimeToLiveInput{TableName: aws.String(tableName)})
+	if err != nil {
+		return
+	}
+	status, attributeName := dynamodb.TimeToLiveStatusDisabled, ""
+	if output.TimeToLiveDescription != nil {
+		status = aws.StringValue(output.TimeToLiveDescription.TimeToLiveStatus)
+		attributeName = aws.StringValue(output.TimeToLiveDescription.AttributeName)
+	}
+	if status != dynamodb.TimeToLiveStatusEnabling {
+		drifts.Compare("ttl_status", dynamodb.TimeToLiveStatusEnabled, status)
+	}
+	drifts.Compare("ttl_attribute", historyTtlAttribute, attributeName)
+	return
+}
+
+func SaveScaleHistory(tableName, asgName string, record cluster.ScaleHistoryRecord) error {
+	err := PutItem(tableName, ScaleHistoryItem{
+		AsgName:            asgName,
+		RunTime:            record.Time.UnixNano(),
+		ExpiresAt:          record.Time.Add(HistoryRetention).Unix(),
+		ScaleHistoryRecord: record,
+	})
+	if err != nil {
+		log.Debug().Msgf("error saving %s scale history to DB %v", asgName, err)
+		return err
+	}
+	return nil
+}
+
+// GetScaleHistory returns the host group scale history since the given time, most recent first,
+// limit 0 returns all of it
+func GetScaleHistory(tableName, asgName string, since time.Time, limit int64) (records []cluster.ScaleHistoryRecord, err error) {
+	// expired items are deleted in the background, up to 2 days later
+	if expired := time.Now().Add(-HistoryRetention); since.Before(expired) {
+		since = expired
+	}
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(tableName),
+		KeyConditionExpression: aws.String("#asg = :asg AND #time >= :since"),
+		ExpressionAttributeNames: map[string]*string{
+			"#asg":  aws.String(historyHashKey),
+			"#time": aws.String(historyRangeKey),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":asg": {
+				S: aws.String(asgName),
+			},
+			":since": {
+				N: aws.String(strconv.FormatInt(since.UnixNano(), 10)),
+			},
+		},
+		ScanIndexForward: aws.Bool(false),
+	}
+	if limit > 0 {
+		input.Limit = aws.Int64(limit)
+	}
+
+	svc := connectors.GetAWSSession().DynamoDB
+	var unmarshalErr error
+	err = svc.QueryPages(input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		var pageItems []ScaleHistoryItem
+		unmarshalErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &pageItems)
+		if unmarshalErr != nil {
+			return false
+		}
+		for _, item := range pageItems {
+			records = append(records, item.ScaleHistoryRecord)
+		}
+		return limit == 0 || int64(len(records)) < limit
+	})
+	if err != nil {
+		return
+	}
+	if unmarshalErr != nil {
+		return nil, unmarshalErr
+	}
+	if limit > 0 && int64(len(records)) > limit {
+		records = records[:limit]
+	}
+	return
+}
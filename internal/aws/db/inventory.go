@@ -0,0 +1,62 @@
+package db
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/rs/zerolog/log"
+	"wekactl/internal/connectors"
+)
+
+func InventoryKey(resourceType, name string) string {
+	return fmt.Sprintf("%s%s#%s", ModelInventoryPrefix, resourceType, name)
+}
+
+func SaveInventoryItem(tableName string, item InventoryItem) error {
+	item.Key = InventoryKey(item.Type, item.Name)
+	err := PutItem(tableName, item)
+	if err != nil {
+		log.Debug().Msgf("error saving inventory item %s to DB %v", item.Key, err)
+		return err
+	}
+	return nil
+}
+
+func DeleteInventoryItem(tableName string, item InventoryItem) error {
+	return DeleteItem(tableName, InventoryKey(item.Type, item.Name))
+}
+
+// GetInventory returns every inventory item of the cluster table, or none if the table doesn't exist
+func GetInventory(tableName string) (items []InventoryItem, err error) {
+	svc := connectors.GetAWSSession().DynamoDB
+	var unmarshalErr error
+	err = svc.ScanPages(&dynamodb.ScanInput{
+		TableName:        aws.String(tableName),
+		FilterExpression: aws.String("begins_with(#key, :prefix)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#key": aws.String("Key"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":prefix": {
+				S: aws.String(ModelInventoryPrefix),
+			},
+		},
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		var pageItems []InventoryItem
+		unmarshalErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &pageItems)
+		if unmarshalErr != nil {
+			return false
+		}
+		items = append(items, pageItems...)
+		return true
+	})
+	if err != nil {
+		if _, ok := err.(*dynamodb.ResourceNotFoundException); ok {
+			return nil, nil
+		}
+		return
+	}
+	err = unmarshalErr
+	return
+}
@@ -1,7 +1,9 @@
 package db
 
 import (
+	"time"
 	"wekactl/internal/aws/common"
+	"wekactl/internal/cluster"
 )
 
 const ModelClusterCreds = "cluster-creds"
@@ -25,3 +27,37 @@ type ResourceVersion struct {
 	Key     string
 	Version string
 }
+
+const ModelInventoryPrefix = "inventory#"
+
+type InventoryItem struct {
+	Key       string
+	Type      string
+	Name      string
+	Id        string
+	Version   string
+	CreatedAt time.Time
+}
+
+// ModelScalingPolicyPrefix is followed by the host group auto scaling group name, which the lambdas know
+const ModelScalingPolicyPrefix = "scaling-policy#"
+
+type ScalingPolicy struct {
+	Key string
+	cluster.ScalingPolicy
+}
+
+const ModelScalingPause = "scaling-pause"
+
+type ScalingPause struct {
+	Key string
+	cluster.ScalingPause
+}
+
+// ModelCoreCountsPrefix is followed by the backend instance type whose computed core counts are overridden
+const ModelCoreCountsPrefix = "core-counts#"
+
+type CoreCounts struct {
+	Key string
+	cluster.CoreCounts
+}
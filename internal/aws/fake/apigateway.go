@@ -0,0 +1,387 @@
+package fake
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/aws/aws-sdk-go/service/apigateway/apigatewayiface"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ApiGateway struct {
+	apigatewayiface.APIGatewayAPI
+	aws        *AWS
+	lock       sync.Mutex
+	restApis   map[string]*fakeRestApi
+	usagePlans map[string]*fakeUsagePlan
+	apiKeys    map[string]*apigateway.ApiKey
+}
+
+type fakeRestApi struct {
+	restApi     *apigateway.RestApi
+	resources   []*apigateway.Resource
+	deployments []*apigateway.Deployment
+}
+
+type fakeUsagePlan struct {
+	usagePlan *apigateway.UsagePlan
+	keys      []*apigateway.UsagePlanKey
+}
+
+func newApiGateway(a *AWS) *ApiGateway {
+	return &ApiGateway{
+		aws:        a,
+		restApis:   map[string]*fakeRestApi{},
+		usagePlans: map[string]*fakeUsagePlan{},
+		apiKeys:    map[string]*apigateway.ApiKey{},
+	}
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &apigateway.NotFoundException{Message_: aws.String(fmt.Sprintf(format, args...))}
+}
+
+func (g *ApiGateway) getRestApi(restApiId *string) (*fakeRestApi, error) {
+	restApi, ok := g.restApis[aws.StringValue(restApiId)]
+	if !ok {
+		return nil, notFound("Invalid API identifier specified %s:%s", AccountId, aws.StringValue(restApiId))
+	}
+	return restApi, nil
+}
+
+func (r *fakeRestApi) getResource(resourceId *string) (*apigateway.Resource, error) {
+	for _, resource := range r.resources {
+		if aws.StringValue(resource.Id) == aws.StringValue(resourceId) {
+			return resource, nil
+		}
+	}
+	return nil, notFound("Invalid Resource identifier specified")
+}
+
+func (g *ApiGateway) CreateRestApi(input *apigateway.CreateRestApiInput) (*apigateway.RestApi, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	restApiId := g.aws.newId("api")
+	restApi := &fakeRestApi{
+		restApi: &apigateway.RestApi{
+			Id:           aws.String(restApiId),
+			Name:         input.Name,
+			Description:  input.Description,
+			ApiKeySource: input.ApiKeySource,
+			CreatedDate:  aws.Time(time.Now().UTC()),
+			Tags:         copyTags(input.Tags),
+		},
+		resources: []*apigateway.Resource{
+			{Id: aws.String(g.aws.newId("res")), Path: aws.String("/")},
+		},
+	}
+	g.restApis[restApiId] = restApi
+	return copyOf(restApi.restApi).(*apigateway.RestApi), nil
+}
+
+func (g *ApiGateway) DeleteRestApi(input *apigateway.DeleteRestApiInput) (*apigateway.DeleteRestApiOutput, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if _, err := g.getRestApi(input.RestApiId); err != nil {
+		return nil, err
+	}
+	delete(g.restApis, aws.StringValue(input.RestApiId))
+	return &apigateway.DeleteRestApiOutput{}, nil
+}
+
+func (g *ApiGateway) GetRestApis(*apigateway.GetRestApisInput) (*apigateway.GetRestApisOutput, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	var ids []string
+	for id := range g.restApis {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	output := &apigateway.GetRestApisOutput{}
+	for _, id := range ids {
+		output.Items = append(output.Items, copyOf(g.restApis[id].restApi).(*apigateway.RestApi))
+	}
+	return output, nil
+}
+
+func (g *ApiGateway) GetRestApisPages(input *apigateway.GetRestApisInput, fn func(*apigateway.GetRestApisOutput, bool) bool) error {
+	output, err := g.GetRestApis(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
+
+func (g *ApiGateway) GetResources(input *apigateway.GetResourcesInput) (*apigateway.GetResourcesOutput, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	restApi, err := g.getRestApi(input.RestApiId)
+	if err != nil {
+		return nil, err
+	}
+	output := &apigateway.GetResourcesOutput{}
+	for _, resource := range restApi.resources {
+		output.Items = append(output.Items, copyOf(resource).(*apigateway.Resource))
+	}
+	return output, nil
+}
+
+func (g *ApiGateway) CreateResource(input *apigateway.CreateResourceInput) (*apigateway.Resource, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	restApi, err := g.getRestApi(input.RestApiId)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := restApi.getResource(input.ParentId)
+	if err != nil {
+		return nil, err
+	}
+	resource := &apigateway.Resource{
+		Id:       aws.String(g.aws.newId("res")),
+		ParentId: parent.Id,
+		PathPart: input.PathPart,
+		Path:     aws.String(strings.TrimSuffix(aws.StringValue(parent.Path), "/") + "/" + aws.StringValue(input.PathPart)),
+	}
+	restApi.resources = append(restApi.resources, resource)
+	return copyOf(resource).(*apigateway.Resource), nil
+}
+
+func (g *ApiGateway) PutMethod(input *apigateway.PutMethodInput) (*apigateway.Method, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	restApi, err := g.getRestApi(input.RestApiId)
+	if err != nil {
+		return nil, err
+	}
+	resource, err := restApi.getResource(input.ResourceId)
+	if err != nil {
+		return nil, err
+	}
+	method := &apigateway.Method{
+		HttpMethod:        input.HttpMethod,
+		AuthorizationType: input.AuthorizationType,
+		ApiKeyRequired:    input.ApiKeyRequired,
+	}
+	if resource.ResourceMethods == nil {
+		resource.ResourceMethods = map[string]*apigateway.Method{}
+	}
+	resource.ResourceMethods[aws.StringValue(input.HttpMethod)] = method
+	return copyOf(method).(*apigateway.Method), nil
+}
+
+func (g *ApiGateway) PutIntegration(input *apigateway.PutIntegrationInput) (*apigateway.Integration, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	restApi, err := g.getRestApi(input.RestApiId)
+	if err != nil {
+		return nil, err
+	}
+	resource, err := restApi.getResource(input.ResourceId)
+	if err != nil {
+		return nil, err
+	}
+	method, ok := resource.ResourceMethods[aws.StringValue(input.HttpMethod)]
+	if !ok {
+		return nil, notFound("Invalid Method identifier specified")
+	}
+	method.MethodIntegration = &apigateway.Integration{
+		Type:       input.Type,
+		HttpMethod: input.IntegrationHttpMethod,
+		Uri:        input.Uri,
+	}
+	return copyOf(method.MethodIntegration).(*apigateway.Integration), nil
+}
+
+func (g *ApiGateway) CreateDeployment(input *apigateway.CreateDeploymentInput) (*apigateway.Deployment, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	restApi, err := g.getRestApi(input.RestApiId)
+	if err != nil {
+		return nil, err
+	}
+	deployment := &apigateway.Deployment{
+		Id:          aws.String(g.aws.newId("dep")),
+		Description: input.Description,
+		CreatedDate: aws.Time(time.Now().UTC()),
+	}
+	restApi.deployments = append(restApi.deployments, deployment)
+	return copyOf(deployment).(*apigateway.Deployment), nil
+}
+
+func (g *ApiGateway) CreateUsagePlan(input *apigateway.CreateUsagePlanInput) (*apigateway.UsagePlan, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	usagePlanId := g.aws.newId("plan")
+	usagePlan := &fakeUsagePlan{
+		usagePlan: &apigateway.UsagePlan{
+			Id:          aws.String(usagePlanId),
+			Name:        input.Name,
+			Description: input.Description,
+			ApiStages:   input.ApiStages,
+			Tags:        copyTags(input.Tags),
+		},
+	}
+	usagePlan.usagePlan = copyOf(usagePlan.usagePlan).(*apigateway.UsagePlan)
+	g.usagePlans[usagePlanId] = usagePlan
+	return copyOf(usagePlan.usagePlan).(*apigateway.UsagePlan), nil
+}
+
+// UpdateUsagePlan supports removing api stages
+func (g *ApiGateway) UpdateUsagePlan(input *apigateway.UpdateUsagePlanInput) (*apigateway.UsagePlan, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	usagePlan, ok := g.usagePlans[aws.StringValue(input.UsagePlanId)]
+	if !ok {
+		return nil, notFound("Invalid Usage Plan ID specified")
+	}
+	for _, operation := range input.PatchOperations {
+		if aws.StringValue(operation.Op) != apigateway.OpRemove || aws.StringValue(operation.Path) != "/apiStages" {
+			return nil, &apigateway.BadRequestException{Message_: aws.String(fmt.Sprintf("Invalid patch path %s", aws.StringValue(operation.Path)))}
+		}
+		var apiStages []*apigateway.ApiStage
+		for _, apiStage := range usagePlan.usagePlan.ApiStages {
+			if fmt.Sprintf("%s:%s", aws.StringValue(apiStage.ApiId), aws.StringValue(apiStage.Stage)) != aws.StringValue(operation.Value) {
+				apiStages = append(apiStages, apiStage)
+			}
+		}
+		usagePlan.usagePlan.ApiStages = apiStages
+	}
+	return copyOf(usagePlan.usagePlan).(*apigateway.UsagePlan), nil
+}
+
+// DeleteUsagePlan fails while the usage plan is still associated with api stages, like aws does
+func (g *ApiGateway) DeleteUsagePlan(input *apigateway.DeleteUsagePlanInput) (*apigateway.DeleteUsagePlanOutput, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	usagePlan, ok := g.usagePlans[aws.StringValue(input.UsagePlanId)]
+	if !ok {
+		return nil, notFound("Invalid Usage Plan ID specified")
+	}
+	for _, apiStage := range usagePlan.usagePlan.ApiStages {
+		if _, ok := g.restApis[aws.StringValue(apiStage.ApiId)]; ok {
+			return nil, &apigateway.BadRequestException{
+				Message_: aws.String(fmt.Sprintf("Cannot delete Usage Plan %s because it is associated with API stages", aws.StringValue(input.UsagePlanId))),
+			}
+		}
+	}
+	delete(g.usagePlans, aws.StringValue(input.UsagePlanId))
+	return &apigateway.DeleteUsagePlanOutput{}, nil
+}
+
+func (g *ApiGateway) GetUsagePlans(*apigateway.GetUsagePlansInput) (*apigateway.GetUsagePlansOutput, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	var ids []string
+	for id := range g.usagePlans {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	output := &apigateway.GetUsagePlansOutput{}
+	for _, id := range ids {
+		output.Items = append(output.Items, copyOf(g.usagePlans[id].usagePlan).(*apigateway.UsagePlan))
+	}
+	return output, nil
+}
+
+func (g *ApiGateway) GetUsagePlansPages(input *apigateway.GetUsagePlansInput, fn func(*apigateway.GetUsagePlansOutput, bool) bool) error {
+	output, err := g.GetUsagePlans(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
+
+func (g *ApiGateway) CreateApiKey(input *apigateway.CreateApiKeyInput) (*apigateway.ApiKey, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	apiKeyId := g.aws.newId("key")
+	apiKey := &apigateway.ApiKey{
+		Id:          aws.String(apiKeyId),
+		Name:        input.Name,
+		Description: input.Description,
+		Enabled:     aws.Bool(aws.BoolValue(input.Enabled)),
+		Value:       aws.String(g.aws.newId("secret")),
+		CreatedDate: aws.Time(time.Now().UTC()),
+		Tags:        copyTags(input.Tags),
+	}
+	if input.Value != nil {
+		apiKey.Value = input.Value
+	}
+	g.apiKeys[apiKeyId] = copyOf(apiKey).(*apigateway.ApiKey)
+	return apiKey, nil
+}
+
+func (g *ApiGateway) DeleteApiKey(input *apigateway.DeleteApiKeyInput) (*apigateway.DeleteApiKeyOutput, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if _, ok := g.apiKeys[aws.StringValue(input.ApiKey)]; !ok {
+		return nil, notFound("Invalid API Key identifier specified")
+	}
+	delete(g.apiKeys, aws.StringValue(input.ApiKey))
+	for _, usagePlan := range g.usagePlans {
+		var keys []*apigateway.UsagePlanKey
+		for _, key := range usagePlan.keys {
+			if aws.StringValue(key.Id) != aws.StringValue(input.ApiKey) {
+				keys = append(keys, key)
+			}
+		}
+		usagePlan.keys = keys
+	}
+	return &apigateway.DeleteApiKeyOutput{}, nil
+}
+
+// GetApiKeys returns the api keys values only when IncludeValues is set, like aws does
+func (g *ApiGateway) GetApiKeys(input *apigateway.GetApiKeysInput) (*apigateway.GetApiKeysOutput, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	var ids []string
+	for id := range g.apiKeys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	output := &apigateway.GetApiKeysOutput{}
+	for _, id := range ids {
+		apiKey := copyOf(g.apiKeys[id]).(*apigateway.ApiKey)
+		if !aws.BoolValue(input.IncludeValues) {
+			apiKey.Value = nil
+		}
+		output.Items = append(output.Items, apiKey)
+	}
+	return output, nil
+}
+
+func (g *ApiGateway) GetApiKeysPages(input *apigateway.GetApiKeysInput, fn func(*apigateway.GetApiKeysOutput, bool) bool) error {
+	output, err := g.GetApiKeys(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
+
+func (g *ApiGateway) CreateUsagePlanKey(input *apigateway.CreateUsagePlanKeyInput) (*apigateway.UsagePlanKey, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	usagePlan, ok := g.usagePlans[aws.StringValue(input.UsagePlanId)]
+	if !ok {
+		return nil, notFound("Invalid Usage Plan ID specified")
+	}
+	apiKey, ok := g.apiKeys[aws.StringValue(input.KeyId)]
+	if !ok {
+		return nil, notFound("Invalid API Key identifier specified")
+	}
+	key := &apigateway.UsagePlanKey{
+		Id:    apiKey.Id,
+		Name:  apiKey.Name,
+		Type:  input.KeyType,
+		Value: apiKey.Value,
+	}
+	usagePlan.keys = append(usagePlan.keys, key)
+	return copyOf(key).(*apigateway.UsagePlanKey), nil
+}
@@ -0,0 +1,546 @@
+package fake
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
+	"sync"
+	"time"
+)
+
+type AutoScaling struct {
+	autoscalingiface.AutoScalingAPI
+	aws    *AWS
+	lock   sync.Mutex
+	groups map[string]*autoscaling.Group
+	// hooks are the lifecycle hooks of every group, by their names
+	hooks map[string]map[string]*autoscaling.LifecycleHook
+	// policies are the scaling policies of every group, by their names
+	policies map[string]map[string]*autoscaling.ScalingPolicy
+}
+
+func newAutoScaling(a *AWS) *AutoScaling {
+	return &AutoScaling{
+		aws:      a,
+		groups:   map[string]*autoscaling.Group{},
+		hooks:    map[string]map[string]*autoscaling.LifecycleHook{},
+		policies: map[string]map[string]*autoscaling.ScalingPolicy{},
+	}
+}
+
+func (s *AutoScaling) getGroup(name *string) (*autoscaling.Group, error) {
+	group, ok := s.groups[aws.StringValue(name)]
+	if !ok {
+		return nil, awserr.New("ValidationError", fmt.Sprintf("AutoScalingGroup name not found - %s", aws.StringValue(name)), nil)
+	}
+	return group, nil
+}
+
+func setAutoScalingTags(group *autoscaling.Group, tags []*autoscaling.Tag) {
+	for _, tag := range tags {
+		description := &autoscaling.TagDescription{
+			Key:               aws.String(aws.StringValue(tag.Key)),
+			Value:             aws.String(aws.StringValue(tag.Value)),
+			PropagateAtLaunch: aws.Bool(aws.BoolValue(tag.PropagateAtLaunch)),
+			ResourceId:        group.AutoScalingGroupName,
+			ResourceType:      aws.String("auto-scaling-group"),
+		}
+		replaced := false
+		for i, existing := range group.Tags {
+			if aws.StringValue(existing.Key) == aws.StringValue(tag.Key) {
+				group.Tags[i] = description
+				replaced = true
+			}
+		}
+		if !replaced {
+			group.Tags = append(group.Tags, description)
+		}
+	}
+}
+
+func (s *AutoScaling) CreateAutoScalingGroup(input *autoscaling.CreateAutoScalingGroupInput) (*autoscaling.CreateAutoScalingGroupOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	name := aws.StringValue(input.AutoScalingGroupName)
+	if _, ok := s.groups[name]; ok {
+		return nil, awserr.New(autoscaling.ErrCodeAlreadyExistsFault, fmt.Sprintf("AutoScalingGroup by this name already exists - %s", name), nil)
+	}
+	desiredCapacity := input.DesiredCapacity
+	if desiredCapacity == nil {
+		desiredCapacity = input.MinSize
+	}
+	group := &autoscaling.Group{
+		AutoScalingGroupName:             aws.String(name),
+		AutoScalingGroupARN:              aws.String(arn("autoscaling", fmt.Sprintf("autoScalingGroup:%s:autoScalingGroupName/%s", s.aws.newId(""), name))),
+		MinSize:                          aws.Int64(aws.Int64Value(input.MinSize)),
+		MaxSize:                          aws.Int64(aws.Int64Value(input.MaxSize)),
+		DesiredCapacity:                  aws.Int64(aws.Int64Value(desiredCapacity)),
+		NewInstancesProtectedFromScaleIn: aws.Bool(aws.BoolValue(input.NewInstancesProtectedFromScaleIn)),
+		CreatedTime:                      aws.Time(time.Now().UTC()),
+		HealthCheckType:                  aws.String("EC2"),
+	}
+	if input.LaunchTemplate != nil {
+		group.LaunchTemplate = copyOf(input.LaunchTemplate).(*autoscaling.LaunchTemplateSpecification)
+	}
+	if input.MixedInstancesPolicy != nil {
+		group.MixedInstancesPolicy = copyOf(input.MixedInstancesPolicy).(*autoscaling.MixedInstancesPolicy)
+	}
+	setAutoScalingTags(group, input.Tags)
+	s.groups[name] = group
+	return &autoscaling.CreateAutoScalingGroupOutput{}, nil
+}
+
+func (s *AutoScaling) SuspendProcesses(input *autoscaling.ScalingProcessQuery) (*autoscaling.SuspendProcessesOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	group, err := s.getGroup(input.AutoScalingGroupName)
+	if err != nil {
+		return nil, err
+	}
+	for _, process := range input.ScalingProcesses {
+		suspended := false
+		for _, suspendedProcess := range group.SuspendedProcesses {
+			if aws.StringValue(suspendedProcess.ProcessName) == aws.StringValue(process) {
+				suspended = true
+			}
+		}
+		if !suspended {
+			group.SuspendedProcesses = append(group.SuspendedProcesses, &autoscaling.SuspendedProcess{
+				ProcessName:      aws.String(aws.StringValue(process)),
+				SuspensionReason: aws.String("User suspended"),
+			})
+		}
+	}
+	return &autoscaling.SuspendProcessesOutput{}, nil
+}
+
+func (s *AutoScaling) DescribeAutoScalingGroups(input *autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	output := &autoscaling.DescribeAutoScalingGroupsOutput{}
+	if len(input.AutoScalingGroupNames) == 0 {
+		for _, group := range s.groups {
+			output.AutoScalingGroups = append(output.AutoScalingGroups, copyOf(group).(*autoscaling.Group))
+		}
+		return output, nil
+	}
+	// like aws, groups that don't exist are omitted rather than failing the call
+	for _, name := range input.AutoScalingGroupNames {
+		if group, ok := s.groups[aws.StringValue(name)]; ok {
+			output.AutoScalingGroups = append(output.AutoScalingGroups, copyOf(group).(*autoscaling.Group))
+		}
+	}
+	return output, nil
+}
+
+func (s *AutoScaling) UpdateAutoScalingGroup(input *autoscaling.UpdateAutoScalingGroupInput) (*autoscaling.UpdateAutoScalingGroupOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	group, err := s.getGroup(input.AutoScalingGroupName)
+	if err != nil {
+		return nil, err
+	}
+	if input.LaunchTemplate != nil && input.MixedInstancesPolicy != nil {
+		return nil, awserr.New("ValidationError", "Valid requests must contain either LaunchTemplate, LaunchConfigurationName or MixedInstancesPolicy parameter.", nil)
+	}
+	// like aws, a launch template replaces the mixed instances policy, and a mixed instances policy is merged into
+	// the one the group has
+	if input.LaunchTemplate != nil {
+		group.LaunchTemplate = copyOf(input.LaunchTemplate).(*autoscaling.LaunchTemplateSpecification)
+		group.MixedInstancesPolicy = nil
+	}
+	if input.MixedInstancesPolicy != nil {
+		if group.MixedInstancesPolicy == nil {
+			group.MixedInstancesPolicy = &autoscaling.MixedInstancesPolicy{}
+		}
+		if input.MixedInstancesPolicy.LaunchTemplate != nil {
+			group.MixedInstancesPolicy.LaunchTemplate = copyOf(input.MixedInstancesPolicy.LaunchTemplate).(*autoscaling.LaunchTemplate)
+		}
+		if input.MixedInstancesPolicy.InstancesDistribution != nil {
+			group.MixedInstancesPolicy.InstancesDistribution = copyOf(input.MixedInstancesPolicy.InstancesDistribution).(*autoscaling.InstancesDistribution)
+		}
+		group.LaunchTemplate = nil
+	}
+	if input.MinSize != nil {
+		group.MinSize = aws.Int64(*input.MinSize)
+	}
+	if input.MaxSize != nil {
+		group.MaxSize = aws.Int64(*input.MaxSize)
+	}
+	if input.DesiredCapacity != nil {
+		group.DesiredCapacity = aws.Int64(*input.DesiredCapacity)
+	}
+	if input.NewInstancesProtectedFromScaleIn != nil {
+		group.NewInstancesProtectedFromScaleIn = aws.Bool(*input.NewInstancesProtectedFromScaleIn)
+	}
+	return &autoscaling.UpdateAutoScalingGroupOutput{}, nil
+}
+
+func (s *AutoScaling) CreateOrUpdateTags(input *autoscaling.CreateOrUpdateTagsInput) (*autoscaling.CreateOrUpdateTagsOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, tag := range input.Tags {
+		group, err := s.getGroup(tag.ResourceId)
+		if err != nil {
+			return nil, err
+		}
+		setAutoScalingTags(group, []*autoscaling.Tag{tag})
+	}
+	return &autoscaling.CreateOrUpdateTagsOutput{}, nil
+}
+
+func (s *AutoScaling) AttachInstances(input *autoscaling.AttachInstancesInput) (*autoscaling.AttachInstancesOutput, error) {
+	var instances []*autoscaling.Instance
+	for _, instanceId := range input.InstanceIds {
+		instance := s.aws.EC2.Instance(aws.StringValue(instanceId))
+		if instance == nil || aws.StringValue(instance.State.Name) != "running" {
+			return nil, awserr.New("ValidationError", fmt.Sprintf("Instance %s is not in correct state", aws.StringValue(instanceId)), nil)
+		}
+		instances = append(instances, &autoscaling.Instance{
+			InstanceId:       instance.InstanceId,
+			InstanceType:     instance.InstanceType,
+			AvailabilityZone: instance.Placement.AvailabilityZone,
+			LifecycleState:   aws.String(autoscaling.LifecycleStateInService),
+			HealthStatus:     aws.String("Healthy"),
+		})
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	group, err := s.getGroup(input.AutoScalingGroupName)
+	if err != nil {
+		return nil, err
+	}
+	desiredCapacity := aws.Int64Value(group.DesiredCapacity) + int64(len(instances))
+	if desiredCapacity > aws.Int64Value(group.MaxSize) {
+		return nil, awserr.New("ValidationError", fmt.Sprintf("Attaching instances to %s would exceed its max size", aws.StringValue(group.AutoScalingGroupName)), nil)
+	}
+	for _, instance := range instances {
+		instance.ProtectedFromScaleIn = aws.Bool(aws.BoolValue(group.NewInstancesProtectedFromScaleIn))
+		instance.LaunchTemplate = copyOf(group.LaunchTemplate).(*autoscaling.LaunchTemplateSpecification)
+		group.Instances = append(group.Instances, instance)
+	}
+	group.DesiredCapacity = aws.Int64(desiredCapacity)
+	return &autoscaling.AttachInstancesOutput{}, nil
+}
+
+func (s *AutoScaling) DetachInstances(input *autoscaling.DetachInstancesInput) (*autoscaling.DetachInstancesOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	group, err := s.getGroup(input.AutoScalingGroupName)
+	if err != nil {
+		return nil, err
+	}
+	detached := map[string]bool{}
+	for _, instanceId := range input.InstanceIds {
+		detached[aws.StringValue(instanceId)] = true
+	}
+	var instances []*autoscaling.Instance
+	for _, instance := range group.Instances {
+		if !detached[aws.StringValue(instance.InstanceId)] {
+			instances = append(instances, instance)
+		}
+	}
+	if len(group.Instances)-len(instances) != len(input.InstanceIds) {
+		return nil, awserr.New("ValidationError", fmt.Sprintf("The instances aren't part of %s", aws.StringValue(group.AutoScalingGroupName)), nil)
+	}
+	group.Instances = instances
+	if aws.BoolValue(input.ShouldDecrementDesiredCapacity) {
+		group.DesiredCapacity = aws.Int64(aws.Int64Value(group.DesiredCapacity) - int64(len(input.InstanceIds)))
+	}
+	return &autoscaling.DetachInstancesOutput{}, nil
+}
+
+func (s *AutoScaling) SetInstanceProtection(input *autoscaling.SetInstanceProtectionInput) (*autoscaling.SetInstanceProtectionOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	group, err := s.getGroup(input.AutoScalingGroupName)
+	if err != nil {
+		return nil, err
+	}
+	for _, instanceId := range input.InstanceIds {
+		for _, instance := range group.Instances {
+			if aws.StringValue(instance.InstanceId) == aws.StringValue(instanceId) {
+				instance.ProtectedFromScaleIn = aws.Bool(aws.BoolValue(input.ProtectedFromScaleIn))
+			}
+		}
+	}
+	return &autoscaling.SetInstanceProtectionOutput{}, nil
+}
+
+// DescribeScalingActivities returns no activities, attaching and detaching instances completes immediately
+func (s *AutoScaling) DescribeScalingActivities(input *autoscaling.DescribeScalingActivitiesInput) (*autoscaling.DescribeScalingActivitiesOutput, error) {
+	return &autoscaling.DescribeScalingActivitiesOutput{}, nil
+}
+
+func (s *AutoScaling) DeleteAutoScalingGroup(input *autoscaling.DeleteAutoScalingGroupInput) (*autoscaling.DeleteAutoScalingGroupOutput, error) {
+	s.lock.Lock()
+	group, err := s.getGroup(input.AutoScalingGroupName)
+	if err != nil {
+		s.lock.Unlock()
+		return nil, err
+	}
+	if len(group.Instances) != 0 && !aws.BoolValue(input.ForceDelete) {
+		s.lock.Unlock()
+		return nil, awserr.New(autoscaling.ErrCodeResourceInUseFault, "You cannot delete an AutoScalingGroup while there are instances still in the group", nil)
+	}
+	delete(s.groups, aws.StringValue(input.AutoScalingGroupName))
+	delete(s.hooks, aws.StringValue(input.AutoScalingGroupName))
+	delete(s.policies, aws.StringValue(input.AutoScalingGroupName))
+	s.lock.Unlock()
+
+	for _, instance := range group.Instances {
+		s.aws.EC2.terminateInstance(aws.StringValue(instance.InstanceId))
+	}
+	return &autoscaling.DeleteAutoScalingGroupOutput{}, nil
+}
+
+func (s *AutoScaling) PutLifecycleHook(input *autoscaling.PutLifecycleHookInput) (*autoscaling.PutLifecycleHookOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	group, err := s.getGroup(input.AutoScalingGroupName)
+	if err != nil {
+		return nil, err
+	}
+	name := aws.StringValue(group.AutoScalingGroupName)
+	if s.hooks[name] == nil {
+		s.hooks[name] = map[string]*autoscaling.LifecycleHook{}
+	}
+	s.hooks[name][aws.StringValue(input.LifecycleHookName)] = &autoscaling.LifecycleHook{
+		AutoScalingGroupName: aws.String(name),
+		LifecycleHookName:    aws.String(aws.StringValue(input.LifecycleHookName)),
+		LifecycleTransition:  aws.String(aws.StringValue(input.LifecycleTransition)),
+		HeartbeatTimeout:     aws.Int64(aws.Int64Value(input.HeartbeatTimeout)),
+		DefaultResult:        aws.String(aws.StringValue(input.DefaultResult)),
+	}
+	return &autoscaling.PutLifecycleHookOutput{}, nil
+}
+
+func (s *AutoScaling) DescribeLifecycleHooks(input *autoscaling.DescribeLifecycleHooksInput) (*autoscaling.DescribeLifecycleHooksOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	group, err := s.getGroup(input.AutoScalingGroupName)
+	if err != nil {
+		return nil, err
+	}
+	output := &autoscaling.DescribeLifecycleHooksOutput{}
+	for name, hook := range s.hooks[aws.StringValue(group.AutoScalingGroupName)] {
+		requested := len(input.LifecycleHookNames) == 0
+		for _, hookName := range input.LifecycleHookNames {
+			if aws.StringValue(hookName) == name {
+				requested = true
+			}
+		}
+		if requested {
+			output.LifecycleHooks = append(output.LifecycleHooks, copyOf(hook).(*autoscaling.LifecycleHook))
+		}
+	}
+	return output, nil
+}
+
+func (s *AutoScaling) PutScalingPolicy(input *autoscaling.PutScalingPolicyInput) (*autoscaling.PutScalingPolicyOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	group, err := s.getGroup(input.AutoScalingGroupName)
+	if err != nil {
+		return nil, err
+	}
+	name := aws.StringValue(group.AutoScalingGroupName)
+	if s.policies[name] == nil {
+		s.policies[name] = map[string]*autoscaling.ScalingPolicy{}
+	}
+	policyName := aws.StringValue(input.PolicyName)
+	policyArn := arn("autoscaling", fmt.Sprintf("scalingPolicy:%s:autoScalingGroupName/%s:policyName/%s", s.aws.newId(""), name, policyName))
+	policy := &autoscaling.ScalingPolicy{
+		AutoScalingGroupName:    aws.String(name),
+		PolicyName:              aws.String(policyName),
+		PolicyARN:               aws.String(policyArn),
+		PolicyType:              aws.String(aws.StringValue(input.PolicyType)),
+		EstimatedInstanceWarmup: input.EstimatedInstanceWarmup,
+		Enabled:                 aws.Bool(true),
+	}
+	if input.TargetTrackingConfiguration != nil {
+		policy.TargetTrackingConfiguration = copyOf(input.TargetTrackingConfiguration).(*autoscaling.TargetTrackingConfiguration)
+	}
+	if existing, ok := s.policies[name][policyName]; ok {
+		policy.PolicyARN = existing.PolicyARN
+	}
+	s.policies[name][policyName] = policy
+	return &autoscaling.PutScalingPolicyOutput{PolicyARN: policy.PolicyARN}, nil
+}
+
+func (s *AutoScaling) DescribePolicies(input *autoscaling.DescribePoliciesInput) (*autoscaling.DescribePoliciesOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	output := &autoscaling.DescribePoliciesOutput{}
+	for groupName, policies := range s.policies {
+		if input.AutoScalingGroupName != nil && aws.StringValue(input.AutoScalingGroupName) != groupName {
+			continue
+		}
+		for name, policy := range policies {
+			requested := len(input.PolicyNames) == 0
+			for _, policyName := range input.PolicyNames {
+				if aws.StringValue(policyName) == name {
+					requested = true
+				}
+			}
+			if requested {
+				output.ScalingPolicies = append(output.ScalingPolicies, copyOf(policy).(*autoscaling.ScalingPolicy))
+			}
+		}
+	}
+	return output, nil
+}
+
+func (s *AutoScaling) DeletePolicy(input *autoscaling.DeletePolicyInput) (*autoscaling.DeletePolicyOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	group, err := s.getGroup(input.AutoScalingGroupName)
+	if err != nil {
+		return nil, err
+	}
+	name := aws.StringValue(group.AutoScalingGroupName)
+	if _, ok := s.policies[name][aws.StringValue(input.PolicyName)]; !ok {
+		return nil, awserr.New("ValidationError", fmt.Sprintf("Policy %s not found", aws.StringValue(input.PolicyName)), nil)
+	}
+	delete(s.policies[name], aws.StringValue(input.PolicyName))
+	return &autoscaling.DeletePolicyOutput{}, nil
+}
+
+// hasTerminationHook tells whether terminations of the group instances wait for a lifecycle action
+func (s *AutoScaling) hasTerminationHook(groupName string) bool {
+	for _, hook := range s.hooks[groupName] {
+		if aws.StringValue(hook.LifecycleTransition) == "autoscaling:EC2_INSTANCE_TERMINATING" {
+			return true
+		}
+	}
+	return false
+}
+
+// groupInstance returns the group instance with the id, or nil when the group has no such instance
+func groupInstance(group *autoscaling.Group, instanceId *string) *autoscaling.Instance {
+	for _, instance := range group.Instances {
+		if aws.StringValue(instance.InstanceId) == aws.StringValue(instanceId) {
+			return instance
+		}
+	}
+	return nil
+}
+
+// removeInstance removes the instance from the group and terminates it, the caller holds the lock
+func (s *AutoScaling) removeInstance(group *autoscaling.Group, instanceId string) {
+	var instances []*autoscaling.Instance
+	for _, instance := range group.Instances {
+		if aws.StringValue(instance.InstanceId) != instanceId {
+			instances = append(instances, instance)
+		}
+	}
+	group.Instances = instances
+	s.aws.EC2.terminateInstance(instanceId)
+}
+
+// TerminateInstanceInAutoScalingGroup moves the instance to Terminating:Wait when the group has a termination
+// lifecycle hook, and terminates it right away otherwise. Like the rest of the fake, no replacement is launched
+func (s *AutoScaling) TerminateInstanceInAutoScalingGroup(input *autoscaling.TerminateInstanceInAutoScalingGroupInput) (*autoscaling.TerminateInstanceInAutoScalingGroupOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for name, group := range s.groups {
+		instance := groupInstance(group, input.InstanceId)
+		if instance == nil {
+			continue
+		}
+		if aws.StringValue(instance.LifecycleState) != autoscaling.LifecycleStateInService {
+			return nil, awserr.New("ValidationError", fmt.Sprintf("The instance %s is not in InService", aws.StringValue(input.InstanceId)), nil)
+		}
+		if aws.BoolValue(input.ShouldDecrementDesiredCapacity) {
+			group.DesiredCapacity = aws.Int64(aws.Int64Value(group.DesiredCapacity) - 1)
+		}
+		if s.hasTerminationHook(name) {
+			instance.LifecycleState = aws.String(autoscaling.LifecycleStateTerminatingWait)
+		} else {
+			s.removeInstance(group, aws.StringValue(input.InstanceId))
+		}
+		return &autoscaling.TerminateInstanceInAutoScalingGroupOutput{}, nil
+	}
+	return nil, awserr.New("ValidationError", fmt.Sprintf("Instance Id not found - %s", aws.StringValue(input.InstanceId)), nil)
+}
+
+// waitingInstance returns the group instance that waits for the lifecycle action of the hook
+func (s *AutoScaling) waitingInstance(groupName, hookName, instanceId *string) (*autoscaling.Group, error) {
+	group, err := s.getGroup(groupName)
+	if err != nil {
+		return nil, err
+	}
+	_, hooked := s.hooks[aws.StringValue(groupName)][aws.StringValue(hookName)]
+	instance := groupInstance(group, instanceId)
+	if !hooked || instance == nil || aws.StringValue(instance.LifecycleState) != autoscaling.LifecycleStateTerminatingWait {
+		return nil, awserr.New("ValidationError", fmt.Sprintf("No active Lifecycle Action found with instance ID %s", aws.StringValue(instanceId)), nil)
+	}
+	return group, nil
+}
+
+func (s *AutoScaling) CompleteLifecycleAction(input *autoscaling.CompleteLifecycleActionInput) (*autoscaling.CompleteLifecycleActionOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	group, err := s.waitingInstance(input.AutoScalingGroupName, input.LifecycleHookName, input.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+	// both CONTINUE and ABANDON terminate the instance
+	s.removeInstance(group, aws.StringValue(input.InstanceId))
+	return &autoscaling.CompleteLifecycleActionOutput{}, nil
+}
+
+func (s *AutoScaling) RecordLifecycleActionHeartbeat(input *autoscaling.RecordLifecycleActionHeartbeatInput) (*autoscaling.RecordLifecycleActionHeartbeatOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err := s.waitingInstance(input.AutoScalingGroupName, input.LifecycleHookName, input.InstanceId)
+	if err != nil {
+		return nil, err
+	}
+	return &autoscaling.RecordLifecycleActionHeartbeatOutput{}, nil
+}
+
+func tagMatchesFilters(tag *autoscaling.TagDescription, filters []*autoscaling.Filter) bool {
+	for _, filter := range filters {
+		var value string
+		switch aws.StringValue(filter.Name) {
+		case "key":
+			value = aws.StringValue(tag.Key)
+		case "value":
+			value = aws.StringValue(tag.Value)
+		case "auto-scaling-group":
+			value = aws.StringValue(tag.ResourceId)
+		default:
+			continue
+		}
+		matched := false
+		for _, filterValue := range filter.Values {
+			if aws.StringValue(filterValue) == value {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *AutoScaling) DescribeTagsPages(input *autoscaling.DescribeTagsInput, fn func(*autoscaling.DescribeTagsOutput, bool) bool) error {
+	s.lock.Lock()
+	output := &autoscaling.DescribeTagsOutput{}
+	for _, group := range s.groups {
+		for _, tag := range group.Tags {
+			if tagMatchesFilters(tag, input.Filters) {
+				output.Tags = append(output.Tags, copyOf(tag).(*autoscaling.TagDescription))
+			}
+		}
+	}
+	s.lock.Unlock()
+
+	fn(output, true)
+	return nil
+}
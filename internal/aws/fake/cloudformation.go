@@ -0,0 +1,127 @@
+package fake
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"sync"
+	"time"
+)
+
+const StackTemplateDescription = "[WekaIO] To learn more about this template visit https://docs.weka.io/install/aws/cloudformation"
+
+type CloudFormation struct {
+	cloudformationiface.CloudFormationAPI
+	aws    *AWS
+	lock   sync.Mutex
+	stacks map[string]*fakeStack
+}
+
+type fakeStack struct {
+	stack     *cloudformation.Stack
+	resources []*cloudformation.StackResource
+}
+
+func newCloudFormation(a *AWS) *CloudFormation {
+	return &CloudFormation{aws: a, stacks: map[string]*fakeStack{}}
+}
+
+// AddStack simulates a weka cloudformation stack with the given number of backend and client instances
+func (a *AWS) AddStack(stackName string, backends, clients int) {
+	stack := &fakeStack{
+		stack: &cloudformation.Stack{
+			StackId:      aws.String(arn("cloudformation", fmt.Sprintf("stack/%s/%s", stackName, a.newId("")))),
+			StackName:    aws.String(stackName),
+			StackStatus:  aws.String(cloudformation.StackStatusCreateComplete),
+			Description:  aws.String(StackTemplateDescription),
+			CreationTime: aws.Time(time.Now().UTC()),
+		},
+	}
+
+	addInstances := func(count int, profile, instanceType string) {
+		for i := 0; i < count; i++ {
+			instanceId := a.EC2.addInstance(&ec2.Instance{
+				ImageId:            aws.String("ami-0e7f4d2c1b0a9f8e7"),
+				InstanceType:       aws.String(instanceType),
+				KeyName:            aws.String("weka-key"),
+				SubnetId:           aws.String("subnet-0a1b2c3d"),
+				SecurityGroups:     []*ec2.GroupIdentifier{{GroupId: aws.String("sg-0a1b2c3d"), GroupName: aws.String(stackName)}},
+				RootDeviceName:     aws.String("/dev/xvda"),
+				IamInstanceProfile: &ec2.IamInstanceProfile{Arn: aws.String(fmt.Sprintf("arn:aws:iam::%s:instance-profile/%s-%s", AccountId, stackName, profile))},
+			}, 48)
+			stack.resources = append(stack.resources, &cloudformation.StackResource{
+				StackName:          aws.String(stackName),
+				StackId:            stack.stack.StackId,
+				LogicalResourceId:  aws.String(fmt.Sprintf("%s%d", profile, i)),
+				PhysicalResourceId: aws.String(instanceId),
+				ResourceType:       aws.String("AWS::EC2::Instance"),
+				ResourceStatus:     aws.String(cloudformation.ResourceStatusCreateComplete),
+			})
+		}
+	}
+	addInstances(backends, "InstanceProfileBackend", "i3en.2xlarge")
+	addInstances(clients, "InstanceProfileClient", "r5.large")
+
+	a.CF.lock.Lock()
+	defer a.CF.lock.Unlock()
+	a.CF.stacks[stackName] = stack
+}
+
+func (c *CloudFormation) getStack(stackName *string) (*fakeStack, error) {
+	stack, ok := c.stacks[aws.StringValue(stackName)]
+	if !ok {
+		return nil, awserr.New("ValidationError", fmt.Sprintf("Stack with id %s does not exist", aws.StringValue(stackName)), nil)
+	}
+	return stack, nil
+}
+
+func (c *CloudFormation) DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	output := &cloudformation.DescribeStacksOutput{}
+	if input.StackName == nil {
+		for _, stack := range c.stacks {
+			output.Stacks = append(output.Stacks, copyOf(stack.stack).(*cloudformation.Stack))
+		}
+		return output, nil
+	}
+	stack, err := c.getStack(input.StackName)
+	if err != nil {
+		return nil, err
+	}
+	output.Stacks = append(output.Stacks, copyOf(stack.stack).(*cloudformation.Stack))
+	return output, nil
+}
+
+func (c *CloudFormation) DescribeStackResources(input *cloudformation.DescribeStackResourcesInput) (*cloudformation.DescribeStackResourcesOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	stack, err := c.getStack(input.StackName)
+	if err != nil {
+		return nil, err
+	}
+	output := &cloudformation.DescribeStackResourcesOutput{}
+	for _, resource := range stack.resources {
+		output.StackResources = append(output.StackResources, copyOf(resource).(*cloudformation.StackResource))
+	}
+	return output, nil
+}
+
+func (c *CloudFormation) ListStacks(*cloudformation.ListStacksInput) (*cloudformation.ListStacksOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	output := &cloudformation.ListStacksOutput{}
+	for _, stack := range c.stacks {
+		output.StackSummaries = append(output.StackSummaries, &cloudformation.StackSummary{
+			StackId:             stack.stack.StackId,
+			StackName:           stack.stack.StackName,
+			StackStatus:         stack.stack.StackStatus,
+			TemplateDescription: stack.stack.Description,
+			CreationTime:        stack.stack.CreationTime,
+		})
+	}
+	return output, nil
+}
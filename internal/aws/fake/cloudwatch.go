@@ -0,0 +1,35 @@
+package fake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"sync"
+)
+
+type CloudWatch struct {
+	cloudwatchiface.CloudWatchAPI
+	lock    sync.Mutex
+	metrics map[string][]*cloudwatch.MetricDatum
+}
+
+func newCloudWatch() *CloudWatch {
+	return &CloudWatch{metrics: map[string][]*cloudwatch.MetricDatum{}}
+}
+
+func (c *CloudWatch) PutMetricData(input *cloudwatch.PutMetricDataInput) (*cloudwatch.PutMetricDataOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	namespace := aws.StringValue(input.Namespace)
+	for _, datum := range input.MetricData {
+		c.metrics[namespace] = append(c.metrics[namespace], copyOf(datum).(*cloudwatch.MetricDatum))
+	}
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+// MetricData returns the data put to the namespace, in order
+func (c *CloudWatch) MetricData(namespace string) []*cloudwatch.MetricDatum {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]*cloudwatch.MetricDatum(nil), c.metrics[namespace]...)
+}
@@ -0,0 +1,220 @@
+package fake
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents/cloudwatcheventsiface"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type CloudWatchEvents struct {
+	cloudwatcheventsiface.CloudWatchEventsAPI
+	aws   *AWS
+	lock  sync.Mutex
+	rules map[string]*fakeRule
+}
+
+type fakeRule struct {
+	rule    *cloudwatchevents.Rule
+	tags    []*cloudwatchevents.Tag
+	targets []*cloudwatchevents.Target
+}
+
+func newCloudWatchEvents(a *AWS) *CloudWatchEvents {
+	return &CloudWatchEvents{aws: a, rules: map[string]*fakeRule{}}
+}
+
+func (c *CloudWatchEvents) getRule(name *string) (*fakeRule, error) {
+	rule, ok := c.rules[aws.StringValue(name)]
+	if !ok {
+		return nil, &cloudwatchevents.ResourceNotFoundException{Message_: aws.String(fmt.Sprintf("Rule %s does not exist on EventBus default.", aws.StringValue(name)))}
+	}
+	return rule, nil
+}
+
+func (c *CloudWatchEvents) getRuleByArn(ruleArn *string) (*fakeRule, error) {
+	for _, rule := range c.rules {
+		if aws.StringValue(rule.rule.Arn) == aws.StringValue(ruleArn) {
+			return rule, nil
+		}
+	}
+	return nil, &cloudwatchevents.ResourceNotFoundException{Message_: aws.String(fmt.Sprintf("Rule %s does not exist.", aws.StringValue(ruleArn)))}
+}
+
+func mergeEventsTags(tags, newTags []*cloudwatchevents.Tag) []*cloudwatchevents.Tag {
+	for _, newTag := range newTags {
+		replaced := false
+		for _, tag := range tags {
+			if aws.StringValue(tag.Key) == aws.StringValue(newTag.Key) {
+				tag.Value = aws.String(aws.StringValue(newTag.Value))
+				replaced = true
+			}
+		}
+		if !replaced {
+			tags = append(tags, copyOf(newTag).(*cloudwatchevents.Tag))
+		}
+	}
+	return tags
+}
+
+// PutRule creates the rule or updates an existing one, tags are only applied on creation like aws does
+func (c *CloudWatchEvents) PutRule(input *cloudwatchevents.PutRuleInput) (*cloudwatchevents.PutRuleOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	name := aws.StringValue(input.Name)
+	rule, ok := c.rules[name]
+	if !ok {
+		rule = &fakeRule{
+			rule: &cloudwatchevents.Rule{
+				Name:         aws.String(name),
+				Arn:          aws.String(arn("events", "rule/"+name)),
+				EventBusName: aws.String("default"),
+			},
+			tags: mergeEventsTags(nil, input.Tags),
+		}
+		c.rules[name] = rule
+	}
+	rule.rule.Description = input.Description
+	rule.rule.ScheduleExpression = input.ScheduleExpression
+	rule.rule.EventPattern = input.EventPattern
+	rule.rule.RoleArn = input.RoleArn
+	rule.rule.State = input.State
+	if rule.rule.State == nil {
+		rule.rule.State = aws.String(cloudwatchevents.RuleStateEnabled)
+	}
+	rule.rule = copyOf(rule.rule).(*cloudwatchevents.Rule)
+	return &cloudwatchevents.PutRuleOutput{RuleArn: aws.String(aws.StringValue(rule.rule.Arn))}, nil
+}
+
+// DeleteRule fails while the rule still has targets, like aws does
+func (c *CloudWatchEvents) DeleteRule(input *cloudwatchevents.DeleteRuleInput) (*cloudwatchevents.DeleteRuleOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	rule, ok := c.rules[aws.StringValue(input.Name)]
+	if !ok {
+		return &cloudwatchevents.DeleteRuleOutput{}, nil
+	}
+	if len(rule.targets) != 0 {
+		return nil, awserr.New("ValidationException", "Rule can't be deleted since it has targets.", nil)
+	}
+	delete(c.rules, aws.StringValue(input.Name))
+	return &cloudwatchevents.DeleteRuleOutput{}, nil
+}
+
+func (c *CloudWatchEvents) DescribeRule(input *cloudwatchevents.DescribeRuleInput) (*cloudwatchevents.DescribeRuleOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	rule, err := c.getRule(input.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudwatchevents.DescribeRuleOutput{
+		Arn:                rule.rule.Arn,
+		Description:        rule.rule.Description,
+		EventBusName:       rule.rule.EventBusName,
+		EventPattern:       rule.rule.EventPattern,
+		Name:               rule.rule.Name,
+		RoleArn:            rule.rule.RoleArn,
+		ScheduleExpression: rule.rule.ScheduleExpression,
+		State:              rule.rule.State,
+	}, nil
+}
+
+func (c *CloudWatchEvents) ListRules(input *cloudwatchevents.ListRulesInput) (*cloudwatchevents.ListRulesOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var names []string
+	for name := range c.rules {
+		if strings.HasPrefix(name, aws.StringValue(input.NamePrefix)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	output := &cloudwatchevents.ListRulesOutput{}
+	for _, name := range names {
+		output.Rules = append(output.Rules, copyOf(c.rules[name].rule).(*cloudwatchevents.Rule))
+	}
+	return output, nil
+}
+
+func (c *CloudWatchEvents) ListTagsForResource(input *cloudwatchevents.ListTagsForResourceInput) (*cloudwatchevents.ListTagsForResourceOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	rule, err := c.getRuleByArn(input.ResourceARN)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudwatchevents.ListTagsForResourceOutput{Tags: mergeEventsTags(nil, rule.tags)}, nil
+}
+
+func (c *CloudWatchEvents) TagResource(input *cloudwatchevents.TagResourceInput) (*cloudwatchevents.TagResourceOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	rule, err := c.getRuleByArn(input.ResourceARN)
+	if err != nil {
+		return nil, err
+	}
+	rule.tags = mergeEventsTags(rule.tags, input.Tags)
+	return &cloudwatchevents.TagResourceOutput{}, nil
+}
+
+func (c *CloudWatchEvents) PutTargets(input *cloudwatchevents.PutTargetsInput) (*cloudwatchevents.PutTargetsOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	rule, err := c.getRule(input.Rule)
+	if err != nil {
+		return nil, err
+	}
+	for _, target := range input.Targets {
+		replaced := false
+		for i, existing := range rule.targets {
+			if aws.StringValue(existing.Id) == aws.StringValue(target.Id) {
+				rule.targets[i] = copyOf(target).(*cloudwatchevents.Target)
+				replaced = true
+			}
+		}
+		if !replaced {
+			rule.targets = append(rule.targets, copyOf(target).(*cloudwatchevents.Target))
+		}
+	}
+	return &cloudwatchevents.PutTargetsOutput{FailedEntryCount: aws.Int64(0)}, nil
+}
+
+func (c *CloudWatchEvents) ListTargetsByRule(input *cloudwatchevents.ListTargetsByRuleInput) (*cloudwatchevents.ListTargetsByRuleOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	rule, err := c.getRule(input.Rule)
+	if err != nil {
+		return nil, err
+	}
+	output := &cloudwatchevents.ListTargetsByRuleOutput{}
+	for _, target := range rule.targets {
+		output.Targets = append(output.Targets, copyOf(target).(*cloudwatchevents.Target))
+	}
+	return output, nil
+}
+
+func (c *CloudWatchEvents) RemoveTargets(input *cloudwatchevents.RemoveTargetsInput) (*cloudwatchevents.RemoveTargetsOutput, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	rule, err := c.getRule(input.Rule)
+	if err != nil {
+		return nil, err
+	}
+	removed := map[string]bool{}
+	for _, id := range input.Ids {
+		removed[aws.StringValue(id)] = true
+	}
+	var targets []*cloudwatchevents.Target
+	for _, target := range rule.targets {
+		if !removed[aws.StringValue(target.Id)] {
+			targets = append(targets, target)
+		}
+	}
+	rule.targets = targets
+	return &cloudwatchevents.RemoveTargetsOutput{FailedEntryCount: aws.Int64(0)}, nil
+}
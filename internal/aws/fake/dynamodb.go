@@ -0,0 +1,484 @@
+package fake
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type DynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	aws    *AWS
+	lock   sync.Mutex
+	tables map[string]*fakeTable
+}
+
+type fakeTable struct {
+	description *dynamodb.TableDescription
+	tags        []*dynamodb.Tag
+	items       map[string]map[string]*dynamodb.AttributeValue
+	timeToLive  *dynamodb.TimeToLiveDescription
+}
+
+func newDynamoDB(a *AWS) *DynamoDB {
+	return &DynamoDB{aws: a, tables: map[string]*fakeTable{}}
+}
+
+func (d *DynamoDB) getTable(tableName *string) (*fakeTable, error) {
+	table, ok := d.tables[aws.StringValue(tableName)]
+	if !ok {
+		return nil, &dynamodb.ResourceNotFoundException{Message_: aws.String(fmt.Sprintf("Requested resource not found: Table: %s not found", aws.StringValue(tableName)))}
+	}
+	return table, nil
+}
+
+func (d *DynamoDB) getTableByArn(tableArn *string) (*fakeTable, error) {
+	for _, table := range d.tables {
+		if aws.StringValue(table.description.TableArn) == aws.StringValue(tableArn) {
+			return table, nil
+		}
+	}
+	return nil, &dynamodb.ResourceNotFoundException{Message_: aws.String(fmt.Sprintf("Requested resource not found: ResourcArn: %s not found", aws.StringValue(tableArn)))}
+}
+
+func attributeString(value *dynamodb.AttributeValue) string {
+	if value.S != nil {
+		return *value.S
+	}
+	return aws.StringValue(value.N)
+}
+
+// itemKey returns the values of the table hash key and range key attributes of the item
+func (t *fakeTable) itemKey(item map[string]*dynamodb.AttributeValue) (string, error) {
+	var values []string
+	for _, keySchema := range t.description.KeySchema {
+		value, ok := item[aws.StringValue(keySchema.AttributeName)]
+		if !ok {
+			return "", validationException("One of the required keys was not given a value")
+		}
+		values = append(values, attributeString(value))
+	}
+	return strings.Join(values, "\x00"), nil
+}
+
+func (t *fakeTable) keyAttribute(keyType string) string {
+	for _, keySchema := range t.description.KeySchema {
+		if aws.StringValue(keySchema.KeyType) == keyType {
+			return aws.StringValue(keySchema.AttributeName)
+		}
+	}
+	return ""
+}
+
+func validationException(message string) error {
+	return awserr.New("ValidationException", message, nil)
+}
+
+func (d *DynamoDB) CreateTable(input *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	name := aws.StringValue(input.TableName)
+	if _, ok := d.tables[name]; ok {
+		return nil, &dynamodb.ResourceInUseException{Message_: aws.String(fmt.Sprintf("Table already exists: %s", name))}
+	}
+
+	description := &dynamodb.TableDescription{
+		TableName:            aws.String(name),
+		TableArn:             aws.String(arn("dynamodb", "table/"+name)),
+		TableId:              aws.String(d.aws.newId("")),
+		TableStatus:          aws.String(dynamodb.TableStatusActive),
+		CreationDateTime:     aws.Time(time.Now().UTC()),
+		KeySchema:            input.KeySchema,
+		AttributeDefinitions: input.AttributeDefinitions,
+		ItemCount:            aws.Int64(0),
+	}
+	if input.BillingMode != nil {
+		description.BillingModeSummary = &dynamodb.BillingModeSummary{BillingMode: input.BillingMode}
+	}
+	if input.SSESpecification != nil && aws.BoolValue(input.SSESpecification.Enabled) {
+		kmsKeyArn := aws.StringValue(input.SSESpecification.KMSMasterKeyId)
+		if !strings.HasPrefix(kmsKeyArn, "arn:") {
+			kmsKeyArn = arn("kms", "key/"+kmsKeyArn)
+		}
+		description.SSEDescription = &dynamodb.SSEDescription{
+			Status:          aws.String(dynamodb.SSEStatusEnabled),
+			SSEType:         aws.String(dynamodb.SSETypeKms),
+			KMSMasterKeyArn: aws.String(kmsKeyArn),
+		}
+	}
+	table := &fakeTable{
+		description: copyOf(description).(*dynamodb.TableDescription),
+		tags:        *copyOf(&input.Tags).(*[]*dynamodb.Tag),
+		items:       map[string]map[string]*dynamodb.AttributeValue{},
+	}
+	d.tables[name] = table
+	return &dynamodb.CreateTableOutput{TableDescription: copyOf(table.description).(*dynamodb.TableDescription)}, nil
+}
+
+// WaitUntilTableExists returns immediately, tables are active once created
+func (d *DynamoDB) WaitUntilTableExists(input *dynamodb.DescribeTableInput) error {
+	_, err := d.DescribeTable(input)
+	return err
+}
+
+// WaitUntilTableNotExists returns immediately, tables are gone once deleted
+func (d *DynamoDB) WaitUntilTableNotExists(input *dynamodb.DescribeTableInput) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if _, err := d.getTable(input.TableName); err == nil {
+		return awserr.New("ResourceNotReady", "exceeded wait attempts", nil)
+	}
+	return nil
+}
+
+func (d *DynamoDB) DescribeTable(input *dynamodb.DescribeTableInput) (*dynamodb.DescribeTableOutput, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	table, err := d.getTable(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	description := copyOf(table.description).(*dynamodb.TableDescription)
+	description.ItemCount = aws.Int64(int64(len(table.items)))
+	return &dynamodb.DescribeTableOutput{Table: description}, nil
+}
+
+// UpdateTimeToLive records the time to live, expired items are not deleted
+func (d *DynamoDB) UpdateTimeToLive(input *dynamodb.UpdateTimeToLiveInput) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	table, err := d.getTable(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	status := dynamodb.TimeToLiveStatusDisabled
+	if aws.BoolValue(input.TimeToLiveSpecification.Enabled) {
+		status = dynamodb.TimeToLiveStatusEnabled
+	}
+	table.timeToLive = &dynamodb.TimeToLiveDescription{
+		AttributeName:    aws.String(aws.StringValue(input.TimeToLiveSpecification.AttributeName)),
+		TimeToLiveStatus: aws.String(status),
+	}
+	return &dynamodb.UpdateTimeToLiveOutput{
+		TimeToLiveSpecification: copyOf(input.TimeToLiveSpecification).(*dynamodb.TimeToLiveSpecification),
+	}, nil
+}
+
+func (d *DynamoDB) DescribeTimeToLive(input *dynamodb.DescribeTimeToLiveInput) (*dynamodb.DescribeTimeToLiveOutput, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	table, err := d.getTable(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	timeToLive := &dynamodb.TimeToLiveDescription{TimeToLiveStatus: aws.String(dynamodb.TimeToLiveStatusDisabled)}
+	if table.timeToLive != nil {
+		timeToLive = copyOf(table.timeToLive).(*dynamodb.TimeToLiveDescription)
+	}
+	return &dynamodb.DescribeTimeToLiveOutput{TimeToLiveDescription: timeToLive}, nil
+}
+
+func (d *DynamoDB) DeleteTable(input *dynamodb.DeleteTableInput) (*dynamodb.DeleteTableOutput, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	table, err := d.getTable(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	delete(d.tables, aws.StringValue(input.TableName))
+	return &dynamodb.DeleteTableOutput{TableDescription: copyOf(table.description).(*dynamodb.TableDescription)}, nil
+}
+
+func (d *DynamoDB) ListTablesPages(input *dynamodb.ListTablesInput, fn func(*dynamodb.ListTablesOutput, bool) bool) error {
+	d.lock.Lock()
+	output := &dynamodb.ListTablesOutput{}
+	for name := range d.tables {
+		output.TableNames = append(output.TableNames, aws.String(name))
+	}
+	d.lock.Unlock()
+
+	sort.Slice(output.TableNames, func(i, j int) bool { return *output.TableNames[i] < *output.TableNames[j] })
+	fn(output, true)
+	return nil
+}
+
+func (d *DynamoDB) ListTagsOfResource(input *dynamodb.ListTagsOfResourceInput) (*dynamodb.ListTagsOfResourceOutput, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	table, err := d.getTableByArn(input.ResourceArn)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.ListTagsOfResourceOutput{Tags: *copyOf(&table.tags).(*[]*dynamodb.Tag)}, nil
+}
+
+func (d *DynamoDB) TagResource(input *dynamodb.TagResourceInput) (*dynamodb.TagResourceOutput, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	table, err := d.getTableByArn(input.ResourceArn)
+	if err != nil {
+		return nil, err
+	}
+	for _, newTag := range input.Tags {
+		replaced := false
+		for _, tag := range table.tags {
+			if aws.StringValue(tag.Key) == aws.StringValue(newTag.Key) {
+				tag.Value = aws.String(aws.StringValue(newTag.Value))
+				replaced = true
+			}
+		}
+		if !replaced {
+			table.tags = append(table.tags, copyOf(newTag).(*dynamodb.Tag))
+		}
+	}
+	return &dynamodb.TagResourceOutput{}, nil
+}
+
+func copyItem(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	if item == nil {
+		return nil
+	}
+	return *copyOf(&item).(*map[string]*dynamodb.AttributeValue)
+}
+
+func (d *DynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	table, err := d.getTable(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := table.itemKey(input.Item)
+	if err != nil {
+		return nil, err
+	}
+	table.items[key] = copyItem(input.Item)
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (d *DynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	table, err := d.getTable(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := table.itemKey(input.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.GetItemOutput{Item: copyItem(table.items[key])}, nil
+}
+
+func (d *DynamoDB) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	table, err := d.getTable(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := table.itemKey(input.Key)
+	if err != nil {
+		return nil, err
+	}
+	delete(table.items, key)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+// expressionAttributeName resolves a "#name" placeholder of an expression
+func expressionAttributeName(name string, names map[string]*string) string {
+	if strings.HasPrefix(name, "#") {
+		return aws.StringValue(names[name])
+	}
+	return name
+}
+
+// UpdateItem supports "set A = :a, B = :b" update expressions, creating the item if it doesn't exist
+func (d *DynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	table, err := d.getTable(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	key, err := table.itemKey(input.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	expression := strings.TrimSpace(aws.StringValue(input.UpdateExpression))
+	if !strings.HasPrefix(strings.ToLower(expression), "set ") {
+		return nil, validationException(fmt.Sprintf("unsupported update expression: %s", expression))
+	}
+	item, ok := table.items[key]
+	if !ok {
+		item = copyItem(input.Key)
+	}
+	for _, assignment := range strings.Split(expression[len("set "):], ",") {
+		parts := strings.Split(assignment, "=")
+		if len(parts) != 2 {
+			return nil, validationException(fmt.Sprintf("unsupported update expression: %s", expression))
+		}
+		name := expressionAttributeName(strings.TrimSpace(parts[0]), input.ExpressionAttributeNames)
+		value, ok := input.ExpressionAttributeValues[strings.TrimSpace(parts[1])]
+		if !ok {
+			return nil, validationException(fmt.Sprintf("value %s wasn't given", strings.TrimSpace(parts[1])))
+		}
+		item[name] = copyOf(value).(*dynamodb.AttributeValue)
+	}
+	table.items[key] = item
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+var beginsWithExpression = regexp.MustCompile(`^begins_with\(\s*([#\w]+)\s*,\s*(:\w+)\s*\)$`)
+
+// itemFilter supports an empty filter expression or a single "begins_with(#name, :value)"
+func itemFilter(input *dynamodb.ScanInput) (func(item map[string]*dynamodb.AttributeValue) bool, error) {
+	expression := strings.TrimSpace(aws.StringValue(input.FilterExpression))
+	if expression == "" {
+		return func(map[string]*dynamodb.AttributeValue) bool { return true }, nil
+	}
+	match := beginsWithExpression.FindStringSubmatch(expression)
+	if match == nil {
+		return nil, validationException(fmt.Sprintf("unsupported filter expression: %s", expression))
+	}
+	name := expressionAttributeName(match[1], input.ExpressionAttributeNames)
+	prefix, ok := input.ExpressionAttributeValues[match[2]]
+	if !ok {
+		return nil, validationException(fmt.Sprintf("value %s wasn't given", match[2]))
+	}
+	return func(item map[string]*dynamodb.AttributeValue) bool {
+		value, ok := item[name]
+		return ok && value.S != nil && strings.HasPrefix(*value.S, aws.StringValue(prefix.S))
+	}, nil
+}
+
+func (d *DynamoDB) Scan(input *dynamodb.ScanInput) (*dynamodb.ScanOutput, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	table, err := d.getTable(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := itemFilter(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for key := range table.items {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	output := &dynamodb.ScanOutput{ScannedCount: aws.Int64(int64(len(keys)))}
+	for _, key := range keys {
+		if filter(table.items[key]) {
+			output.Items = append(output.Items, copyItem(table.items[key]))
+		}
+	}
+	output.Count = aws.Int64(int64(len(output.Items)))
+	return output, nil
+}
+
+func (d *DynamoDB) ScanPages(input *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	output, err := d.Scan(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
+
+var keyConditionExpression = regexp.MustCompile(`^([#\w]+)\s*=\s*(:\w+)(?:\s+AND\s+([#\w]+)\s*(>=|<=|=)\s*(:\w+))?$`)
+
+// compareAttributes orders string attributes lexically and number attributes numerically
+func compareAttributes(a, b *dynamodb.AttributeValue) int {
+	if a.N != nil && b.N != nil {
+		x, _ := strconv.ParseFloat(*a.N, 64)
+		y, _ := strconv.ParseFloat(*b.N, 64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(attributeString(a), attributeString(b))
+}
+
+// Query supports "#hash = :hash" key conditions with an optional "AND #range >= :value" (or <=, =) range condition,
+// the items come sorted by their range key and a single page holds them all
+func (d *DynamoDB) Query(input *dynamodb.QueryInput) (*dynamodb.QueryOutput, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	table, err := d.getTable(input.TableName)
+	if err != nil {
+		return nil, err
+	}
+	expression := strings.TrimSpace(aws.StringValue(input.KeyConditionExpression))
+	match := keyConditionExpression.FindStringSubmatch(expression)
+	if match == nil {
+		return nil, validationException(fmt.Sprintf("unsupported key condition expression: %s", expression))
+	}
+	for _, placeholder := range []string{match[2], match[5]} {
+		if _, ok := input.ExpressionAttributeValues[placeholder]; placeholder != "" && !ok {
+			return nil, validationException(fmt.Sprintf("value %s wasn't given", placeholder))
+		}
+	}
+	hashName := expressionAttributeName(match[1], input.ExpressionAttributeNames)
+	if hashName != table.keyAttribute(dynamodb.KeyTypeHash) {
+		return nil, validationException("Query condition missed key schema element")
+	}
+	rangeName := table.keyAttribute(dynamodb.KeyTypeRange)
+	if match[3] != "" && expressionAttributeName(match[3], input.ExpressionAttributeNames) != rangeName {
+		return nil, validationException("Query condition missed key schema element")
+	}
+
+	var items []map[string]*dynamodb.AttributeValue
+	for _, item := range table.items {
+		if compareAttributes(item[hashName], input.ExpressionAttributeValues[match[2]]) != 0 {
+			continue
+		}
+		if match[3] != "" {
+			order := compareAttributes(item[rangeName], input.ExpressionAttributeValues[match[5]])
+			if (match[4] == ">=" && order < 0) || (match[4] == "<=" && order > 0) || (match[4] == "=" && order != 0) {
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+	if rangeName != "" {
+		forward := input.ScanIndexForward == nil || *input.ScanIndexForward
+		sort.Slice(items, func(i, j int) bool {
+			order := compareAttributes(items[i][rangeName], items[j][rangeName])
+			return (forward && order < 0) || (!forward && order > 0)
+		})
+	}
+	if input.Limit != nil && int64(len(items)) > *input.Limit {
+		items = items[:*input.Limit]
+	}
+
+	output := &dynamodb.QueryOutput{Count: aws.Int64(int64(len(items))), ScannedCount: aws.Int64(int64(len(items)))}
+	for _, item := range items {
+		output.Items = append(output.Items, copyItem(item))
+	}
+	return output, nil
+}
+
+func (d *DynamoDB) QueryPages(input *dynamodb.QueryInput, fn func(*dynamodb.QueryOutput, bool) bool) error {
+	output, err := d.Query(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
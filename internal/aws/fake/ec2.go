@@ -0,0 +1,518 @@
+package fake
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type EC2 struct {
+	ec2iface.EC2API
+	aws                   *AWS
+	lock                  sync.Mutex
+	instances             map[string]*ec2.Instance
+	disableApiTermination map[string]bool
+	volumes               map[string]*ec2.Volume
+	launchTemplates       map[string]*fakeLaunchTemplate
+}
+
+type fakeLaunchTemplate struct {
+	template *ec2.LaunchTemplate
+	versions []*ec2.LaunchTemplateVersion
+}
+
+func newEC2(a *AWS) *EC2 {
+	return &EC2{
+		aws:                   a,
+		instances:             map[string]*ec2.Instance{},
+		disableApiTermination: map[string]bool{},
+		volumes:               map[string]*ec2.Volume{},
+		launchTemplates:       map[string]*fakeLaunchTemplate{},
+	}
+}
+
+// addInstance adds a running instance with a root volume of the given size
+func (e *EC2) addInstance(instance *ec2.Instance, volumeSize int64) string {
+	instanceId := e.aws.newId("i-")
+	volumeId := e.aws.newId("vol-")
+	instance.InstanceId = aws.String(instanceId)
+	instance.State = &ec2.InstanceState{Code: aws.Int64(16), Name: aws.String(ec2.InstanceStateNameRunning)}
+	instance.Placement = &ec2.Placement{AvailabilityZone: aws.String(Region + "a")}
+	instance.LaunchTime = aws.Time(time.Now().UTC())
+	instance.BlockDeviceMappings = []*ec2.InstanceBlockDeviceMapping{
+		{
+			DeviceName: instance.RootDeviceName,
+			Ebs: &ec2.EbsInstanceBlockDevice{
+				VolumeId:            aws.String(volumeId),
+				DeleteOnTermination: aws.Bool(true),
+				Status:              aws.String(ec2.AttachmentStatusAttached),
+			},
+		},
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	instance.PrivateIpAddress = aws.String(fmt.Sprintf("10.0.%d.%d", len(e.instances)/250, len(e.instances)%250+4))
+	e.instances[instanceId] = instance
+	e.volumes[volumeId] = &ec2.Volume{
+		VolumeId:   aws.String(volumeId),
+		Size:       aws.Int64(volumeSize),
+		VolumeType: aws.String(ec2.VolumeTypeGp2),
+		State:      aws.String(ec2.VolumeStateInUse),
+	}
+	return instanceId
+}
+
+// Instance returns a copy of the instance, or nil if it doesn't exist
+func (e *EC2) Instance(instanceId string) *ec2.Instance {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	instance, ok := e.instances[instanceId]
+	if !ok {
+		return nil
+	}
+	return copyOf(instance).(*ec2.Instance)
+}
+
+func (e *EC2) terminateInstance(instanceId string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if instance, ok := e.instances[instanceId]; ok {
+		instance.State = &ec2.InstanceState{Code: aws.Int64(48), Name: aws.String(ec2.InstanceStateNameTerminated)}
+	}
+}
+
+func tagsMatchFilters(tags []*ec2.Tag, filters []*ec2.Filter) bool {
+	for _, filter := range filters {
+		name := aws.StringValue(filter.Name)
+		if !strings.HasPrefix(name, "tag:") {
+			continue
+		}
+		matched := false
+		for _, tag := range tags {
+			if aws.StringValue(tag.Key) != strings.TrimPrefix(name, "tag:") {
+				continue
+			}
+			for _, value := range filter.Values {
+				if aws.StringValue(value) == aws.StringValue(tag.Value) {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func instanceMatchesFilters(instance *ec2.Instance, filters []*ec2.Filter) bool {
+	for _, filter := range filters {
+		if aws.StringValue(filter.Name) != "instance-state-name" {
+			continue
+		}
+		matched := false
+		for _, value := range filter.Values {
+			if aws.StringValue(value) == aws.StringValue(instance.State.Name) {
+				matched = true
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return tagsMatchFilters(instance.Tags, filters)
+}
+
+func (e *EC2) DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	reservation := &ec2.Reservation{ReservationId: aws.String("r-00000000")}
+	if len(input.InstanceIds) != 0 {
+		for _, instanceId := range input.InstanceIds {
+			instance, ok := e.instances[aws.StringValue(instanceId)]
+			if !ok {
+				return nil, awserr.New("InvalidInstanceID.NotFound", fmt.Sprintf("The instance ID '%s' does not exist", aws.StringValue(instanceId)), nil)
+			}
+			if instanceMatchesFilters(instance, input.Filters) {
+				reservation.Instances = append(reservation.Instances, copyOf(instance).(*ec2.Instance))
+			}
+		}
+	} else {
+		for _, instance := range e.instances {
+			if instanceMatchesFilters(instance, input.Filters) {
+				reservation.Instances = append(reservation.Instances, copyOf(instance).(*ec2.Instance))
+			}
+		}
+	}
+
+	output := &ec2.DescribeInstancesOutput{}
+	if len(reservation.Instances) != 0 {
+		output.Reservations = []*ec2.Reservation{reservation}
+	}
+	return output, nil
+}
+
+func (e *EC2) TerminateInstances(input *ec2.TerminateInstancesInput) (*ec2.TerminateInstancesOutput, error) {
+	output := &ec2.TerminateInstancesOutput{}
+	for _, instanceId := range input.InstanceIds {
+		e.lock.Lock()
+		_, ok := e.instances[aws.StringValue(instanceId)]
+		protected := e.disableApiTermination[aws.StringValue(instanceId)]
+		e.lock.Unlock()
+		if !ok {
+			return nil, awserr.New("InvalidInstanceID.NotFound", fmt.Sprintf("The instance ID '%s' does not exist", aws.StringValue(instanceId)), nil)
+		}
+		if protected {
+			return nil, awserr.New("OperationNotPermitted", fmt.Sprintf("The instance '%s' may not be terminated", aws.StringValue(instanceId)), nil)
+		}
+		e.terminateInstance(aws.StringValue(instanceId))
+		output.TerminatingInstances = append(output.TerminatingInstances, &ec2.InstanceStateChange{
+			InstanceId:   instanceId,
+			CurrentState: &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameShuttingDown)},
+		})
+	}
+	return output, nil
+}
+
+func (e *EC2) DescribeVolumes(input *ec2.DescribeVolumesInput) (*ec2.DescribeVolumesOutput, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	output := &ec2.DescribeVolumesOutput{}
+	for _, volumeId := range input.VolumeIds {
+		volume, ok := e.volumes[aws.StringValue(volumeId)]
+		if !ok {
+			return nil, awserr.New("InvalidVolume.NotFound", fmt.Sprintf("The volume '%s' does not exist.", aws.StringValue(volumeId)), nil)
+		}
+		output.Volumes = append(output.Volumes, copyOf(volume).(*ec2.Volume))
+	}
+	return output, nil
+}
+
+func fakeInstanceType(instanceType string, vCpus, cores, disks int64, networkPerformance string) *ec2.InstanceTypeInfo {
+	info := &ec2.InstanceTypeInfo{
+		InstanceType: aws.String(instanceType),
+		VCpuInfo: &ec2.VCpuInfo{
+			DefaultVCpus:          aws.Int64(vCpus),
+			DefaultCores:          aws.Int64(cores),
+			DefaultThreadsPerCore: aws.Int64(vCpus / cores),
+		},
+		NetworkInfo:              &ec2.NetworkInfo{NetworkPerformance: aws.String(networkPerformance)},
+		InstanceStorageSupported: aws.Bool(disks != 0),
+	}
+	if disks != 0 {
+		info.InstanceStorageInfo = &ec2.InstanceStorageInfo{
+			Disks:       []*ec2.DiskInfo{{Count: aws.Int64(disks), SizeInGB: aws.Int64(2500), Type: aws.String(ec2.DiskTypeSsd)}},
+			NvmeSupport: aws.String(ec2.EphemeralNvmeSupportRequired),
+		}
+	}
+	return info
+}
+
+// instanceTypes are the instance types the fake knows, the ones the tests use
+var instanceTypes = map[string]*ec2.InstanceTypeInfo{
+	"i3en.2xlarge":  fakeInstanceType("i3en.2xlarge", 8, 4, 2, "Up to 25 Gigabit"),
+	"i3en.3xlarge":  fakeInstanceType("i3en.3xlarge", 12, 6, 1, "Up to 25 Gigabit"),
+	"i3en.24xlarge": fakeInstanceType("i3en.24xlarge", 96, 48, 8, "100 Gigabit"),
+	"r5.large":      fakeInstanceType("r5.large", 2, 1, 0, "Up to 10 Gigabit"),
+}
+
+func (e *EC2) DescribeInstanceTypes(input *ec2.DescribeInstanceTypesInput) (*ec2.DescribeInstanceTypesOutput, error) {
+	output := &ec2.DescribeInstanceTypesOutput{}
+	for _, instanceType := range input.InstanceTypes {
+		info, ok := instanceTypes[aws.StringValue(instanceType)]
+		if !ok {
+			return nil, awserr.New("InvalidInstanceType", fmt.Sprintf("The following supplied instance types do not exist: [%s]", aws.StringValue(instanceType)), nil)
+		}
+		output.InstanceTypes = append(output.InstanceTypes, copyOf(info).(*ec2.InstanceTypeInfo))
+	}
+	return output, nil
+}
+
+func (e *EC2) DescribeInstanceAttribute(input *ec2.DescribeInstanceAttributeInput) (*ec2.DescribeInstanceAttributeOutput, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if _, ok := e.instances[aws.StringValue(input.InstanceId)]; !ok {
+		return nil, awserr.New("InvalidInstanceID.NotFound", fmt.Sprintf("The instance ID '%s' does not exist", aws.StringValue(input.InstanceId)), nil)
+	}
+	output := &ec2.DescribeInstanceAttributeOutput{InstanceId: input.InstanceId}
+	if aws.StringValue(input.Attribute) == ec2.InstanceAttributeNameDisableApiTermination {
+		output.DisableApiTermination = &ec2.AttributeBooleanValue{Value: aws.Bool(e.disableApiTermination[aws.StringValue(input.InstanceId)])}
+	}
+	return output, nil
+}
+
+func (e *EC2) ModifyInstanceAttribute(input *ec2.ModifyInstanceAttributeInput) (*ec2.ModifyInstanceAttributeOutput, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if _, ok := e.instances[aws.StringValue(input.InstanceId)]; !ok {
+		return nil, awserr.New("InvalidInstanceID.NotFound", fmt.Sprintf("The instance ID '%s' does not exist", aws.StringValue(input.InstanceId)), nil)
+	}
+	if input.DisableApiTermination != nil {
+		e.disableApiTermination[aws.StringValue(input.InstanceId)] = aws.BoolValue(input.DisableApiTermination.Value)
+	}
+	return &ec2.ModifyInstanceAttributeOutput{}, nil
+}
+
+// DisableApiTermination returns whether the instance is protected from api termination
+func (e *EC2) DisableApiTermination(instanceId string) bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.disableApiTermination[instanceId]
+}
+
+func (e *EC2) CreateTags(input *ec2.CreateTagsInput) (*ec2.CreateTagsOutput, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, resourceId := range input.Resources {
+		if instance, ok := e.instances[aws.StringValue(resourceId)]; ok {
+			instance.Tags = mergeEc2Tags(instance.Tags, input.Tags)
+			continue
+		}
+		launchTemplate := e.getLaunchTemplateById(aws.StringValue(resourceId))
+		if launchTemplate == nil {
+			return nil, awserr.New("InvalidID", fmt.Sprintf("The ID '%s' is not valid", aws.StringValue(resourceId)), nil)
+		}
+		launchTemplate.template.Tags = mergeEc2Tags(launchTemplate.template.Tags, input.Tags)
+	}
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+func mergeEc2Tags(tags, newTags []*ec2.Tag) []*ec2.Tag {
+	for _, newTag := range newTags {
+		replaced := false
+		for _, tag := range tags {
+			if aws.StringValue(tag.Key) == aws.StringValue(newTag.Key) {
+				tag.Value = aws.String(aws.StringValue(newTag.Value))
+				replaced = true
+			}
+		}
+		if !replaced {
+			tags = append(tags, &ec2.Tag{Key: aws.String(aws.StringValue(newTag.Key)), Value: aws.String(aws.StringValue(newTag.Value))})
+		}
+	}
+	return tags
+}
+
+func (e *EC2) getLaunchTemplateById(launchTemplateId string) *fakeLaunchTemplate {
+	for _, launchTemplate := range e.launchTemplates {
+		if aws.StringValue(launchTemplate.template.LaunchTemplateId) == launchTemplateId {
+			return launchTemplate
+		}
+	}
+	return nil
+}
+
+func (e *EC2) getLaunchTemplate(launchTemplateId, launchTemplateName *string) (*fakeLaunchTemplate, error) {
+	var launchTemplate *fakeLaunchTemplate
+	if launchTemplateId != nil {
+		launchTemplate = e.getLaunchTemplateById(aws.StringValue(launchTemplateId))
+	} else {
+		launchTemplate = e.launchTemplates[aws.StringValue(launchTemplateName)]
+	}
+	if launchTemplate == nil {
+		return nil, awserr.New("InvalidLaunchTemplateName.NotFoundException",
+			fmt.Sprintf("At least one of the launch templates specified in the request does not exist: %s", aws.StringValue(launchTemplateName)), nil)
+	}
+	return launchTemplate, nil
+}
+
+// responseLaunchTemplateData converts the launch template data of a request to the one describe calls return
+func responseLaunchTemplateData(data *ec2.RequestLaunchTemplateData) *ec2.ResponseLaunchTemplateData {
+	response := &ec2.ResponseLaunchTemplateData{
+		ImageId:               data.ImageId,
+		InstanceType:          data.InstanceType,
+		KeyName:               data.KeyName,
+		UserData:              data.UserData,
+		DisableApiTermination: data.DisableApiTermination,
+	}
+	if data.IamInstanceProfile != nil {
+		response.IamInstanceProfile = &ec2.LaunchTemplateIamInstanceProfileSpecification{
+			Arn:  data.IamInstanceProfile.Arn,
+			Name: data.IamInstanceProfile.Name,
+		}
+	}
+	for _, blockDeviceMapping := range data.BlockDeviceMappings {
+		mapping := &ec2.LaunchTemplateBlockDeviceMapping{DeviceName: blockDeviceMapping.DeviceName}
+		if blockDeviceMapping.Ebs != nil {
+			mapping.Ebs = &ec2.LaunchTemplateEbsBlockDevice{
+				VolumeType:          blockDeviceMapping.Ebs.VolumeType,
+				VolumeSize:          blockDeviceMapping.Ebs.VolumeSize,
+				DeleteOnTermination: blockDeviceMapping.Ebs.DeleteOnTermination,
+			}
+		}
+		response.BlockDeviceMappings = append(response.BlockDeviceMappings, mapping)
+	}
+	for _, networkInterface := range data.NetworkInterfaces {
+		response.NetworkInterfaces = append(response.NetworkInterfaces, &ec2.LaunchTemplateInstanceNetworkInterfaceSpecification{
+			AssociatePublicIpAddress: networkInterface.AssociatePublicIpAddress,
+			DeviceIndex:              networkInterface.DeviceIndex,
+			Ipv6AddressCount:         networkInterface.Ipv6AddressCount,
+			SubnetId:                 networkInterface.SubnetId,
+			Groups:                   networkInterface.Groups,
+		})
+	}
+	for _, tagSpecification := range data.TagSpecifications {
+		response.TagSpecifications = append(response.TagSpecifications, &ec2.LaunchTemplateTagSpecification{
+			ResourceType: tagSpecification.ResourceType,
+			Tags:         tagSpecification.Tags,
+		})
+	}
+	return copyOf(response).(*ec2.ResponseLaunchTemplateData)
+}
+
+func (e *EC2) CreateLaunchTemplate(input *ec2.CreateLaunchTemplateInput) (*ec2.CreateLaunchTemplateOutput, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	name := aws.StringValue(input.LaunchTemplateName)
+	if _, ok := e.launchTemplates[name]; ok {
+		return nil, awserr.New("InvalidLaunchTemplateName.AlreadyExistsException",
+			fmt.Sprintf("Launch template name already in use: %s", name), nil)
+	}
+
+	launchTemplateId := e.aws.newId("lt-")
+	template := &ec2.LaunchTemplate{
+		LaunchTemplateId:     aws.String(launchTemplateId),
+		LaunchTemplateName:   aws.String(name),
+		CreateTime:           aws.Time(time.Now().UTC()),
+		DefaultVersionNumber: aws.Int64(1),
+		LatestVersionNumber:  aws.Int64(1),
+	}
+	for _, tagSpecification := range input.TagSpecifications {
+		if aws.StringValue(tagSpecification.ResourceType) == ec2.ResourceTypeLaunchTemplate {
+			template.Tags = mergeEc2Tags(template.Tags, tagSpecification.Tags)
+		}
+	}
+	e.launchTemplates[name] = &fakeLaunchTemplate{
+		template: template,
+		versions: []*ec2.LaunchTemplateVersion{
+			{
+				LaunchTemplateId:   aws.String(launchTemplateId),
+				LaunchTemplateName: aws.String(name),
+				VersionNumber:      aws.Int64(1),
+				VersionDescription: input.VersionDescription,
+				LaunchTemplateData: responseLaunchTemplateData(input.LaunchTemplateData),
+			},
+		},
+	}
+	return &ec2.CreateLaunchTemplateOutput{LaunchTemplate: copyOf(template).(*ec2.LaunchTemplate)}, nil
+}
+
+func (e *EC2) CreateLaunchTemplateVersion(input *ec2.CreateLaunchTemplateVersionInput) (*ec2.CreateLaunchTemplateVersionOutput, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	launchTemplate, err := e.getLaunchTemplate(input.LaunchTemplateId, input.LaunchTemplateName)
+	if err != nil {
+		return nil, err
+	}
+	versionNumber := aws.Int64Value(launchTemplate.template.LatestVersionNumber) + 1
+	version := &ec2.LaunchTemplateVersion{
+		LaunchTemplateId:   launchTemplate.template.LaunchTemplateId,
+		LaunchTemplateName: launchTemplate.template.LaunchTemplateName,
+		VersionNumber:      aws.Int64(versionNumber),
+		VersionDescription: input.VersionDescription,
+		LaunchTemplateData: responseLaunchTemplateData(input.LaunchTemplateData),
+	}
+	launchTemplate.versions = append(launchTemplate.versions, version)
+	launchTemplate.template.LatestVersionNumber = aws.Int64(versionNumber)
+	return &ec2.CreateLaunchTemplateVersionOutput{LaunchTemplateVersion: copyOf(version).(*ec2.LaunchTemplateVersion)}, nil
+}
+
+func (e *EC2) ModifyLaunchTemplate(input *ec2.ModifyLaunchTemplateInput) (*ec2.ModifyLaunchTemplateOutput, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	launchTemplate, err := e.getLaunchTemplate(input.LaunchTemplateId, input.LaunchTemplateName)
+	if err != nil {
+		return nil, err
+	}
+	if input.DefaultVersion != nil {
+		versionNumber, err := strconv.ParseInt(aws.StringValue(input.DefaultVersion), 10, 64)
+		if err != nil || versionNumber < 1 || versionNumber > aws.Int64Value(launchTemplate.template.LatestVersionNumber) {
+			return nil, awserr.New("InvalidLaunchTemplateId.VersionNotFound",
+				fmt.Sprintf("Could not find launch template version %s", aws.StringValue(input.DefaultVersion)), nil)
+		}
+		launchTemplate.template.DefaultVersionNumber = aws.Int64(versionNumber)
+	}
+	return &ec2.ModifyLaunchTemplateOutput{LaunchTemplate: copyOf(launchTemplate.template).(*ec2.LaunchTemplate)}, nil
+}
+
+func (e *EC2) DeleteLaunchTemplate(input *ec2.DeleteLaunchTemplateInput) (*ec2.DeleteLaunchTemplateOutput, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	launchTemplate, err := e.getLaunchTemplate(input.LaunchTemplateId, input.LaunchTemplateName)
+	if err != nil {
+		return nil, err
+	}
+	delete(e.launchTemplates, aws.StringValue(launchTemplate.template.LaunchTemplateName))
+	return &ec2.DeleteLaunchTemplateOutput{LaunchTemplate: copyOf(launchTemplate.template).(*ec2.LaunchTemplate)}, nil
+}
+
+func (e *EC2) DescribeLaunchTemplates(input *ec2.DescribeLaunchTemplatesInput) (*ec2.DescribeLaunchTemplatesOutput, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	output := &ec2.DescribeLaunchTemplatesOutput{}
+	if len(input.LaunchTemplateNames) != 0 {
+		for _, name := range input.LaunchTemplateNames {
+			launchTemplate, err := e.getLaunchTemplate(nil, name)
+			if err != nil {
+				return nil, err
+			}
+			if tagsMatchFilters(launchTemplate.template.Tags, input.Filters) {
+				output.LaunchTemplates = append(output.LaunchTemplates, copyOf(launchTemplate.template).(*ec2.LaunchTemplate))
+			}
+		}
+		return output, nil
+	}
+	for _, launchTemplate := range e.launchTemplates {
+		if tagsMatchFilters(launchTemplate.template.Tags, input.Filters) {
+			output.LaunchTemplates = append(output.LaunchTemplates, copyOf(launchTemplate.template).(*ec2.LaunchTemplate))
+		}
+	}
+	return output, nil
+}
+
+func (e *EC2) DescribeLaunchTemplatesPages(input *ec2.DescribeLaunchTemplatesInput, fn func(*ec2.DescribeLaunchTemplatesOutput, bool) bool) error {
+	output, err := e.DescribeLaunchTemplates(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
+
+func (e *EC2) DescribeLaunchTemplateVersions(input *ec2.DescribeLaunchTemplateVersionsInput) (*ec2.DescribeLaunchTemplateVersionsOutput, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	launchTemplate, err := e.getLaunchTemplate(input.LaunchTemplateId, input.LaunchTemplateName)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ec2.DescribeLaunchTemplateVersionsOutput{}
+	for _, version := range launchTemplate.versions {
+		versionNumber := aws.Int64Value(version.VersionNumber)
+		selected := len(input.Versions) == 0
+		for _, requested := range input.Versions {
+			switch aws.StringValue(requested) {
+			case "$Default":
+				selected = selected || versionNumber == aws.Int64Value(launchTemplate.template.DefaultVersionNumber)
+			case "$Latest":
+				selected = selected || versionNumber == aws.Int64Value(launchTemplate.template.LatestVersionNumber)
+			default:
+				selected = selected || aws.StringValue(requested) == strconv.FormatInt(versionNumber, 10)
+			}
+		}
+		if selected {
+			version := copyOf(version).(*ec2.LaunchTemplateVersion)
+			version.DefaultVersion = aws.Bool(versionNumber == aws.Int64Value(launchTemplate.template.DefaultVersionNumber))
+			output.LaunchTemplateVersions = append(output.LaunchTemplateVersions, version)
+		}
+	}
+	return output, nil
+}
@@ -0,0 +1,87 @@
+// Package fake is an in-memory implementation of the aws services wekactl uses, it keeps just enough
+// state for the cluster operations to run in tests against a simulated cloudformation stack.
+// Calls wekactl doesn't make are left unimplemented and panic
+package fake
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"sync"
+	"wekactl/internal/connectors"
+)
+
+const (
+	AccountId = "123456789012"
+	Region    = "eu-central-1"
+)
+
+type AWS struct {
+	lock    sync.Mutex
+	counter int
+
+	CF               *CloudFormation
+	EC2              *EC2
+	ASG              *AutoScaling
+	KMS              *KMS
+	DynamoDB         *DynamoDB
+	IAM              *IAM
+	Lambda           *Lambda
+	ApiGateway       *ApiGateway
+	STS              *STS
+	SFN              *SFN
+	CloudWatchEvents *CloudWatchEvents
+	CloudWatch       *CloudWatch
+}
+
+func New() *AWS {
+	a := &AWS{}
+	a.CF = newCloudFormation(a)
+	a.EC2 = newEC2(a)
+	a.ASG = newAutoScaling(a)
+	a.KMS = newKMS(a)
+	a.DynamoDB = newDynamoDB(a)
+	a.IAM = newIAM(a)
+	a.Lambda = newLambda(a)
+	a.ApiGateway = newApiGateway(a)
+	a.STS = &STS{}
+	a.SFN = newSFN(a)
+	a.CloudWatchEvents = newCloudWatchEvents(a)
+	a.CloudWatch = newCloudWatch()
+	return a
+}
+
+// Install makes the connectors session use the fake services
+func (a *AWS) Install() *AWS {
+	connectors.SetAWSSession(func(s *connectors.SAwsSession) {
+		s.CF = a.CF
+		s.EC2 = a.EC2
+		s.ASG = a.ASG
+		s.KMS = a.KMS
+		s.DynamoDB = a.DynamoDB
+		s.IAM = a.IAM
+		s.Lambda = a.Lambda
+		s.ApiGateway = a.ApiGateway
+		s.STS = a.STS
+		s.SFN = a.SFN
+		s.CloudWatchEvents = a.CloudWatchEvents
+		s.CloudWatch = a.CloudWatch
+	})
+	return a
+}
+
+// newId returns a unique id with the given prefix, e.g. "i-00000001"
+func (a *AWS) newId(prefix string) string {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.counter++
+	return fmt.Sprintf("%s%08x", prefix, a.counter)
+}
+
+func arn(service, resource string) string {
+	return fmt.Sprintf("arn:aws:%s:%s:%s:%s", service, Region, AccountId, resource)
+}
+
+// copyOf returns a deep copy of v, so callers can't modify the fake state through returned values
+func copyOf(v interface{}) interface{} {
+	return awsutil.CopyOf(v)
+}
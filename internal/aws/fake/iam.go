@@ -0,0 +1,371 @@
+package fake
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+type IAM struct {
+	iamiface.IAMAPI
+	aws      *AWS
+	lock     sync.Mutex
+	roles    map[string]*fakeRole
+	policies map[string]*fakePolicy
+}
+
+type fakeRole struct {
+	role             *iam.Role
+	tags             []*iam.Tag
+	attachedPolicies []string
+	inlinePolicies   map[string]string
+}
+
+type fakePolicy struct {
+	policy   *iam.Policy
+	document string
+}
+
+func newIAM(a *AWS) *IAM {
+	return &IAM{aws: a, roles: map[string]*fakeRole{}, policies: map[string]*fakePolicy{}}
+}
+
+func noSuchEntity(format string, args ...interface{}) error {
+	return awserr.New(iam.ErrCodeNoSuchEntityException, fmt.Sprintf(format, args...), nil)
+}
+
+func (i *IAM) getRole(roleName *string) (*fakeRole, error) {
+	role, ok := i.roles[aws.StringValue(roleName)]
+	if !ok {
+		return nil, noSuchEntity("The role with name %s cannot be found.", aws.StringValue(roleName))
+	}
+	return role, nil
+}
+
+func (i *IAM) getPolicy(policyArn *string) (*fakePolicy, error) {
+	policy, ok := i.policies[aws.StringValue(policyArn)]
+	if !ok {
+		return nil, noSuchEntity("Policy %s does not exist or is not attachable.", aws.StringValue(policyArn))
+	}
+	return policy, nil
+}
+
+func mergeIamTags(tags, newTags []*iam.Tag) []*iam.Tag {
+	for _, newTag := range newTags {
+		replaced := false
+		for _, tag := range tags {
+			if aws.StringValue(tag.Key) == aws.StringValue(newTag.Key) {
+				tag.Value = aws.String(aws.StringValue(newTag.Value))
+				replaced = true
+			}
+		}
+		if !replaced {
+			tags = append(tags, copyOf(newTag).(*iam.Tag))
+		}
+	}
+	return tags
+}
+
+func (i *IAM) CreateRole(input *iam.CreateRoleInput) (*iam.CreateRoleOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	name := aws.StringValue(input.RoleName)
+	if _, ok := i.roles[name]; ok {
+		return nil, awserr.New(iam.ErrCodeEntityAlreadyExistsException, fmt.Sprintf("Role with name %s already exists.", name), nil)
+	}
+	path := aws.StringValue(input.Path)
+	if path == "" {
+		path = "/"
+	}
+	role := &fakeRole{
+		role: &iam.Role{
+			RoleName:                 aws.String(name),
+			RoleId:                   aws.String(i.aws.newId("AROA")),
+			Arn:                      aws.String(fmt.Sprintf("arn:aws:iam::%s:role%s%s", AccountId, path, name)),
+			Path:                     aws.String(path),
+			AssumeRolePolicyDocument: aws.String(url.QueryEscape(aws.StringValue(input.AssumeRolePolicyDocument))),
+			CreateDate:               aws.Time(time.Now().UTC()),
+		},
+		tags:           mergeIamTags(nil, input.Tags),
+		inlinePolicies: map[string]string{},
+	}
+	i.roles[name] = role
+	output := copyOf(role.role).(*iam.Role)
+	output.Tags = mergeIamTags(nil, role.tags)
+	return &iam.CreateRoleOutput{Role: output}, nil
+}
+
+// WaitUntilRoleExists returns immediately, roles exist once created
+func (i *IAM) WaitUntilRoleExists(input *iam.GetRoleInput) error {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	_, err := i.getRole(input.RoleName)
+	return err
+}
+
+func (i *IAM) GetRole(input *iam.GetRoleInput) (*iam.GetRoleOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	role, err := i.getRole(input.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	output := copyOf(role.role).(*iam.Role)
+	output.Tags = mergeIamTags(nil, role.tags)
+	return &iam.GetRoleOutput{Role: output}, nil
+}
+
+func (i *IAM) DeleteRole(input *iam.DeleteRoleInput) (*iam.DeleteRoleOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	role, err := i.getRole(input.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	if len(role.attachedPolicies) != 0 || len(role.inlinePolicies) != 0 {
+		return nil, awserr.New(iam.ErrCodeDeleteConflictException, "Cannot delete entity, must detach all policies first.", nil)
+	}
+	delete(i.roles, aws.StringValue(input.RoleName))
+	return &iam.DeleteRoleOutput{}, nil
+}
+
+// ListRoles returns all the roles without their tags, like aws does
+func (i *IAM) ListRoles(*iam.ListRolesInput) (*iam.ListRolesOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	var names []string
+	for name := range i.roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	output := &iam.ListRolesOutput{IsTruncated: aws.Bool(false)}
+	for _, name := range names {
+		output.Roles = append(output.Roles, copyOf(i.roles[name].role).(*iam.Role))
+	}
+	return output, nil
+}
+
+func (i *IAM) ListRolesPages(input *iam.ListRolesInput, fn func(*iam.ListRolesOutput, bool) bool) error {
+	output, err := i.ListRoles(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
+
+func (i *IAM) ListRoleTags(input *iam.ListRoleTagsInput) (*iam.ListRoleTagsOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	role, err := i.getRole(input.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	return &iam.ListRoleTagsOutput{Tags: mergeIamTags(nil, role.tags), IsTruncated: aws.Bool(false)}, nil
+}
+
+func (i *IAM) TagRole(input *iam.TagRoleInput) (*iam.TagRoleOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	role, err := i.getRole(input.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	role.tags = mergeIamTags(role.tags, input.Tags)
+	return &iam.TagRoleOutput{}, nil
+}
+
+func (i *IAM) CreatePolicy(input *iam.CreatePolicyInput) (*iam.CreatePolicyOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	name := aws.StringValue(input.PolicyName)
+	policyArn := fmt.Sprintf("arn:aws:iam::%s:policy/%s", AccountId, name)
+	if _, ok := i.policies[policyArn]; ok {
+		return nil, awserr.New(iam.ErrCodeEntityAlreadyExistsException, fmt.Sprintf("A policy called %s already exists.", name), nil)
+	}
+	policy := &fakePolicy{
+		policy: &iam.Policy{
+			PolicyName:       aws.String(name),
+			PolicyId:         aws.String(i.aws.newId("ANPA")),
+			Arn:              aws.String(policyArn),
+			Path:             aws.String("/"),
+			DefaultVersionId: aws.String("v1"),
+			AttachmentCount:  aws.Int64(0),
+			IsAttachable:     aws.Bool(true),
+			CreateDate:       aws.Time(time.Now().UTC()),
+		},
+		document: aws.StringValue(input.PolicyDocument),
+	}
+	i.policies[policyArn] = policy
+	return &iam.CreatePolicyOutput{Policy: copyOf(policy.policy).(*iam.Policy)}, nil
+}
+
+func (i *IAM) GetPolicy(input *iam.GetPolicyInput) (*iam.GetPolicyOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	policy, err := i.getPolicy(input.PolicyArn)
+	if err != nil {
+		return nil, err
+	}
+	return &iam.GetPolicyOutput{Policy: copyOf(policy.policy).(*iam.Policy)}, nil
+}
+
+// GetPolicyVersion returns the url encoded policy document, like aws does
+func (i *IAM) GetPolicyVersion(input *iam.GetPolicyVersionInput) (*iam.GetPolicyVersionOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	policy, err := i.getPolicy(input.PolicyArn)
+	if err != nil {
+		return nil, err
+	}
+	if aws.StringValue(input.VersionId) != aws.StringValue(policy.policy.DefaultVersionId) {
+		return nil, noSuchEntity("Policy %s version %s does not exist.", aws.StringValue(input.PolicyArn), aws.StringValue(input.VersionId))
+	}
+	return &iam.GetPolicyVersionOutput{PolicyVersion: &iam.PolicyVersion{
+		VersionId:        policy.policy.DefaultVersionId,
+		IsDefaultVersion: aws.Bool(true),
+		Document:         aws.String(url.QueryEscape(policy.document)),
+	}}, nil
+}
+
+func (i *IAM) DeletePolicy(input *iam.DeletePolicyInput) (*iam.DeletePolicyOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	policy, err := i.getPolicy(input.PolicyArn)
+	if err != nil {
+		return nil, err
+	}
+	if aws.Int64Value(policy.policy.AttachmentCount) != 0 {
+		return nil, awserr.New(iam.ErrCodeDeleteConflictException, "Cannot delete a policy attached to entities.", nil)
+	}
+	delete(i.policies, aws.StringValue(input.PolicyArn))
+	return &iam.DeletePolicyOutput{}, nil
+}
+
+func (i *IAM) ListPolicies(*iam.ListPoliciesInput) (*iam.ListPoliciesOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	var arns []string
+	for policyArn := range i.policies {
+		arns = append(arns, policyArn)
+	}
+	sort.Strings(arns)
+	output := &iam.ListPoliciesOutput{IsTruncated: aws.Bool(false)}
+	for _, policyArn := range arns {
+		output.Policies = append(output.Policies, copyOf(i.policies[policyArn].policy).(*iam.Policy))
+	}
+	return output, nil
+}
+
+func (i *IAM) ListPoliciesPages(input *iam.ListPoliciesInput, fn func(*iam.ListPoliciesOutput, bool) bool) error {
+	output, err := i.ListPolicies(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
+
+func (i *IAM) AttachRolePolicy(input *iam.AttachRolePolicyInput) (*iam.AttachRolePolicyOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	role, err := i.getRole(input.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := i.getPolicy(input.PolicyArn)
+	if err != nil {
+		return nil, err
+	}
+	for _, policyArn := range role.attachedPolicies {
+		if policyArn == aws.StringValue(input.PolicyArn) {
+			return &iam.AttachRolePolicyOutput{}, nil
+		}
+	}
+	role.attachedPolicies = append(role.attachedPolicies, aws.StringValue(input.PolicyArn))
+	policy.policy.AttachmentCount = aws.Int64(aws.Int64Value(policy.policy.AttachmentCount) + 1)
+	return &iam.AttachRolePolicyOutput{}, nil
+}
+
+func (i *IAM) DetachRolePolicy(input *iam.DetachRolePolicyInput) (*iam.DetachRolePolicyOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	role, err := i.getRole(input.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	for index, policyArn := range role.attachedPolicies {
+		if policyArn != aws.StringValue(input.PolicyArn) {
+			continue
+		}
+		role.attachedPolicies = append(role.attachedPolicies[:index], role.attachedPolicies[index+1:]...)
+		if policy, ok := i.policies[policyArn]; ok {
+			policy.policy.AttachmentCount = aws.Int64(aws.Int64Value(policy.policy.AttachmentCount) - 1)
+		}
+		return &iam.DetachRolePolicyOutput{}, nil
+	}
+	return nil, noSuchEntity("Policy %s was not found.", aws.StringValue(input.PolicyArn))
+}
+
+func (i *IAM) ListAttachedRolePolicies(input *iam.ListAttachedRolePoliciesInput) (*iam.ListAttachedRolePoliciesOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	role, err := i.getRole(input.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	output := &iam.ListAttachedRolePoliciesOutput{IsTruncated: aws.Bool(false)}
+	for _, policyArn := range role.attachedPolicies {
+		attachedPolicy := &iam.AttachedPolicy{PolicyArn: aws.String(policyArn)}
+		if policy, ok := i.policies[policyArn]; ok {
+			attachedPolicy.PolicyName = aws.String(aws.StringValue(policy.policy.PolicyName))
+		}
+		output.AttachedPolicies = append(output.AttachedPolicies, attachedPolicy)
+	}
+	return output, nil
+}
+
+func (i *IAM) PutRolePolicy(input *iam.PutRolePolicyInput) (*iam.PutRolePolicyOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	role, err := i.getRole(input.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	role.inlinePolicies[aws.StringValue(input.PolicyName)] = aws.StringValue(input.PolicyDocument)
+	return &iam.PutRolePolicyOutput{}, nil
+}
+
+func (i *IAM) ListRolePolicies(input *iam.ListRolePoliciesInput) (*iam.ListRolePoliciesOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	role, err := i.getRole(input.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	output := &iam.ListRolePoliciesOutput{IsTruncated: aws.Bool(false)}
+	for name := range role.inlinePolicies {
+		output.PolicyNames = append(output.PolicyNames, aws.String(name))
+	}
+	return output, nil
+}
+
+func (i *IAM) DeleteRolePolicy(input *iam.DeleteRolePolicyInput) (*iam.DeleteRolePolicyOutput, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	role, err := i.getRole(input.RoleName)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := role.inlinePolicies[aws.StringValue(input.PolicyName)]; !ok {
+		return nil, noSuchEntity("The role policy with name %s cannot be found.", aws.StringValue(input.PolicyName))
+	}
+	delete(role.inlinePolicies, aws.StringValue(input.PolicyName))
+	return &iam.DeleteRolePolicyOutput{}, nil
+}
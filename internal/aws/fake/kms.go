@@ -0,0 +1,170 @@
+package fake
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"sort"
+	"sync"
+	"time"
+)
+
+type KMS struct {
+	kmsiface.KMSAPI
+	aws     *AWS
+	lock    sync.Mutex
+	keys    map[string]*fakeKey
+	aliases map[string]*kms.AliasListEntry
+}
+
+type fakeKey struct {
+	metadata *kms.KeyMetadata
+	tags     []*kms.Tag
+}
+
+func newKMS(a *AWS) *KMS {
+	return &KMS{aws: a, keys: map[string]*fakeKey{}, aliases: map[string]*kms.AliasListEntry{}}
+}
+
+func (k *KMS) getKey(keyId *string) (*fakeKey, error) {
+	for _, key := range k.keys {
+		if aws.StringValue(key.metadata.KeyId) == aws.StringValue(keyId) || aws.StringValue(key.metadata.Arn) == aws.StringValue(keyId) {
+			return key, nil
+		}
+	}
+	return nil, &kms.NotFoundException{Message_: aws.String(fmt.Sprintf("Key '%s' does not exist", aws.StringValue(keyId)))}
+}
+
+// sortedKeys returns the keys in creation order, so listing is deterministic
+func (k *KMS) sortedKeys() (keys []*fakeKey) {
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return aws.StringValue(keys[i].metadata.KeyId) < aws.StringValue(keys[j].metadata.KeyId)
+	})
+	return
+}
+
+func (k *KMS) CreateKey(input *kms.CreateKeyInput) (*kms.CreateKeyOutput, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	keyId := k.aws.newId("key-")
+	key := &fakeKey{
+		metadata: &kms.KeyMetadata{
+			KeyId:        aws.String(keyId),
+			Arn:          aws.String(arn("kms", "key/"+keyId)),
+			AWSAccountId: aws.String(AccountId),
+			CreationDate: aws.Time(time.Now().UTC()),
+			Enabled:      aws.Bool(true),
+			KeyManager:   aws.String(kms.KeyManagerTypeCustomer),
+			KeyState:     aws.String(kms.KeyStateEnabled),
+			KeyUsage:     aws.String(kms.KeyUsageTypeEncryptDecrypt),
+		},
+		tags: *copyOf(&input.Tags).(*[]*kms.Tag),
+	}
+	k.keys[keyId] = key
+	return &kms.CreateKeyOutput{KeyMetadata: copyOf(key.metadata).(*kms.KeyMetadata)}, nil
+}
+
+func (k *KMS) CreateAlias(input *kms.CreateAliasInput) (*kms.CreateAliasOutput, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	name := aws.StringValue(input.AliasName)
+	if _, ok := k.aliases[name]; ok {
+		return nil, &kms.AlreadyExistsException{Message_: aws.String(fmt.Sprintf("An alias with the name %s already exists", name))}
+	}
+	key, err := k.getKey(input.TargetKeyId)
+	if err != nil {
+		return nil, err
+	}
+	k.aliases[name] = &kms.AliasListEntry{
+		AliasName:   aws.String(name),
+		AliasArn:    aws.String(arn("kms", name)),
+		TargetKeyId: key.metadata.KeyId,
+	}
+	return &kms.CreateAliasOutput{}, nil
+}
+
+func (k *KMS) DeleteAlias(input *kms.DeleteAliasInput) (*kms.DeleteAliasOutput, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	name := aws.StringValue(input.AliasName)
+	if _, ok := k.aliases[name]; !ok {
+		return nil, &kms.NotFoundException{Message_: aws.String(fmt.Sprintf("Alias %s is not found", name))}
+	}
+	delete(k.aliases, name)
+	return &kms.DeleteAliasOutput{}, nil
+}
+
+func (k *KMS) ListAliases(input *kms.ListAliasesInput) (*kms.ListAliasesOutput, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	output := &kms.ListAliasesOutput{Truncated: aws.Bool(false)}
+	for _, alias := range k.aliases {
+		if input.KeyId == nil || aws.StringValue(alias.TargetKeyId) == aws.StringValue(input.KeyId) {
+			output.Aliases = append(output.Aliases, copyOf(alias).(*kms.AliasListEntry))
+		}
+	}
+	return output, nil
+}
+
+func (k *KMS) ListKeys(*kms.ListKeysInput) (*kms.ListKeysOutput, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	output := &kms.ListKeysOutput{Truncated: aws.Bool(false)}
+	for _, key := range k.sortedKeys() {
+		output.Keys = append(output.Keys, &kms.KeyListEntry{
+			KeyId:  aws.String(aws.StringValue(key.metadata.KeyId)),
+			KeyArn: aws.String(aws.StringValue(key.metadata.Arn)),
+		})
+	}
+	return output, nil
+}
+
+func (k *KMS) ListKeysPages(input *kms.ListKeysInput, fn func(*kms.ListKeysOutput, bool) bool) error {
+	output, err := k.ListKeys(input)
+	if err != nil {
+		return err
+	}
+	fn(output, true)
+	return nil
+}
+
+func (k *KMS) DescribeKey(input *kms.DescribeKeyInput) (*kms.DescribeKeyOutput, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	key, err := k.getKey(input.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	return &kms.DescribeKeyOutput{KeyMetadata: copyOf(key.metadata).(*kms.KeyMetadata)}, nil
+}
+
+func (k *KMS) ListResourceTags(input *kms.ListResourceTagsInput) (*kms.ListResourceTagsOutput, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	key, err := k.getKey(input.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	return &kms.ListResourceTagsOutput{Tags: *copyOf(&key.tags).(*[]*kms.Tag), Truncated: aws.Bool(false)}, nil
+}
+
+func (k *KMS) ScheduleKeyDeletion(input *kms.ScheduleKeyDeletionInput) (*kms.ScheduleKeyDeletionOutput, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	key, err := k.getKey(input.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	if aws.StringValue(key.metadata.KeyState) == kms.KeyStatePendingDeletion {
+		return nil, &kms.InvalidStateException{Message_: aws.String(fmt.Sprintf("%s is pending deletion", aws.StringValue(key.metadata.Arn)))}
+	}
+	deletionDate := time.Now().UTC().AddDate(0, 0, int(aws.Int64Value(input.PendingWindowInDays)))
+	key.metadata.KeyState = aws.String(kms.KeyStatePendingDeletion)
+	key.metadata.Enabled = aws.Bool(false)
+	key.metadata.DeletionDate = aws.Time(deletionDate)
+	return &kms.ScheduleKeyDeletionOutput{KeyId: key.metadata.KeyId, DeletionDate: aws.Time(deletionDate)}, nil
+}
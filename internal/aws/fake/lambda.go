@@ -0,0 +1,245 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Lambda struct {
+	lambdaiface.LambdaAPI
+	aws       *AWS
+	lock      sync.Mutex
+	functions map[string]*fakeFunction
+}
+
+type fakeFunction struct {
+	configuration *lambda.FunctionConfiguration
+	tags          map[string]*string
+	codeLocation  string
+	statements    []map[string]interface{}
+}
+
+func newLambda(a *AWS) *Lambda {
+	return &Lambda{aws: a, functions: map[string]*fakeFunction{}}
+}
+
+func (l *Lambda) getFunction(functionName *string) (*fakeFunction, error) {
+	for _, function := range l.functions {
+		if aws.StringValue(function.configuration.FunctionName) == aws.StringValue(functionName) ||
+			aws.StringValue(function.configuration.FunctionArn) == aws.StringValue(functionName) {
+			return function, nil
+		}
+	}
+	return nil, &lambda.ResourceNotFoundException{
+		Message_: aws.String(fmt.Sprintf("Function not found: %s", arn("lambda", "function:"+aws.StringValue(functionName)))),
+		Type:     aws.String("User"),
+	}
+}
+
+func copyTags(tags map[string]*string) map[string]*string {
+	copied := map[string]*string{}
+	for key, value := range tags {
+		copied[key] = aws.String(aws.StringValue(value))
+	}
+	return copied
+}
+
+func codeLocation(bucket, key *string) string {
+	return fmt.Sprintf("https://s3.%s.amazonaws.com/%s/%s", Region, aws.StringValue(bucket), aws.StringValue(key))
+}
+
+func (l *Lambda) CreateFunction(input *lambda.CreateFunctionInput) (*lambda.FunctionConfiguration, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	name := aws.StringValue(input.FunctionName)
+	if _, ok := l.functions[name]; ok {
+		return nil, &lambda.ResourceConflictException{
+			Message_: aws.String(fmt.Sprintf("Function already exist: %s", name)),
+			Type:     aws.String("User"),
+		}
+	}
+
+	configuration := &lambda.FunctionConfiguration{
+		FunctionName: aws.String(name),
+		FunctionArn:  aws.String(arn("lambda", "function:"+name)),
+		Description:  input.Description,
+		Handler:      input.Handler,
+		MemorySize:   input.MemorySize,
+		Role:         input.Role,
+		Runtime:      input.Runtime,
+		Timeout:      input.Timeout,
+		LastModified: aws.String(time.Now().UTC().Format("2006-01-02T15:04:05.000+0000")),
+		State:        aws.String(lambda.StateActive),
+		Version:      aws.String("$LATEST"),
+	}
+	if input.Environment != nil {
+		configuration.Environment = &lambda.EnvironmentResponse{Variables: input.Environment.Variables}
+	}
+	if input.VpcConfig != nil {
+		configuration.VpcConfig = &lambda.VpcConfigResponse{
+			SubnetIds:        input.VpcConfig.SubnetIds,
+			SecurityGroupIds: input.VpcConfig.SecurityGroupIds,
+			VpcId:            aws.String("vpc-0a1b2c3d"),
+		}
+	}
+	if input.TracingConfig != nil {
+		configuration.TracingConfig = &lambda.TracingConfigResponse{Mode: input.TracingConfig.Mode}
+	}
+	function := &fakeFunction{
+		configuration: copyOf(configuration).(*lambda.FunctionConfiguration),
+		tags:          copyTags(input.Tags),
+	}
+	if input.Code != nil {
+		function.codeLocation = codeLocation(input.Code.S3Bucket, input.Code.S3Key)
+	}
+	l.functions[name] = function
+	return copyOf(function.configuration).(*lambda.FunctionConfiguration), nil
+}
+
+func (l *Lambda) DeleteFunction(input *lambda.DeleteFunctionInput) (*lambda.DeleteFunctionOutput, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	function, err := l.getFunction(input.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	delete(l.functions, aws.StringValue(function.configuration.FunctionName))
+	return &lambda.DeleteFunctionOutput{}, nil
+}
+
+func (l *Lambda) GetFunction(input *lambda.GetFunctionInput) (*lambda.GetFunctionOutput, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	function, err := l.getFunction(input.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	return &lambda.GetFunctionOutput{
+		Configuration: copyOf(function.configuration).(*lambda.FunctionConfiguration),
+		Code: &lambda.FunctionCodeLocation{
+			Location:       aws.String(function.codeLocation),
+			RepositoryType: aws.String("S3"),
+		},
+		Tags: copyTags(function.tags),
+	}, nil
+}
+
+func (l *Lambda) GetFunctionConfiguration(input *lambda.GetFunctionConfigurationInput) (*lambda.FunctionConfiguration, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	function, err := l.getFunction(input.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	return copyOf(function.configuration).(*lambda.FunctionConfiguration), nil
+}
+
+func (l *Lambda) UpdateFunctionCode(input *lambda.UpdateFunctionCodeInput) (*lambda.FunctionConfiguration, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	function, err := l.getFunction(input.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	function.codeLocation = codeLocation(input.S3Bucket, input.S3Key)
+	function.configuration.LastModified = aws.String(time.Now().UTC().Format("2006-01-02T15:04:05.000+0000"))
+	return copyOf(function.configuration).(*lambda.FunctionConfiguration), nil
+}
+
+func (l *Lambda) ListFunctionsPages(input *lambda.ListFunctionsInput, fn func(*lambda.ListFunctionsOutput, bool) bool) error {
+	l.lock.Lock()
+	var names []string
+	for name := range l.functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	output := &lambda.ListFunctionsOutput{}
+	for _, name := range names {
+		output.Functions = append(output.Functions, copyOf(l.functions[name].configuration).(*lambda.FunctionConfiguration))
+	}
+	l.lock.Unlock()
+
+	fn(output, true)
+	return nil
+}
+
+func (l *Lambda) TagResource(input *lambda.TagResourceInput) (*lambda.TagResourceOutput, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	function, err := l.getFunction(input.Resource)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range input.Tags {
+		function.tags[key] = aws.String(aws.StringValue(value))
+	}
+	return &lambda.TagResourceOutput{}, nil
+}
+
+func (l *Lambda) ListTags(input *lambda.ListTagsInput) (*lambda.ListTagsOutput, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	function, err := l.getFunction(input.Resource)
+	if err != nil {
+		return nil, err
+	}
+	return &lambda.ListTagsOutput{Tags: copyTags(function.tags)}, nil
+}
+
+func (l *Lambda) AddPermission(input *lambda.AddPermissionInput) (*lambda.AddPermissionOutput, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	function, err := l.getFunction(input.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	statement := map[string]interface{}{
+		"Sid":       aws.StringValue(input.StatementId),
+		"Effect":    "Allow",
+		"Principal": map[string]string{"Service": aws.StringValue(input.Principal)},
+		"Action":    aws.StringValue(input.Action),
+		"Resource":  aws.StringValue(function.configuration.FunctionArn),
+	}
+	if input.SourceArn != nil {
+		statement["Condition"] = map[string]interface{}{
+			"ArnLike": map[string]string{"AWS:SourceArn": aws.StringValue(input.SourceArn)},
+		}
+	}
+	function.statements = append(function.statements, statement)
+	b, err := json.Marshal(statement)
+	if err != nil {
+		return nil, err
+	}
+	return &lambda.AddPermissionOutput{Statement: aws.String(string(b))}, nil
+}
+
+// GetPolicy returns the function resource policy, which only exists once a permission was added
+func (l *Lambda) GetPolicy(input *lambda.GetPolicyInput) (*lambda.GetPolicyOutput, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	function, err := l.getFunction(input.FunctionName)
+	if err != nil {
+		return nil, err
+	}
+	if len(function.statements) == 0 {
+		return nil, &lambda.ResourceNotFoundException{
+			Message_: aws.String("The resource you requested does not exist."),
+			Type:     aws.String("User"),
+		}
+	}
+	b, err := json.Marshal(map[string]interface{}{
+		"Version":   "2012-10-17",
+		"Id":        "default",
+		"Statement": function.statements,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &lambda.GetPolicyOutput{Policy: aws.String(string(b)), RevisionId: aws.String(l.aws.newId(""))}, nil
+}
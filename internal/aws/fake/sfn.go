@@ -0,0 +1,173 @@
+package fake
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/aws/aws-sdk-go/service/sfn/sfniface"
+	"sort"
+	"sync"
+	"time"
+)
+
+type SFN struct {
+	sfniface.SFNAPI
+	aws           *AWS
+	lock          sync.Mutex
+	stateMachines map[string]*fakeStateMachine
+}
+
+type fakeStateMachine struct {
+	stateMachine *sfn.DescribeStateMachineOutput
+	tags         []*sfn.Tag
+	executions   []*sfn.ExecutionListItem
+}
+
+func newSFN(a *AWS) *SFN {
+	return &SFN{aws: a, stateMachines: map[string]*fakeStateMachine{}}
+}
+
+func (s *SFN) getStateMachine(stateMachineArn *string) (*fakeStateMachine, error) {
+	stateMachine, ok := s.stateMachines[aws.StringValue(stateMachineArn)]
+	if !ok {
+		return nil, &sfn.StateMachineDoesNotExist{Message_: aws.String(fmt.Sprintf("State Machine Does Not Exist: '%s'", aws.StringValue(stateMachineArn)))}
+	}
+	return stateMachine, nil
+}
+
+func mergeSfnTags(tags, newTags []*sfn.Tag) []*sfn.Tag {
+	for _, newTag := range newTags {
+		replaced := false
+		for _, tag := range tags {
+			if aws.StringValue(tag.Key) == aws.StringValue(newTag.Key) {
+				tag.Value = aws.String(aws.StringValue(newTag.Value))
+				replaced = true
+			}
+		}
+		if !replaced {
+			tags = append(tags, copyOf(newTag).(*sfn.Tag))
+		}
+	}
+	return tags
+}
+
+func (s *SFN) CreateStateMachine(input *sfn.CreateStateMachineInput) (*sfn.CreateStateMachineOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stateMachineArn := arn("states", "stateMachine:"+aws.StringValue(input.Name))
+	if _, ok := s.stateMachines[stateMachineArn]; ok {
+		return nil, &sfn.StateMachineAlreadyExists{Message_: aws.String(fmt.Sprintf("State Machine Already Exists: '%s'", stateMachineArn))}
+	}
+	creationDate := time.Now().UTC()
+	s.stateMachines[stateMachineArn] = &fakeStateMachine{
+		stateMachine: &sfn.DescribeStateMachineOutput{
+			StateMachineArn: aws.String(stateMachineArn),
+			Name:            aws.String(aws.StringValue(input.Name)),
+			Definition:      aws.String(aws.StringValue(input.Definition)),
+			RoleArn:         aws.String(aws.StringValue(input.RoleArn)),
+			Status:          aws.String(sfn.StateMachineStatusActive),
+			Type:            aws.String(sfn.StateMachineTypeStandard),
+			CreationDate:    aws.Time(creationDate),
+		},
+		tags: mergeSfnTags(nil, input.Tags),
+	}
+	return &sfn.CreateStateMachineOutput{StateMachineArn: aws.String(stateMachineArn), CreationDate: aws.Time(creationDate)}, nil
+}
+
+func (s *SFN) UpdateStateMachine(input *sfn.UpdateStateMachineInput) (*sfn.UpdateStateMachineOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stateMachine, err := s.getStateMachine(input.StateMachineArn)
+	if err != nil {
+		return nil, err
+	}
+	if input.Definition != nil {
+		stateMachine.stateMachine.Definition = aws.String(*input.Definition)
+	}
+	if input.RoleArn != nil {
+		stateMachine.stateMachine.RoleArn = aws.String(*input.RoleArn)
+	}
+	return &sfn.UpdateStateMachineOutput{UpdateDate: aws.Time(time.Now().UTC())}, nil
+}
+
+func (s *SFN) DeleteStateMachine(input *sfn.DeleteStateMachineInput) (*sfn.DeleteStateMachineOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, err := s.getStateMachine(input.StateMachineArn); err != nil {
+		return nil, err
+	}
+	delete(s.stateMachines, aws.StringValue(input.StateMachineArn))
+	return &sfn.DeleteStateMachineOutput{}, nil
+}
+
+func (s *SFN) DescribeStateMachine(input *sfn.DescribeStateMachineInput) (*sfn.DescribeStateMachineOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stateMachine, err := s.getStateMachine(input.StateMachineArn)
+	if err != nil {
+		return nil, err
+	}
+	return copyOf(stateMachine.stateMachine).(*sfn.DescribeStateMachineOutput), nil
+}
+
+func (s *SFN) ListStateMachinesPages(input *sfn.ListStateMachinesInput, fn func(*sfn.ListStateMachinesOutput, bool) bool) error {
+	s.lock.Lock()
+	var arns []string
+	for stateMachineArn := range s.stateMachines {
+		arns = append(arns, stateMachineArn)
+	}
+	sort.Strings(arns)
+	output := &sfn.ListStateMachinesOutput{}
+	for _, stateMachineArn := range arns {
+		stateMachine := s.stateMachines[stateMachineArn].stateMachine
+		output.StateMachines = append(output.StateMachines, &sfn.StateMachineListItem{
+			StateMachineArn: aws.String(stateMachineArn),
+			Name:            aws.String(aws.StringValue(stateMachine.Name)),
+			Type:            aws.String(aws.StringValue(stateMachine.Type)),
+			CreationDate:    aws.Time(aws.TimeValue(stateMachine.CreationDate)),
+		})
+	}
+	s.lock.Unlock()
+
+	fn(output, true)
+	return nil
+}
+
+func (s *SFN) ListTagsForResource(input *sfn.ListTagsForResourceInput) (*sfn.ListTagsForResourceOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stateMachine, ok := s.stateMachines[aws.StringValue(input.ResourceArn)]
+	if !ok {
+		return nil, &sfn.ResourceNotFound{Message_: aws.String(fmt.Sprintf("Resource not found: '%s'", aws.StringValue(input.ResourceArn)))}
+	}
+	return &sfn.ListTagsForResourceOutput{Tags: mergeSfnTags(nil, stateMachine.tags)}, nil
+}
+
+func (s *SFN) TagResource(input *sfn.TagResourceInput) (*sfn.TagResourceOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stateMachine, ok := s.stateMachines[aws.StringValue(input.ResourceArn)]
+	if !ok {
+		return nil, &sfn.ResourceNotFound{Message_: aws.String(fmt.Sprintf("Resource not found: '%s'", aws.StringValue(input.ResourceArn)))}
+	}
+	stateMachine.tags = mergeSfnTags(stateMachine.tags, input.Tags)
+	return &sfn.TagResourceOutput{}, nil
+}
+
+// ListExecutions returns the state machine executions, most recent first
+func (s *SFN) ListExecutions(input *sfn.ListExecutionsInput) (*sfn.ListExecutionsOutput, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stateMachine, err := s.getStateMachine(input.StateMachineArn)
+	if err != nil {
+		return nil, err
+	}
+	output := &sfn.ListExecutionsOutput{}
+	for i := len(stateMachine.executions) - 1; i >= 0; i-- {
+		if input.MaxResults != nil && int64(len(output.Executions)) == *input.MaxResults {
+			break
+		}
+		output.Executions = append(output.Executions, copyOf(stateMachine.executions[i]).(*sfn.ExecutionListItem))
+	}
+	return output, nil
+}
@@ -0,0 +1,19 @@
+package fake
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+)
+
+type STS struct {
+	stsiface.STSAPI
+}
+
+func (s *STS) GetCallerIdentity(input *sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String(AccountId),
+		Arn:     aws.String("arn:aws:iam::" + AccountId + ":user/wekactl"),
+		UserId:  aws.String("AIDA0A1B2C3D4E5F6G7H8"),
+	}, nil
+}
@@ -179,6 +179,78 @@ func GetScaleLambdaPolicy() PolicyDocument {
 	return policyDocument
 }
 
+func GetLifecycleLambdaPolicy() PolicyDocument {
+	policyDocument := PolicyDocument{
+		Version: "2012-10-17",
+		Statement: []StatementEntry{
+			{
+				Effect: "Allow",
+				Action: []string{
+					"logs:CreateLogStream",
+					"logs:PutLogEvents",
+					"logs:CreateLogGroup",
+					"ec2:CreateNetworkInterface",
+					"ec2:DescribeNetworkInterfaces",
+					"ec2:DeleteNetworkInterface",
+					"autoscaling:CompleteLifecycleAction",
+					"autoscaling:RecordLifecycleActionHeartbeat",
+				},
+				Resource: "*",
+			},
+		},
+	}
+	return policyDocument
+}
+
+func GetInterruptionLambdaPolicy() PolicyDocument {
+	policyDocument := PolicyDocument{
+		Version: "2012-10-17",
+		Statement: []StatementEntry{
+			{
+				Effect: "Allow",
+				Action: []string{
+					"logs:CreateLogStream",
+					"logs:PutLogEvents",
+					"logs:CreateLogGroup",
+					"ec2:CreateNetworkInterface",
+					"ec2:DescribeNetworkInterfaces",
+					"ec2:DeleteNetworkInterface",
+					"dynamodb:GetItem",
+					"autoscaling:Describe*",
+					"autoscaling:TerminateInstanceInAutoScalingGroup",
+					"ec2:Describe*",
+					"kms:Decrypt",
+				},
+				Resource: "*",
+			},
+		},
+	}
+	return policyDocument
+}
+
+func GetMetricsLambdaPolicy() PolicyDocument {
+	policyDocument := PolicyDocument{
+		Version: "2012-10-17",
+		Statement: []StatementEntry{
+			{
+				Effect: "Allow",
+				Action: []string{
+					"logs:CreateLogStream",
+					"logs:PutLogEvents",
+					"logs:CreateLogGroup",
+					"ec2:CreateNetworkInterface",
+					"ec2:DescribeNetworkInterfaces",
+					"ec2:DeleteNetworkInterface",
+					"autoscaling:DescribePolicies",
+					"cloudwatch:PutMetricData",
+				},
+				Resource: "*",
+			},
+		},
+	}
+	return policyDocument
+}
+
 func GetTerminateLambdaPolicy() PolicyDocument {
 	policyDocument := PolicyDocument{
 		Version: "2012-10-17",
@@ -192,12 +264,11 @@ func GetTerminateLambdaPolicy() PolicyDocument {
 					"ec2:CreateNetworkInterface",
 					"ec2:DescribeNetworkInterfaces",
 					"ec2:DeleteNetworkInterface",
-					"ec2:ModifyInstanceAttribute",
-					"ec2:TerminateInstances",
-					"autoscaling:DetachInstances",
+					"autoscaling:TerminateInstanceInAutoScalingGroup",
 					"autoscaling:Describe*",
 					"autoscaling:SetInstanceProtection",
 					"ec2:Describe*",
+					"dynamodb:PutItem",
 				},
 				Resource: "*",
 			},
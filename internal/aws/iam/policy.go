@@ -3,9 +3,11 @@ package iam
 import (
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/rs/zerolog/log"
 	"wekactl/internal/connectors"
+	"wekactl/internal/lib/strings"
 )
 
 func createIamPolicy(policyName string, policy PolicyDocument) (*iam.Policy, error) {
@@ -31,3 +33,28 @@ func deleteIamPolicy(policyArn *string) error {
 
 	return err
 }
+
+// GetLeftoverPolicies returns the policies with one of the given names which aren't attached to any role, the sdk
+// can't tag policies so they are matched by their exact names
+func GetLeftoverPolicies(policyNames []string) (policies []*iam.Policy, err error) {
+	svc := connectors.GetAWSSession().IAM
+	err = svc.ListPoliciesPages(&iam.ListPoliciesInput{Scope: aws.String(iam.PolicyScopeTypeLocal)}, func(page *iam.ListPoliciesOutput, lastPage bool) bool {
+		for _, policy := range page.Policies {
+			if strings.AnyOf(*policy.PolicyName, policyNames...) && *policy.AttachmentCount == 0 {
+				policies = append(policies, policy)
+			}
+		}
+		return true
+	})
+	return
+}
+
+func DeletePolicy(policyArn string) error {
+	err := deleteIamPolicy(&policyArn)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+			return nil
+		}
+	}
+	return err
+}
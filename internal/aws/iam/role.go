@@ -2,8 +2,11 @@ package iam
 
 import (
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/rs/zerolog/log"
+	"net/url"
+	"strconv"
 	"strings"
 	"wekactl/internal/cluster"
 	"wekactl/internal/connectors"
@@ -202,3 +205,131 @@ func UpdateRolePolicy(roleBaseName, policyName string, policy PolicyDocument, ve
 	})
 	return err
 }
+
+func getPolicyDocument(policyArn *string) (document string, err error) {
+	svc := connectors.GetAWSSession().IAM
+	policyOutput, err := svc.GetPolicy(&iam.GetPolicyInput{PolicyArn: policyArn})
+	if err != nil {
+		return
+	}
+	versionOutput, err := svc.GetPolicyVersion(&iam.GetPolicyVersionInput{
+		PolicyArn: policyArn,
+		VersionId: policyOutput.Policy.DefaultVersionId,
+	})
+	if err != nil {
+		return
+	}
+	return url.QueryUnescape(aws.StringValue(versionOutput.PolicyVersion.Document))
+}
+
+// DiffIamRole compares the role trust policy and attached policy documents with the ones CreateIamRole would use
+func DiffIamRole(roleBaseName string, assumeRolePolicy AssumeRolePolicyDocument, policy PolicyDocument) (drifts cluster.Drifts, err error) {
+	role, err := getIamRole(roleBaseName, nil)
+	if err != nil || role == nil {
+		return
+	}
+
+	actualAssumeRolePolicy, err := url.QueryUnescape(aws.StringValue(role.AssumeRolePolicyDocument))
+	if err != nil {
+		return
+	}
+	drifts.CompareJson("assume_role_policy", assumeRolePolicy.String(), actualAssumeRolePolicy)
+
+	svc := connectors.GetAWSSession().IAM
+	result, err := svc.ListAttachedRolePolicies(&iam.ListAttachedRolePoliciesInput{
+		RoleName: role.RoleName,
+	})
+	if err != nil {
+		return
+	}
+
+	expectedPolicy := ""
+	if policy.Version != "" {
+		expectedPolicy = policy.String()
+	}
+	actualPolicy := ""
+	if len(result.AttachedPolicies) > 1 {
+		drifts.Compare("attached_policies", "1", strconv.Itoa(len(result.AttachedPolicies)))
+	}
+	if len(result.AttachedPolicies) > 0 {
+		actualPolicy, err = getPolicyDocument(result.AttachedPolicies[0].PolicyArn)
+		if err != nil {
+			return
+		}
+	}
+	drifts.CompareJson("policy", expectedPolicy, actualPolicy)
+	return
+}
+
+func GetRoleNameFromArn(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
+// GetClusterRoles returns the names of all the roles tagged with the cluster name
+func GetClusterRoles(clusterName cluster.ClusterName) (roleNames []string, err error) {
+	svc := connectors.GetAWSSession().IAM
+	var tagsErr error
+	err = svc.ListRolesPages(&iam.ListRolesInput{}, func(page *iam.ListRolesOutput, lastPage bool) bool {
+		for _, role := range page.Roles {
+			if !strings.HasPrefix(*role.RoleName, "wekactl-") {
+				continue
+			}
+			var tagsOutput *iam.ListRoleTagsOutput
+			tagsOutput, tagsErr = svc.ListRoleTags(&iam.ListRoleTagsInput{RoleName: role.RoleName})
+			if tagsErr != nil {
+				return false
+			}
+			for _, tag := range tagsOutput.Tags {
+				if *tag.Key == cluster.ClusterNameTagKey && *tag.Value == string(clusterName) {
+					roleNames = append(roleNames, *role.RoleName)
+					break
+				}
+			}
+		}
+		return true
+	})
+	if err == nil {
+		err = tagsErr
+	}
+	return
+}
+
+// DeleteIamRoleByName deletes the role along with its attached and inline policies
+func DeleteIamRoleByName(roleName string) error {
+	svc := connectors.GetAWSSession().IAM
+	attachedOutput, err := svc.ListAttachedRolePolicies(&iam.ListAttachedRolePoliciesInput{RoleName: &roleName})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == iam.ErrCodeNoSuchEntityException {
+			return nil
+		}
+		return err
+	}
+	for _, policy := range attachedOutput.AttachedPolicies {
+		_, err = svc.DetachRolePolicy(&iam.DetachRolePolicyInput{RoleName: &roleName, PolicyArn: policy.PolicyArn})
+		if err != nil {
+			return err
+		}
+		err = deleteIamPolicy(policy.PolicyArn)
+		if err != nil {
+			log.Debug().Msgf("policy %s wasn't deleted: %v", *policy.PolicyName, err)
+		}
+	}
+
+	inlineOutput, err := svc.ListRolePolicies(&iam.ListRolePoliciesInput{RoleName: &roleName})
+	if err != nil {
+		return err
+	}
+	for _, policyName := range inlineOutput.PolicyNames {
+		_, err = svc.DeleteRolePolicy(&iam.DeleteRolePolicyInput{RoleName: &roleName, PolicyName: policyName})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = svc.DeleteRole(&iam.DeleteRoleInput{RoleName: &roleName})
+	if err != nil {
+		return err
+	}
+	log.Debug().Msgf("role %s was deleted successfully", roleName)
+	return nil
+}
@@ -133,3 +133,63 @@ func GetKMSKeyId(clusterName cluster.ClusterName) (arn string, err error) {
 	arn = *kmsKey.KeyId
 	return
 }
+
+// GetClusterKmsKeys returns the ids of all the keys tagged with the cluster name, which aren't pending deletion
+func GetClusterKmsKeys(clusterName cluster.ClusterName) (keyIds []string, err error) {
+	svc := connectors.GetAWSSession().KMS
+	var keys []*kms.KeyListEntry
+	err = svc.ListKeysPages(&kms.ListKeysInput{}, func(page *kms.ListKeysOutput, lastPage bool) bool {
+		keys = append(keys, page.Keys...)
+		return true
+	})
+	if err != nil {
+		return
+	}
+
+	for _, key := range keys {
+		keyInfo, err := svc.DescribeKey(&kms.DescribeKeyInput{KeyId: key.KeyId})
+		if err != nil {
+			return nil, err
+		}
+		if *keyInfo.KeyMetadata.KeyState == kms.KeyStatePendingDeletion || *keyInfo.KeyMetadata.KeyManager == kms.KeyManagerTypeAws {
+			continue
+		}
+		tags, err := svc.ListResourceTags(&kms.ListResourceTagsInput{KeyId: key.KeyId})
+		if err != nil {
+			return nil, err
+		}
+		for _, tag := range tags.Tags {
+			if *tag.TagKey == cluster.ClusterNameTagKey && *tag.TagValue == string(clusterName) {
+				keyIds = append(keyIds, *key.KeyId)
+				break
+			}
+		}
+	}
+	return
+}
+
+// DeleteKMSKeyId deletes the key aliases and schedules the key deletion
+func DeleteKMSKeyId(keyId string) error {
+	svc := connectors.GetAWSSession().KMS
+	aliasesOutput, err := svc.ListAliases(&kms.ListAliasesInput{KeyId: &keyId})
+	if err != nil {
+		return err
+	}
+	for _, alias := range aliasesOutput.Aliases {
+		_, err = svc.DeleteAlias(&kms.DeleteAliasInput{AliasName: alias.AliasName})
+		if err != nil {
+			return err
+		}
+		log.Debug().Msgf("kms alias %s was deleted successfully", *alias.AliasName)
+	}
+
+	_, err = svc.ScheduleKeyDeletion(&kms.ScheduleKeyDeletionInput{
+		KeyId:               &keyId,
+		PendingWindowInDays: aws.Int64(7),
+	})
+	if err != nil {
+		return err
+	}
+	log.Debug().Msgf("kms key %s was deleted successfully", keyId)
+	return nil
+}
@@ -0,0 +1,194 @@
+package lambdas
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"regexp"
+	"strconv"
+	"wekactl/internal/aws/db"
+	"wekactl/internal/cluster"
+	"wekactl/internal/connectors"
+)
+
+// minDedicatedCores is the fewest physical cores a backend needs for dedicated frontend and drive cores, smaller
+// backends run a single core
+const minDedicatedCores = 4
+
+// builtinBackendCoreCounts are the cores weka ran on backends before they were computed from the instance type info,
+// they take precedence over the computed cores so these instance types keep joining the same way
+var builtinBackendCoreCounts = map[string]cluster.CoreCounts{
+	"r3.large":      {Total: 1, Frontend: 0, Drive: 0},
+	"r3.xlarge":     {Total: 1, Frontend: 0, Drive: 0},
+	"r3.2xlarge":    {Total: 3, Frontend: 1, Drive: 1},
+	"r3.4xlarge":    {Total: 7, Frontend: 1, Drive: 1},
+	"r3.8xlarge":    {Total: 7, Frontend: 1, Drive: 2},
+	"i3.large":      {Total: 1, Frontend: 0, Drive: 0},
+	"i3.xlarge":     {Total: 1, Frontend: 0, Drive: 0},
+	"i3.2xlarge":    {Total: 3, Frontend: 1, Drive: 1},
+	"i3.4xlarge":    {Total: 7, Frontend: 1, Drive: 1},
+	"i3.8xlarge":    {Total: 7, Frontend: 1, Drive: 2},
+	"i3.16xlarge":   {Total: 14, Frontend: 1, Drive: 4},
+	"i3en.large":    {Total: 1, Frontend: 0, Drive: 0},
+	"i3en.xlarge":   {Total: 1, Frontend: 0, Drive: 0},
+	"i3en.2xlarge":  {Total: 3, Frontend: 1, Drive: 1},
+	"i3en.3xlarge":  {Total: 3, Frontend: 1, Drive: 1},
+	"i3en.6xlarge":  {Total: 7, Frontend: 1, Drive: 2},
+	"i3en.12xlarge": {Total: 7, Frontend: 1, Drive: 2},
+	"i3en.24xlarge": {Total: 14, Frontend: 1, Drive: 4},
+}
+
+// networkGigabitsPattern matches network performance like "10 Gigabit", "Up to 25 Gigabit" and "4x 100 Gigabit"
+var networkGigabitsPattern = regexp.MustCompile(`^(?:Up to )?(?:(\d+)x )?(\d+(?:\.\d+)?) Gigabit$`)
+
+// namedNetworkPerformance are the network performance levels of older instance types, all of them up to 10 Gigabit
+var namedNetworkPerformance = map[string]bool{
+	"Very Low":        true,
+	"Low":             true,
+	"Low to Moderate": true,
+	"Moderate":        true,
+	"High":            true,
+}
+
+// networkGigabits returns the bandwidth of the instance type network performance, an "Up to" bandwidth counts as
+// the full bandwidth
+func networkGigabits(performance string) (float64, error) {
+	if namedNetworkPerformance[performance] {
+		return 10, nil
+	}
+	match := networkGigabitsPattern.FindStringSubmatch(performance)
+	if match == nil {
+		return 0, errors.New(fmt.Sprintf("unknown network performance %q", performance))
+	}
+	gigabits, err := strconv.ParseFloat(match[2], 64)
+	if err != nil {
+		return 0, err
+	}
+	if match[1] != "" {
+		cards, err := strconv.Atoi(match[1])
+		if err != nil {
+			return 0, err
+		}
+		gigabits *= float64(cards)
+	}
+	return gigabits, nil
+}
+
+// networkMaxCores is how many cores the network bandwidth of a backend keeps busy
+func networkMaxCores(gigabits float64) int {
+	switch {
+	case gigabits <= 10:
+		return 7
+	case gigabits <= 25:
+		return 14
+	default:
+		return cluster.MaxBackendCores
+	}
+}
+
+// backendCoreCounts computes the cores weka runs on a backend of the instance type. Backends with less than
+// minDedicatedCores physical cores run a single core, which is also their frontend and drive core. Larger backends
+// leave a physical core to the os and run weka on the rest, up to what their network bandwidth keeps busy: 7 cores up
+// to 10 Gigabit, 14 up to 25 Gigabit and cluster.MaxBackendCores above. They have a single frontend core, and a drive
+// core for every two NVMe instance storage disks, leaving at least a single compute core. Instance types without NVMe
+// instance storage can't be backends
+func backendCoreCounts(info *ec2.InstanceTypeInfo) (counts cluster.CoreCounts, err error) {
+	instanceType := aws.StringValue(info.InstanceType)
+	storage := info.InstanceStorageInfo
+	if storage == nil || aws.StringValue(storage.NvmeSupport) == ec2.EphemeralNvmeSupportUnsupported {
+		err = errors.New(fmt.Sprintf("instance type %s has no NVMe instance storage for weka drives", instanceType))
+		return
+	}
+	var disks int64
+	for _, disk := range storage.Disks {
+		disks += aws.Int64Value(disk.Count)
+	}
+	if disks == 0 {
+		err = errors.New(fmt.Sprintf("instance type %s has no NVMe instance storage for weka drives", instanceType))
+		return
+	}
+
+	if info.VCpuInfo == nil {
+		err = errors.New(fmt.Sprintf("instance type %s has no vCPU info", instanceType))
+		return
+	}
+	cores := aws.Int64Value(info.VCpuInfo.DefaultCores)
+	if cores == 0 && aws.Int64Value(info.VCpuInfo.DefaultThreadsPerCore) != 0 {
+		cores = aws.Int64Value(info.VCpuInfo.DefaultVCpus) / aws.Int64Value(info.VCpuInfo.DefaultThreadsPerCore)
+	}
+	if cores == 0 {
+		cores = aws.Int64Value(info.VCpuInfo.DefaultVCpus)
+	}
+	if cores == 0 {
+		err = errors.New(fmt.Sprintf("instance type %s has no cores", instanceType))
+		return
+	}
+	if cores < minDedicatedCores {
+		return cluster.CoreCounts{Total: 1}, nil
+	}
+
+	if info.NetworkInfo == nil {
+		err = errors.New(fmt.Sprintf("instance type %s has no network info", instanceType))
+		return
+	}
+	gigabits, err := networkGigabits(aws.StringValue(info.NetworkInfo.NetworkPerformance))
+	if err != nil {
+		err = errors.New(fmt.Sprintf("instance type %s: %s", instanceType, err))
+		return
+	}
+
+	counts.Total = int(cores) - 1
+	if maxCores := networkMaxCores(gigabits); counts.Total > maxCores {
+		counts.Total = maxCores
+	}
+	counts.Frontend = 1
+	counts.Drive = int(disks+1) / 2
+	if maxDrive := counts.Total - counts.Frontend - 1; counts.Drive > maxDrive {
+		counts.Drive = maxDrive
+	}
+	return counts, counts.Validate()
+}
+
+// GetBackendCoreCounts returns the core counts overridden for the instance type in the cluster table, the built-in
+// ones, or the ones computed from its EC2 instance type info
+func GetBackendCoreCounts(tableName, instanceType string) (counts cluster.CoreCounts, err error) {
+	if instanceType == "" {
+		err = errors.New("backend instance type is unknown")
+		return
+	}
+	counts, found, err := db.GetCoreCounts(tableName, instanceType)
+	if err != nil {
+		return
+	}
+	if found {
+		return
+	}
+	if counts, found = builtinBackendCoreCounts[instanceType]; found {
+		return
+	}
+
+	info, err := GetInstanceTypeInfo(instanceType)
+	if err != nil {
+		return
+	}
+	counts, err = backendCoreCounts(info)
+	if err != nil {
+		err = errors.New(fmt.Sprintf("can't determine the weka cores of %s, set them with cluster set-cores: %s", instanceType, err))
+	}
+	return
+}
+
+func GetInstanceTypeInfo(instanceType string) (*ec2.InstanceTypeInfo, error) {
+	svc := connectors.GetAWSSession().EC2
+	output, err := svc.DescribeInstanceTypes(&ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []*string{aws.String(instanceType)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(output.InstanceTypes) == 0 {
+		return nil, errors.New(fmt.Sprintf("instance type %s not found", instanceType))
+	}
+	return output.InstanceTypes[0], nil
+}
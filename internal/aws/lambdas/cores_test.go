@@ -0,0 +1,61 @@
+package lambdas
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"testing"
+	"wekactl/internal/cluster"
+)
+
+func instanceTypeInfo(vCpus, cores, disks int64, networkPerformance string) *ec2.InstanceTypeInfo {
+	info := &ec2.InstanceTypeInfo{
+		InstanceType: aws.String("test.xlarge"),
+		VCpuInfo: &ec2.VCpuInfo{
+			DefaultVCpus:          aws.Int64(vCpus),
+			DefaultCores:          aws.Int64(cores),
+			DefaultThreadsPerCore: aws.Int64(vCpus / cores),
+		},
+		NetworkInfo: &ec2.NetworkInfo{NetworkPerformance: aws.String(networkPerformance)},
+	}
+	if disks != 0 {
+		info.InstanceStorageInfo = &ec2.InstanceStorageInfo{
+			Disks:       []*ec2.DiskInfo{{Count: aws.Int64(disks)}},
+			NvmeSupport: aws.String(ec2.EphemeralNvmeSupportRequired),
+		}
+	}
+	return info
+}
+
+func TestBackendCoreCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    *ec2.InstanceTypeInfo
+		want    cluster.CoreCounts
+		wantErr bool
+	}{
+		{"i3.large", instanceTypeInfo(2, 1, 1, "Up to 10 Gigabit"), cluster.CoreCounts{Total: 1}, false},
+		{"i3.xlarge", instanceTypeInfo(4, 2, 1, "Up to 10 Gigabit"), cluster.CoreCounts{Total: 1}, false},
+		{"i3.2xlarge", instanceTypeInfo(8, 4, 1, "Up to 10 Gigabit"), cluster.CoreCounts{Total: 3, Frontend: 1, Drive: 1}, false},
+		{"i3.4xlarge", instanceTypeInfo(16, 8, 2, "Up to 10 Gigabit"), cluster.CoreCounts{Total: 7, Frontend: 1, Drive: 1}, false},
+		{"i3.8xlarge", instanceTypeInfo(32, 16, 4, "10 Gigabit"), cluster.CoreCounts{Total: 7, Frontend: 1, Drive: 2}, false},
+		{"i3.16xlarge", instanceTypeInfo(64, 32, 8, "25 Gigabit"), cluster.CoreCounts{Total: 14, Frontend: 1, Drive: 4}, false},
+		{"i3en.2xlarge", instanceTypeInfo(8, 4, 2, "Up to 25 Gigabit"), cluster.CoreCounts{Total: 3, Frontend: 1, Drive: 1}, false},
+		{"100Gigabit", instanceTypeInfo(96, 48, 8, "100 Gigabit"), cluster.CoreCounts{Total: 19, Frontend: 1, Drive: 4}, false},
+		{"namedNetwork", instanceTypeInfo(32, 16, 2, "High"), cluster.CoreCounts{Total: 7, Frontend: 1, Drive: 1}, false},
+		{"networkCards", instanceTypeInfo(96, 48, 8, "4x 100 Gigabit"), cluster.CoreCounts{Total: 19, Frontend: 1, Drive: 4}, false},
+		{"compute", instanceTypeInfo(8, 4, 8, "Up to 25 Gigabit"), cluster.CoreCounts{Total: 3, Frontend: 1, Drive: 1}, false},
+		{"noInstanceStorage", instanceTypeInfo(16, 8, 0, "Up to 10 Gigabit"), cluster.CoreCounts{}, true},
+		{"unknownNetwork", instanceTypeInfo(16, 8, 2, "Fast"), cluster.CoreCounts{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counts, err := backendCoreCounts(tt.info)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("backendCoreCounts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && counts != tt.want {
+				t.Errorf("backendCoreCounts() = %+v, want %+v", counts, tt.want)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"wekactl/internal/aws/common"
+	"wekactl/internal/aws/db"
 	"wekactl/internal/aws/lambdas/protocol"
 	"wekactl/internal/connectors"
 )
@@ -16,7 +17,8 @@ func GetFetchDataParams(clusterName, asgName, tableName, role string) (fd protoc
 		return
 	}
 
-	instanceIds := common.UnpackASGInstanceIds(asgOutput.AutoScalingGroups[0].Instances)
+	asgInstances := asgOutput.AutoScalingGroups[0].Instances
+	instanceIds := common.UnpackASGInstanceIds(asgInstances)
 	instances, err := common.GetInstances(instanceIds)
 	if err != nil {
 		return
@@ -32,22 +34,40 @@ func GetFetchDataParams(clusterName, asgName, tableName, role string) (fd protoc
 		return
 	}
 
+	policy, err := db.GetScalingPolicy(tableName, asgName)
+	if err != nil {
+		return
+	}
+
+	pause, err := db.GetScalingPause(tableName)
+	if err != nil {
+		return
+	}
+
 	return protocol.HostGroupInfoResponse{
 		Username:        creds.Username,
 		Password:        creds.Password,
 		DesiredCapacity: getAutoScalingGroupDesiredCapacity(asgOutput),
-		Instances:       getHostGroupInfoInstances(instances),
+		Instances:       getHostGroupInfoInstances(instances, asgInstances),
 		BackendIps:      backendIps,
 		Role:            role,
+		Policy:          policy,
+		Pause:           pause,
 	}, nil
 }
 
-func getHostGroupInfoInstances(instances []*ec2.Instance) (ret []protocol.HgInstance) {
+func getHostGroupInfoInstances(instances []*ec2.Instance, asgInstances []*autoscaling.Instance) (ret []protocol.HgInstance) {
+	terminating := map[string]bool{}
+	for _, i := range asgInstances {
+		terminating[*i.InstanceId] = *i.LifecycleState == autoscaling.LifecycleStateTerminatingWait
+	}
 	for _, i := range instances {
 		if i.InstanceId != nil && i.PrivateIpAddress != nil {
 			ret = append(ret, protocol.HgInstance{
-				Id:        *i.InstanceId,
-				PrivateIp: *i.PrivateIpAddress,
+				Id:          *i.InstanceId,
+				PrivateIp:   *i.PrivateIpAddress,
+				Protected:   common.IsInstanceProtected(i),
+				Terminating: terminating[*i.InstanceId],
 			})
 		}
 	}
@@ -0,0 +1,145 @@
+package interruption
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/rs/zerolog/log"
+	"os"
+	"time"
+	"wekactl/internal/aws/autoscaling"
+	"wekactl/internal/aws/common"
+	"wekactl/internal/aws/lambdas"
+	"wekactl/internal/aws/lambdas/protocol"
+	"wekactl/internal/aws/lambdas/scale"
+	"wekactl/internal/lib/types"
+	"wekactl/internal/lib/weka"
+)
+
+// pollInterval is how often the host state is checked while it deactivates
+const pollInterval = 2 * time.Second
+
+// spotInterruptionDetail is the detail of an "EC2 Spot Instance Interruption Warning" event
+type spotInterruptionDetail struct {
+	InstanceId     string `json:"instance-id"`
+	InstanceAction string `json:"instance-action"`
+}
+
+// Handler evicts the weka host of an interrupted spot instance of the host group during the two minute notice, and
+// terminates the instance so the auto scaling group replaces it right away. A host that doesn't become inactive
+// before the lambda times out is removed by the lifecycle lambda, the termination lifecycle hook holds the instance
+// until then
+func Handler(ctx context.Context, event events.CloudWatchEvent) error {
+	clusterName, asgName, tableName, role := os.Getenv("CLUSTER_NAME"), os.Getenv("ASG_NAME"), os.Getenv("TABLE_NAME"), os.Getenv("ROLE")
+	if asgName == "" {
+		return errors.New("ASG_NAME env var is mandatory")
+	}
+
+	var detail spotInterruptionDetail
+	err := json.Unmarshal(event.Detail, &detail)
+	if err != nil {
+		return err
+	}
+	// every client host group has its own rule, each of them handles the instances of its own group
+	asgInstanceIds, err := common.GetAutoScalingGroupInstanceIds(asgName)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, instanceId := range asgInstanceIds {
+		if aws.StringValue(instanceId) == detail.InstanceId {
+			found = true
+		}
+	}
+	if !found {
+		log.Debug().Msgf("instance %s is not in %s, ignoring its interruption", detail.InstanceId, asgName)
+		return nil
+	}
+	log.Info().Msgf("spot instance %s is interrupted, action: %s", detail.InstanceId, detail.InstanceAction)
+
+	info, err := lambdas.GetFetchDataParams(clusterName, asgName, tableName, role)
+	if err != nil {
+		return err
+	}
+	for _, instance := range info.Instances {
+		if instance.Id != detail.InstanceId {
+			continue
+		}
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			deadline = time.Now().Add(time.Minute)
+		}
+		wait := func() bool {
+			if time.Now().Add(2 * pollInterval).After(deadline) {
+				return false
+			}
+			time.Sleep(pollInterval)
+			return true
+		}
+		removed, err := Evict(scale.NewClusterApi(ctx, info), instance, wait)
+		if err != nil {
+			log.Error().Err(err).Msgf("evicting the host of spot instance %s failed", instance.Id)
+		} else if removed {
+			log.Info().Msgf("host of spot instance %s was removed", instance.Id)
+		}
+	}
+
+	_, errs := autoscaling.TerminateInstances(asgName, []string{detail.InstanceId})
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+func instanceHost(hosts weka.HostListResponse, instance protocol.HgInstance) (weka.HostId, weka.Host, bool) {
+	for hostId, host := range hosts {
+		if host.Aws.InstanceId == instance.Id || host.HostIp == instance.PrivateIp {
+			return hostId, host, true
+		}
+	}
+	return weka.HostId{}, weka.Host{}, false
+}
+
+// Evict deactivates the host of the instance and removes it once it is inactive, wait is called between checks of
+// the host state and returns false once there is no time left to wait for it
+func Evict(api scale.ClusterApi, instance protocol.HgInstance, wait func() bool) (removed bool, err error) {
+	for {
+		var hosts weka.HostListResponse
+		err = api.Call(weka.JrpcHostList, struct{}{}, &hosts)
+		if err != nil {
+			return
+		}
+		hostId, host, ok := instanceHost(hosts, instance)
+		if !ok {
+			return true, nil
+		}
+
+		switch host.State {
+		case "INACTIVE":
+			log.Info().Msgf("removing host %s of interrupted spot instance %s", hostId, instance.Id)
+			api.Drop(host.HostIp)
+			err = api.Call(weka.JrpcRemoveHost, types.JsonDict{
+				"host_id": hostId.Int(),
+				"no_wait": true,
+			}, nil)
+			return err == nil, err
+		case "ACTIVE":
+			log.Info().Msgf("deactivating host %s of interrupted spot instance %s", hostId, instance.Id)
+			api.Drop(host.HostIp)
+			err = api.Call(weka.JrpcDeactivateHosts, types.JsonDict{
+				"host_ids":                 []weka.HostId{hostId},
+				"skip_resource_validation": false,
+			}, nil)
+			if err != nil {
+				err = errors.New(fmt.Sprintf("deactivating host %s failed: %s", hostId, err))
+				return
+			}
+		}
+		if !wait() {
+			return
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package interruption
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"wekactl/internal/aws/lambdas/protocol"
+	"wekactl/internal/connectors"
+	"wekactl/internal/lib/jrpc"
+	wekafake "wekactl/internal/lib/weka/fake"
+)
+
+func TestEvictInterruptedHost(t *testing.T) {
+	wekaCluster := wekafake.NewCluster("admin", "password")
+	server, err := wekaCluster.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api := &jrpc.Pool{
+		Ips:     []string{server.Addr()},
+		Clients: map[string]*jrpc.BaseClient{},
+		Builder: func(ip string) *jrpc.BaseClient {
+			host, port, _ := net.SplitHostPort(ip)
+			portNumber, _ := strconv.Atoi(port)
+			return connectors.NewJrpcClient(ctx, host, portNumber, "admin", "password")
+		},
+		Ctx: ctx,
+	}
+	wekaCluster.AddHost("client", "i-client-1", "10.0.1.1", 0)
+	wekaCluster.AddHost("client", "i-client-2", "10.0.1.2", 0)
+	instance := protocol.HgInstance{Id: "i-client-1", PrivateIp: "10.0.1.1"}
+
+	// out of time before the host is inactive, the lifecycle lambda removes it later on
+	removed, err := Evict(api, instance, func() bool { return false })
+	if err != nil || removed {
+		t.Fatalf("Evict() = %t, %v, want the host deactivating", removed, err)
+	}
+	if state := wekaCluster.HostStates()["i-client-1"]; state != "DEACTIVATING" {
+		t.Fatalf("Evict() host state = %s, want DEACTIVATING", state)
+	}
+
+	waits := 0
+	removed, err = Evict(api, instance, func() bool {
+		waits++
+		wekaCluster.Advance(wekafake.DeactivateDuration)
+		return true
+	})
+	if err != nil || !removed {
+		t.Fatalf("Evict() = %t, %v, want the host removed", removed, err)
+	}
+	if waits != 1 {
+		t.Errorf("Evict() waited %d times, want 1", waits)
+	}
+	if states := wekaCluster.HostStates(); len(states) != 1 || states["i-client-2"] != "ACTIVE" {
+		t.Errorf("Evict() left hosts %v, want only the other client", states)
+	}
+}
@@ -11,44 +11,21 @@ import (
 	"wekactl/internal/connectors"
 )
 
-type BackendCoreCount struct {
-	total    int
-	frontend int
-	drive    int
-}
-
-type BackendCoreCounts map[string]BackendCoreCount
-
 func shuffleSlice(slice []string) {
 	rand.Seed(time.Now().UnixNano())
 	rand.Shuffle(len(slice), func(i, j int) { slice[i], slice[j] = slice[j], slice[i] })
 }
 
-func getBackendCoreCounts() BackendCoreCounts {
-	backendCoreCounts := BackendCoreCounts{
-		"r3.large":      BackendCoreCount{total: 1, frontend: 0, drive: 0},
-		"r3.xlarge":     BackendCoreCount{total: 1, frontend: 0, drive: 0},
-		"r3.2xlarge":    BackendCoreCount{total: 3, frontend: 1, drive: 1},
-		"r3.4xlarge":    BackendCoreCount{total: 7, frontend: 1, drive: 1},
-		"r3.8xlarge":    BackendCoreCount{total: 7, frontend: 1, drive: 2},
-		"i3.large":      BackendCoreCount{total: 1, frontend: 0, drive: 0},
-		"i3.xlarge":     BackendCoreCount{total: 1, frontend: 0, drive: 0},
-		"i3.2xlarge":    BackendCoreCount{total: 3, frontend: 1, drive: 1},
-		"i3.4xlarge":    BackendCoreCount{total: 7, frontend: 1, drive: 1},
-		"i3.8xlarge":    BackendCoreCount{total: 7, frontend: 1, drive: 2},
-		"i3.16xlarge":   BackendCoreCount{total: 14, frontend: 1, drive: 4},
-		"i3en.large":    BackendCoreCount{total: 1, frontend: 0, drive: 0},
-		"i3en.xlarge":   BackendCoreCount{total: 1, frontend: 0, drive: 0},
-		"i3en.2xlarge":  BackendCoreCount{total: 3, frontend: 1, drive: 1},
-		"i3en.3xlarge":  BackendCoreCount{total: 3, frontend: 1, drive: 1},
-		"i3en.6xlarge":  BackendCoreCount{total: 7, frontend: 1, drive: 2},
-		"i3en.12xlarge": BackendCoreCount{total: 7, frontend: 1, drive: 2},
-		"i3en.24xlarge": BackendCoreCount{total: 14, frontend: 1, drive: 4},
-	}
-	return backendCoreCounts
+// GetJoinErrorScript returns the script the join lambda answers with when it fails, the script fails as well so the
+// error shows in the instance cloud-init output and the instance shuts down
+func GetJoinErrorScript(err error) string {
+	message := strings.ReplaceAll(err.Error(), "'", `'\''`)
+	return fmt.Sprintf("echo 'joining the cluster failed: %s' >&2\nexit 1\n", message)
 }
 
-func GetJoinParams(clusterName, asgName, tableName, role string) (string, error) {
+// GetJoinParams returns the script that joins an instance of the given type to the cluster, an empty instance type
+// is taken from the auto scaling group instances, for instances launched before the join script passed it
+func GetJoinParams(clusterName, asgName, tableName, role, instanceType string) (string, error) {
 	svc := connectors.GetAWSSession().ASG
 	input := &autoscaling.DescribeAutoScalingGroupsInput{AutoScalingGroupNames: []*string{&asgName}}
 	asgOutput, err := svc.DescribeAutoScalingGroups(input)
@@ -60,7 +37,9 @@ func GetJoinParams(clusterName, asgName, tableName, role string) (string, error)
 	if err != nil {
 		return "", err
 	}
-	instanceType := common.GetInstanceTypeFromAutoScalingGroupOutput(asgOutput)
+	if instanceType == "" {
+		instanceType = common.GetInstanceTypeFromAutoScalingGroupOutput(asgOutput)
+	}
 	shuffleSlice(ips)
 	creds, err := getUsernameAndPassword(tableName)
 	if err != nil {
@@ -120,10 +99,13 @@ func GetJoinParams(clusterName, asgName, tableName, role string) (string, error)
 	`
 	var cores, frontend, drive int
 	if role == "backend" {
-		backendCoreCounts := getBackendCoreCounts()
-		cores = backendCoreCounts[instanceType].total
-		frontend = backendCoreCounts[instanceType].frontend
-		drive = backendCoreCounts[instanceType].drive
+		coreCounts, err := GetBackendCoreCounts(tableName, instanceType)
+		if err != nil {
+			return "", err
+		}
+		cores = coreCounts.Total
+		frontend = coreCounts.Frontend
+		drive = coreCounts.Drive
 		bashScriptTemplate += " --dedicate" + isReady + addDrives
 	} else {
 		bashScriptTemplate += isReady
@@ -6,12 +6,14 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/lambda"
 	"github.com/rs/zerolog/log"
+	"strconv"
 	"time"
 	"wekactl/internal/aws/common"
 	"wekactl/internal/aws/dist"
 	"wekactl/internal/cluster"
 	"wekactl/internal/connectors"
 	"wekactl/internal/env"
+	"wekactl/internal/lib/strings"
 	"wekactl/internal/logging"
 )
 
@@ -22,6 +24,24 @@ func GetLambdaVpcConfig(subnetId string, securityGroupIds []*string) lambda.VpcC
 	}
 }
 
+const (
+	lambdaHandler = "lambdas-bin"
+	runtime       = "go1.x"
+	memorySize    = 256
+	timeout       = 15
+)
+
+func generateEnvironmentVariables(lambdaType LambdaType, asgName, tableName string, hostGroupInfo common.HostGroupInfo) map[string]*string {
+	return map[string]*string{
+		"LAMBDA":       aws.String(string(lambdaType)),
+		"REGION":       aws.String(env.Config.Region),
+		"CLUSTER_NAME": aws.String(string(hostGroupInfo.ClusterName)),
+		"ASG_NAME":     aws.String(asgName),
+		"TABLE_NAME":   aws.String(tableName),
+		"ROLE":         aws.String(string(hostGroupInfo.Role)),
+	}
+}
+
 func CreateLambda(tags cluster.TagsRefsValues, lambdaType LambdaType, resourceName, roleArn, asgName, tableName string, hostGroupInfo common.HostGroupInfo, vpcConfig lambda.VpcConfig) (*lambda.FunctionConfiguration, error) {
 	svc := connectors.GetAWSSession().Lambda
 
@@ -31,8 +51,6 @@ func CreateLambda(tags cluster.TagsRefsValues, lambdaType LambdaType, resourceNa
 	}
 
 	lambdaPackage := string(dist.WekaCtl)
-	lambdaHandler := "lambdas-bin"
-	runtime := "go1.x"
 
 	s3Key := fmt.Sprintf("%s/%s", dist.LambdasID, lambdaPackage)
 
@@ -45,23 +63,16 @@ func CreateLambda(tags cluster.TagsRefsValues, lambdaType LambdaType, resourceNa
 		},
 		Description: aws.String(fmt.Sprintf("Wekactl %s", string(lambdaType))),
 		Environment: &lambda.Environment{
-			Variables: map[string]*string{
-				"LAMBDA":       aws.String(string(lambdaType)),
-				"REGION":       aws.String(env.Config.Region),
-				"CLUSTER_NAME": aws.String(string(hostGroupInfo.ClusterName)),
-				"ASG_NAME":     aws.String(asgName),
-				"TABLE_NAME":   aws.String(tableName),
-				"ROLE":         aws.String(string(hostGroupInfo.Role)),
-			},
+			Variables: generateEnvironmentVariables(lambdaType, asgName, tableName, hostGroupInfo),
 		},
 		Handler:      aws.String(lambdaHandler),
 		FunctionName: aws.String(lambdaName),
-		MemorySize:   aws.Int64(256),
+		MemorySize:   aws.Int64(memorySize),
 		Publish:      aws.Bool(true),
 		Role:         &roleArn,
 		Runtime:      aws.String(runtime),
 		Tags:         tags,
-		Timeout:      aws.Int64(15),
+		Timeout:      aws.Int64(timeout),
 		TracingConfig: &lambda.TracingConfig{
 			Mode: aws.String("Active"),
 		},
@@ -138,6 +149,9 @@ func GetLambdaArn(lambdaName string) (arn string, err error) {
 		FunctionName: &lambdaName,
 	})
 	if err != nil {
+		if _, ok := err.(*lambda.ResourceNotFoundException); ok {
+			return "", nil
+		}
 		return
 	}
 	arn = *lambdaOutput.Configuration.FunctionArn
@@ -187,3 +201,69 @@ func UpdateLambdaHandler(lambdaName string, versionTag cluster.TagsRefsValues) e
 
 	return err
 }
+
+// DiffLambda compares the lambda configuration with the one CreateLambda would use
+func DiffLambda(lambdaType LambdaType, lambdaName, roleArn, asgName, tableName string, hostGroupInfo common.HostGroupInfo, vpcConfig lambda.VpcConfig) (drifts cluster.Drifts, err error) {
+	svc := connectors.GetAWSSession().Lambda
+	configuration, err := svc.GetFunctionConfiguration(&lambda.GetFunctionConfigurationInput{
+		FunctionName: &lambdaName,
+	})
+	if err != nil {
+		return
+	}
+
+	drifts.Compare("handler", lambdaHandler, aws.StringValue(configuration.Handler))
+	drifts.Compare("runtime", runtime, aws.StringValue(configuration.Runtime))
+	drifts.Compare("memory_size", strconv.Itoa(memorySize), strconv.FormatInt(aws.Int64Value(configuration.MemorySize), 10))
+	drifts.Compare("timeout", strconv.Itoa(timeout), strconv.FormatInt(aws.Int64Value(configuration.Timeout), 10))
+	drifts.Compare("role", roleArn, aws.StringValue(configuration.Role))
+
+	actualVariables := map[string]*string{}
+	if configuration.Environment != nil {
+		actualVariables = configuration.Environment.Variables
+	}
+	expectedVariables := generateEnvironmentVariables(lambdaType, asgName, tableName, hostGroupInfo)
+	for key, value := range expectedVariables {
+		drifts.Compare("environment."+key, aws.StringValue(value), aws.StringValue(actualVariables[key]))
+	}
+	for key, value := range actualVariables {
+		if _, ok := expectedVariables[key]; !ok {
+			drifts.Compare("environment."+key, "", aws.StringValue(value))
+		}
+	}
+
+	var actualSubnets, actualSecurityGroups []string
+	if configuration.VpcConfig != nil {
+		actualSubnets = strings.RefListToList(configuration.VpcConfig.SubnetIds)
+		actualSecurityGroups = strings.RefListToList(configuration.VpcConfig.SecurityGroupIds)
+	}
+	drifts.CompareList("vpc_config.subnets", strings.RefListToList(vpcConfig.SubnetIds), actualSubnets)
+	drifts.CompareList("vpc_config.security_groups", strings.RefListToList(vpcConfig.SecurityGroupIds), actualSecurityGroups)
+	return
+}
+
+// GetClusterLambdas returns the names of all the lambdas tagged with the cluster name
+func GetClusterLambdas(clusterName cluster.ClusterName) (lambdaNames []string, err error) {
+	svc := connectors.GetAWSSession().Lambda
+	var tagsErr error
+	err = svc.ListFunctionsPages(&lambda.ListFunctionsInput{}, func(page *lambda.ListFunctionsOutput, lastPage bool) bool {
+		for _, function := range page.Functions {
+			if !strings.AnyOfPrefix(*function.FunctionName, "wekactl-") {
+				continue
+			}
+			var tagsOutput *lambda.ListTagsOutput
+			tagsOutput, tagsErr = svc.ListTags(&lambda.ListTagsInput{Resource: function.FunctionArn})
+			if tagsErr != nil {
+				return false
+			}
+			if value, ok := tagsOutput.Tags[cluster.ClusterNameTagKey]; ok && *value == string(clusterName) {
+				lambdaNames = append(lambdaNames, *function.FunctionName)
+			}
+		}
+		return true
+	})
+	if err == nil {
+		err = tagsErr
+	}
+	return
+}
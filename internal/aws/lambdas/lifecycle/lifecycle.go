@@ -0,0 +1,190 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"os"
+	"time"
+	"wekactl/internal/aws/autoscaling"
+	"wekactl/internal/aws/lambdas/protocol"
+	"wekactl/internal/aws/lambdas/scale"
+	"wekactl/internal/lib/types"
+	"wekactl/internal/lib/weka"
+)
+
+// Handler removes the weka hosts of the instances the termination lifecycle hook holds in Terminating:Wait, and
+// passes the host group info on to the scale lambda
+func Handler(ctx context.Context, info protocol.HostGroupInfoResponse) (protocol.HostGroupInfoResponse, error) {
+	asgName := os.Getenv("ASG_NAME")
+	if asgName == "" {
+		return info, errors.New("ASG_NAME env var is mandatory")
+	}
+	return Drain(scale.NewClusterApi(ctx, info), asgName, info, time.Now()), nil
+}
+
+// cluster holds the weka api responses a lifecycle run drains hosts by
+type cluster struct {
+	status weka.StatusResponse
+	hosts  weka.HostListResponse
+	drives weka.DriveListResponse
+}
+
+func fetchCluster(api scale.ClusterApi, role string) (c cluster, err error) {
+	err = api.Call(weka.JrpcStatus, struct{}{}, &c.status)
+	if err != nil {
+		return
+	}
+	err = api.Call(weka.JrpcHostList, struct{}{}, &c.hosts)
+	if err != nil {
+		return
+	}
+	if role == "backend" {
+		err = api.Call(weka.JrpcDrivesList, struct{}{}, &c.drives)
+	}
+	return
+}
+
+// instanceHost returns the host of the instance, down hosts might have lost their instance id so they are matched by ip
+func (c cluster) instanceHost(instance protocol.HgInstance) (weka.HostId, weka.Host, bool) {
+	for hostId, host := range c.hosts {
+		if host.Aws.InstanceId == instance.Id || host.HostIp == instance.PrivateIp {
+			return hostId, host, true
+		}
+	}
+	return weka.HostId{}, weka.Host{}, false
+}
+
+func (c cluster) hostDrives(hostId weka.HostId) (drives []weka.Drive) {
+	for _, drive := range c.drives {
+		if drive.HostId == hostId {
+			drives = append(drives, drive)
+		}
+	}
+	return
+}
+
+// Drain moves the hosts of the terminating instances towards removal, one step a run. Terminations continue once
+// their hosts are removed, until then they are extended so the hook doesn't time out on a long deactivation
+func Drain(api scale.ClusterApi, asgName string, info protocol.HostGroupInfoResponse, now time.Time) protocol.HostGroupInfoResponse {
+	var terminating []protocol.HgInstance
+	for _, instance := range info.Instances {
+		if instance.Terminating {
+			terminating = append(terminating, instance)
+		}
+	}
+	if len(terminating) == 0 {
+		return info
+	}
+
+	extend := func(instance protocol.HgInstance) {
+		err := autoscaling.ExtendTermination(asgName, instance.Id)
+		if err != nil {
+			log.Error().Err(err).Msgf("extending the termination of instance %s failed", instance.Id)
+			info.AddTransientError(err, "extendTermination")
+		}
+	}
+	if info.Pause.Active(now) {
+		log.Info().Msgf("scaling is %s, holding %d terminating instances", info.Pause, len(terminating))
+		for _, instance := range terminating {
+			extend(instance)
+		}
+		return info
+	}
+
+	c, err := fetchCluster(api, info.Role)
+	if err != nil {
+		log.Error().Err(err).Msg("fetching the cluster state failed")
+		info.AddTransientError(err, "lifecycle")
+		for _, instance := range terminating {
+			extend(instance)
+		}
+		return info
+	}
+
+	for _, instance := range terminating {
+		hostId, host, ok := c.instanceHost(instance)
+		if ok {
+			removed, err := drainHost(api, c, hostId, host)
+			if err != nil {
+				log.Error().Err(err).Msgf("draining host %s of terminating instance %s failed", hostId, instance.Id)
+				info.AddTransientError(err, "drainHost")
+			}
+			if !removed {
+				extend(instance)
+				continue
+			}
+		}
+		err = autoscaling.CompleteTermination(asgName, instance.Id)
+		if err != nil {
+			log.Error().Err(err).Msgf("completing the termination of instance %s failed", instance.Id)
+			info.AddTransientError(err, "completeTermination")
+		}
+	}
+	return info
+}
+
+// drainHost removes the host once it is inactive, and otherwise deactivates its drives and then the host itself.
+// A backend doesn't start deactivating while data protection is degraded, unless it is down
+func drainHost(api scale.ClusterApi, c cluster, hostId weka.HostId, host weka.Host) (removed bool, err error) {
+	drives := c.hostDrives(hostId)
+	if host.State == "INACTIVE" {
+		log.Info().Msgf("removing host %s of terminating instance %s", hostId, host.Aws.InstanceId)
+		api.Drop(host.HostIp)
+		err = api.Call(weka.JrpcRemoveHost, types.JsonDict{
+			"host_id": hostId.Int(),
+			"no_wait": true,
+		}, nil)
+		if err != nil {
+			return
+		}
+		// drives left behind are removed by the scale lambda later on, they don't hold the termination
+		for _, drive := range drives {
+			driveErr := api.Call(weka.JrpcRemoveDrive, types.JsonDict{
+				"drive_uuids": []uuid.UUID{drive.Uuid},
+			}, nil)
+			if driveErr != nil {
+				log.Error().Err(driveErr).Msgf("removing drive %s of host %s failed", drive.Uuid, hostId)
+			}
+		}
+		return true, nil
+	}
+	if host.State != "ACTIVE" {
+		return
+	}
+
+	var toDeactivate []uuid.UUID
+	allInactive := true
+	for _, drive := range drives {
+		if drive.ShouldBeActive {
+			toDeactivate = append(toDeactivate, drive.Uuid)
+		}
+		if drive.Status != "INACTIVE" {
+			allInactive = false
+		}
+	}
+	if len(toDeactivate) == len(drives) && len(drives) > 0 && host.Status != "DOWN" {
+		if c.status.Rebuilding() || c.status.DegradedMiB() > 0 {
+			err = errors.New(fmt.Sprintf("holding host %s deactivation, data protection is degraded", hostId))
+			return
+		}
+	}
+	if len(toDeactivate) > 0 {
+		log.Info().Msgf("deactivating the drives of host %s of terminating instance %s", hostId, host.Aws.InstanceId)
+		err = api.Call(weka.JrpcDeactivateDrives, types.JsonDict{
+			"drive_uuids": toDeactivate,
+		}, nil)
+		return
+	}
+	if allInactive {
+		log.Info().Msgf("deactivating host %s of terminating instance %s", hostId, host.Aws.InstanceId)
+		api.Drop(host.HostIp)
+		err = api.Call(weka.JrpcDeactivateHosts, types.JsonDict{
+			"host_ids":                 []weka.HostId{hostId},
+			"skip_resource_validation": false,
+		}, nil)
+	}
+	return
+}
@@ -0,0 +1,127 @@
+package lifecycle
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+	autoscaling2 "wekactl/internal/aws/autoscaling"
+	cluster2 "wekactl/internal/aws/cluster"
+	"wekactl/internal/aws/common"
+	"wekactl/internal/aws/dist"
+	"wekactl/internal/aws/fake"
+	"wekactl/internal/aws/lambdas"
+	"wekactl/internal/connectors"
+	"wekactl/internal/env"
+	"wekactl/internal/lib/jrpc"
+	wekafake "wekactl/internal/lib/weka/fake"
+)
+
+const testStackName = "test-cluster"
+
+func TestTerminationLifecycle(t *testing.T) {
+	env.Config.Region = fake.Region
+	dist.LambdasSource[fake.Region] = "weka-lambdas"
+	dist.LambdasID = "v1"
+	a := fake.New().Install()
+	a.AddStack(testStackName, 3, 2)
+	err := cluster2.ImportCluster(testStackName, "admin", "password", nil)
+	if err != nil {
+		t.Fatalf("ImportCluster() error = %v", err)
+	}
+	asgName := common.GenerateResourceName(testStackName, "Backends")
+	drifts, err := autoscaling2.DiffAutoScalingGroup("", common.SpotParams{}, common.InstanceTypesParams{}, common.AutoscaleParams{}, 0, asgName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, drift := range drifts {
+		if drift.Attribute == "termination_lifecycle_hook" || drift.Attribute == "new_instances_protected_from_scale_in" {
+			t.Errorf("ImportCluster() auto scaling group drifted: %+v", drift)
+		}
+	}
+	groups, err := a.ASG.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, group := range groups.AutoScalingGroups {
+		for _, instance := range group.Instances {
+			if !aws.BoolValue(instance.ProtectedFromScaleIn) {
+				t.Errorf("ImportCluster() left instance %s unprotected from scale in", aws.StringValue(instance.InstanceId))
+			}
+		}
+	}
+
+	wekaCluster := wekafake.NewCluster("admin", "password")
+	server, err := wekaCluster.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	newApi := func() *jrpc.Pool {
+		return &jrpc.Pool{
+			Ips:     []string{server.Addr()},
+			Clients: map[string]*jrpc.BaseClient{},
+			Builder: func(ip string) *jrpc.BaseClient {
+				host, port, _ := net.SplitHostPort(ip)
+				portNumber, _ := strconv.Atoi(port)
+				return connectors.NewJrpcClient(ctx, host, portNumber, "admin", "password")
+			},
+			Ctx: ctx,
+		}
+	}
+
+	tableName := common.GenerateResourceName(testStackName, "")
+	info, err := lambdas.GetFetchDataParams(testStackName, asgName, tableName, "backend")
+	if err != nil {
+		t.Fatalf("GetFetchDataParams() error = %v", err)
+	}
+	for _, instance := range info.Instances {
+		wekaCluster.AddHost("backend", instance.Id, instance.PrivateIp, 2)
+	}
+	terminating := info.Instances[0].Id
+	_, err = a.ASG.TerminateInstanceInAutoScalingGroup(&autoscaling.TerminateInstanceInAutoScalingGroupInput{
+		InstanceId:                     aws.String(terminating),
+		ShouldDecrementDesiredCapacity: aws.Bool(true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// drives phase out, then the host deactivates, then it is removed and the termination completes
+	for i := 0; i < 2; i++ {
+		info, err = lambdas.GetFetchDataParams(testStackName, asgName, tableName, "backend")
+		if err != nil {
+			t.Fatalf("GetFetchDataParams() error = %v", err)
+		}
+		if !info.Instances[0].Terminating {
+			t.Fatalf("instance %s isn't terminating", terminating)
+		}
+		info = Drain(newApi(), asgName, info, time.Now())
+		if len(info.TransientErrors) != 0 {
+			t.Fatalf("Drain() transient errors %v", info.TransientErrors)
+		}
+		if state := *a.EC2.Instance(terminating).State.Name; state != ec2.InstanceStateNameRunning {
+			t.Fatalf("Drain() let instance %s terminate before its host was removed", terminating)
+		}
+		wekaCluster.Advance(wekafake.DeactivateDuration)
+	}
+	info = Drain(newApi(), asgName, info, time.Now())
+	if len(info.TransientErrors) != 0 {
+		t.Fatalf("Drain() transient errors %v", info.TransientErrors)
+	}
+	if _, ok := wekaCluster.HostStates()[terminating]; ok {
+		t.Errorf("Drain() didn't remove the host of %s", terminating)
+	}
+	if state := *a.EC2.Instance(terminating).State.Name; state != ec2.InstanceStateNameTerminated {
+		t.Errorf("Drain() instance %s state = %s, want terminated", terminating, state)
+	}
+	if active := wekaCluster.HostStates(); len(active) != 2 {
+		t.Errorf("Drain() left hosts %v, want the 2 other backends", active)
+	}
+}
@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/rs/zerolog/log"
+	"os"
+	"time"
+	"wekactl/internal/aws/autoscaling"
+	"wekactl/internal/aws/common"
+	"wekactl/internal/aws/lambdas/protocol"
+	"wekactl/internal/aws/lambdas/scale"
+	"wekactl/internal/connectors"
+	"wekactl/internal/lib/weka"
+)
+
+// FrontendMetrics are averages over the active weka hosts of the host group instances
+type FrontendMetrics struct {
+	Hosts int
+	// Throughput is in megabytes per second
+	Throughput float64
+	Iops       float64
+	Cpu        float64
+}
+
+// Handler publishes the host group frontend metrics while its auto scaling group tracks a target of one of them, and
+// passes the host group info on to the scale lambda. Failing to publish doesn't fail the run, it is reported as a
+// transient error
+func Handler(ctx context.Context, info protocol.HostGroupInfoResponse) (protocol.HostGroupInfoResponse, error) {
+	asgName := os.Getenv("ASG_NAME")
+	if asgName == "" {
+		return info, errors.New("ASG_NAME env var is mandatory")
+	}
+	enabled, err := autoscaling.AutoscaleEnabled(asgName)
+	if err != nil {
+		info.AddTransientError(err, "metricsPolicy")
+		return info, nil
+	}
+	if !enabled {
+		return info, nil
+	}
+	// without data points the target tracking policy leaves the desired capacity as is
+	now := time.Now()
+	if info.Pause.Active(now) {
+		log.Info().Msgf("scaling is %s, not publishing metrics", info.Pause)
+		return info, nil
+	}
+
+	metrics, err := Collect(scale.NewClusterApi(ctx, info), info)
+	if err != nil {
+		info.AddTransientError(err, "metricsCollect")
+		return info, nil
+	}
+	if metrics.Hosts == 0 {
+		log.Info().Msg("no active hosts, not publishing metrics")
+		return info, nil
+	}
+	err = Publish(asgName, metrics, now)
+	if err != nil {
+		info.AddTransientError(err, "metricsPublish")
+	}
+	return info, nil
+}
+
+func belongsToHostGroup(host weka.Host, instances []protocol.HgInstance) bool {
+	for _, instance := range instances {
+		if instance.Terminating {
+			continue
+		}
+		if host.Aws.InstanceId == instance.Id || host.HostIp == instance.PrivateIp {
+			return true
+		}
+	}
+	return false
+}
+
+// Collect averages the realtime stats of the frontend nodes over the active hosts of the host group, hosts whose
+// frontends have no stats count as idle
+func Collect(api scale.ClusterApi, info protocol.HostGroupInfoResponse) (metrics FrontendMetrics, err error) {
+	var hosts weka.HostListResponse
+	err = api.Call(weka.JrpcHostList, struct{}{}, &hosts)
+	if err != nil {
+		return
+	}
+	var nodes weka.NodeListResponse
+	err = api.Call(weka.JrpcNodeList, struct{}{}, &nodes)
+	if err != nil {
+		return
+	}
+	var stats weka.StatsRealtimeResponse
+	err = api.Call(weka.JrpcStatsRealtime, struct{}{}, &stats)
+	if err != nil {
+		return
+	}
+
+	hostGroupHosts := map[weka.HostId]bool{}
+	for hostId, host := range hosts {
+		if host.State == "ACTIVE" && host.Status == "UP" && belongsToHostGroup(host, info.Instances) {
+			hostGroupHosts[hostId] = true
+		}
+	}
+	if len(hostGroupHosts) == 0 {
+		return
+	}
+
+	hostCpu := map[weka.HostId]float64{}
+	hostFrontends := map[weka.HostId]int{}
+	for nodeId, nodeStats := range stats {
+		if nodeId.IsManagement() {
+			continue
+		}
+		node, ok := nodes[nodeId]
+		if !ok || !hostGroupHosts[node.HostId] {
+			continue
+		}
+		metrics.Throughput += (nodeStats.ReadBytes + nodeStats.WriteBytes) / 1e6
+		metrics.Iops += nodeStats.Ops
+		hostCpu[node.HostId] += nodeStats.Cpu
+		hostFrontends[node.HostId]++
+	}
+	for hostId, cpu := range hostCpu {
+		metrics.Cpu += cpu / float64(hostFrontends[hostId])
+	}
+
+	metrics.Hosts = len(hostGroupHosts)
+	metrics.Throughput /= float64(metrics.Hosts)
+	metrics.Iops /= float64(metrics.Hosts)
+	metrics.Cpu /= float64(metrics.Hosts)
+	return
+}
+
+// Publish puts the host group metrics to cloudwatch, as the target tracking policy of its auto scaling group
+// expects them
+func Publish(asgName string, metrics FrontendMetrics, now time.Time) error {
+	datum := func(metricName string, value float64) *cloudwatch.MetricDatum {
+		return &cloudwatch.MetricDatum{
+			MetricName: aws.String(metricName),
+			Dimensions: []*cloudwatch.Dimension{
+				{
+					Name:  aws.String(common.MetricsDimension),
+					Value: aws.String(asgName),
+				},
+			},
+			Timestamp: aws.Time(now),
+			Unit:      aws.String(common.MetricUnits[metricName]),
+			Value:     aws.Float64(value),
+		}
+	}
+
+	svc := connectors.GetAWSSession().CloudWatch
+	_, err := svc.PutMetricData(&cloudwatch.PutMetricDataInput{
+		Namespace: aws.String(common.MetricsNamespace),
+		MetricData: []*cloudwatch.MetricDatum{
+			datum(common.MetricFrontendThroughput, metrics.Throughput),
+			datum(common.MetricFrontendIops, metrics.Iops),
+			datum(common.MetricFrontendCpu, metrics.Cpu),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("published metrics of %d hosts: %.1f MB/s, %.1f iops, %.1f%% cpu",
+		metrics.Hosts, metrics.Throughput, metrics.Iops, metrics.Cpu)
+	return nil
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/aws"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+	"wekactl/internal/aws/common"
+	"wekactl/internal/aws/fake"
+	"wekactl/internal/aws/lambdas/protocol"
+	"wekactl/internal/connectors"
+	"wekactl/internal/env"
+	"wekactl/internal/lib/jrpc"
+	"wekactl/internal/lib/weka"
+	wekafake "wekactl/internal/lib/weka/fake"
+)
+
+func TestFrontendMetrics(t *testing.T) {
+	env.Config.Region = fake.Region
+	a := fake.New().Install()
+	wekaCluster := wekafake.NewCluster("admin", "password")
+	server, err := wekaCluster.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api := &jrpc.Pool{
+		Ips:     []string{server.Addr()},
+		Clients: map[string]*jrpc.BaseClient{},
+		Builder: func(ip string) *jrpc.BaseClient {
+			host, port, _ := net.SplitHostPort(ip)
+			portNumber, _ := strconv.Atoi(port)
+			return connectors.NewJrpcClient(ctx, host, portNumber, "admin", "password")
+		},
+		Ctx: ctx,
+	}
+	wekaCluster.AddHost("backend", "i-backend-1", "10.0.0.1", 2)
+	client1 := wekaCluster.AddHost("client", "i-client-1", "10.0.1.1", 0)
+	client2 := wekaCluster.AddHost("client", "i-client-2", "10.0.1.2", 0)
+	wekaCluster.AddHost("client", "i-client-3", "10.0.1.3", 0)
+	other := wekaCluster.AddHost("client", "i-other-1", "10.0.2.1", 0)
+	for hostId, stats := range map[weka.HostId]weka.NodeStats{
+		client1: {Ops: 1000, ReadBytes: 30e6, WriteBytes: 10e6, Cpu: 40},
+		client2: {Ops: 3000, ReadBytes: 50e6, WriteBytes: 30e6, Cpu: 60},
+		other:   {Ops: 9000, ReadBytes: 900e6, Cpu: 90},
+	} {
+		if err = wekaCluster.SetHostStats(hostId, stats); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the third client has no frontend stats and counts as idle, terminating instances don't count
+	info := protocol.HostGroupInfoResponse{
+		Instances: []protocol.HgInstance{
+			{Id: "i-client-1", PrivateIp: "10.0.1.1"},
+			{Id: "i-client-2", PrivateIp: "10.0.1.2"},
+			{Id: "i-client-3", PrivateIp: "10.0.1.3"},
+			{Id: "i-client-4", PrivateIp: "10.0.1.4", Terminating: true},
+		},
+	}
+
+	frontendMetrics, err := Collect(api, info)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	want := FrontendMetrics{Hosts: 3, Throughput: 40, Iops: 4000.0 / 3, Cpu: 100.0 / 3}
+	if frontendMetrics != want {
+		t.Errorf("Collect() = %+v, want %+v", frontendMetrics, want)
+	}
+
+	asgName := common.GenerateResourceName("test-cluster", "Clients")
+	err = Publish(asgName, frontendMetrics, time.Now())
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	published := map[string]float64{}
+	for _, datum := range a.CloudWatch.MetricData(common.MetricsNamespace) {
+		if len(datum.Dimensions) != 1 || aws.StringValue(datum.Dimensions[0].Value) != asgName {
+			t.Errorf("Publish() metric %s dimensions = %v, want the %s group", aws.StringValue(datum.MetricName), datum.Dimensions, asgName)
+		}
+		published[aws.StringValue(datum.MetricName)] = aws.Float64Value(datum.Value)
+	}
+	wantPublished := map[string]float64{
+		common.MetricFrontendThroughput: want.Throughput,
+		common.MetricFrontendIops:       want.Iops,
+		common.MetricFrontendCpu:        want.Cpu,
+	}
+	if !reflect.DeepEqual(published, wantPublished) {
+		t.Errorf("Publish() published %v, want %v", published, wantPublished)
+	}
+}
@@ -7,3 +7,6 @@ const LambdaScale LambdaType = "scale"
 const LambdaTerminate LambdaType = "terminate"
 const LambdaJoin LambdaType = "join"
 const LambdaTransient LambdaType = "transient"
+const LambdaLifecycle LambdaType = "lifecycle"
+const LambdaInterruption LambdaType = "interruption"
+const LambdaMetrics LambdaType = "metrics"
@@ -3,21 +3,35 @@ package protocol
 import (
 	"fmt"
 	"time"
+	"wekactl/internal/cluster"
 	"wekactl/internal/lib/weka"
 )
 
 type HgInstance struct {
 	Id        string
 	PrivateIp string
+	// Protected instances are tagged to opt out of scale in, they are not deactivated for downscale nor terminated
+	Protected bool
+	// Terminating instances wait in Terminating:Wait for the lifecycle lambda to remove their weka hosts
+	Terminating bool
 }
 
 type HostGroupInfoResponse struct {
-	Username        string       `json:"username"`
-	Password        string       `json:"password"`
-	DesiredCapacity int          `json:"desired_capacity"`
-	Instances       []HgInstance `json:"instances"`
-	BackendIps      []string     `json:"backend_ips"`
-	Role            string       `json:"role"`
+	Username        string                `json:"username"`
+	Password        string                `json:"password"`
+	DesiredCapacity int                   `json:"desired_capacity"`
+	Instances       []HgInstance          `json:"instances"`
+	BackendIps      []string              `json:"backend_ips"`
+	Role            string                `json:"role"`
+	Policy          cluster.ScalingPolicy `json:"policy"`
+	Pause           cluster.ScalingPause  `json:"pause"`
+	// TransientErrors of the lifecycle lambda, the scale lambda passes them on
+	TransientErrors []string `json:"transient_errors,omitempty"`
+}
+
+// AddTransientError records an error a later run might not hit, the state machine reports it once the run ends
+func (r *HostGroupInfoResponse) AddTransientError(err error, caller string) {
+	r.TransientErrors = append(r.TransientErrors, fmt.Sprintf("%s:%s", caller, err.Error()))
 }
 
 type ScaleResponseHost struct {
@@ -25,11 +39,26 @@ type ScaleResponseHost struct {
 	State      string      `json:"status"`
 	AddedTime  time.Time   `json:"added_time"`
 	HostId     weka.HostId `json:"host_id"`
+	ScaleState string      `json:"scale_state"`
+}
+
+// ScaleResponseDecision is a host the scale lambda deactivated or removed, and why
+type ScaleResponseDecision struct {
+	InstanceId string      `json:"instance_id"`
+	HostId     weka.HostId `json:"host_id"`
+	Reason     string      `json:"reason"`
 }
 
 type ScaleResponse struct {
-	Hosts           []ScaleResponseHost `json:"hosts"`
-	ToTerminate     []HgInstance        `json:"to_terminate"`
+	Hosts           []ScaleResponseHost     `json:"hosts"`
+	ToTerminate     []HgInstance            `json:"to_terminate"`
+	Deactivated     []ScaleResponseDecision `json:"deactivated"`
+	RemovedInactive []ScaleResponseDecision `json:"removed_inactive"`
+	ScaleDownLimit  string                  `json:"scale_down_limit,omitempty"`
+	Policy          cluster.ScalingPolicy   `json:"policy"`
+	// Paused describes the scaling pause when the scale lambda left the cluster as is, the terminate lambda
+	// then terminates no instances
+	Paused          string `json:"paused,omitempty"`
 	TransientErrors []string
 }
 
@@ -43,6 +72,14 @@ func (r *ScaleResponse) AddTransientError(err error, caller string) {
 	r.TransientErrors = append(r.TransientErrors, fmt.Sprintf("%s:%s", caller, err.Error()))
 }
 
+func (r *ScaleResponse) AddDeactivated(instanceId string, hostId weka.HostId, reason string) {
+	r.Deactivated = append(r.Deactivated, ScaleResponseDecision{InstanceId: instanceId, HostId: hostId, Reason: reason})
+}
+
+func (r *ScaleResponse) AddRemovedInactive(instanceId string, hostId weka.HostId, reason string) {
+	r.RemovedInactive = append(r.RemovedInactive, ScaleResponseDecision{InstanceId: instanceId, HostId: hostId, Reason: reason})
+}
+
 type TerminatedInstance struct {
 	InstanceId string    `json:"instance_id"`
 	Creation   time.Time `json:"creation_date"`
@@ -52,9 +89,9 @@ type TerminatedInstancesResponse struct {
 	TransientErrors []string
 }
 
-func (r *TerminatedInstancesResponse) AddTransientErrors(errs []error) {
+func (r *TerminatedInstancesResponse) AddTransientErrors(errs []error, caller string) {
 	for _, err := range errs {
-		r.TransientErrors = append(r.TransientErrors, err.Error())
+		r.TransientErrors = append(r.TransientErrors, fmt.Sprintf("%s:%s", caller, err.Error()))
 	}
 }
 
@@ -0,0 +1,532 @@
+package scale
+
+import (
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"sort"
+	gostrings "strings"
+	"time"
+	"wekactl/internal/aws/lambdas/protocol"
+	"wekactl/internal/cluster"
+	"wekactl/internal/lib/math"
+	"wekactl/internal/lib/strings"
+	"wekactl/internal/lib/weka"
+)
+
+type hostState int
+
+func (h hostState) String() string {
+	switch h {
+	case DEACTIVATING:
+		return "DEACTIVATING"
+	case HEALTHY:
+		return "HEALTHY"
+	case UNHEALTHY:
+		return "UNHEALTHY"
+	default:
+		return fmt.Sprintf("UNKNOWN(%d)", h)
+	}
+}
+
+const (
+	/*
+		Order matters, it defines priority of hosts removal
+	*/
+	DEACTIVATING hostState = iota
+	UNHEALTHY
+	HEALTHY
+)
+
+type driveMap map[weka.DriveId]weka.Drive
+type nodeMap map[weka.NodeId]weka.Node
+type hostInfo struct {
+	weka.Host
+	id         weka.HostId
+	drives     driveMap
+	nodes      nodeMap
+	scaleState hostState
+	// scaleReason is why the host is not HEALTHY
+	scaleReason string
+	// mounts and frontendOps are the client activity, clients with less of it are scaled in first
+	mounts      int
+	frontendOps float64
+	// protected hosts are tagged to opt out of scale in
+	protected bool
+}
+
+func (host hostInfo) belongsToHg(instances []protocol.HgInstance) bool {
+	for _, instance := range instances {
+		if host.Aws.InstanceId == instance.Id {
+			return true
+		}
+	}
+	return false
+}
+
+func (host hostInfo) protectedInstance(instances []protocol.HgInstance) bool {
+	for _, instance := range instances {
+		if host.Aws.InstanceId == instance.Id {
+			return instance.Protected
+		}
+	}
+	return false
+}
+
+func (host hostInfo) belongsToHgIpBased(instances []protocol.HgInstance) bool {
+	for _, instance := range instances {
+		if host.HostIp == instance.PrivateIp {
+			return true
+		}
+	}
+	return false
+}
+
+func (host hostInfo) numNotHealthyDrives() int {
+	notActive := 0
+	for _, drive := range host.drives {
+		if strings.AnyOf(drive.Status, "INACTIVE") {
+			notActive += 1
+		}
+	}
+	return notActive
+}
+
+func (host hostInfo) allDisksBeingRemoved() bool {
+	ret := false
+	for _, drive := range host.drives {
+		ret = true
+		if drive.ShouldBeActive {
+			return false
+		}
+	}
+	return ret
+}
+
+func (host hostInfo) anyDiskBeingRemoved() bool {
+	for _, drive := range host.drives {
+		if !drive.ShouldBeActive {
+			return true
+		}
+	}
+	return false
+}
+
+func (host hostInfo) allDrivesInactive() bool {
+	for _, drive := range host.drives {
+		if drive.Status != "INACTIVE" {
+			return false
+		}
+	}
+	return true
+}
+
+func (host hostInfo) managementTimedOut(now time.Time, timeout time.Duration) bool {
+	for nodeId, node := range host.nodes {
+		if !nodeId.IsManagement() {
+			continue
+		}
+		if node.Status == "DOWN" && now.Sub(*node.LastFencingTime) > timeout {
+			return true
+		}
+	}
+	return false
+}
+
+// failureDomain returns the host failure domain, hosts without one are a failure domain of their own
+func (host hostInfo) failureDomain() string {
+	if host.FailureDomain == "" {
+		return host.id.String()
+	}
+	return host.FailureDomain
+}
+
+// clusterState holds the weka api responses a scale run decides by
+type clusterState struct {
+	status weka.StatusResponse
+	hosts  weka.HostListResponse
+	drives weka.DriveListResponse
+	nodes  weka.NodeListResponse
+	// mounts and stats are fetched for clients only
+	mounts weka.MountsListResponse
+	stats  weka.StatsRealtimeResponse
+}
+
+// scaleDecision is what a scale run should do, it is derived from the cluster state alone
+type scaleDecision struct {
+	// hosts of the host group, in the order they should be deactivated
+	hosts           []hostInfo
+	numToDeactivate int
+	scaleDownLimit  string
+	// downHosts are active backends that are not in the host group and were down for too long
+	downHosts     []hostInfo
+	inactiveHosts []hostInfo
+	// protectionLimit explains why new deactivations were held back while data protection is degraded
+	protectionLimit string
+}
+
+func (d scaleDecision) toDeactivate() []hostInfo {
+	return d.hosts[:d.numToDeactivate]
+}
+
+func deactivateReason(host hostInfo) string {
+	if host.scaleState == HEALTHY {
+		return "downscale"
+	}
+	return host.scaleReason
+}
+
+func decide(state clusterState, info protocol.HostGroupInfoResponse, now time.Time) (decision scaleDecision) {
+	hosts := map[weka.HostId]hostInfo{}
+	for hostId, host := range state.hosts {
+		hosts[hostId] = hostInfo{
+			Host:   host,
+			id:     hostId,
+			drives: driveMap{},
+			nodes:  nodeMap{},
+		}
+	}
+	for driveId, drive := range state.drives {
+		if _, ok := hosts[drive.HostId]; ok {
+			hosts[drive.HostId].drives[driveId] = drive
+		}
+	}
+
+	for nodeId, node := range state.nodes {
+		if _, ok := hosts[node.HostId]; ok {
+			hosts[node.HostId].nodes[nodeId] = node
+		}
+	}
+
+	for hostId, mounts := range state.mounts {
+		if host, ok := hosts[hostId]; ok {
+			host.mounts = len(mounts)
+			hosts[hostId] = host
+		}
+	}
+	for nodeId, stats := range state.stats {
+		node, ok := state.nodes[nodeId]
+		if !ok {
+			continue
+		}
+		if host, ok := hosts[node.HostId]; ok {
+			host.frontendOps += stats.Ops
+			hosts[node.HostId] = host
+		}
+	}
+
+	var hostsList []hostInfo
+
+	for _, host := range hosts {
+		switch host.State {
+		case "INACTIVE":
+			if host.belongsToHgIpBased(info.Instances) {
+				decision.inactiveHosts = append(decision.inactiveHosts, host)
+			} else {
+				if info.Role == "backend" && now.Sub(host.StateChangedTime) > info.Policy.BackendCleanupDelay { // Giving own HG chance to take care
+					// Since terminate logic is mostly delta based, and remove might be transient errors
+					// We might have leftovers, that we are unable to recognize
+					// So decision is, to kick out whatever is inactive.
+					decision.inactiveHosts = append(decision.inactiveHosts, host)
+				}
+			}
+		default:
+			if host.belongsToHg(info.Instances) {
+				host.protected = host.protectedInstance(info.Instances)
+				hostsList = append(hostsList, host)
+			} else if host.Status == "DOWN" {
+				log.Info().Msgf("found down host, %s : %s : %s", host.id, host.Status, host.HostIp)
+				if host.belongsToHgIpBased(info.Instances) {
+					log.Info().Msgf("including in known hosts  %s : %s", host.id, host.Status)
+					hostsList = append(hostsList, host)
+				}
+				// Down hosts lose instanceIds, so have to account basing on IPs
+			}
+		}
+
+		switch host.Status {
+		case "DOWN":
+			if info.Role == "backend" {
+				if host.State != "INACTIVE" && host.managementTimedOut(now, info.Policy.DownKickOutTimeout) {
+					log.Info().Msgf("host %s is still active but down for too long, kicking out", host.id)
+					decision.downHosts = append(decision.downHosts, host)
+				}
+			}
+		}
+	}
+
+	calculateHostsState(hostsList, info.Policy, now)
+
+	sort.Slice(hostsList, func(i, j int) bool {
+		// Giving priority to disks to hosts with disk being removed
+		// Then hosts with disks not in active state
+		// Then clients with fewer mounts and less frontend activity
+		// Then hosts sorted by add time
+		a := hostsList[i]
+		b := hostsList[j]
+		if a.scaleState < b.scaleState {
+			return true
+		}
+		if a.scaleState > b.scaleState {
+			return false
+		}
+		if a.numNotHealthyDrives() > b.numNotHealthyDrives() {
+			return true
+		}
+		if a.numNotHealthyDrives() < b.numNotHealthyDrives() {
+			return false
+		}
+		if a.mounts != b.mounts {
+			return a.mounts < b.mounts
+		}
+		if a.frontendOps != b.frontendOps {
+			return a.frontendOps < b.frontendOps
+		}
+		return a.AddedTime.Before(b.AddedTime)
+	})
+	decision.hosts = hostsList
+
+	decision.numToDeactivate = getNumToDeactivate(hostsList, info.DesiredCapacity, info.Policy.MaxUnhealthyDeactivating)
+	var limits []string
+	candidates, protected := splitProtected(hostsList)
+	if decision.numToDeactivate > len(candidates) {
+		limits = append(limits, fmt.Sprintf(
+			"protect tag: deactivating only %d of %d hosts, the rest are protected from scale in",
+			len(candidates), decision.numToDeactivate))
+		decision.numToDeactivate = len(candidates)
+	}
+	decision.hosts = candidates
+	if info.Role == "backend" {
+		var limit string
+		decision.hosts, decision.numToDeactivate, limit = spreadDeactivation(decision.hosts, decision.numToDeactivate, state.status.StripeProtectionDrives)
+		if limit != "" {
+			limits = append(limits, limit)
+		}
+		decision.hosts, decision.numToDeactivate, decision.protectionLimit = limitDeactivateByProtection(decision.hosts, decision.numToDeactivate, state.status)
+		if decision.protectionLimit != "" {
+			log.Warn().Msg(decision.protectionLimit)
+		}
+		decision.numToDeactivate, limit = limitDeactivateByCapacity(decision.hosts, decision.numToDeactivate, state.drives, state.status.Capacity)
+		if limit != "" {
+			limits = append(limits, limit)
+		}
+	}
+	decision.hosts = append(decision.hosts, protected...)
+	decision.scaleDownLimit = gostrings.Join(limits, "; ")
+	if decision.scaleDownLimit != "" {
+		log.Warn().Msg(decision.scaleDownLimit)
+	}
+	return
+}
+
+// splitProtected sets apart the healthy hosts that are protected from scale in, unhealthy ones are still replaced
+func splitProtected(hosts []hostInfo) (candidates, protected []hostInfo) {
+	for _, host := range hosts {
+		if host.protected && host.scaleState == HEALTHY {
+			protected = append(protected, host)
+		} else {
+			candidates = append(candidates, host)
+		}
+	}
+	return
+}
+
+// spreadDeactivation reorders the hosts so the first ones to deactivate spread evenly across failure domains and
+// availability zones, still deactivating unhealthy hosts ahead of healthy ones.
+// Hosts that are already deactivating come first, and no failure domain gets more hosts deactivating than the
+// protection level, the number of hosts to deactivate is capped instead. A protection level of 0 doesn't cap
+func spreadDeactivation(hosts []hostInfo, numToDeactivate, protection int) ([]hostInfo, int, string) {
+	domainPicks := map[string]int{}
+	zonePicks := map[string]int{}
+	domainLeft := map[string]int{}
+	for _, host := range hosts {
+		domainLeft[host.failureDomain()]++
+	}
+	capped := func(host hostInfo) bool {
+		return host.scaleState != DEACTIVATING && protection > 0 && domainPicks[host.failureDomain()] >= protection
+	}
+	// less tells whether a is a better pick than b
+	less := func(a, b hostInfo) bool {
+		if a.scaleState != b.scaleState {
+			return a.scaleState < b.scaleState
+		}
+		if domainPicks[a.failureDomain()] != domainPicks[b.failureDomain()] {
+			return domainPicks[a.failureDomain()] < domainPicks[b.failureDomain()]
+		}
+		if zonePicks[a.Aws.AvailabilityZone] != zonePicks[b.Aws.AvailabilityZone] {
+			return zonePicks[a.Aws.AvailabilityZone] < zonePicks[b.Aws.AvailabilityZone]
+		}
+		return domainLeft[a.failureDomain()] > domainLeft[b.failureDomain()]
+	}
+
+	remaining := append([]hostInfo(nil), hosts...)
+	var picked []hostInfo
+	for len(picked) < numToDeactivate {
+		best := -1
+		for i, host := range remaining {
+			if capped(host) {
+				continue
+			}
+			if best == -1 || less(host, remaining[best]) {
+				best = i
+			}
+		}
+		if best == -1 {
+			break
+		}
+		host := remaining[best]
+		picked = append(picked, host)
+		domainPicks[host.failureDomain()]++
+		zonePicks[host.Aws.AvailabilityZone]++
+		domainLeft[host.failureDomain()]--
+		remaining = append(remaining[:best], remaining[best+1:]...)
+	}
+
+	ordered := append(picked, remaining...)
+	if len(picked) < numToDeactivate {
+		return ordered, len(picked), fmt.Sprintf(
+			"failure domain guard: deactivating only %d of %d hosts, the failure domains of the rest already have %d hosts deactivating",
+			len(picked), numToDeactivate, protection)
+	}
+	return ordered, numToDeactivate, ""
+}
+
+// protectionDegraded describes why deactivating another backend could lose data, "" when data is fully protected
+func protectionDegraded(status weka.StatusResponse) string {
+	var reasons []string
+	if status.Rebuilding() {
+		reasons = append(reasons, fmt.Sprintf("rebuild is %d%% done", status.Rebuild.ProgressPercent))
+	}
+	if mib := status.DegradedMiB(); mib > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d MiB of data lost protection", mib))
+	}
+	if len(reasons) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s, %d of %d drives and %d of %d backends unavailable",
+		gostrings.Join(reasons, ", "),
+		status.Drives.Unavailable(), status.Drives.Total,
+		status.Hosts.Backends.Unavailable(), status.Hosts.Backends.Total)
+}
+
+// limitDeactivateByProtection holds back new deactivations while weka rebuilds data or its protection is degraded,
+// only hosts that are already deactivating or DOWN are kept among the hosts to deactivate, they are moved first
+func limitDeactivateByProtection(hosts []hostInfo, numToDeactivate int, status weka.StatusResponse) ([]hostInfo, int, string) {
+	degraded := protectionDegraded(status)
+	if degraded == "" {
+		return hosts, numToDeactivate, ""
+	}
+	var allowed, held []hostInfo
+	for _, host := range hosts[:numToDeactivate] {
+		if host.scaleState == DEACTIVATING || host.Status == "DOWN" {
+			allowed = append(allowed, host)
+		} else {
+			held = append(held, host)
+		}
+	}
+	if len(held) == 0 {
+		return hosts, numToDeactivate, ""
+	}
+	ordered := append(append(allowed, held...), hosts[numToDeactivate:]...)
+	return ordered, len(allowed), fmt.Sprintf(
+		"protection guard: deactivating only %d of %d hosts, %s, only hosts that are already DOWN are replaced",
+		len(allowed), numToDeactivate, degraded)
+}
+
+func getNumToDeactivate(hostInfo []hostInfo, desired, maxUnhealthy int) int {
+	/*
+		A - Fully active, healthy
+		T - Target state
+		U - Unhealthy, we want to remove it for whatever reason. DOWN host, FAILED drive, so on
+		D - Drives/hosts being deactivated
+		M - Max unhealthy hosts deactivating at once, from the scaling policy
+		new_D - Decision to start deactivating, i.e transition to D, basing on U. Never more then M for U
+
+		new_D = func(A, U, T, D)
+
+		new_D = max(A+U+D-T, min(M-D, U), 0)
+	*/
+
+	nHealthy := 0
+	nUnhealthy := 0
+	nDeactivating := 0
+
+	for _, host := range hostInfo {
+		switch host.scaleState {
+		case HEALTHY:
+			nHealthy++
+		case UNHEALTHY:
+			nUnhealthy++
+		case DEACTIVATING:
+			nDeactivating++
+		}
+	}
+
+	toDeactivate := calculateDeactivateTarget(nHealthy, nUnhealthy, nDeactivating, desired, maxUnhealthy)
+	log.Info().Msgf("%d hosts set to deactivate. nHealthy: %d nUnhealthy:%d nDeactivating: %d desired:%d", toDeactivate, nHealthy, nUnhealthy, nDeactivating, desired)
+	return toDeactivate
+}
+
+func activeDrivesBytes(drives map[weka.DriveId]weka.Drive) (size int64) {
+	for _, drive := range drives {
+		if drive.ShouldBeActive {
+			size += drive.SizeBytes
+		}
+	}
+	return
+}
+
+// limitDeactivateByCapacity caps the number of hosts to deactivate so the drives left active can still hold
+// the capacity provisioned to the filesystems, since filesystems are not shrunk on scale down.
+// The net capacity of the remaining drives is estimated by the current ratio of net capacity to raw drives size
+func limitDeactivateByCapacity(hostsList []hostInfo, numToDeactivate int, drives weka.DriveListResponse, capacity weka.Capacity) (int, string) {
+	provisioned := capacity.TotalBytes - capacity.UnprovisionedBytes
+	activeBytes := activeDrivesBytes(drives)
+	if provisioned <= 0 || activeBytes == 0 {
+		return numToDeactivate, ""
+	}
+	netRatio := float64(capacity.TotalBytes) / float64(activeBytes)
+
+	remainingBytes := activeBytes
+	for i, host := range hostsList[:numToDeactivate] {
+		hostBytes := activeDrivesBytes(host.drives)
+		if float64(remainingBytes-hostBytes)*netRatio < float64(provisioned) {
+			return i, fmt.Sprintf(
+				"capacity guard: deactivating only %d of %d hosts, deactivating host %s would leave %d net bytes for %d provisioned bytes",
+				i, numToDeactivate, host.id, int64(float64(remainingBytes-hostBytes)*netRatio), provisioned)
+		}
+		remainingBytes -= hostBytes
+	}
+	return numToDeactivate, ""
+}
+
+func calculateDeactivateTarget(nHealthy int, nUnhealthy int, nDeactivating int, desired int, maxUnhealthy int) int {
+	ret := math.Max(nHealthy+nUnhealthy+nDeactivating-desired, math.Min(maxUnhealthy-nDeactivating, nUnhealthy))
+	ret = math.Max(nDeactivating, ret)
+	return ret
+}
+
+func deriveHostState(host *hostInfo, policy cluster.ScalingPolicy, now time.Time) (hostState, string) {
+	if host.allDisksBeingRemoved() {
+		log.Info().Msgf("Marking %s as deactivating due to unhealthy disks", host.id.String())
+		return DEACTIVATING, "all drives being removed"
+	}
+	if strings.AnyOf(host.State, "DEACTIVATING", "REMOVING", "INACTIVE") {
+		return DEACTIVATING, fmt.Sprintf("already %s", host.State)
+	}
+	if host.Status == "DOWN" && host.managementTimedOut(now, policy.UnhealthyDeactivateTimeout) {
+		log.Info().Msgf("Marking %s as unhealthy due to DOWN", host.id.String())
+		return UNHEALTHY, "DOWN"
+	}
+	if host.numNotHealthyDrives() > 0 || host.anyDiskBeingRemoved() {
+		log.Info().Msgf("Marking %s as unhealthy due to unhealthy drives", host.id.String())
+		return UNHEALTHY, "unhealthy drives"
+	}
+	return HEALTHY, ""
+}
+
+func calculateHostsState(hosts []hostInfo, policy cluster.ScalingPolicy, now time.Time) {
+	for i := range hosts {
+		host := &hosts[i]
+		host.scaleState, host.scaleReason = deriveHostState(host, policy, now)
+	}
+}
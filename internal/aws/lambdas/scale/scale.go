@@ -7,275 +7,127 @@ import (
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"math/rand"
-	"sort"
 	"time"
 	"wekactl/internal/aws/lambdas/protocol"
 	"wekactl/internal/connectors"
 	"wekactl/internal/lib/jrpc"
-	"wekactl/internal/lib/math"
-	"wekactl/internal/lib/strings"
 	"wekactl/internal/lib/types"
 	"wekactl/internal/lib/weka"
 )
 
-type hostState int
-
-const unhealthyDeactivateTimeout = 120 * time.Minute
-const backendCleanupDelay = 5*time.Minute // Giving own HG chance to take care
-const downKickOutTimeout = 3 * time.Hour
-
-func (h hostState) String() string {
-	switch h {
-	case DEACTIVATING:
-		return "DEACTIVATING"
-	case HEALTHY:
-		return "HEALTHY"
-	case UNHEALTHY:
-		return "UNHEALTHY"
-	default:
-		return fmt.Sprintf("UNKNOWN(%d)", h)
-	}
-}
-
-const (
-	/*
-		Order matters, it defines priority of hosts removal
-	*/
-	DEACTIVATING hostState = iota
-	UNHEALTHY
-	HEALTHY
-)
-
-type driveMap map[weka.DriveId]weka.Drive
-type nodeMap map[weka.NodeId]weka.Node
-type hostInfo struct {
-	weka.Host
-	id         weka.HostId
-	drives     driveMap
-	nodes      nodeMap
-	scaleState hostState
-}
-
-func (host hostInfo) belongsToHg(instances []protocol.HgInstance) bool {
-	for _, instance := range instances {
-		if host.Aws.InstanceId == instance.Id {
-			return true
-		}
-	}
-	return false
-}
-
-func (host hostInfo) belongsToHgIpBased(instances []protocol.HgInstance) bool {
-	for _, instance := range instances {
-		if host.HostIp == instance.PrivateIp {
-			return true
-		}
-	}
-	return false
-}
-
-func (host hostInfo) numNotHealthyDrives() int {
-	notActive := 0
-	for _, drive := range host.drives {
-		if strings.AnyOf(drive.Status, "INACTIVE") {
-			notActive += 1
-		}
-	}
-	return notActive
+// ClusterApi is the part of the weka api a scale run uses, jrpc.Pool implements it
+type ClusterApi interface {
+	Call(method weka.JrpcMethod, params, result interface{}) error
+	Drop(ip string)
 }
 
-func (host hostInfo) allDisksBeingRemoved() bool {
-	ret := false
-	for _, drive := range host.drives {
-		ret = true
-		if drive.ShouldBeActive {
-			return false
-		}
-	}
-	return ret
-}
-
-func (host hostInfo) anyDiskBeingRemoved() bool {
-	for _, drive := range host.drives {
-		if !drive.ShouldBeActive {
-			return true
-		}
-	}
-	return false
-}
-
-func (host hostInfo) allDrivesInactive() bool {
-	for _, drive := range host.drives {
-		if drive.Status != "INACTIVE" {
-			return false
-		}
-	}
-	return true
-}
-
-func (host hostInfo) managementTimedOut(timeout time.Duration) bool {
-	for nodeId, node := range host.nodes {
-		if !nodeId.IsManagement() {
-			continue
-		}
-		if node.Status == "DOWN" && time.Since(*node.LastFencingTime) > timeout {
-			return true
-		}
-	}
-	return false
-}
-
-
 func Handler(ctx context.Context, info protocol.HostGroupInfoResponse) (response protocol.ScaleResponse, err error) {
-	/*
-		Code in here based on following logic:
-
-		A - Fully active, healthy
-		T - Desired target number
-		U - Unhealthy, we want to remove it for whatever reason. DOWN host, FAILED drive, so on
-		D - Drives/hosts being deactivated
-		NEW_D - Decision to start deactivating, i.e transition to D, basing on U. Never more then 2 for U
-
-		NEW_D = func(A, U, T, D)
+	return Scale(NewClusterApi(ctx, info), info, time.Now())
+}
 
-		NEW_D = max(A+U+D-T, min(2-D, U), 0)
-	*/
+// NewClusterApi returns a pool of the cluster backends management apis, shuffled so runs spread over the backends
+func NewClusterApi(ctx context.Context, info protocol.HostGroupInfoResponse) *jrpc.Pool {
 	jrpcBuilder := func(ip string) *jrpc.BaseClient {
 		return connectors.NewJrpcClient(ctx, ip, weka.ManagementJrpcPort, info.Username, info.Password)
 	}
 	ips := info.BackendIps
 	rand.Seed(time.Now().UnixNano())
 	rand.Shuffle(len(ips), func(i, j int) { ips[i], ips[j] = ips[j], ips[i] })
-	jpool := &jrpc.Pool{
+	return &jrpc.Pool{
 		Ips:     ips,
 		Clients: map[string]*jrpc.BaseClient{},
 		Active:  "",
 		Builder: jrpcBuilder,
 		Ctx:     ctx,
 	}
+}
 
-	systemStatus := weka.StatusResponse{}
-	hostsApiList := weka.HostListResponse{}
-	driveApiList := weka.DriveListResponse{}
-	nodeApiList := weka.NodeListResponse{}
-
-	err = jpool.Call(weka.JrpcStatus, struct{}{}, &systemStatus)
+func fetchClusterState(api ClusterApi, role string) (state clusterState, err error) {
+	err = api.Call(weka.JrpcStatus, struct{}{}, &state.status)
 	if err != nil {
 		return
 	}
-	err = isAllowedToScale(systemStatus)
+	err = isAllowedToScale(state.status)
 	if err != nil {
 		return
 	}
-	err = jpool.Call(weka.JrpcHostList, struct{}{}, &hostsApiList)
+	err = api.Call(weka.JrpcHostList, struct{}{}, &state.hosts)
 	if err != nil {
 		return
 	}
-	if info.Role == "backend" {
-		err = jpool.Call(weka.JrpcDrivesList, struct{}{}, &driveApiList)
+	if role == "backend" {
+		err = api.Call(weka.JrpcDrivesList, struct{}{}, &state.drives)
 		if err != nil {
 			return
 		}
 	}
-	err = jpool.Call(weka.JrpcNodeList, struct{}{}, &nodeApiList)
+	err = api.Call(weka.JrpcNodeList, struct{}{}, &state.nodes)
 	if err != nil {
 		return
 	}
+	if role == "client" {
+		fetchClientActivity(api, &state)
+	}
+	return
+}
 
-	hosts := map[weka.HostId]hostInfo{}
-	for hostId, host := range hostsApiList {
-		hosts[hostId] = hostInfo{
-			Host:   host,
-			id:     hostId,
-			drives: driveMap{},
-			nodes:  nodeMap{},
-		}
+// fetchClientActivity fetches the client mounts and frontend operations, when the cluster doesn't serve them
+// clients are scaled in by their added time alone
+func fetchClientActivity(api ClusterApi, state *clusterState) {
+	err := api.Call(weka.JrpcMountsList, struct{}{}, &state.mounts)
+	if err != nil {
+		log.Warn().Msgf("failed listing client mounts: %s", err)
 	}
-	for driveId, drive := range driveApiList {
-		if _, ok := hosts[drive.HostId]; ok {
-			hosts[drive.HostId].drives[driveId] = drive
-		}
+	err = api.Call(weka.JrpcStatsRealtime, struct{}{}, &state.stats)
+	if err != nil {
+		log.Warn().Msgf("failed getting frontend stats: %s", err)
 	}
+}
 
-	for nodeId, node := range nodeApiList {
-		if _, ok := hosts[node.HostId]; ok {
-			hosts[node.HostId].nodes[nodeId] = node
-		}
-	}
+// Scale decides which hosts to deactivate and remove by the cluster state, and applies the decision through the api
+func Scale(api ClusterApi, info protocol.HostGroupInfoResponse, now time.Time) (response protocol.ScaleResponse, err error) {
+	/*
+		Code in here based on following logic:
 
-	var hostsList []hostInfo
-	var inactiveHosts []hostInfo
-	var downHosts []hostInfo
+		A - Fully active, healthy
+		T - Desired target number
+		U - Unhealthy, we want to remove it for whatever reason. DOWN host, FAILED drive, so on
+		D - Drives/hosts being deactivated
+		M - Max unhealthy hosts deactivating at once, from the scaling policy
+		NEW_D - Decision to start deactivating, i.e transition to D, basing on U. Never more then M for U
 
-	for _, host := range hosts {
-		switch host.State {
-		case "INACTIVE":
-			if host.belongsToHgIpBased(info.Instances) {
-				inactiveHosts = append(inactiveHosts, host)
-			} else {
-				if info.Role == "backend" && time.Since(host.StateChangedTime) > backendCleanupDelay{
-					// Since terminate logic is mostly delta based, and remove might be transient errors
-					// We might have leftovers, that we are unable to recognize
-					// So decision is, to kick out whatever is inactive.
-					inactiveHosts = append(inactiveHosts, host)
-				}
-			}
-		default:
-			if host.belongsToHg(info.Instances) {
-				hostsList = append(hostsList, host)
-			} else if host.Status == "DOWN" {
-				log.Info().Msgf("found down host, %s : %s : %s", host.id, host.Status, host.HostIp)
-				if host.belongsToHgIpBased(info.Instances) {
-					log.Info().Msgf("including in known hosts  %s : %s", host.id, host.Status)
-					hostsList = append(hostsList, host)
-				}
-				// Down hosts lose instanceIds, so have to account basing on IPs
-			}
-		}
+		NEW_D = func(A, U, T, D)
 
-		switch host.Status {
-		case "DOWN":
-			if info.Role == "backend" {
-				if host.State != "INACTIVE" && host.managementTimedOut(downKickOutTimeout) {
-					log.Info().Msgf("host %s is still active but down for too long, kicking out", host.id)
-					downHosts = append(downHosts, host)
-				}
-			}
-		}
+		NEW_D = max(A+U+D-T, min(M-D, U), 0)
+	*/
+	response.Policy = info.Policy
+	response.TransientErrors = info.TransientErrors
+	if info.Pause.Active(now) {
+		// the cluster might be under maintenance, so it isn't even asked for its status
+		log.Info().Msgf("scaling is %s, leaving the cluster as is", info.Pause)
+		response.Paused = info.Pause.String()
+		return
 	}
+	state, err := fetchClusterState(api, info.Role)
+	if err != nil {
+		return
+	}
+	// the lifecycle lambda removes the hosts of terminating instances, they are left out of the host group
+	info.Instances = servingInstances(info.Instances)
+	decision := decide(state, info, now)
 
-	calculateHostsState(hostsList)
-
-	sort.Slice(hostsList, func(i, j int) bool {
-		// Giving priority to disks to hosts with disk being removed
-		// Then hosts with disks not in active state
-		// Then hosts sorted by add time
-		a := hostsList[i]
-		b := hostsList[j]
-		if a.scaleState < b.scaleState {
-			return true
-		}
-		if a.scaleState > b.scaleState {
-			return false
-		}
-		if a.numNotHealthyDrives() > b.numNotHealthyDrives() {
-			return true
-		}
-		if a.numNotHealthyDrives() < b.numNotHealthyDrives() {
-			return false
-		}
-		return a.AddedTime.Before(b.AddedTime)
-	})
-
-	removeInactive(inactiveHosts, jpool, info.Instances, &response)
-	removeOldDrives(driveApiList, jpool, &response)
-	numToDeactivate := getNumToDeactivate(hostsList, info.DesiredCapacity)
+	response.ScaleDownLimit = decision.scaleDownLimit
+	if decision.protectionLimit != "" {
+		response.AddTransientError(errors.New(decision.protectionLimit), "protectionGuard")
+	}
+	removeInactive(decision.inactiveHosts, api, info.Instances, &response)
+	removeOldDrives(state.drives, api, &response)
 
 	deactivateHost := func(host hostInfo) {
 		log.Info().Msgf("Trying to deactivate host %s", host.id)
 		for _, drive := range host.drives {
 			if drive.ShouldBeActive {
-				err := jpool.Call(weka.JrpcDeactivateDrives, types.JsonDict{
+				err := api.Call(weka.JrpcDeactivateDrives, types.JsonDict{
 					"drive_uuids": []uuid.UUID{drive.Uuid},
 				}, nil)
 				if err != nil {
@@ -286,8 +138,8 @@ func Handler(ctx context.Context, info protocol.HostGroupInfoResponse) (response
 		}
 
 		if host.allDrivesInactive() {
-			jpool.Drop(host.HostIp)
-			err := jpool.Call(weka.JrpcDeactivateHosts, types.JsonDict{
+			api.Drop(host.HostIp)
+			err := api.Call(weka.JrpcDeactivateHosts, types.JsonDict{
 				"host_ids":                 []weka.HostId{host.id},
 				"skip_resource_validation": false,
 			}, nil)
@@ -299,66 +151,39 @@ func Handler(ctx context.Context, info protocol.HostGroupInfoResponse) (response
 
 	}
 
-	for _, host := range hostsList[:numToDeactivate] {
+	for _, host := range decision.toDeactivate() {
 		deactivateHost(host)
+		response.AddDeactivated(host.Aws.InstanceId, host.id, deactivateReason(host))
 	}
 
-	for _, host := range downHosts {
+	for _, host := range decision.downHosts {
 		deactivateHost(host)
+		response.AddDeactivated(host.Aws.InstanceId, host.id, "down for too long, not in host group")
 	}
 
-	for _, host := range hostsList {
+	for _, host := range decision.hosts {
 		response.Hosts = append(response.Hosts, protocol.ScaleResponseHost{
 			InstanceId: host.Aws.InstanceId,
 			State:      host.State,
 			AddedTime:  host.AddedTime,
 			HostId:     host.id,
+			ScaleState: host.scaleState.String(),
 		})
 	}
 	return
 }
 
-func remoteDownHosts(hosts []hostInfo, jpool *jrpc.Pool) {
-
-}
-
-func getNumToDeactivate(hostInfo []hostInfo, desired int) int {
-	/*
-		A - Fully active, healthy
-		T - Target state
-		U - Unhealthy, we want to remove it for whatever reason. DOWN host, FAILED drive, so on
-		D - Drives/hosts being deactivated
-		new_D - Decision to start deactivating, i.e transition to D, basing on U. Never more then 2 for U
-
-		new_D = func(A, U, T, D)
-
-		new_D = max(A+U+D-T, min(2-D, U), 0)
-	*/
-
-	nHealthy := 0
-	nUnhealthy := 0
-	nDeactivating := 0
-
-	for _, host := range hostInfo {
-		switch host.scaleState {
-		case HEALTHY:
-			nHealthy++
-		case UNHEALTHY:
-			nUnhealthy++
-		case DEACTIVATING:
-			nDeactivating++
+func servingInstances(instances []protocol.HgInstance) (serving []protocol.HgInstance) {
+	for _, instance := range instances {
+		if !instance.Terminating {
+			serving = append(serving, instance)
 		}
 	}
-
-	toDeactivate := calculateDeactivateTarget(nHealthy, nUnhealthy, nDeactivating, desired)
-	log.Info().Msgf("%d hosts set to deactivate. nHealthy: %d nUnhealthy:%d nDeactivating: %d desired:%d", toDeactivate, nHealthy, nUnhealthy, nDeactivating, desired)
-	return toDeactivate
+	return
 }
 
-func calculateDeactivateTarget(nHealthy int, nUnhealthy int, nDeactivating int, desired int) int {
-	ret := math.Max(nHealthy+nUnhealthy+nDeactivating-desired, math.Min(2-nDeactivating, nUnhealthy))
-	ret = math.Max(nDeactivating, ret)
-	return ret
+func remoteDownHosts(hosts []hostInfo, api ClusterApi) {
+
 }
 
 func isAllowedToScale(status weka.StatusResponse) error {
@@ -372,32 +197,6 @@ func isAllowedToScale(status weka.StatusResponse) error {
 	return nil
 }
 
-func deriveHostState(host *hostInfo) hostState {
-	if host.allDisksBeingRemoved() {
-		log.Info().Msgf("Marking %s as deactivating due to unhealthy disks", host.id.String())
-		return DEACTIVATING
-	}
-	if strings.AnyOf(host.State, "DEACTIVATING", "REMOVING", "INACTIVE") {
-		return DEACTIVATING
-	}
-	if host.Status == "DOWN" && host.managementTimedOut(unhealthyDeactivateTimeout) {
-		log.Info().Msgf("Marking %s as unhealthy due to DOWN", host.id.String())
-		return UNHEALTHY
-	}
-	if host.numNotHealthyDrives() > 0 || host.anyDiskBeingRemoved() {
-		log.Info().Msgf("Marking %s as unhealthy due to unhealthy drives", host.id.String())
-		return UNHEALTHY
-	}
-	return HEALTHY
-}
-
-func calculateHostsState(hosts []hostInfo) {
-	for i := range hosts {
-		host := &hosts[i]
-		host.scaleState = deriveHostState(host)
-	}
-}
-
 func selectInstanceByIp(ip string, instances []protocol.HgInstance) *protocol.HgInstance {
 	for _, i := range instances {
 		if i.PrivateIp == ip {
@@ -407,10 +206,10 @@ func selectInstanceByIp(ip string, instances []protocol.HgInstance) *protocol.Hg
 	return nil
 }
 
-func removeInactive(hosts []hostInfo, jpool *jrpc.Pool, instances []protocol.HgInstance, p *protocol.ScaleResponse) {
+func removeInactive(hosts []hostInfo, api ClusterApi, instances []protocol.HgInstance, p *protocol.ScaleResponse) {
 	for _, host := range hosts {
-		jpool.Drop(host.HostIp)
-		err := jpool.Call(weka.JrpcRemoveHost, types.JsonDict{
+		api.Drop(host.HostIp)
+		err := api.Call(weka.JrpcRemoveHost, types.JsonDict{
 			"host_id": host.id.Int(),
 			"no_wait": true,
 		}, nil)
@@ -422,25 +221,28 @@ func removeInactive(hosts []hostInfo, jpool *jrpc.Pool, instances []protocol.HgI
 		instance := selectInstanceByIp(host.HostIp, instances)
 		if instance != nil {
 			p.ToTerminate = append(p.ToTerminate, *instance)
+			p.AddRemovedInactive(instance.Id, host.id, "inactive")
+		} else {
+			p.AddRemovedInactive(host.Aws.InstanceId, host.id, "inactive leftover, not in host group")
 		}
 
 		for _, drive := range host.drives {
-			removeDrive(jpool, drive, p)
+			removeDrive(api, drive, p)
 		}
 	}
 	return
 }
 
-func removeOldDrives(drives weka.DriveListResponse, jpool *jrpc.Pool, p *protocol.ScaleResponse) {
+func removeOldDrives(drives weka.DriveListResponse, api ClusterApi, p *protocol.ScaleResponse) {
 	for _, drive := range drives {
 		if drive.HostId.Int() == -1 && drive.Status == "INACTIVE" {
-			removeDrive(jpool, drive, p)
+			removeDrive(api, drive, p)
 		}
 	}
 }
 
-func removeDrive(jpool *jrpc.Pool, drive weka.Drive, p *protocol.ScaleResponse) {
-	err := jpool.Call(weka.JrpcRemoveDrive, types.JsonDict{
+func removeDrive(api ClusterApi, drive weka.Drive, p *protocol.ScaleResponse) {
+	err := api.Call(weka.JrpcRemoveDrive, types.JsonDict{
 		"drive_uuids": []uuid.UUID{drive.Uuid},
 	}, nil)
 	if err != nil {
@@ -1,6 +1,17 @@
 package scale
 
-import "testing"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	gostrings "strings"
+	"testing"
+	"time"
+	"wekactl/internal/aws/lambdas/protocol"
+	"wekactl/internal/cluster"
+	"wekactl/internal/lib/weka"
+	"wekactl/internal/lib/weka/fake"
+)
 
 func Test_calculateDeactivateTarget(t *testing.T) {
 	type args struct {
@@ -27,9 +38,446 @@ func Test_calculateDeactivateTarget(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := calculateDeactivateTarget(tt.args.nHealthy, tt.args.nUnhealthy, tt.args.nDeactivating, tt.args.desired); got != tt.want {
+			if got := calculateDeactivateTarget(tt.args.nHealthy, tt.args.nUnhealthy, tt.args.nDeactivating, tt.args.desired, 2); got != tt.want {
 				t.Errorf("calculateDeactivateTarget() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func Test_limitDeactivateByCapacity(t *testing.T) {
+	const driveBytes = 1000
+	newHosts := func(nHosts int) ([]hostInfo, weka.DriveListResponse) {
+		var hosts []hostInfo
+		drives := weka.DriveListResponse{}
+		for i := 0; i < nHosts; i++ {
+			host := hostInfo{drives: driveMap{}}
+			for j := 0; j < 2; j++ {
+				driveId := weka.DriveId{}
+				_ = driveId.UnmarshalText([]byte(fmt.Sprintf("DiskId<%d>", i*2+j)))
+				drive := weka.Drive{ShouldBeActive: true, SizeBytes: driveBytes}
+				host.drives[driveId] = drive
+				drives[driveId] = drive
+			}
+			hosts = append(hosts, host)
+		}
+		return hosts, drives
+	}
+
+	type args struct {
+		nHosts          int
+		numToDeactivate int
+		capacity        weka.Capacity
+	}
+	tests := []struct {
+		name        string
+		args        args
+		want        int
+		wantLimited bool
+	}{
+		// 10 hosts of 2000 raw bytes each, net capacity is half of the raw size
+		{"emptyFilesystems", args{10, 4, weka.Capacity{TotalBytes: 10000, UnprovisionedBytes: 10000}}, 4, false},
+		{"fits", args{10, 4, weka.Capacity{TotalBytes: 10000, UnprovisionedBytes: 5000}}, 4, false},
+		{"fitsExactly", args{10, 4, weka.Capacity{TotalBytes: 10000, UnprovisionedBytes: 4000}}, 4, false},
+		{"capped", args{10, 4, weka.Capacity{TotalBytes: 10000, UnprovisionedBytes: 2000}}, 2, true},
+		{"refused", args{10, 4, weka.Capacity{TotalBytes: 10000, UnprovisionedBytes: 500}}, 0, true},
+		{"noCapacityInfo", args{10, 4, weka.Capacity{}}, 4, false},
+		{"nothingToDeactivate", args{10, 0, weka.Capacity{TotalBytes: 10000}}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hosts, drives := newHosts(tt.args.nHosts)
+			got, reason := limitDeactivateByCapacity(hosts, tt.args.numToDeactivate, drives, tt.args.capacity)
+			if got != tt.want {
+				t.Errorf("limitDeactivateByCapacity() = %v, want %v", got, tt.want)
+			}
+			if (reason != "") != tt.wantLimited {
+				t.Errorf("limitDeactivateByCapacity() reason = %q, wantLimited %v", reason, tt.wantLimited)
+			}
+		})
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	snapshot := Snapshot{
+		weka.JrpcStatus: json.RawMessage(`{"io_status": "STARTED", "upgrade": ""}`),
+		weka.JrpcHostList: json.RawMessage(`{
+			"HostId<0>": {"mode": "backend", "state": "ACTIVE", "status": "UP", "host_ip": "10.0.0.0", "added_time": "2021-01-12T08:00:00Z", "aws": {"instance_id": "i-0"}},
+			"HostId<1>": {"mode": "backend", "state": "ACTIVE", "status": "UP", "host_ip": "10.0.0.1", "added_time": "2021-01-12T08:01:00Z", "aws": {"instance_id": "i-1"}},
+			"HostId<2>": {"mode": "backend", "state": "ACTIVE", "status": "UP", "host_ip": "10.0.0.2", "added_time": "2021-01-12T08:02:00Z", "aws": {"instance_id": "i-2"}},
+			"HostId<3>": {"mode": "backend", "state": "ACTIVE", "status": "DOWN", "host_ip": "10.0.0.3", "added_time": "2021-01-12T08:03:00Z", "aws": {"instance_id": "i-3"}},
+			"HostId<4>": {"mode": "backend", "state": "INACTIVE", "status": "UP", "host_ip": "10.0.0.4", "added_time": "2021-01-12T08:04:00Z", "aws": {"instance_id": "i-4"}},
+			"HostId<5>": {"mode": "client", "state": "ACTIVE", "status": "UP", "host_ip": "10.0.0.5", "added_time": "2021-01-12T08:05:00Z", "aws": {"instance_id": "i-5"}}
+		}`),
+		weka.JrpcDrivesList: json.RawMessage(`{
+			"DiskId<0>": {"host_id": "HostId<0>", "status": "ACTIVE", "should_be_active": true, "uuid": "00000000-0000-0000-0000-000000000000"},
+			"DiskId<1>": {"host_id": "HostId<1>", "status": "INACTIVE", "should_be_active": true, "uuid": "00000000-0000-0000-0000-000000000001"},
+			"DiskId<2>": {"host_id": "HostId<2>", "status": "ACTIVE", "should_be_active": true, "uuid": "00000000-0000-0000-0000-000000000002"},
+			"DiskId<3>": {"host_id": "HostId<3>", "status": "ACTIVE", "should_be_active": true, "uuid": "00000000-0000-0000-0000-000000000003"},
+			"DiskId<4>": {"host_id": "HostId<4>", "status": "INACTIVE", "should_be_active": false, "uuid": "00000000-0000-0000-0000-000000000004"}
+		}`),
+		weka.JrpcNodeList: json.RawMessage(`{
+			"NodeId<60>": {"host_id": "HostId<3>", "status": "DOWN", "last_fencing_time": "2021-01-12T09:30:00Z"}
+		}`),
+	}
+	instances, err := SnapshotInstances(snapshot, "backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 5 {
+		t.Fatalf("SnapshotInstances() returned %d instances, want 5", len(instances))
+	}
+
+	now, _ := time.Parse(time.RFC3339, "2021-01-12T12:00:00Z")
+	response, calls, err := Simulate(snapshot, protocol.HostGroupInfoResponse{
+		DesiredCapacity: 3,
+		Instances:       instances,
+		Role:            "backend",
+		Policy:          cluster.DefaultScalingPolicy(),
+	}, now)
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	if len(response.Hosts) != 4 {
+		t.Errorf("Simulate() response has %d hosts, want 4", len(response.Hosts))
+	}
+	var deactivated []string
+	for _, decision := range response.Deactivated {
+		deactivated = append(deactivated, decision.InstanceId+":"+decision.Reason)
+	}
+	if want := []string{"i-1:unhealthy drives", "i-3:DOWN"}; fmt.Sprint(deactivated) != fmt.Sprint(want) {
+		t.Errorf("Simulate() deactivated %v, want %v", deactivated, want)
+	}
+	if len(response.ToTerminate) != 1 || response.ToTerminate[0].Id != "i-4" {
+		t.Errorf("Simulate() to terminate %v, want i-4", response.ToTerminate)
+	}
+
+	methods := map[weka.JrpcMethod]int{}
+	for _, call := range calls {
+		methods[call.Method]++
+	}
+	want := map[weka.JrpcMethod]int{
+		weka.JrpcRemoveHost:       1,
+		weka.JrpcRemoveDrive:      1,
+		weka.JrpcDeactivateDrives: 2,
+		weka.JrpcDeactivateHosts:  1,
+	}
+	if fmt.Sprint(methods) != fmt.Sprint(want) {
+		t.Errorf("Simulate() calls %v, want %v", methods, want)
+	}
+
+	// the lifecycle lambda drains terminating instances, the scale run leaves them out
+	for i := range instances {
+		instances[i].Terminating = instances[i].Id == "i-2"
+	}
+	response, _, err = Simulate(snapshot, protocol.HostGroupInfoResponse{
+		DesiredCapacity: 2,
+		Instances:       instances,
+		Role:            "backend",
+		Policy:          cluster.DefaultScalingPolicy(),
+	}, now)
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+	for _, host := range response.Hosts {
+		if host.InstanceId == "i-2" {
+			t.Error("Simulate() included the terminating instance i-2 in the host group")
+		}
+	}
+	for _, decision := range response.Deactivated {
+		if decision.InstanceId == "i-2" {
+			t.Error("Simulate() deactivated the terminating instance i-2")
+		}
+	}
+
+	delete(snapshot, weka.JrpcStatus)
+	if _, _, err = Simulate(snapshot, protocol.HostGroupInfoResponse{Role: "backend"}, now); err == nil {
+		t.Error("Simulate() without a recorded status succeeded")
+	}
+
+	pause := cluster.ScalingPause{PausedBy: "admin", PausedAt: now.Add(-time.Hour), Until: now.Add(time.Hour)}
+	response, calls, err = Simulate(snapshot, protocol.HostGroupInfoResponse{Role: "backend", Pause: pause}, now)
+	if err != nil {
+		t.Fatalf("Simulate() while paused error = %v", err)
+	}
+	if response.Paused != pause.String() || len(calls) != 0 {
+		t.Errorf("Simulate() while paused = %q with %d calls, want %q with none", response.Paused, len(calls), pause.String())
+	}
+}
+
+func TestSimulateClients(t *testing.T) {
+	snapshot := Snapshot{
+		weka.JrpcStatus: json.RawMessage(`{"io_status": "STARTED", "upgrade": ""}`),
+		weka.JrpcHostList: json.RawMessage(`{
+			"HostId<0>": {"mode": "client", "state": "ACTIVE", "status": "UP", "host_ip": "10.0.0.0", "added_time": "2021-01-12T08:00:00Z", "aws": {"instance_id": "i-0"}},
+			"HostId<1>": {"mode": "client", "state": "ACTIVE", "status": "UP", "host_ip": "10.0.0.1", "added_time": "2021-01-12T08:01:00Z", "aws": {"instance_id": "i-1"}},
+			"HostId<2>": {"mode": "client", "state": "ACTIVE", "status": "UP", "host_ip": "10.0.0.2", "added_time": "2021-01-12T08:02:00Z", "aws": {"instance_id": "i-2"}},
+			"HostId<3>": {"mode": "client", "state": "ACTIVE", "status": "UP", "host_ip": "10.0.0.3", "added_time": "2021-01-12T08:03:00Z", "aws": {"instance_id": "i-3"}}
+		}`),
+		weka.JrpcNodeList: json.RawMessage(`{
+			"NodeId<0>": {"host_id": "HostId<0>", "status": "UP"},
+			"NodeId<20>": {"host_id": "HostId<1>", "status": "UP"},
+			"NodeId<40>": {"host_id": "HostId<2>", "status": "UP"},
+			"NodeId<60>": {"host_id": "HostId<3>", "status": "UP"}
+		}`),
+		weka.JrpcMountsList: json.RawMessage(`{
+			"HostId<0>": [{"filesystem": "default", "mount_point": "/mnt/weka"}, {"filesystem": "scratch", "mount_point": "/mnt/scratch"}]
+		}`),
+		weka.JrpcStatsRealtime: json.RawMessage(`{"NodeId<40>": {"ops": 500}}`),
+	}
+	instances, err := SnapshotInstances(snapshot, "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range instances {
+		instances[i].Protected = instances[i].Id == "i-1"
+	}
+	now, _ := time.Parse(time.RFC3339, "2021-01-12T12:00:00Z")
+	info := protocol.HostGroupInfoResponse{
+		DesiredCapacity: 2,
+		Instances:       instances,
+		Role:            "client",
+		Policy:          cluster.DefaultScalingPolicy(),
+	}
+
+	deactivated := func(response protocol.ScaleResponse) (ids []string) {
+		for _, decision := range response.Deactivated {
+			ids = append(ids, decision.InstanceId)
+		}
+		return
+	}
+	// i-1 is protected, i-3 is idle, i-2 serves ops and i-0 has mounts
+	response, _, err := Simulate(snapshot, info, now)
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+	if got, want := deactivated(response), []string{"i-3", "i-2"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Simulate() deactivated %v, want %v", got, want)
+	}
+
+	info.DesiredCapacity = 0
+	response, _, err = Simulate(snapshot, info, now)
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+	if got, want := deactivated(response), []string{"i-3", "i-2", "i-0"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Simulate() deactivated %v, want %v", got, want)
+	}
+	if response.ScaleDownLimit == "" {
+		t.Error("Simulate() didn't report the protected host it kept")
+	}
+
+	// without activity data clients are scaled in by added time
+	delete(snapshot, weka.JrpcMountsList)
+	delete(snapshot, weka.JrpcStatsRealtime)
+	info.DesiredCapacity = 2
+	response, _, err = Simulate(snapshot, info, now)
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+	if got, want := deactivated(response), []string{"i-0", "i-2"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Simulate() deactivated %v, want %v", got, want)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	wekaCluster := fake.NewCluster("admin", "password")
+	// the lambda connects to the weka management port, so every backend listens on its own loopback address
+	backendIps := []string{"127.0.0.21", "127.0.0.22"}
+	for _, ip := range backendIps {
+		server, err := wekaCluster.ListenManagement(ip)
+		if err != nil {
+			t.Skipf("can't listen on %s: %v", ip, err)
+		}
+		defer server.Close()
+	}
+
+	var instances []protocol.HgInstance
+	var hostIds []weka.HostId
+	for i := 0; i < 4; i++ {
+		instance := protocol.HgInstance{Id: fmt.Sprintf("i-%d", i), PrivateIp: fmt.Sprintf("10.0.0.%d", i)}
+		instances = append(instances, instance)
+		hostIds = append(hostIds, wekaCluster.AddHost("backend", instance.Id, instance.PrivateIp, 2))
+	}
+	if err := wekaCluster.SetHostDrivesStatus(hostIds[1], "INACTIVE"); err != nil {
+		t.Fatal(err)
+	}
+	info := protocol.HostGroupInfoResponse{
+		Username:        "admin",
+		Password:        "password",
+		DesiredCapacity: 4,
+		Instances:       instances,
+		BackendIps:      backendIps,
+		Role:            "backend",
+		Policy:          cluster.DefaultScalingPolicy(),
+	}
+
+	response, err := Handler(context.Background(), info)
+	if err != nil {
+		t.Fatalf("Handler() error = %v", err)
+	}
+	if len(response.Deactivated) != 1 || response.Deactivated[0].InstanceId != "i-1" {
+		t.Fatalf("Handler() deactivated %+v, want i-1", response.Deactivated)
+	}
+	if len(response.TransientErrors) != 0 {
+		t.Errorf("Handler() transient errors %v", response.TransientErrors)
+	}
+	if state := wekaCluster.HostStates()["i-1"]; state != "DEACTIVATING" {
+		t.Errorf("host i-1 state = %s, want DEACTIVATING", state)
+	}
+
+	wekaCluster.Advance(fake.DeactivateDuration)
+	response, err = Handler(context.Background(), info)
+	if err != nil {
+		t.Fatalf("Handler() error = %v", err)
+	}
+	if len(response.ToTerminate) != 1 || response.ToTerminate[0].Id != "i-1" {
+		t.Errorf("Handler() to terminate %+v, want i-1", response.ToTerminate)
+	}
+	if _, ok := wekaCluster.HostStates()["i-1"]; ok {
+		t.Error("Handler() didn't remove the inactive host i-1")
+	}
+	if removed := wekaCluster.CalledMethods()[string(weka.JrpcRemoveDrive)]; removed != 2 {
+		t.Errorf("Handler() removed %d drives, want 2", removed)
+	}
+
+	// while data is rebuilt only DOWN hosts are replaced
+	wekaCluster.SetRebuild(40, 1024)
+	info.DesiredCapacity = 2
+	response, err = Handler(context.Background(), info)
+	if err != nil {
+		t.Fatalf("Handler() error = %v", err)
+	}
+	if len(response.Deactivated) != 0 || len(response.TransientErrors) != 1 {
+		t.Errorf("Handler() while rebuilding deactivated %+v with transient errors %v, want none and the protection guard", response.Deactivated, response.TransientErrors)
+	}
+	if err = wekaCluster.SetHostDown(hostIds[3], time.Now().Add(-150*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	response, err = Handler(context.Background(), info)
+	if err != nil {
+		t.Fatalf("Handler() error = %v", err)
+	}
+	if len(response.Deactivated) != 1 || response.Deactivated[0].InstanceId != "i-3" {
+		t.Errorf("Handler() while rebuilding deactivated %+v, want the DOWN host i-3", response.Deactivated)
+	}
+
+	wekaCluster.SetIoStatus("STOPPED", "")
+	if _, err = Handler(context.Background(), info); err == nil {
+		t.Error("Handler() with io stopped succeeded")
+	}
+}
+
+func Test_limitDeactivateByProtection(t *testing.T) {
+	newHost := func(instanceId string, state hostState, status string) hostInfo {
+		host := hostInfo{scaleState: state}
+		host.Aws.InstanceId = instanceId
+		host.Status = status
+		return host
+	}
+	hosts := []hostInfo{
+		newHost("i-0", DEACTIVATING, "UP"),
+		newHost("i-1", UNHEALTHY, "UP"),
+		newHost("i-2", UNHEALTHY, "DOWN"),
+		newHost("i-3", HEALTHY, "UP"),
+	}
+	rebuilding := weka.StatusResponse{Rebuild: weka.Rebuild{ProgressPercent: 40}}
+	degraded := weka.StatusResponse{Rebuild: weka.Rebuild{ProtectionState: []weka.ProtectionState{
+		{MiB: 1000, NumFailures: 0},
+		{MiB: 10, NumFailures: 1},
+	}}}
+	tests := []struct {
+		name      string
+		num       int
+		status    weka.StatusResponse
+		want      []string
+		wantLimit bool
+	}{
+		{"protected", 3, weka.StatusResponse{}, []string{"i-0", "i-1", "i-2"}, false},
+		{"rebuilding", 3, rebuilding, []string{"i-0", "i-2"}, true},
+		{"degraded", 4, degraded, []string{"i-0", "i-2"}, true},
+		{"nothingHeld", 1, degraded, []string{"i-0"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, num, limit := limitDeactivateByProtection(hosts, tt.num, tt.status)
+			var got []string
+			for _, host := range ordered[:num] {
+				got = append(got, host.Aws.InstanceId)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("limitDeactivateByProtection() = %v, want %v", got, tt.want)
+			}
+			if len(ordered) != len(hosts) {
+				t.Errorf("limitDeactivateByProtection() returned %d hosts, want %d", len(ordered), len(hosts))
+			}
+			if (limit != "") != tt.wantLimit {
+				t.Errorf("limitDeactivateByProtection() limit = %q, wantLimit %v", limit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func Test_spreadDeactivation(t *testing.T) {
+	// hosts are given as "instance/state/failure domain/zone", an empty failure domain is the host own one
+	newHosts := func(specs ...string) (hosts []hostInfo) {
+		for i, spec := range specs {
+			var instanceId, domain, zone string
+			var state hostState
+			var stateName string
+			_, _ = fmt.Sscanf(gostrings.ReplaceAll(spec, "/", " "), "%s %s %s %s", &instanceId, &stateName, &domain, &zone)
+			switch stateName {
+			case "D":
+				state = DEACTIVATING
+			case "U":
+				state = UNHEALTHY
+			default:
+				state = HEALTHY
+			}
+			host := hostInfo{scaleState: state}
+			_ = host.id.UnmarshalText([]byte(fmt.Sprintf("HostId<%d>", i)))
+			host.Aws.InstanceId = instanceId
+			host.Aws.AvailabilityZone = zone
+			if domain != "-" {
+				host.FailureDomain = domain
+			}
+			hosts = append(hosts, host)
+		}
+		return
+	}
+	tests := []struct {
+		name       string
+		hosts      []hostInfo
+		num        int
+		protection int
+		want       []string
+		wantLimit  bool
+	}{
+		{"noDomains", newHosts("i-0/H/-/-", "i-1/H/-/-", "i-2/H/-/-"), 2, 2, []string{"i-0", "i-1"}, false},
+		{"zones", newHosts("i-0/H/-/a", "i-1/H/-/a", "i-2/H/-/a", "i-3/H/-/b", "i-4/H/-/b", "i-5/H/-/c"), 3, 2, []string{"i-0", "i-3", "i-5"}, false},
+		{"largestDomainFirst", newHosts("i-0/H/d1/a", "i-1/H/d2/a", "i-2/H/d2/a"), 1, 2, []string{"i-1"}, false},
+		{"unhealthyFirst", newHosts("i-0/U/-/a", "i-1/U/-/a", "i-2/H/-/b", "i-3/H/-/c"), 2, 2, []string{"i-0", "i-1"}, false},
+		{"domainCap", newHosts("i-0/H/d1/a", "i-1/H/d1/a", "i-2/H/d1/a", "i-3/H/d2/b"), 3, 1, []string{"i-0", "i-3"}, true},
+		{"deactivatingCountsToCap", newHosts("i-0/D/d1/a", "i-1/H/d1/a", "i-2/H/d2/b"), 2, 1, []string{"i-0", "i-2"}, false},
+		{"noProtectionLevel", newHosts("i-0/H/d1/a", "i-1/H/d1/a"), 2, 0, []string{"i-0", "i-1"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, num, limit := spreadDeactivation(tt.hosts, tt.num, tt.protection)
+			var got []string
+			for _, host := range ordered[:num] {
+				got = append(got, host.Aws.InstanceId)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("spreadDeactivation() = %v, want %v", got, tt.want)
+			}
+			if len(ordered) != len(tt.hosts) {
+				t.Errorf("spreadDeactivation() returned %d hosts, want %d", len(ordered), len(tt.hosts))
+			}
+			if (limit != "") != tt.wantLimit {
+				t.Errorf("spreadDeactivation() limit = %q, wantLimit %v", limit, tt.wantLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package scale
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"wekactl/internal/aws/lambdas/protocol"
+	"wekactl/internal/lib/weka"
+)
+
+// Snapshot holds recorded weka api responses, keyed by the method that returned them
+type Snapshot map[weka.JrpcMethod]json.RawMessage
+
+// SimulatedCall is a call that changes the cluster, which a simulated scale run made instead of the lambda
+type SimulatedCall struct {
+	Method weka.JrpcMethod
+	Params interface{}
+}
+
+type simulatedApi struct {
+	snapshot Snapshot
+	calls    []SimulatedCall
+}
+
+func (s *simulatedApi) Call(method weka.JrpcMethod, params, result interface{}) error {
+	switch method {
+	case weka.JrpcStatus, weka.JrpcHostList, weka.JrpcDrivesList, weka.JrpcNodeList, weka.JrpcMountsList, weka.JrpcStatsRealtime:
+		recorded, ok := s.snapshot[method]
+		if !ok {
+			return errors.New(fmt.Sprintf("no recorded response for %s", method))
+		}
+		return json.Unmarshal(recorded, result)
+	default:
+		s.calls = append(s.calls, SimulatedCall{Method: method, Params: params})
+		return nil
+	}
+}
+
+func (s *simulatedApi) Drop(ip string) {}
+
+// Simulate runs the scale decisions on recorded api responses as if it was now, and returns the lambda response
+// along with the calls it would have made to change the cluster
+func Simulate(snapshot Snapshot, info protocol.HostGroupInfoResponse, now time.Time) (protocol.ScaleResponse, []SimulatedCall, error) {
+	api := &simulatedApi{snapshot: snapshot}
+	response, err := Scale(api, info, now)
+	return response, api.calls, err
+}
+
+// SnapshotInstances returns the host group instances as the hosts of the role in the recorded hosts list,
+// for simulating without the auto scaling group
+func SnapshotInstances(snapshot Snapshot, role string) (instances []protocol.HgInstance, err error) {
+	hosts := weka.HostListResponse{}
+	err = json.Unmarshal(snapshot[weka.JrpcHostList], &hosts)
+	if err != nil {
+		return
+	}
+	for _, host := range hosts {
+		if host.Mode == role && host.Aws.InstanceId != "" {
+			instances = append(instances, protocol.HgInstance{Id: host.Aws.InstanceId, PrivateIp: host.HostIp})
+		}
+	}
+	return
+}
@@ -10,10 +10,11 @@ import (
 	"time"
 	autoscaling2 "wekactl/internal/aws/autoscaling"
 	"wekactl/internal/aws/common"
+	"wekactl/internal/aws/db"
 	"wekactl/internal/aws/lambdas/protocol"
-	"wekactl/internal/connectors"
-	"wekactl/internal/lib/strings"
+	"wekactl/internal/cluster"
 	"wekactl/internal/lib/types"
+	"wekactl/internal/lib/weka"
 )
 
 type instancesMap map[string]*ec2.Instance
@@ -46,17 +47,14 @@ func getDeltaInstancesIds(asgInstanceIds []*string, scaleResponse protocol.Scale
 	return deltaInstanceIDs, nil
 }
 
-func removeAutoScalingProtection(asgName string, instanceIds []string) error {
-	svc := connectors.GetAWSSession().ASG
-	_, err := svc.SetInstanceProtection(&autoscaling.SetInstanceProtectionInput{
-		AutoScalingGroupName: &asgName,
-		InstanceIds:          strings.ListToRefList(instanceIds),
-		ProtectedFromScaleIn: aws.Bool(false),
-	})
-	if err != nil {
-		return err
+// inServiceInstanceIds leaves out the instances that are still launching or already terminating
+func inServiceInstanceIds(instances []*autoscaling.Instance) (instanceIds []*string) {
+	for _, instance := range instances {
+		if aws.StringValue(instance.LifecycleState) == autoscaling.LifecycleStateInService {
+			instanceIds = append(instanceIds, instance.InstanceId)
+		}
 	}
-	return nil
+	return
 }
 
 func setForExplicitRemoval(instance *ec2.Instance, toRemove []protocol.HgInstance) bool {
@@ -68,29 +66,17 @@ func setForExplicitRemoval(instance *ec2.Instance, toRemove []protocol.HgInstanc
 	return false
 }
 
-func terminateInstances(instanceIds []string) (terminatingInstances []*string, err error) {
-	svc := connectors.GetAWSSession().EC2
-	log.Info().Msgf("Terminating instances %s", instanceIds)
-	res, err := svc.TerminateInstances(&ec2.TerminateInstancesInput{
-		InstanceIds: strings.ListToRefList(instanceIds),
-	})
-	if err != nil {
-		log.Error().Msgf("error terminating instances %s", err.Error())
-		return
-	}
-	for _, terminatingInstance := range res.TerminatingInstances {
-		terminatingInstances = append(terminatingInstances, terminatingInstance.InstanceId)
-	}
-	return
-}
-
-func terminateUnneededInstances(asgName string, instances []*ec2.Instance, explicitRemoval []protocol.HgInstance) (terminated []*ec2.Instance, errs []error) {
+func terminateUnneededInstances(asgName string, instances []*ec2.Instance, explicitRemoval []protocol.HgInstance, launchGracePeriod time.Duration) (terminated []*ec2.Instance, errs []error) {
 	terminateInstanceIds := make([]string, 0, 0)
 	imap := instancesToMap(instances)
 
 	for _, instance := range instances {
+		if common.IsInstanceProtected(instance) {
+			log.Info().Msgf("instance %s is tagged %s, not terminating it", *instance.InstanceId, common.ProtectTagKey)
+			continue
+		}
 		if !setForExplicitRemoval(instance, explicitRemoval) {
-			if time.Now().Sub(*instance.LaunchTime) < time.Minute*30 {
+			if time.Now().Sub(*instance.LaunchTime) < launchGracePeriod {
 				continue
 			}
 		}
@@ -103,30 +89,10 @@ func terminateUnneededInstances(asgName string, instances []*ec2.Instance, expli
 	if len(terminateInstanceIds) == 0 {
 		return
 	}
-	setToTerminate, errs := common.SetDisableInstancesApiTermination(
-		terminateInstanceIds[:common.Min(len(terminateInstanceIds), 50)],
-		false,
-	)
-
-	err := removeAutoScalingProtection(asgName, setToTerminate)
-	if err != nil {
-		// WARNING: This is debatable if error here is transient or not
-		//	Specifically now we can return empty list of what we were able to terminate because this API call failed
-		//   But in future with adding more lambdas into state machine this might become wrong decision
-		log.Error().Err(err)
-		setToTerminate = setToTerminate[:0]
-		errs = append(errs, err)
-	}
-
-	terminatingInstances, err := terminateInstances(setToTerminate)
-	if err != nil {
-		log.Error().Err(err)
-		errs = append(errs, err)
-		return
-	}
-
+	// the termination lifecycle hook holds the instances until the lifecycle lambda removes their weka hosts
+	terminatingInstances, errs := autoscaling2.TerminateInstances(asgName, terminateInstanceIds[:common.Min(len(terminateInstanceIds), 50)])
 	for _, id := range terminatingInstances {
-		terminated = append(terminated, imap[*id])
+		terminated = append(terminated, imap[id])
 	}
 	return
 }
@@ -138,6 +104,9 @@ func Handler(scaleResponse protocol.ScaleResponse) (response protocol.Terminated
 		return
 	}
 	response.TransientErrors = scaleResponse.TransientErrors[0:len(scaleResponse.TransientErrors):len(scaleResponse.TransientErrors)]
+	defer func() {
+		recordScaleHistory(os.Getenv("CLUSTER_NAME"), asgName, scaleResponse, response, err)
+	}()
 
 	asgInstances, err := common.GetASGInstances(asgName)
 	asgInstanceIds := common.UnpackASGInstanceIds(asgInstances)
@@ -146,13 +115,26 @@ func Handler(scaleResponse protocol.ScaleResponse) (response protocol.Terminated
 		return
 	}
 
+	err = protectFromScaleIn(asgName, asgInstances)
+	if err != nil {
+		log.Error().Msgf("error setting scale in protection: %s", err)
+		response.AddTransientError(err, "scale in protection")
+		err = nil
+	}
+
 	err = detachUnhealthyInstances(asgInstances, asgName)
 	if err != nil {
-		log.Error().Msgf("error detaching instances", err)
+		log.Error().Msgf("error detaching instances: %s", err)
 		response.AddTransientError(err, "detach unhealthy")
+		err = nil
+	}
+
+	if scaleResponse.Paused != "" {
+		log.Info().Msgf("scaling is %s, not terminating instances", scaleResponse.Paused)
+		return
 	}
 
-	deltaInstanceIds, err := getDeltaInstancesIds(asgInstanceIds, scaleResponse)
+	deltaInstanceIds, err := getDeltaInstancesIds(inServiceInstanceIds(asgInstances), scaleResponse)
 	if err != nil {
 		return
 	}
@@ -166,8 +148,8 @@ func Handler(scaleResponse protocol.ScaleResponse) (response protocol.Terminated
 		return
 	}
 
-	terminatedInstances, errs := terminateUnneededInstances(asgName, candidatesToTerminate, scaleResponse.ToTerminate)
-	response.AddTransientErrors(errs)
+	terminatedInstances, errs := terminateUnneededInstances(asgName, candidatesToTerminate, scaleResponse.ToTerminate, scaleResponse.Policy.LaunchGracePeriod)
+	response.AddTransientErrors(errs, "terminate")
 
 	//detachTerminated(asgName)
 
@@ -181,10 +163,64 @@ func Handler(scaleResponse protocol.ScaleResponse) (response protocol.Terminated
 	return
 }
 
+func scaleHistoryHost(instanceId string, hostId weka.HostId, state, scaleState, reason string) cluster.ScaleHistoryHost {
+	return cluster.ScaleHistoryHost{
+		HostId:     hostId.String(),
+		InstanceId: instanceId,
+		State:      state,
+		ScaleState: scaleState,
+		Reason:     reason,
+	}
+}
+
+// recordScaleHistory saves the decisions of the state machine run, failing to save them doesn't fail the run
+func recordScaleHistory(clusterName, asgName string, scaleResponse protocol.ScaleResponse, response protocol.TerminatedInstancesResponse, err error) {
+	record := cluster.ScaleHistoryRecord{
+		Time:            time.Now().UTC(),
+		ScaleDownLimit:  scaleResponse.ScaleDownLimit,
+		Paused:          scaleResponse.Paused,
+		TransientErrors: response.TransientErrors,
+	}
+	for _, host := range scaleResponse.Hosts {
+		record.Hosts = append(record.Hosts, scaleHistoryHost(host.InstanceId, host.HostId, host.State, host.ScaleState, ""))
+	}
+	for _, host := range scaleResponse.Deactivated {
+		record.Deactivated = append(record.Deactivated, scaleHistoryHost(host.InstanceId, host.HostId, "", "", host.Reason))
+	}
+	for _, host := range scaleResponse.RemovedInactive {
+		record.RemovedInactive = append(record.RemovedInactive, scaleHistoryHost(host.InstanceId, host.HostId, "", "", host.Reason))
+	}
+	for _, instance := range response.Instances {
+		record.Terminated = append(record.Terminated, instance.InstanceId)
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	historyErr := db.SaveScaleHistory(db.HistoryTableName(cluster.ClusterName(clusterName)), asgName, record)
+	if historyErr != nil {
+		log.Error().Err(historyErr).Msg("failed recording scale history")
+	}
+}
+
+// protectFromScaleIn protects the in service instances that aren't protected from scale in yet, so lowering the
+// auto scaling group desired capacity leaves picking the instances to the scale lambda
+func protectFromScaleIn(asgName string, asgInstances []*autoscaling.Instance) error {
+	var toProtect []string
+	for _, instance := range asgInstances {
+		if aws.StringValue(instance.LifecycleState) == autoscaling.LifecycleStateInService && !aws.BoolValue(instance.ProtectedFromScaleIn) {
+			toProtect = append(toProtect, *instance.InstanceId)
+		}
+	}
+	return autoscaling2.ProtectFromScaleIn(asgName, toProtect)
+}
+
+// detachUnhealthyInstances detaches the unhealthy instances that aren't terminating through the auto scaling group,
+// like instances terminated through EC2, the auto scaling group doesn't replace unhealthy instances by itself
 func detachUnhealthyInstances(instances []*autoscaling.Instance, asgName string) error {
 	toDetach := []*string{}
 	for _, instance := range instances {
-		if *instance.HealthStatus == "Unhealthy" && !*instance.ProtectedFromScaleIn {
+		if *instance.HealthStatus == "Unhealthy" && aws.StringValue(instance.LifecycleState) == autoscaling.LifecycleStateInService {
 			log.Info().Msgf("detaching %s", *instance.InstanceId)
 			toDetach = append(toDetach, instance.InstanceId)
 		}
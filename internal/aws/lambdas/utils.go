@@ -2,15 +2,29 @@ package lambdas
 
 import (
 	"github.com/aws/aws-sdk-go/service/autoscaling"
+	autoscaling2 "wekactl/internal/aws/autoscaling"
 	"wekactl/internal/aws/db"
 )
 
+// getAutoScalingGroupDesiredCapacity returns the desired capacity in hosts. The desired capacity of a group with
+// weighted instance types counts capacity units, they are converted by the average weight of the group instances,
+// rounded up so the scale lambda doesn't deactivate the group below its desired capacity
 func getAutoScalingGroupDesiredCapacity(asgOutput *autoscaling.DescribeAutoScalingGroupsOutput) int {
 	if len(asgOutput.AutoScalingGroups) == 0 {
 		return -1
 	}
 
-	return int(*asgOutput.AutoScalingGroups[0].DesiredCapacity)
+	asg := asgOutput.AutoScalingGroups[0]
+	desired := *asg.DesiredCapacity
+	instances := int64(len(asg.Instances))
+	var units int64
+	for _, instance := range asg.Instances {
+		units += autoscaling2.InstanceWeight(instance)
+	}
+	if units == instances {
+		return int(desired)
+	}
+	return int((desired*instances + units - 1) / units)
 }
 
 func getUsernameAndPassword(tableName string) (creds db.ClusterCreds, err error) {
@@ -0,0 +1,40 @@
+package lambdas
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"testing"
+)
+
+func TestAutoScalingGroupDesiredCapacity(t *testing.T) {
+	group := func(desired int64, weights ...string) *autoscaling.DescribeAutoScalingGroupsOutput {
+		asg := &autoscaling.Group{DesiredCapacity: aws.Int64(desired)}
+		for _, weight := range weights {
+			instance := &autoscaling.Instance{}
+			if weight != "" {
+				instance.WeightedCapacity = aws.String(weight)
+			}
+			asg.Instances = append(asg.Instances, instance)
+		}
+		return &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{asg}}
+	}
+	tests := []struct {
+		name  string
+		asg   *autoscaling.DescribeAutoScalingGroupsOutput
+		hosts int
+	}{
+		{"no group", &autoscaling.DescribeAutoScalingGroupsOutput{}, -1},
+		{"no instances", group(6), 6},
+		{"unweighted", group(6, "", "", ""), 6},
+		{"weight 1", group(2, "1", "1", "1"), 2},
+		{"weight 2", group(6, "2", "2", "2", "2"), 3},
+		{"mixed weights", group(6, "2", "2", "2", "1", "1"), 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if hosts := getAutoScalingGroupDesiredCapacity(tt.asg); hosts != tt.hosts {
+				t.Errorf("getAutoScalingGroupDesiredCapacity() = %d, want %d", hosts, tt.hosts)
+			}
+		})
+	}
+}
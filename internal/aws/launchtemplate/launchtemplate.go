@@ -2,16 +2,19 @@ package launchtemplate
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/lithammer/dedent"
 	"github.com/rs/zerolog/log"
+	"strconv"
 	"wekactl/internal/aws/apigateway"
 	"wekactl/internal/aws/common"
 	"wekactl/internal/cluster"
 	"wekactl/internal/connectors"
+	"wekactl/internal/lib/strings"
 )
 
 func generateBlockDeviceMappingRequest(name common.HostGroupName, volumeInfo VolumeInfo) []*ec2.LaunchTemplateBlockDeviceMappingRequest {
@@ -30,49 +33,57 @@ func generateBlockDeviceMappingRequest(name common.HostGroupName, volumeInfo Vol
 	}
 }
 
-func CreateLaunchTemplate(tags []*ec2.Tag, hostGroupName common.HostGroupName, hostGroupParams common.HostGroupParams, restApiGateway apigateway.RestApiGateway, launchTemplateName string) (err error) {
-	svc := connectors.GetAWSSession().EC2
+func generateLaunchTemplateData(tags []*ec2.Tag, hostGroupName common.HostGroupName, hostGroupParams common.HostGroupParams, restApiGateway apigateway.RestApiGateway) *ec2.RequestLaunchTemplateData {
+	// the join lambda allocates cores by the instance type, which differs between instances of a mixed host group
 	userDataTemplate := `
 	#!/usr/bin/env bash
 	
-	if ! curl --location --request GET '%s' --header 'x-api-key: %s' | sudo sh; then
+	token=$(curl -s -X PUT 'http://169.254.169.254/latest/api/token' --header 'X-aws-ec2-metadata-token-ttl-seconds: 300')
+	instance_type=$(curl -s 'http://169.254.169.254/latest/meta-data/instance-type' --header "X-aws-ec2-metadata-token: $token")
+	
+	if ! curl --location --request GET "%s?instance_type=$instance_type" --header 'x-api-key: %s' | sudo sh; then
 		shutdown now
 	fi
 	`
 
 	userData := fmt.Sprintf(dedent.Dedent(userDataTemplate), restApiGateway.Url(), restApiGateway.ApiKey)
-	input := &ec2.CreateLaunchTemplateInput{
-		LaunchTemplateData: &ec2.RequestLaunchTemplateData{
-			ImageId:               &hostGroupParams.ImageID,
-			InstanceType:          &hostGroupParams.InstanceType,
-			KeyName:               &hostGroupParams.KeyName,
-			UserData:              aws.String(base64.StdEncoding.EncodeToString([]byte(userData))),
-			DisableApiTermination: aws.Bool(true),
-			IamInstanceProfile: &ec2.LaunchTemplateIamInstanceProfileSpecificationRequest{
-				Arn: &hostGroupParams.IamArn,
-			},
-			BlockDeviceMappings: generateBlockDeviceMappingRequest(hostGroupName, VolumeInfo{
-				Name: hostGroupParams.VolumeName,
-				Type: hostGroupParams.VolumeType,
-				Size: hostGroupParams.VolumeSize,
-			}),
-			TagSpecifications: []*ec2.LaunchTemplateTagSpecificationRequest{
-				{
-					ResourceType: aws.String("instance"),
-					Tags:         tags,
-				},
+	return &ec2.RequestLaunchTemplateData{
+		ImageId:               &hostGroupParams.ImageID,
+		InstanceType:          &hostGroupParams.InstanceType,
+		KeyName:               &hostGroupParams.KeyName,
+		UserData:              aws.String(base64.StdEncoding.EncodeToString([]byte(userData))),
+		DisableApiTermination: aws.Bool(true),
+		IamInstanceProfile: &ec2.LaunchTemplateIamInstanceProfileSpecificationRequest{
+			Arn: &hostGroupParams.IamArn,
+		},
+		BlockDeviceMappings: generateBlockDeviceMappingRequest(hostGroupName, VolumeInfo{
+			Name: hostGroupParams.VolumeName,
+			Type: hostGroupParams.VolumeType,
+			Size: hostGroupParams.VolumeSize,
+		}),
+		TagSpecifications: []*ec2.LaunchTemplateTagSpecificationRequest{
+			{
+				ResourceType: aws.String("instance"),
+				Tags:         tags,
 			},
-			NetworkInterfaces: []*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
-				{
-					AssociatePublicIpAddress: aws.Bool(true),
-					DeviceIndex:              aws.Int64(0),
-					Ipv6AddressCount:         aws.Int64(0),
-					SubnetId:                 &hostGroupParams.Subnet,
-					Groups:                   hostGroupParams.SecurityGroupsIds,
-				},
+		},
+		NetworkInterfaces: []*ec2.LaunchTemplateInstanceNetworkInterfaceSpecificationRequest{
+			{
+				AssociatePublicIpAddress: aws.Bool(true),
+				DeviceIndex:              aws.Int64(0),
+				Ipv6AddressCount:         aws.Int64(0),
+				SubnetId:                 &hostGroupParams.Subnet,
+				Groups:                   hostGroupParams.SecurityGroupsIds,
 			},
 		},
-		VersionDescription: aws.String("v1"),
+	}
+}
+
+func CreateLaunchTemplate(tags []*ec2.Tag, hostGroupName common.HostGroupName, hostGroupParams common.HostGroupParams, restApiGateway apigateway.RestApiGateway, launchTemplateName, version string) (err error) {
+	svc := connectors.GetAWSSession().EC2
+	input := &ec2.CreateLaunchTemplateInput{
+		LaunchTemplateData: generateLaunchTemplateData(tags, hostGroupName, hostGroupParams, restApiGateway),
+		VersionDescription: aws.String(version),
 		LaunchTemplateName: aws.String(launchTemplateName),
 		TagSpecifications: []*ec2.TagSpecification{
 			{
@@ -90,6 +101,36 @@ func CreateLaunchTemplate(tags []*ec2.Tag, hostGroupName common.HostGroupName, h
 	return
 }
 
+// UpdateLaunchTemplate creates a new launch template version and makes it the default one
+func UpdateLaunchTemplate(tags []*ec2.Tag, hostGroupName common.HostGroupName, hostGroupParams common.HostGroupParams, restApiGateway apigateway.RestApiGateway, launchTemplateName, version string) (err error) {
+	svc := connectors.GetAWSSession().EC2
+	versionOutput, err := svc.CreateLaunchTemplateVersion(&ec2.CreateLaunchTemplateVersionInput{
+		LaunchTemplateData: generateLaunchTemplateData(tags, hostGroupName, hostGroupParams, restApiGateway),
+		VersionDescription: aws.String(version),
+		LaunchTemplateName: aws.String(launchTemplateName),
+	})
+	if err != nil {
+		return
+	}
+	versionNumber := strconv.FormatInt(*versionOutput.LaunchTemplateVersion.VersionNumber, 10)
+	log.Debug().Msgf("LaunchTemplate: \"%s\" version %s was created successfully!", launchTemplateName, versionNumber)
+
+	_, err = svc.ModifyLaunchTemplate(&ec2.ModifyLaunchTemplateInput{
+		LaunchTemplateName: aws.String(launchTemplateName),
+		DefaultVersion:     aws.String(versionNumber),
+	})
+	if err != nil {
+		return
+	}
+	log.Debug().Msgf("LaunchTemplate: \"%s\" default version was set to %s", launchTemplateName, versionNumber)
+
+	_, err = svc.CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{versionOutput.LaunchTemplateVersion.LaunchTemplateId},
+		Tags:      tags,
+	})
+	return
+}
+
 func DeleteLaunchTemplate(launchTemplateName string) error {
 	svc := connectors.GetAWSSession().EC2
 	_, err := svc.DeleteLaunchTemplate(&ec2.DeleteLaunchTemplateInput{
@@ -133,3 +174,91 @@ func GetLaunchTemplateVersion(launchTemplateName string) (version string, err er
 
 	return
 }
+
+func GetDefaultLaunchTemplateData(launchTemplateName string) (*ec2.ResponseLaunchTemplateData, error) {
+	svc := connectors.GetAWSSession().EC2
+	launchTemplateVersionsOutput, err := svc.DescribeLaunchTemplateVersions(&ec2.DescribeLaunchTemplateVersionsInput{
+		LaunchTemplateName: &launchTemplateName,
+		Versions:           []*string{aws.String("$Default")},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(launchTemplateVersionsOutput.LaunchTemplateVersions) == 0 {
+		return nil, errors.New(fmt.Sprintf("launch template %s has no default version", launchTemplateName))
+	}
+	return launchTemplateVersionsOutput.LaunchTemplateVersions[0].LaunchTemplateData, nil
+}
+
+func decodeUserData(userData *string) string {
+	decoded, err := base64.StdEncoding.DecodeString(aws.StringValue(userData))
+	if err != nil {
+		return aws.StringValue(userData)
+	}
+	return string(decoded)
+}
+
+// DiffLaunchTemplate compares the launch template default version with the data CreateLaunchTemplate would use
+func DiffLaunchTemplate(tags []*ec2.Tag, hostGroupName common.HostGroupName, hostGroupParams common.HostGroupParams, restApiGateway apigateway.RestApiGateway, launchTemplateName string) (drifts cluster.Drifts, err error) {
+	actual, err := GetDefaultLaunchTemplateData(launchTemplateName)
+	if err != nil {
+		return
+	}
+	expected := generateLaunchTemplateData(tags, hostGroupName, hostGroupParams, restApiGateway)
+
+	drifts.Compare("image_id", aws.StringValue(expected.ImageId), aws.StringValue(actual.ImageId))
+	drifts.Compare("instance_type", aws.StringValue(expected.InstanceType), aws.StringValue(actual.InstanceType))
+	drifts.Compare("key_name", aws.StringValue(expected.KeyName), aws.StringValue(actual.KeyName))
+	drifts.Compare("user_data", decodeUserData(expected.UserData), decodeUserData(actual.UserData))
+	drifts.Compare("disable_api_termination",
+		strconv.FormatBool(aws.BoolValue(expected.DisableApiTermination)), strconv.FormatBool(aws.BoolValue(actual.DisableApiTermination)))
+
+	actualIamArn := ""
+	if actual.IamInstanceProfile != nil {
+		actualIamArn = aws.StringValue(actual.IamInstanceProfile.Arn)
+	}
+	drifts.Compare("iam_instance_profile", aws.StringValue(expected.IamInstanceProfile.Arn), actualIamArn)
+
+	expectedDevice := expected.BlockDeviceMappings[0]
+	if len(actual.BlockDeviceMappings) == 0 || actual.BlockDeviceMappings[0].Ebs == nil {
+		drifts.Compare("volume", aws.StringValue(expectedDevice.DeviceName), "")
+	} else {
+		actualDevice := actual.BlockDeviceMappings[0]
+		drifts.Compare("volume_name", aws.StringValue(expectedDevice.DeviceName), aws.StringValue(actualDevice.DeviceName))
+		drifts.Compare("volume_type", aws.StringValue(expectedDevice.Ebs.VolumeType), aws.StringValue(actualDevice.Ebs.VolumeType))
+		drifts.Compare("volume_size",
+			strconv.FormatInt(aws.Int64Value(expectedDevice.Ebs.VolumeSize), 10), strconv.FormatInt(aws.Int64Value(actualDevice.Ebs.VolumeSize), 10))
+	}
+
+	expectedInterface := expected.NetworkInterfaces[0]
+	if len(actual.NetworkInterfaces) == 0 {
+		drifts.Compare("subnet", aws.StringValue(expectedInterface.SubnetId), "")
+	} else {
+		actualInterface := actual.NetworkInterfaces[0]
+		drifts.Compare("subnet", aws.StringValue(expectedInterface.SubnetId), aws.StringValue(actualInterface.SubnetId))
+		drifts.CompareList("security_groups",
+			strings.RefListToList(expectedInterface.Groups), strings.RefListToList(actualInterface.Groups))
+		drifts.Compare("associate_public_ip",
+			strconv.FormatBool(aws.BoolValue(expectedInterface.AssociatePublicIpAddress)), strconv.FormatBool(aws.BoolValue(actualInterface.AssociatePublicIpAddress)))
+	}
+	return
+}
+
+// GetClusterLaunchTemplates returns the names of all the launch templates tagged with the cluster name
+func GetClusterLaunchTemplates(clusterName cluster.ClusterName) (launchTemplateNames []string, err error) {
+	svc := connectors.GetAWSSession().EC2
+	err = svc.DescribeLaunchTemplatesPages(&ec2.DescribeLaunchTemplatesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("tag:" + cluster.ClusterNameTagKey),
+				Values: []*string{aws.String(string(clusterName))},
+			},
+		},
+	}, func(page *ec2.DescribeLaunchTemplatesOutput, lastPage bool) bool {
+		for _, launchTemplate := range page.LaunchTemplates {
+			launchTemplateNames = append(launchTemplateNames, *launchTemplate.LaunchTemplateName)
+		}
+		return true
+	})
+	return
+}
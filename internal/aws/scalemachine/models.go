@@ -25,6 +25,9 @@ type StateMachine struct {
 
 type StateMachineLambdasArn struct {
 	Fetch     string
+	Lifecycle string
+	// Metrics is empty for host groups without a metrics lambda
+	Metrics   string
 	Scale     string
 	Terminate string
 	Transient string
@@ -2,6 +2,8 @@ package scalemachine
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sfn"
 	"github.com/rs/zerolog/log"
@@ -9,15 +11,30 @@ import (
 	"wekactl/internal/connectors"
 )
 
-func CreateStateMachine(tags []*sfn.Tag, lambda StateMachineLambdasArn, roleArn, stateMachineName string) (*string, error) {
-	svc := connectors.GetAWSSession().SFN
-
+func generateStateMachineDefinition(lambda StateMachineLambdasArn) (string, error) {
 	states := make(map[string]interface{})
 	states["HostGroupInfo"] = NextState{
 		Type:     "Task",
 		Resource: lambda.Fetch,
+		Next:     "Lifecycle",
+	}
+	states["Lifecycle"] = NextState{
+		Type:     "Task",
+		Resource: lambda.Lifecycle,
 		Next:     "Scale",
 	}
+	if lambda.Metrics != "" {
+		states["Lifecycle"] = NextState{
+			Type:     "Task",
+			Resource: lambda.Lifecycle,
+			Next:     "Metrics",
+		}
+		states["Metrics"] = NextState{
+			Type:     "Task",
+			Resource: lambda.Metrics,
+			Next:     "Scale",
+		}
+	}
 	states["Scale"] = NextState{
 		Type:     "Task",
 		Resource: lambda.Scale,
@@ -59,9 +76,18 @@ func CreateStateMachine(tags []*sfn.Tag, lambda StateMachineLambdasArn, roleArn,
 	b, err := json.Marshal(&stateMachine)
 	if err != nil {
 		log.Debug().Msg("Error marshaling stateMachine")
+		return "", err
+	}
+	return string(b), nil
+}
+
+func CreateStateMachine(tags []*sfn.Tag, lambda StateMachineLambdasArn, roleArn, stateMachineName string) (*string, error) {
+	svc := connectors.GetAWSSession().SFN
+
+	definition, err := generateStateMachineDefinition(lambda)
+	if err != nil {
 		return nil, err
 	}
-	definition := string(b)
 	log.Debug().Msgf("Creating state machine :%s", stateMachineName)
 
 	result, err := svc.CreateStateMachine(&sfn.CreateStateMachineInput{
@@ -77,70 +103,173 @@ func CreateStateMachine(tags []*sfn.Tag, lambda StateMachineLambdasArn, roleArn,
 	return result.StateMachineArn, nil
 }
 
-func DeleteStateMachine(stateMachineName string) error {
+func UpdateStateMachine(versionTag []*sfn.Tag, lambda StateMachineLambdasArn, roleArn, stateMachineName string) (*string, error) {
 	svc := connectors.GetAWSSession().SFN
 
-	stateMachinesOutput, err := svc.ListStateMachines(&sfn.ListStateMachinesInput{})
+	arn, err := GetStateMachineArn(stateMachineName)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if arn == "" {
+		return nil, errors.New(fmt.Sprintf("state machine %s wasn't found", stateMachineName))
+	}
+
+	definition, err := generateStateMachineDefinition(lambda)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = svc.UpdateStateMachine(&sfn.UpdateStateMachineInput{
+		StateMachineArn: &arn,
+		RoleArn:         &roleArn,
+		Definition:      aws.String(definition),
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().Msgf("State machine %s was updated successfully!", stateMachineName)
+
+	_, err = svc.TagResource(&sfn.TagResourceInput{
+		ResourceArn: &arn,
+		Tags:        versionTag,
+	})
+	if err != nil {
+		return nil, err
 	}
-	for _, stateMachine := range stateMachinesOutput.StateMachines {
-		if *stateMachine.Name != stateMachineName {
-			continue
+	return &arn, nil
+}
+
+func getStateMachine(stateMachineName string) (stateMachine *sfn.StateMachineListItem, err error) {
+	svc := connectors.GetAWSSession().SFN
+	err = svc.ListStateMachinesPages(&sfn.ListStateMachinesInput{}, func(page *sfn.ListStateMachinesOutput, lastPage bool) bool {
+		for _, item := range page.StateMachines {
+			if *item.Name == stateMachineName {
+				stateMachine = item
+				return false
+			}
 		}
-		_, err = svc.DeleteStateMachine(&sfn.DeleteStateMachineInput{
-			StateMachineArn: stateMachine.StateMachineArn,
-		})
-		if err != nil {
+		return true
+	})
+	return
+}
+
+func DeleteStateMachineArn(stateMachineArn string) error {
+	svc := connectors.GetAWSSession().SFN
+	_, err := svc.DeleteStateMachine(&sfn.DeleteStateMachineInput{
+		StateMachineArn: &stateMachineArn,
+	})
+	if err != nil {
+		if _, ok := err.(*sfn.StateMachineDoesNotExist); !ok {
 			return err
 		}
-		log.Debug().Msgf("state machine %s was deleted successfully", stateMachineName)
 	}
-
+	log.Debug().Msgf("state machine %s was deleted successfully", stateMachineArn)
 	return nil
 }
 
+func DeleteStateMachine(stateMachineName string) error {
+	stateMachine, err := getStateMachine(stateMachineName)
+	if err != nil || stateMachine == nil {
+		return err
+	}
+	return DeleteStateMachineArn(*stateMachine.StateMachineArn)
+}
+
 func GetStateMachineVersion(stateMachineName string) (version string, err error) {
-	svc := connectors.GetAWSSession().SFN
+	stateMachine, err := getStateMachine(stateMachineName)
+	if err != nil || stateMachine == nil {
+		return
+	}
 
-	stateMachinesOutput, err := svc.ListStateMachines(&sfn.ListStateMachinesInput{})
+	svc := connectors.GetAWSSession().SFN
+	tagsOutput, err := svc.ListTagsForResource(&sfn.ListTagsForResourceInput{
+		ResourceArn: stateMachine.StateMachineArn,
+	})
 	if err != nil {
 		return
 	}
-	for _, stateMachine := range stateMachinesOutput.StateMachines {
-		if *stateMachine.Name != stateMachineName {
-			continue
-		}
-		tagsOutput, err := svc.ListTagsForResource(&sfn.ListTagsForResourceInput{
-			ResourceArn: stateMachine.StateMachineArn,
-		})
-		if err != nil {
-			return "", err
-		}
-		for _, tag := range tagsOutput.Tags {
-			if *tag.Key == cluster.VersionTagKey {
-				version = *tag.Value
-				return version, nil
-			}
+	for _, tag := range tagsOutput.Tags {
+		if *tag.Key == cluster.VersionTagKey {
+			version = *tag.Value
+			return
 		}
 	}
-
 	return
 }
 
 func GetStateMachineArn(stateMachineName string) (arn string, err error) {
+	stateMachine, err := getStateMachine(stateMachineName)
+	if err != nil || stateMachine == nil {
+		return
+	}
+	arn = *stateMachine.StateMachineArn
+	return
+}
+
+// DiffStateMachine compares the state machine definition and role with the ones CreateStateMachine would use
+func DiffStateMachine(lambda StateMachineLambdasArn, roleArn, stateMachineName string) (drifts cluster.Drifts, err error) {
 	svc := connectors.GetAWSSession().SFN
 
-	stateMachinesOutput, err := svc.ListStateMachines(&sfn.ListStateMachinesInput{})
+	arn, err := GetStateMachineArn(stateMachineName)
+	if err != nil || arn == "" {
+		return
+	}
+	stateMachineOutput, err := svc.DescribeStateMachine(&sfn.DescribeStateMachineInput{
+		StateMachineArn: &arn,
+	})
+	if err != nil {
+		return
+	}
+
+	definition, err := generateStateMachineDefinition(lambda)
 	if err != nil {
 		return
 	}
-	for _, stateMachine := range stateMachinesOutput.StateMachines {
-		if *stateMachine.Name != stateMachineName {
-			continue
+	drifts.CompareJson("definition", definition, aws.StringValue(stateMachineOutput.Definition))
+	drifts.Compare("role_arn", roleArn, aws.StringValue(stateMachineOutput.RoleArn))
+	return
+}
+
+// GetClusterStateMachines returns all the state machines tagged with the cluster name
+func GetClusterStateMachines(clusterName cluster.ClusterName) (stateMachines []*sfn.StateMachineListItem, err error) {
+	svc := connectors.GetAWSSession().SFN
+	var tagsErr error
+	err = svc.ListStateMachinesPages(&sfn.ListStateMachinesInput{}, func(page *sfn.ListStateMachinesOutput, lastPage bool) bool {
+		for _, stateMachine := range page.StateMachines {
+			var tagsOutput *sfn.ListTagsForResourceOutput
+			tagsOutput, tagsErr = svc.ListTagsForResource(&sfn.ListTagsForResourceInput{
+				ResourceArn: stateMachine.StateMachineArn,
+			})
+			if tagsErr != nil {
+				return false
+			}
+			for _, tag := range tagsOutput.Tags {
+				if *tag.Key == cluster.ClusterNameTagKey && *tag.Value == string(clusterName) {
+					stateMachines = append(stateMachines, stateMachine)
+					break
+				}
+			}
 		}
-		arn = *stateMachine.StateMachineArn
-		break
+		return true
+	})
+	if err == nil {
+		err = tagsErr
 	}
 	return
-}
\ No newline at end of file
+}
+
+// GetLastExecution returns the state machine most recent execution, or nil if it was never executed
+func GetLastExecution(stateMachineArn string) (*sfn.ExecutionListItem, error) {
+	svc := connectors.GetAWSSession().SFN
+	executionsOutput, err := svc.ListExecutions(&sfn.ListExecutionsInput{
+		StateMachineArn: &stateMachineArn,
+		MaxResults:      aws.Int64(1),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(executionsOutput.Executions) == 0 {
+		return nil, nil
+	}
+	return executionsOutput.Executions[0], nil
+}
@@ -1,14 +1,9 @@
 package cluster
 
 import (
-	"errors"
-	"fmt"
 	"github.com/spf13/cobra"
-	"wekactl/internal/aws/common"
-	"wekactl/internal/aws/db"
-	"wekactl/internal/cluster"
-	"wekactl/internal/env"
 	"wekactl/internal/logging"
+	"wekactl/internal/provider"
 )
 
 var changeCredentialsCmd = &cobra.Command{
@@ -16,19 +11,17 @@ var changeCredentialsCmd = &cobra.Command{
 	Short: "",
 	Long:  "",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if env.Config.Provider == "aws" {
-			tableNAme := common.GenerateResourceName(cluster.ClusterName(importParams.name), "")
-			err := db.ChangeCredentials(tableNAme, importParams.username, importParams.password)
-			if err != nil {
-				logging.UserFailure("Credentials change failed!")
-				return err
-			}
-			logging.UserSuccess("Credentials change finished successfully!")
-		} else {
-			err := errors.New(fmt.Sprintf("Cloud provider '%s' is not supported with this action", env.Config.Provider))
+		p, err := provider.Current()
+		if err != nil {
 			logging.UserFailure(err.Error())
 			return err
 		}
+		err = p.ChangeCredentials(importParams.name, importParams.username, importParams.password)
+		if err != nil {
+			logging.UserFailure("Credentials change failed!")
+			return err
+		}
+		logging.UserSuccess("Credentials change finished successfully!")
 		return nil
 	},
 }
@@ -3,6 +3,7 @@ package cluster
 import (
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"wekactl/internal/cluster"
 )
 
 var Region string
@@ -26,6 +27,13 @@ func init() {
 	Cluster.AddCommand(listCmd)
 	Cluster.AddCommand(destroyCmd)
 	Cluster.AddCommand(updateCmd)
+	Cluster.AddCommand(diffCmd)
+	Cluster.AddCommand(statusCmd)
+	Cluster.AddCommand(reconcileInventoryCmd)
 	Cluster.AddCommand(changeCredentialsCmd)
+	Cluster.AddCommand(pauseScalingCmd)
+	Cluster.AddCommand(resumeScalingCmd)
+	Cluster.AddCommand(setCoresCmd)
+	Cluster.PersistentFlags().IntVarP(&cluster.Workers, "workers", "w", cluster.DefaultWorkers, "Max number of resources to reconcile concurrently")
 	_ = Cluster.MarkPersistentFlagRequired("region")
 }
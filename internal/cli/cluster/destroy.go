@@ -1,77 +1,71 @@
 package cluster
 
 import (
-	"errors"
-	"fmt"
 	"github.com/spf13/cobra"
-	"wekactl/internal/aws/autoscaling"
-	cluster2 "wekactl/internal/aws/cluster"
-	"wekactl/internal/aws/common"
-	"wekactl/internal/aws/db"
 	"wekactl/internal/cluster"
-	"wekactl/internal/env"
+	"wekactl/internal/lib/table"
 	"wekactl/internal/logging"
+	"wekactl/internal/provider"
 )
 
 var keepInstances bool
+var destroyTagged bool
+var destroyYes bool
+
+func destroyTaggedResources(p provider.Provider) error {
+	resources, err := p.DiscoverTaggedResources(StackName, keepInstances)
+	if err != nil {
+		logging.UserFailure("Discovering cluster resources failed!")
+		return err
+	}
+	if len(resources) == 0 {
+		logging.UserSuccess("No resources tagged with cluster %s were found", StackName)
+		return nil
+	}
+
+	var data [][]string
+	for _, resource := range resources {
+		data = append(data, []string{resource.Type, resource.Name})
+	}
+	table.Render([]string{"Type", "Name"}, data)
+	if !destroyYes && !logging.UserConfirm("Delete the %d resources above?", len(resources)) {
+		logging.UserWarning("Destroying was cancelled")
+		return nil
+	}
+
+	errs := cluster.DestroyTaggedResources(resources)
+	if len(errs) != 0 {
+		for _, err := range errs {
+			logging.UserFailure(err.Error())
+		}
+		logging.UserFailure("Destroying failed!")
+		return errs[0]
+	}
+	logging.UserSuccess("Destroying finished successfully!")
+	return nil
+}
 
 var destroyCmd = &cobra.Command{
 	Use:   "destroy [flags]",
 	Short: "",
 	Long:  "",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if env.Config.Provider == "aws" {
-
-			clusterName := cluster.ClusterName(StackName)
-
-			backendsHostGroup := cluster2.GenerateHostGroup(
-				clusterName,
-				common.HostGroupParams{},
-				common.RoleBackend,
-				"Backends",
-			)
-
-			clientsHostGroup := cluster2.GenerateHostGroup(
-				clusterName,
-				common.HostGroupParams{},
-				common.RoleClient,
-				"Clients",
-			)
-
-			dynamoDb := cluster2.DynamoDb{
-				ClusterName: clusterName,
-			}
-
-			awsCluster := cluster2.AWSCluster{
-				Name:          clusterName,
-				DefaultParams: db.DefaultClusterParams{},
-				CFStack: cluster2.Stack{
-					StackName: StackName,
-				},
-				DynamoDb: dynamoDb,
-				HostGroups: []cluster2.HostGroup{
-					backendsHostGroup,
-					clientsHostGroup,
-				},
-			}
-
-			if keepInstances {
-				autoscaling.KeepInstances = true
-			}
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
 
-			awsCluster.Init()
-			err := cluster.DestroyResource(&awsCluster)
+		if destroyTagged {
+			return destroyTaggedResources(p)
+		}
 
-			if err != nil {
-				logging.UserFailure("Destroying failed!")
-				return err
-			}
-			logging.UserSuccess("Destroying finished successfully!")
-		} else {
-			err := errors.New(fmt.Sprintf("Cloud provider '%s' is not supported with this action", env.Config.Provider))
-			logging.UserFailure(err.Error())
+		err = p.DestroyCluster(StackName, keepInstances)
+		if err != nil {
+			logging.UserFailure("Destroying failed!")
 			return err
 		}
+		logging.UserSuccess("Destroying finished successfully!")
 		return nil
 	},
 }
@@ -79,5 +73,7 @@ var destroyCmd = &cobra.Command{
 func init() {
 	destroyCmd.Flags().StringVarP(&StackName, "name", "n", "", "EKS cluster name")
 	destroyCmd.Flags().BoolVarP(&keepInstances, "keep-instances", "k", false, "Keep instances")
+	destroyCmd.Flags().BoolVar(&destroyTagged, "tagged", false, "Destroy every resource tagged with the cluster name, including orphans outside the cluster resource tree")
+	destroyCmd.Flags().BoolVarP(&destroyYes, "yes", "y", false, "Don't ask for confirmation before destroying the tagged resources")
 	_ = destroyCmd.MarkFlagRequired("name")
 }
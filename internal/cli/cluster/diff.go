@@ -0,0 +1,33 @@
+package cluster
+
+import (
+	"github.com/spf13/cobra"
+	"os"
+	"wekactl/internal/logging"
+	"wekactl/internal/provider"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [flags]",
+	Short: "Show drift between the cluster resources and the ones wekactl would create",
+	Long:  "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		diff, err := p.DiffCluster(StackName)
+		if err != nil {
+			logging.UserFailure("Diff failed!")
+			return err
+		}
+		diff.Render(os.Stdout)
+		return nil
+	},
+}
+
+func init() {
+	diffCmd.Flags().StringVarP(&StackName, "name", "n", "", "EKS cluster name")
+	_ = diffCmd.MarkFlagRequired("name")
+}
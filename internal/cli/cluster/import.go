@@ -2,17 +2,42 @@ package cluster
 
 import (
 	"errors"
-	"fmt"
 	"github.com/spf13/cobra"
-	"wekactl/internal/aws/cluster"
-	"wekactl/internal/env"
+	"os"
+	"wekactl/internal/cluster"
 	"wekactl/internal/logging"
+	"wekactl/internal/provider"
 )
 
 var importParams struct {
 	name     string
 	username string
 	password string
+	plan     bool
+	rollback bool
+}
+
+func rollbackImport(p provider.Provider, journal *cluster.Journal) {
+	if journal.Len() == 0 {
+		return
+	}
+	if !importParams.rollback {
+		for _, entry := range journal.Entries() {
+			logging.UserWarning("import %s", entry.Description)
+		}
+		if !logging.UserConfirm("Roll back %d changes made by the failed import?", journal.Len()) {
+			return
+		}
+	}
+	errs := p.RollbackImport(journal)
+	if len(errs) != 0 {
+		for _, err := range errs {
+			logging.UserFailure(err.Error())
+		}
+		logging.UserFailure("Rollback failed, some resources must be removed manually!")
+		return
+	}
+	logging.UserSuccess("Rollback finished successfully!")
 }
 
 var importCmd = &cobra.Command{
@@ -20,18 +45,34 @@ var importCmd = &cobra.Command{
 	Short: "",
 	Long:  "",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if env.Config.Provider == "aws" {
-			err := cluster.ImportCluster(importParams.name, importParams.username, importParams.password)
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		if importParams.plan {
+			plan, err := p.PlanImportCluster(importParams.name)
 			if err != nil {
-				logging.UserFailure("Import failed!")
+				logging.UserFailure("Import plan failed!")
 				return err
 			}
-			logging.UserSuccess("Import finished successfully!")
-		} else {
-			err := errors.New(fmt.Sprintf("Cloud provider '%s' is not supported with this action", env.Config.Provider))
+			plan.Render(os.Stdout)
+			return nil
+		}
+		// a plan only fetches the resources, the credentials are needed to import
+		if importParams.username == "" || importParams.password == "" {
+			err = errors.New("--username and --password are required unless --plan is set")
 			logging.UserFailure(err.Error())
 			return err
 		}
+		journal := &cluster.Journal{}
+		err = p.ImportCluster(importParams.name, importParams.username, importParams.password, journal)
+		if err != nil {
+			logging.UserFailure("Import failed!")
+			rollbackImport(p, journal)
+			return err
+		}
+		logging.UserSuccess("Import finished successfully!")
 		return nil
 	},
 }
@@ -40,7 +81,7 @@ func init() {
 	importCmd.Flags().StringVarP(&importParams.name, "name", "n", "", "EKS cluster name")
 	importCmd.Flags().StringVarP(&importParams.username, "username", "u", "", "Cluster username")
 	importCmd.Flags().StringVarP(&importParams.password, "password", "p", "", "Cluster password")
+	importCmd.Flags().BoolVar(&importParams.plan, "plan", false, "Only show which resources would be created or updated")
+	importCmd.Flags().BoolVar(&importParams.rollback, "rollback", false, "Roll back without asking if the import fails")
 	_ = importCmd.MarkFlagRequired("name")
-	_ = importCmd.MarkFlagRequired("username")
-	_ = importCmd.MarkFlagRequired("password")
 }
@@ -1,21 +1,32 @@
 package cluster
 
 import (
-	"fmt"
 	"github.com/spf13/cobra"
-	"wekactl/internal/aws/cluster"
-	"wekactl/internal/env"
+	"wekactl/internal/lib/table"
+	"wekactl/internal/logging"
+	"wekactl/internal/provider"
 )
 
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "",
 	Long:  "",
-	Run: func(cmd *cobra.Command, args []string) {
-		if env.Config.Provider == "aws" {
-			cluster.RenderStacksTable(Region)
-		} else {
-			fmt.Printf("Cloud provider '%s' is not supported with this action\n", env.Config.Provider)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
 		}
+		clusters, err := p.ListClusters()
+		if err != nil {
+			logging.UserFailure("Listing clusters failed!")
+			return err
+		}
+		var data [][]string
+		for _, c := range clusters {
+			data = append(data, []string{c.Name, c.CreationTime})
+		}
+		table.Render([]string{"stackName", "creationTime"}, data)
+		return nil
 	},
 }
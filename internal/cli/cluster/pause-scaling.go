@@ -0,0 +1,66 @@
+package cluster
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"time"
+	"wekactl/internal/logging"
+	"wekactl/internal/provider"
+)
+
+var pauseScalingParams struct {
+	name   string
+	until  string
+	period time.Duration
+	reason string
+}
+
+var pauseScalingCmd = &cobra.Command{
+	Use:   "pause-scaling [flags]",
+	Short: "Stop the host group lambdas from deactivating, removing or terminating hosts, e.g. during maintenance",
+	Long:  "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var until time.Time
+		if pauseScalingParams.until != "" && pauseScalingParams.period != 0 {
+			err := errors.New("--until and --for can't be used together")
+			logging.UserFailure(err.Error())
+			return err
+		}
+		if pauseScalingParams.until != "" {
+			var err error
+			until, err = time.Parse(time.RFC3339, pauseScalingParams.until)
+			if err != nil {
+				logging.UserFailure("Invalid --until time, expected RFC3339, e.g. 2006-01-02T15:04:05Z")
+				return err
+			}
+		}
+		if pauseScalingParams.period != 0 {
+			until = time.Now().Add(pauseScalingParams.period)
+		}
+
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		err = p.PauseScaling(pauseScalingParams.name, until, pauseScalingParams.reason)
+		if err != nil {
+			logging.UserFailure("Pausing scaling failed: %s", err.Error())
+			return err
+		}
+		if until.IsZero() {
+			logging.UserSuccess("Scaling was paused until resumed")
+		} else {
+			logging.UserSuccess("Scaling was paused until %s", until.UTC().Format(time.RFC3339))
+		}
+		return nil
+	},
+}
+
+func init() {
+	pauseScalingCmd.Flags().StringVarP(&pauseScalingParams.name, "name", "n", "", "EKS cluster name")
+	pauseScalingCmd.Flags().StringVar(&pauseScalingParams.until, "until", "", "Time in RFC3339 when scaling resumes by itself")
+	pauseScalingCmd.Flags().DurationVar(&pauseScalingParams.period, "for", 0, "How long until scaling resumes by itself")
+	pauseScalingCmd.Flags().StringVar(&pauseScalingParams.reason, "reason", "", "Why scaling is paused, shown in cluster status")
+	_ = pauseScalingCmd.MarkFlagRequired("name")
+}
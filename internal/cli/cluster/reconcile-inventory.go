@@ -0,0 +1,32 @@
+package cluster
+
+import (
+	"github.com/spf13/cobra"
+	"wekactl/internal/logging"
+	"wekactl/internal/provider"
+)
+
+var reconcileInventoryCmd = &cobra.Command{
+	Use:   "reconcile-inventory [flags]",
+	Short: "Rebuild the cluster resources inventory from the resources tags",
+	Long:  "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		recorded, removed, err := p.ReconcileInventory(StackName)
+		if err != nil {
+			logging.UserFailure("Reconciling inventory failed!")
+			return err
+		}
+		logging.UserSuccess("Inventory reconciled successfully! %d resources recorded, %d stale items removed", recorded, removed)
+		return nil
+	},
+}
+
+func init() {
+	reconcileInventoryCmd.Flags().StringVarP(&StackName, "name", "n", "", "EKS cluster name")
+	_ = reconcileInventoryCmd.MarkFlagRequired("name")
+}
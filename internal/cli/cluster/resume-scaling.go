@@ -0,0 +1,36 @@
+package cluster
+
+import (
+	"github.com/spf13/cobra"
+	"wekactl/internal/logging"
+	"wekactl/internal/provider"
+)
+
+var resumeScalingParams struct {
+	name string
+}
+
+var resumeScalingCmd = &cobra.Command{
+	Use:   "resume-scaling [flags]",
+	Short: "Resume scaling paused with pause-scaling",
+	Long:  "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		err = p.ResumeScaling(resumeScalingParams.name)
+		if err != nil {
+			logging.UserFailure("Resuming scaling failed: %s", err.Error())
+			return err
+		}
+		logging.UserSuccess("Scaling was resumed")
+		return nil
+	},
+}
+
+func init() {
+	resumeScalingCmd.Flags().StringVarP(&resumeScalingParams.name, "name", "n", "", "EKS cluster name")
+	_ = resumeScalingCmd.MarkFlagRequired("name")
+}
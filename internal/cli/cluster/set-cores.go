@@ -0,0 +1,55 @@
+package cluster
+
+import (
+	"github.com/spf13/cobra"
+	"wekactl/internal/cluster"
+	"wekactl/internal/logging"
+	"wekactl/internal/provider"
+)
+
+var setCoresParams struct {
+	name         string
+	instanceType string
+	counts       cluster.CoreCounts
+	reset        bool
+}
+
+var setCoresCmd = &cobra.Command{
+	Use:   "set-cores [flags]",
+	Short: "Override the cores weka runs on backends of an instance type that join the cluster",
+	Long:  "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		if setCoresParams.reset {
+			err = p.ResetBackendCoreCounts(setCoresParams.name, setCoresParams.instanceType)
+			if err != nil {
+				logging.UserFailure("Resetting cores failed: %s", err.Error())
+				return err
+			}
+			logging.UserSuccess("%s backends join with the cores computed from their instance type", setCoresParams.instanceType)
+			return nil
+		}
+		err = p.SetBackendCoreCounts(setCoresParams.name, setCoresParams.instanceType, setCoresParams.counts)
+		if err != nil {
+			logging.UserFailure("Setting cores failed: %s", err.Error())
+			return err
+		}
+		logging.UserSuccess("%s backends join with %s", setCoresParams.instanceType, setCoresParams.counts)
+		return nil
+	},
+}
+
+func init() {
+	setCoresCmd.Flags().StringVarP(&setCoresParams.name, "name", "n", "", "EKS cluster name")
+	setCoresCmd.Flags().StringVar(&setCoresParams.instanceType, "instance-type", "", "Backend instance type")
+	setCoresCmd.Flags().IntVar(&setCoresParams.counts.Total, "cores", 0, "Total cores weka runs")
+	setCoresCmd.Flags().IntVar(&setCoresParams.counts.Frontend, "frontend-cores", 1, "Dedicated frontend cores")
+	setCoresCmd.Flags().IntVar(&setCoresParams.counts.Drive, "drive-cores", 1, "Dedicated drive cores")
+	setCoresCmd.Flags().BoolVar(&setCoresParams.reset, "reset", false, "Go back to the cores computed from the instance type")
+	_ = setCoresCmd.MarkFlagRequired("name")
+	_ = setCoresCmd.MarkFlagRequired("instance-type")
+}
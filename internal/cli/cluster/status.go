@@ -0,0 +1,33 @@
+package cluster
+
+import (
+	"github.com/spf13/cobra"
+	"os"
+	"wekactl/internal/logging"
+	"wekactl/internal/provider"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status [flags]",
+	Short: "Show the cluster resources, their versions and host groups capacity",
+	Long:  "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		tree, err := p.StatusCluster(StackName)
+		if err != nil {
+			logging.UserFailure("Status failed!")
+			return err
+		}
+		tree.Render(os.Stdout)
+		return nil
+	},
+}
+
+func init() {
+	statusCmd.Flags().StringVarP(&StackName, "name", "n", "", "EKS cluster name")
+	_ = statusCmd.MarkFlagRequired("name")
+}
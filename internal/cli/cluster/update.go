@@ -1,36 +1,45 @@
 package cluster
 
 import (
-	"errors"
-	"fmt"
 	"github.com/spf13/cobra"
-	"wekactl/internal/aws/cluster"
-	"wekactl/internal/env"
+	"os"
 	"wekactl/internal/logging"
+	"wekactl/internal/provider"
 )
 
+var updatePlan bool
+
 var updateCmd = &cobra.Command{
 	Use:   "update [flags]",
 	Short: "",
 	Long:  "",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if env.Config.Provider == "aws" {
-			err := cluster.UpdateCluster(StackName)
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		if updatePlan {
+			plan, err := p.PlanUpdateCluster(StackName)
 			if err != nil {
-				logging.UserFailure("Update failed!")
+				logging.UserFailure("Update plan failed!")
 				return err
 			}
-			logging.UserSuccess("Update finished successfully!")
-		} else {
-			err := errors.New(fmt.Sprintf("Cloud provider '%s' is not supported with this action", env.Config.Provider))
-			logging.UserFailure(err.Error())
+			plan.Render(os.Stdout)
+			return nil
+		}
+		err = p.UpdateCluster(StackName)
+		if err != nil {
+			logging.UserFailure("Update failed!")
 			return err
 		}
+		logging.UserSuccess("Update finished successfully!")
 		return nil
 	},
 }
 
 func init() {
 	updateCmd.Flags().StringVarP(&StackName, "name", "n", "", "EKS cluster name")
+	updateCmd.Flags().BoolVar(&updatePlan, "plan", false, "Only show which resources would be created or updated")
 	_ = updateCmd.MarkFlagRequired("name")
 }
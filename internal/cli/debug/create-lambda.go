@@ -76,6 +76,21 @@ var createLambdaCmd = &cobra.Command{
 				lambdaType = lambdas.LambdaScale
 				instance := stackInstances.Backends[0]
 				lambdaVpcConfig = lambdas.GetLambdaVpcConfig(*instance.SubnetId, cluster2.GetInstanceSecurityGroupsId(instance))
+			case "lifecycle":
+				policy = iam.GetLifecycleLambdaPolicy()
+				lambdaType = lambdas.LambdaLifecycle
+				instance := stackInstances.Backends[0]
+				lambdaVpcConfig = lambdas.GetLambdaVpcConfig(*instance.SubnetId, cluster2.GetInstanceSecurityGroupsId(instance))
+			case "interruption":
+				policy = iam.GetInterruptionLambdaPolicy()
+				lambdaType = lambdas.LambdaInterruption
+				instance := stackInstances.Backends[0]
+				lambdaVpcConfig = lambdas.GetLambdaVpcConfig(*instance.SubnetId, cluster2.GetInstanceSecurityGroupsId(instance))
+			case "metrics":
+				policy = iam.GetMetricsLambdaPolicy()
+				lambdaType = lambdas.LambdaMetrics
+				instance := stackInstances.Backends[0]
+				lambdaVpcConfig = lambdas.GetLambdaVpcConfig(*instance.SubnetId, cluster2.GetInstanceSecurityGroupsId(instance))
 			case "terminate":
 				policy = iam.GetTerminateLambdaPolicy()
 				lambdaType = lambdas.LambdaTerminate
@@ -39,6 +39,11 @@ var createStateMachineCmd = &cobra.Command{
 				return err
 			}
 
+			lifecycleLambda, err := createLambda(hostGroup, lambdas.LambdaLifecycle, iam.GetLifecycleLambdaPolicy(), lambdaVpcConfig)
+			if err != nil {
+				return err
+			}
+
 			scaleLambda, err := createLambda(hostGroup, lambdas.LambdaScale, iam.GetScaleLambdaPolicy(), lambdaVpcConfig)
 			if err != nil {
 				return err
@@ -56,6 +61,7 @@ var createStateMachineCmd = &cobra.Command{
 
 			lambdas := scalemachine.StateMachineLambdasArn{
 				Fetch:     *fetchLambda.FunctionArn,
+				Lifecycle: *lifecycleLambda.FunctionArn,
 				Scale:     *scaleLambda.FunctionArn,
 				Terminate: *terminateLambda.FunctionArn,
 				Transient: *transientLambda.FunctionArn,
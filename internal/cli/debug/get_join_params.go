@@ -10,13 +10,14 @@ import (
 var AsgName string
 var TableName string
 var StackId string
+var InstanceType string
 var GetInstanceJoinParamsCmd = &cobra.Command{
 	Use:   "get-join-params",
 	Short: "",
 	Long:  "",
 	Run: func(cmd *cobra.Command, args []string) {
 		if env.Config.Provider == "aws" {
-			res, err := lambdas.GetJoinParams(StackName, AsgName, TableName, "Backends")
+			res, err := lambdas.GetJoinParams(StackName, AsgName, TableName, "Backends", InstanceType)
 			if err != nil {
 				fmt.Println(err)
 			} else {
@@ -32,6 +33,7 @@ func init() {
 	GetInstanceJoinParamsCmd.Flags().StringVarP(&StackName, "name", "n", "", "StackName")
 	GetInstanceJoinParamsCmd.Flags().StringVarP(&AsgName, "asg-name", "g", "", "Auto scaling group name")
 	GetInstanceJoinParamsCmd.Flags().StringVarP(&TableName, "table-name", "t", "", "Dynamo DB table name")
+	GetInstanceJoinParamsCmd.Flags().StringVar(&InstanceType, "instance-type", "", "Joining instance type, defaults to the type of the first auto scaling group instance")
 
 	_ = GetInstanceJoinParamsCmd.MarkFlagRequired("name")
 	_ = GetInstanceJoinParamsCmd.MarkFlagRequired("asg-name")
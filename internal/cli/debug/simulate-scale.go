@@ -0,0 +1,166 @@
+package debug
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"strings"
+	"time"
+	"wekactl/internal/aws/lambdas/protocol"
+	"wekactl/internal/aws/lambdas/scale"
+	"wekactl/internal/cluster"
+	"wekactl/internal/lib/table"
+	"wekactl/internal/lib/weka"
+)
+
+var simulateScaleArgs struct {
+	HostsList        string
+	DisksList        string
+	NodesList        string
+	MountsList       string
+	Stats            string
+	Status           string
+	Instances        string
+	Role             string
+	Desired          int
+	Now              string
+	MaxUnhealthy     int
+	UnhealthyTimeout time.Duration
+}
+
+func readSnapshot() (scale.Snapshot, error) {
+	snapshot := scale.Snapshot{}
+	files := map[weka.JrpcMethod]string{
+		weka.JrpcHostList:      simulateScaleArgs.HostsList,
+		weka.JrpcDrivesList:    simulateScaleArgs.DisksList,
+		weka.JrpcNodeList:      simulateScaleArgs.NodesList,
+		weka.JrpcStatus:        simulateScaleArgs.Status,
+		weka.JrpcMountsList:    simulateScaleArgs.MountsList,
+		weka.JrpcStatsRealtime: simulateScaleArgs.Stats,
+	}
+	for method, file := range files {
+		if file == "" {
+			continue
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		snapshot[method] = data
+	}
+	return snapshot, nil
+}
+
+func readInstances(snapshot scale.Snapshot) (instances []protocol.HgInstance, err error) {
+	if simulateScaleArgs.Instances == "" {
+		return scale.SnapshotInstances(snapshot, simulateScaleArgs.Role)
+	}
+	data, err := ioutil.ReadFile(simulateScaleArgs.Instances)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &instances)
+	return
+}
+
+func decisionsData(decisions []protocol.ScaleResponseDecision) (data [][]string) {
+	for _, decision := range decisions {
+		data = append(data, []string{decision.InstanceId, decision.HostId.String(), decision.Reason})
+	}
+	return
+}
+
+var simulateScaleCmd = &cobra.Command{
+	Use:   "simulate-scale",
+	Short: "Print what the scale lambda would do, given recorded weka api responses",
+	Long:  "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snapshot, err := readSnapshot()
+		if err != nil {
+			return err
+		}
+		instances, err := readInstances(snapshot)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if simulateScaleArgs.Now != "" {
+			now, err = time.Parse(time.RFC3339, simulateScaleArgs.Now)
+			if err != nil {
+				return err
+			}
+		}
+		policy := cluster.DefaultScalingPolicy()
+		if simulateScaleArgs.MaxUnhealthy > 0 {
+			policy.MaxUnhealthyDeactivating = simulateScaleArgs.MaxUnhealthy
+		}
+		if simulateScaleArgs.UnhealthyTimeout > 0 {
+			policy.UnhealthyDeactivateTimeout = simulateScaleArgs.UnhealthyTimeout
+		}
+
+		response, calls, err := scale.Simulate(snapshot, protocol.HostGroupInfoResponse{
+			DesiredCapacity: simulateScaleArgs.Desired,
+			Instances:       instances,
+			Role:            simulateScaleArgs.Role,
+			Policy:          policy,
+		}, now)
+		if err != nil {
+			return err
+		}
+
+		var hosts [][]string
+		for _, host := range response.Hosts {
+			hosts = append(hosts, []string{host.InstanceId, host.HostId.String(), host.State, host.ScaleState, host.AddedTime.Format(time.RFC3339)})
+		}
+		fmt.Printf("Host group hosts, in deactivation order (%d instances, desired %d):\n", len(instances), simulateScaleArgs.Desired)
+		table.Render([]string{"Instance", "Host", "State", "Scale State", "Added"}, hosts)
+
+		fmt.Println("Deactivated:")
+		table.Render([]string{"Instance", "Host", "Reason"}, decisionsData(response.Deactivated))
+		fmt.Println("Removed inactive:")
+		table.Render([]string{"Instance", "Host", "Reason"}, decisionsData(response.RemovedInactive))
+
+		var toTerminate []string
+		for _, instance := range response.ToTerminate {
+			toTerminate = append(toTerminate, instance.Id)
+		}
+		fmt.Printf("To terminate: %s\n", strings.Join(toTerminate, ", "))
+		if response.ScaleDownLimit != "" {
+			fmt.Println(response.ScaleDownLimit)
+		}
+		for _, transientError := range response.TransientErrors {
+			fmt.Println(transientError)
+		}
+
+		fmt.Println("Weka api calls:")
+		for _, call := range calls {
+			params, err := json.Marshal(call.Params)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s %s\n", call.Method, params)
+		}
+		return nil
+	},
+}
+
+func init() {
+	simulateScaleCmd.Flags().StringVar(&simulateScaleArgs.HostsList, "hosts-list", "", "hosts_list response json file")
+	simulateScaleCmd.Flags().StringVar(&simulateScaleArgs.DisksList, "disks-list", "", "disks_list response json file, required for backends")
+	simulateScaleCmd.Flags().StringVar(&simulateScaleArgs.NodesList, "nodes-list", "", "nodes_list response json file")
+	simulateScaleCmd.Flags().StringVar(&simulateScaleArgs.MountsList, "mounts-list", "", "mounts_list response json file, clients with fewer mounts are scaled in first")
+	simulateScaleCmd.Flags().StringVar(&simulateScaleArgs.Stats, "stats", "", "stats_get_realtime response json file, clients with fewer frontend ops are scaled in first")
+	simulateScaleCmd.Flags().StringVar(&simulateScaleArgs.Status, "status", "", "status response json file")
+	simulateScaleCmd.Flags().StringVar(&simulateScaleArgs.Instances, "instances", "", "host group instances json file, [{\"Id\": ..., \"PrivateIp\": ..., \"Protected\": ...}], defaults to the hosts of the role")
+	simulateScaleCmd.Flags().StringVar(&simulateScaleArgs.Role, "role", "backend", "host group role, backend or client")
+	simulateScaleCmd.Flags().IntVar(&simulateScaleArgs.Desired, "desired", 0, "host group desired capacity")
+	simulateScaleCmd.Flags().StringVar(&simulateScaleArgs.Now, "now", "", "simulation time in RFC3339, defaults to the current time")
+	simulateScaleCmd.Flags().IntVar(&simulateScaleArgs.MaxUnhealthy, "max-unhealthy-deactivating", 0, "scaling policy max unhealthy deactivating, defaults to the default policy")
+	simulateScaleCmd.Flags().DurationVar(&simulateScaleArgs.UnhealthyTimeout, "unhealthy-deactivate-timeout", 0, "scaling policy unhealthy deactivate timeout, defaults to the default policy")
+	_ = simulateScaleCmd.MarkFlagRequired("hosts-list")
+	_ = simulateScaleCmd.MarkFlagRequired("nodes-list")
+	_ = simulateScaleCmd.MarkFlagRequired("status")
+	_ = simulateScaleCmd.MarkFlagRequired("desired")
+	Debug.AddCommand(simulateScaleCmd)
+}
@@ -0,0 +1,77 @@
+package hostgroup
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"wekactl/internal/logging"
+	"wekactl/internal/provider"
+)
+
+var autoscaleParams provider.Autoscale
+
+var autoscaleCmd = &cobra.Command{
+	Use:   "autoscale [command] [flags]",
+	Short: "Scale a client host group by the load of its frontends",
+	Run: func(c *cobra.Command, _ []string) {
+		if err := c.Help(); err != nil {
+			log.Debug().Msgf("ignoring cobra error %q", err.Error())
+		}
+	},
+	SilenceUsage: true,
+}
+
+var autoscaleEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Keep a frontend metric of a client host group at a target value",
+	Long:  "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		err = p.EnableHostGroupAutoscale(policyParams.clusterName, policyParams.hostGroup, autoscaleParams)
+		if err != nil {
+			logging.UserFailure("Enabling host group autoscale failed: %s", err.Error())
+			return err
+		}
+		logging.UserSuccess("Host group keeps %s at %g with %d to %d instances",
+			autoscaleParams.Metric, autoscaleParams.Target, autoscaleParams.MinSize, autoscaleParams.MaxSize)
+		return nil
+	},
+}
+
+var autoscaleDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Stop scaling a client host group by its frontend metrics",
+	Long:  "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		err = p.DisableHostGroupAutoscale(policyParams.clusterName, policyParams.hostGroup)
+		if err != nil {
+			logging.UserFailure("Disabling host group autoscale failed: %s", err.Error())
+			return err
+		}
+		logging.UserSuccess("Host group desired capacity is set by hand")
+		return nil
+	},
+}
+
+func init() {
+	addPolicyFlags(autoscaleEnableCmd)
+	autoscaleEnableCmd.Flags().StringVar(&autoscaleParams.Metric, "metric", "", "Metric to track, throughput (MB/s), iops or cpu (percent), averaged over the host group frontends")
+	autoscaleEnableCmd.Flags().Float64Var(&autoscaleParams.Target, "target", 0, "Value the metric is kept at")
+	autoscaleEnableCmd.Flags().Int64Var(&autoscaleParams.MinSize, "min", 0, "Fewest instances the host group scales in to")
+	autoscaleEnableCmd.Flags().Int64Var(&autoscaleParams.MaxSize, "max", 0, "Most instances the host group scales out to")
+	_ = autoscaleEnableCmd.MarkFlagRequired("metric")
+	_ = autoscaleEnableCmd.MarkFlagRequired("target")
+	_ = autoscaleEnableCmd.MarkFlagRequired("max")
+	addPolicyFlags(autoscaleDisableCmd)
+	autoscaleCmd.AddCommand(autoscaleEnableCmd)
+	autoscaleCmd.AddCommand(autoscaleDisableCmd)
+	HostGroup.AddCommand(autoscaleCmd)
+}
@@ -0,0 +1,52 @@
+package hostgroup
+
+import (
+	"github.com/spf13/cobra"
+	"strconv"
+	"wekactl/internal/lib/table"
+	"wekactl/internal/logging"
+	"wekactl/internal/provider"
+)
+
+var policyParams struct {
+	clusterName string
+	hostGroup   string
+}
+
+var getPolicyCmd = &cobra.Command{
+	Use:   "get-policy",
+	Short: "Show the host group scaling policy",
+	Long:  "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		policy, err := p.GetScalingPolicy(policyParams.clusterName, policyParams.hostGroup)
+		if err != nil {
+			logging.UserFailure("Getting host group scaling policy failed!")
+			return err
+		}
+		table.Render([]string{"Setting", "Value"}, [][]string{
+			{"unhealthy-deactivate-timeout", policy.UnhealthyDeactivateTimeout.String()},
+			{"backend-cleanup-delay", policy.BackendCleanupDelay.String()},
+			{"down-kick-out-timeout", policy.DownKickOutTimeout.String()},
+			{"max-unhealthy-deactivating", strconv.Itoa(policy.MaxUnhealthyDeactivating)},
+			{"launch-grace-period", policy.LaunchGracePeriod.String()},
+		})
+		return nil
+	},
+}
+
+func addPolicyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&policyParams.clusterName, "name", "n", "", "EKS cluster name")
+	cmd.Flags().StringVarP(&policyParams.hostGroup, "hostgroup", "g", "", "Host group name, e.g. Backends")
+	_ = cmd.MarkFlagRequired("name")
+	_ = cmd.MarkFlagRequired("hostgroup")
+}
+
+func init() {
+	addPolicyFlags(getPolicyCmd)
+	HostGroup.AddCommand(getPolicyCmd)
+}
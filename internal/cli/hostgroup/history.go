@@ -0,0 +1,95 @@
+package hostgroup
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"strconv"
+	"strings"
+	"time"
+	"wekactl/internal/cluster"
+	"wekactl/internal/lib/table"
+	"wekactl/internal/logging"
+	"wekactl/internal/provider"
+)
+
+var historyParams struct {
+	since   time.Duration
+	changes bool
+	errors  bool
+	limit   int
+}
+
+func formatHistoryHosts(hosts []cluster.ScaleHistoryHost) string {
+	var lines []string
+	for _, host := range hosts {
+		lines = append(lines, fmt.Sprintf("%s: %s", host.InstanceId, host.Reason))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatHistoryErrors(record cluster.ScaleHistoryRecord) string {
+	var lines []string
+	lines = append(lines, record.TransientErrors...)
+	if record.ScaleDownLimit != "" {
+		lines = append(lines, record.ScaleDownLimit)
+	}
+	if record.Error != "" {
+		lines = append(lines, record.Error)
+	}
+	return strings.Join(lines, "\n")
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the decisions of the host group scale state machine runs",
+	Long:  "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		// the runs --changes and --errors skip can't be left out of the limit the history is read with
+		limit := historyParams.limit
+		if historyParams.changes || historyParams.errors {
+			limit = 0
+		}
+		records, err := p.ScaleHistory(policyParams.clusterName, policyParams.hostGroup, time.Now().Add(-historyParams.since), limit)
+		if err != nil {
+			logging.UserFailure("Getting host group scale history failed: %s", err.Error())
+			return err
+		}
+
+		var data [][]string
+		for _, record := range records {
+			if historyParams.changes && !record.Changed() {
+				continue
+			}
+			if historyParams.errors && !record.Failed() {
+				continue
+			}
+			if historyParams.limit > 0 && len(data) == historyParams.limit {
+				break
+			}
+			data = append(data, []string{
+				record.Time.Local().Format(time.RFC3339),
+				strconv.Itoa(len(record.Hosts)),
+				formatHistoryHosts(record.Deactivated),
+				formatHistoryHosts(record.RemovedInactive),
+				strings.Join(record.Terminated, "\n"),
+				formatHistoryErrors(record),
+			})
+		}
+		table.Render([]string{"Time", "Hosts", "Deactivated", "Removed", "Terminated", "Errors"}, data)
+		return nil
+	},
+}
+
+func init() {
+	addPolicyFlags(historyCmd)
+	historyCmd.Flags().DurationVar(&historyParams.since, "since", 24*time.Hour, "Show runs from this long ago")
+	historyCmd.Flags().BoolVar(&historyParams.changes, "changes", false, "Show only runs that deactivated, removed or terminated hosts")
+	historyCmd.Flags().BoolVar(&historyParams.errors, "errors", false, "Show only runs that had errors")
+	historyCmd.Flags().IntVar(&historyParams.limit, "limit", 0, "Show at most this many runs, 0 for no limit")
+	HostGroup.AddCommand(historyCmd)
+}
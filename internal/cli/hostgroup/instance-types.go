@@ -0,0 +1,69 @@
+package hostgroup
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"strconv"
+	"strings"
+	"wekactl/internal/logging"
+	"wekactl/internal/provider"
+)
+
+var instanceTypesParams struct {
+	instanceTypes      []string
+	allocationStrategy string
+}
+
+// parseInstanceTypes parses TYPE[:WEIGHT] values, the weight defaults to 1
+func parseInstanceTypes(values []string) (instanceTypes []provider.InstanceTypeWeight, err error) {
+	for _, value := range values {
+		parts := strings.SplitN(value, ":", 2)
+		instanceType := provider.InstanceTypeWeight{InstanceType: parts[0], Weight: 1}
+		if len(parts) == 2 {
+			instanceType.Weight, err = strconv.ParseInt(parts[1], 10, 64)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("invalid weight of instance type %s: %s", parts[0], parts[1]))
+			}
+		}
+		instanceTypes = append(instanceTypes, instanceType)
+	}
+	return
+}
+
+var instanceTypesCmd = &cobra.Command{
+	Use:   "instance-types",
+	Short: "Set the instance types a host group launches",
+	Long:  "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		instanceTypes, err := parseInstanceTypes(instanceTypesParams.instanceTypes)
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		err = p.SetHostGroupInstanceTypes(policyParams.clusterName, policyParams.hostGroup, instanceTypes, instanceTypesParams.allocationStrategy)
+		if err != nil {
+			logging.UserFailure("Setting host group instance types failed: %s", err.Error())
+			return err
+		}
+		if len(instanceTypes) == 0 {
+			logging.UserSuccess("Host group launches its launch template instance type only")
+		} else {
+			logging.UserSuccess("Host group launches %s", strings.Join(instanceTypesParams.instanceTypes, ", "))
+		}
+		return nil
+	},
+}
+
+func init() {
+	addPolicyFlags(instanceTypesCmd)
+	instanceTypesCmd.Flags().StringSliceVar(&instanceTypesParams.instanceTypes, "instance-types", nil, "Instance types in priority order, as TYPE[:WEIGHT], the weight is the capacity units an instance counts for (default 1). Empty to launch the launch template instance type only")
+	instanceTypesCmd.Flags().StringVar(&instanceTypesParams.allocationStrategy, "allocation-strategy", "prioritized", "How the type of on-demand instances is picked, prioritized or lowest-price")
+	_ = instanceTypesCmd.MarkFlagRequired("instance-types")
+	HostGroup.AddCommand(instanceTypesCmd)
+}
@@ -1,20 +1,48 @@
 package hostgroup
 
 import (
-	"fmt"
 	"github.com/spf13/cobra"
+	"strconv"
+	"wekactl/internal/lib/table"
+	"wekactl/internal/logging"
+	"wekactl/internal/provider"
 )
 
+var listParams struct {
+	clusterName string
+}
+
 var listCmd = &cobra.Command{
 	Use:   "list",
-	Short: "",
+	Short: "List the cluster host groups and their capacity",
 	Long:  "",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		fmt.Println("hostgroup list called")
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		hostGroups, err := p.ListHostGroups(listParams.clusterName)
+		if err != nil {
+			logging.UserFailure("Listing host groups failed!")
+			return err
+		}
+		var data [][]string
+		for _, hostGroup := range hostGroups {
+			data = append(data, []string{
+				hostGroup.Name,
+				hostGroup.Role,
+				strconv.FormatInt(hostGroup.Desired, 10),
+				strconv.FormatInt(hostGroup.Instances, 10),
+			})
+		}
+		table.Render([]string{"Name", "Role", "Desired", "Instances"}, data)
 		return nil
 	},
 }
 
 func init() {
+	listCmd.Flags().StringVarP(&listParams.clusterName, "name", "n", "", "EKS cluster name")
+	_ = listCmd.MarkFlagRequired("name")
 	HostGroup.AddCommand(listCmd)
 }
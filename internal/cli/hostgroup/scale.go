@@ -0,0 +1,83 @@
+package hostgroup
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"time"
+	"wekactl/internal/logging"
+	"wekactl/internal/provider"
+)
+
+const scalePollInterval = 30 * time.Second
+
+var scaleParams struct {
+	desired      int64
+	raiseMaxSize bool
+	wait         bool
+	timeout      time.Duration
+}
+
+func waitForHostGroup(p provider.Provider) error {
+	deadline := time.Now().Add(scaleParams.timeout)
+	for {
+		progress, err := p.HostGroupProgress(policyParams.clusterName, policyParams.hostGroup)
+		if err != nil {
+			// weka might not answer while hosts join or leave, so progress errors are retried until the timeout
+			logging.UserWarning("Getting host group progress failed: %s", err.Error())
+		} else {
+			logging.UserProgress("%d/%d instances, %d/%d active weka hosts",
+				progress.Instances, scaleParams.desired, progress.Active, scaleParams.desired)
+			if progress.Desired != scaleParams.desired {
+				return errors.New(fmt.Sprintf("host group desired capacity was changed to %d", progress.Desired))
+			}
+			if progress.Instances == scaleParams.desired && progress.Active == scaleParams.desired {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return errors.New(fmt.Sprintf("host group didn't reach %d active hosts within %s", scaleParams.desired, scaleParams.timeout))
+		}
+		time.Sleep(scalePollInterval)
+	}
+}
+
+var scaleCmd = &cobra.Command{
+	Use:   "scale",
+	Short: "Set the host group desired capacity, and optionally wait until weka reports it",
+	Long:  "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		err = p.ScaleHostGroup(policyParams.clusterName, policyParams.hostGroup, scaleParams.desired, scaleParams.raiseMaxSize)
+		if err != nil {
+			logging.UserFailure("Scaling host group failed: %s", err.Error())
+			return err
+		}
+		logging.UserSuccess("Host group desired capacity was set to %d", scaleParams.desired)
+		if !scaleParams.wait {
+			return nil
+		}
+
+		err = waitForHostGroup(p)
+		if err != nil {
+			logging.UserFailure("Waiting for host group failed: %s", err.Error())
+			return err
+		}
+		logging.UserSuccess("Host group has %d active hosts", scaleParams.desired)
+		return nil
+	},
+}
+
+func init() {
+	addPolicyFlags(scaleCmd)
+	scaleCmd.Flags().Int64Var(&scaleParams.desired, "desired", 0, "Host group desired capacity")
+	scaleCmd.Flags().BoolVar(&scaleParams.raiseMaxSize, "raise-max-size", false, "Raise the host group max size when the desired capacity is above it")
+	scaleCmd.Flags().BoolVar(&scaleParams.wait, "wait", false, "Wait until weka reports the desired number of active hosts")
+	scaleCmd.Flags().DurationVar(&scaleParams.timeout, "timeout", time.Hour, "How long to wait with --wait")
+	_ = scaleCmd.MarkFlagRequired("desired")
+	HostGroup.AddCommand(scaleCmd)
+}
@@ -0,0 +1,69 @@
+package hostgroup
+
+import (
+	"github.com/spf13/cobra"
+	"time"
+	"wekactl/internal/logging"
+	"wekactl/internal/provider"
+)
+
+var setPolicyParams struct {
+	unhealthyDeactivateTimeout time.Duration
+	backendCleanupDelay        time.Duration
+	downKickOutTimeout         time.Duration
+	maxUnhealthyDeactivating   int
+	launchGracePeriod          time.Duration
+}
+
+var setPolicyCmd = &cobra.Command{
+	Use:   "set-policy",
+	Short: "Change the host group scaling policy, settings that are not passed keep their current value",
+	Long:  "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		policy, err := p.GetScalingPolicy(policyParams.clusterName, policyParams.hostGroup)
+		if err != nil {
+			logging.UserFailure("Getting host group scaling policy failed!")
+			return err
+		}
+
+		flags := cmd.Flags()
+		if flags.Changed("unhealthy-deactivate-timeout") {
+			policy.UnhealthyDeactivateTimeout = setPolicyParams.unhealthyDeactivateTimeout
+		}
+		if flags.Changed("backend-cleanup-delay") {
+			policy.BackendCleanupDelay = setPolicyParams.backendCleanupDelay
+		}
+		if flags.Changed("down-kick-out-timeout") {
+			policy.DownKickOutTimeout = setPolicyParams.downKickOutTimeout
+		}
+		if flags.Changed("max-unhealthy-deactivating") {
+			policy.MaxUnhealthyDeactivating = setPolicyParams.maxUnhealthyDeactivating
+		}
+		if flags.Changed("launch-grace-period") {
+			policy.LaunchGracePeriod = setPolicyParams.launchGracePeriod
+		}
+
+		err = p.SetScalingPolicy(policyParams.clusterName, policyParams.hostGroup, policy)
+		if err != nil {
+			logging.UserFailure("Setting host group scaling policy failed: %s", err.Error())
+			return err
+		}
+		logging.UserSuccess("Host group scaling policy was set successfully!")
+		return nil
+	},
+}
+
+func init() {
+	addPolicyFlags(setPolicyCmd)
+	setPolicyCmd.Flags().DurationVar(&setPolicyParams.unhealthyDeactivateTimeout, "unhealthy-deactivate-timeout", 0, "How long a host may be down before it is deactivated as unhealthy")
+	setPolicyCmd.Flags().DurationVar(&setPolicyParams.backendCleanupDelay, "backend-cleanup-delay", 0, "How long an inactive backend of another host group is left for its own host group to remove")
+	setPolicyCmd.Flags().DurationVar(&setPolicyParams.downKickOutTimeout, "down-kick-out-timeout", 0, "How long a down backend of another host group may stay active")
+	setPolicyCmd.Flags().IntVar(&setPolicyParams.maxUnhealthyDeactivating, "max-unhealthy-deactivating", 0, "How many unhealthy hosts may be deactivated at once")
+	setPolicyCmd.Flags().DurationVar(&setPolicyParams.launchGracePeriod, "launch-grace-period", 0, "How long a new instance has to join the cluster before it is terminated")
+	HostGroup.AddCommand(setPolicyCmd)
+}
@@ -0,0 +1,45 @@
+package hostgroup
+
+import (
+	"github.com/spf13/cobra"
+	"wekactl/internal/logging"
+	"wekactl/internal/provider"
+)
+
+var spotParams struct {
+	onDemandBase   int64
+	spotPercentage int64
+}
+
+var spotCmd = &cobra.Command{
+	Use:   "spot",
+	Short: "Set the share of spot instances a client host group launches",
+	Long:  "",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		p, err := provider.Current()
+		if err != nil {
+			logging.UserFailure(err.Error())
+			return err
+		}
+		err = p.SetHostGroupSpot(policyParams.clusterName, policyParams.hostGroup, spotParams.onDemandBase, spotParams.spotPercentage)
+		if err != nil {
+			logging.UserFailure("Setting host group spot instances failed: %s", err.Error())
+			return err
+		}
+		if spotParams.spotPercentage == 0 {
+			logging.UserSuccess("Host group launches on-demand instances only")
+		} else {
+			logging.UserSuccess("Host group launches %d%% spot instances above %d on-demand instances",
+				spotParams.spotPercentage, spotParams.onDemandBase)
+		}
+		return nil
+	},
+}
+
+func init() {
+	addPolicyFlags(spotCmd)
+	spotCmd.Flags().Int64Var(&spotParams.onDemandBase, "on-demand-base", 0, "Instances launched on-demand before any spot instance")
+	spotCmd.Flags().Int64Var(&spotParams.spotPercentage, "spot-percentage", 0, "Percentage of the instances above the on-demand base launched as spot, 0 for on-demand only")
+	_ = spotCmd.MarkFlagRequired("spot-percentage")
+	HostGroup.AddCommand(spotCmd)
+}
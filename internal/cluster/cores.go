@@ -0,0 +1,38 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxBackendCores is the most cores weka runs on a single host
+const MaxBackendCores = 19
+
+// CoreCounts is how many cores weka runs on a backend, the cores that are neither frontend nor drive cores are
+// compute cores
+type CoreCounts struct {
+	Total    int `json:"total"`
+	Frontend int `json:"frontend"`
+	Drive    int `json:"drive"`
+}
+
+func (c CoreCounts) Compute() int {
+	return c.Total - c.Frontend - c.Drive
+}
+
+func (c CoreCounts) Validate() error {
+	if c.Total < 1 || c.Total > MaxBackendCores {
+		return errors.New(fmt.Sprintf("total cores must be between 1 and %d", MaxBackendCores))
+	}
+	if c.Frontend < 0 || c.Drive < 0 {
+		return errors.New("frontend and drive cores can't be negative")
+	}
+	if c.Compute() < 1 {
+		return errors.New("at least 1 core must be left as a compute core")
+	}
+	return nil
+}
+
+func (c CoreCounts) String() string {
+	return fmt.Sprintf("%d cores (%d frontend, %d drive, %d compute)", c.Total, c.Frontend, c.Drive, c.Compute())
+}
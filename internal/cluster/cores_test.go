@@ -0,0 +1,26 @@
+package cluster
+
+import "testing"
+
+func TestCoreCountsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		counts  CoreCounts
+		wantErr bool
+	}{
+		{"single", CoreCounts{Total: 1}, false},
+		{"dedicated", CoreCounts{Total: 7, Frontend: 1, Drive: 2}, false},
+		{"max", CoreCounts{Total: MaxBackendCores, Frontend: 1, Drive: 4}, false},
+		{"noCores", CoreCounts{}, true},
+		{"tooManyCores", CoreCounts{Total: MaxBackendCores + 1, Frontend: 1, Drive: 4}, true},
+		{"negativeDrive", CoreCounts{Total: 3, Frontend: 1, Drive: -1}, true},
+		{"noCompute", CoreCounts{Total: 3, Frontend: 1, Drive: 2}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.counts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type Drift struct {
+	Attribute string
+	Expected  string
+	Actual    string
+}
+
+type Drifts []Drift
+
+func (d *Drifts) Compare(attribute, expected, actual string) {
+	if expected != actual {
+		*d = append(*d, Drift{
+			Attribute: attribute,
+			Expected:  expected,
+			Actual:    actual,
+		})
+	}
+}
+
+// CompareList compares two lists ignoring their order
+func (d *Drifts) CompareList(attribute string, expected, actual []string) {
+	expected = append([]string{}, expected...)
+	actual = append([]string{}, actual...)
+	sort.Strings(expected)
+	sort.Strings(actual)
+	d.Compare(attribute, strings.Join(expected, ","), strings.Join(actual, ","))
+}
+
+// CompareJson compares two json documents semantically, ignoring formatting and keys order
+func (d *Drifts) CompareJson(attribute, expected, actual string) {
+	var expectedValue, actualValue interface{}
+	if json.Unmarshal([]byte(expected), &expectedValue) == nil &&
+		json.Unmarshal([]byte(actual), &actualValue) == nil &&
+		reflect.DeepEqual(expectedValue, actualValue) {
+		return
+	}
+	d.Compare(attribute, expected, actual)
+}
+
+// Differ is implemented by resources that can compare their live attributes with the ones
+// the current code would create
+type Differ interface {
+	Diff() (Drifts, error)
+}
+
+type ResourceDiff struct {
+	Type         string
+	Name         string
+	Missing      bool
+	Drifts       Drifts
+	SubResources []ResourceDiff
+}
+
+// DiffResource walks the resource tree and compares every deployed resource with its desired state,
+// resources which don't implement Differ only group their sub resources
+func DiffResource(r Resource) (diff ResourceDiff, err error) {
+	for _, subresource := range r.SubResources() {
+		subDiff, err := DiffResource(subresource)
+		if err != nil {
+			return diff, err
+		}
+		diff.SubResources = append(diff.SubResources, subDiff)
+	}
+
+	err = r.Fetch()
+	if err != nil {
+		return
+	}
+
+	diff.Type = ResourceType(r)
+	diff.Name = r.ResourceName()
+
+	differ, ok := r.(Differ)
+	if !ok {
+		return
+	}
+	if r.DeployedVersion() == "" {
+		diff.Missing = true
+		return
+	}
+	diff.Drifts.Compare("version", r.TargetVersion(), r.DeployedVersion())
+
+	drifts, err := differ.Diff()
+	if err != nil {
+		return diff, fmt.Errorf("%s %s: %w", diff.Type, diff.Name, err)
+	}
+	diff.Drifts = append(diff.Drifts, drifts...)
+	return
+}
+
+func (d ResourceDiff) drifted() bool {
+	return d.Missing || len(d.Drifts) != 0
+}
+
+// Count returns how many resources of the tree are missing or drifted
+func (d ResourceDiff) Count() (count int) {
+	if d.drifted() {
+		count++
+	}
+	for _, subDiff := range d.SubResources {
+		count += subDiff.Count()
+	}
+	return
+}
+
+func (d ResourceDiff) render(w io.Writer, prefix, childPrefix string) {
+	status := "in sync"
+	if d.Missing {
+		status = "missing"
+	} else if len(d.Drifts) != 0 {
+		status = "drifted"
+	}
+	_, _ = fmt.Fprintf(w, "%s%s %s [%s]\n", prefix, d.Type, d.Name, status)
+
+	for _, drift := range d.Drifts {
+		attributePrefix := childPrefix + "│ "
+		if len(d.SubResources) == 0 {
+			attributePrefix = childPrefix + "  "
+		}
+		_, _ = fmt.Fprintf(w, "%s~ %s: expected %q, actual %q\n", attributePrefix, drift.Attribute, drift.Expected, drift.Actual)
+	}
+
+	for i, subDiff := range d.SubResources {
+		if i == len(d.SubResources)-1 {
+			subDiff.render(w, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			subDiff.render(w, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
+
+// Render prints the diff as a tree followed by a summary line
+func (d ResourceDiff) Render(w io.Writer) {
+	d.render(w, "", "")
+	_, _ = fmt.Fprintf(w, "\nDiff: %d resources drifted\n", d.Count())
+}
@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDrifts(t *testing.T) {
+	var drifts Drifts
+	drifts.Compare("same", "a", "a")
+	drifts.CompareList("list", []string{"a", "b"}, []string{"b", "a"})
+	drifts.CompareJson("json", `{"a": 1, "b": [1, 2]}`, `{"b":[1,2],"a":1}`)
+	if len(drifts) != 0 {
+		t.Fatalf("expected no drifts, got %v", drifts)
+	}
+
+	drifts.Compare("instance_type", "i3.large", "i3.xlarge")
+	drifts.CompareList("suspended_processes", []string{"ReplaceUnhealthy"}, nil)
+	drifts.CompareJson("definition", `{"a": 1}`, `{"a": 2}`)
+	if len(drifts) != 3 {
+		t.Fatalf("expected 3 drifts, got %v", drifts)
+	}
+	if drifts[1].Expected != "ReplaceUnhealthy" || drifts[1].Actual != "" {
+		t.Errorf("unexpected list drift %v", drifts[1])
+	}
+}
+
+func TestResourceDiffRender(t *testing.T) {
+	diff := ResourceDiff{
+		Type: "AWSCluster",
+		Name: "cluster",
+		SubResources: []ResourceDiff{
+			{Type: "LaunchTemplate", Name: "lt", Drifts: Drifts{{Attribute: "instance_type", Expected: "i3.large", Actual: "i3.xlarge"}}},
+			{Type: "CloudWatch", Name: "cw", Missing: true},
+			{Type: "Lambda", Name: "fetch"},
+		},
+	}
+	if diff.Count() != 2 {
+		t.Errorf("expected 2 drifted resources, got %d", diff.Count())
+	}
+
+	var out bytes.Buffer
+	diff.Render(&out)
+	for _, expected := range []string{
+		"├── LaunchTemplate lt [drifted]",
+		`~ instance_type: expected "i3.large", actual "i3.xlarge"`,
+		"├── CloudWatch cw [missing]",
+		"└── Lambda fetch [in sync]",
+		"Diff: 2 resources drifted",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/semaphore"
+	"strings"
+	"sync"
+)
+
+const DefaultWorkers = 8
+
+// Workers limits how many resources are reconciled concurrently by EnsureResource and DestroyResource
+var Workers = DefaultWorkers
+
+var ErrDependencyFailed = errors.New("skipped, dependency failed")
+
+type ResourceError struct {
+	Type string
+	Name string
+	Err  error
+}
+
+func (e ResourceError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Type, e.Name, e.Err.Error())
+}
+
+func (e ResourceError) Unwrap() error {
+	return e.Err
+}
+
+type ExecutionErrors []ResourceError
+
+func (e ExecutionErrors) Error() string {
+	var lines []string
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	return fmt.Sprintf("%d resources failed:\n%s", len(e), strings.Join(lines, "\n"))
+}
+
+type resourceAction func(r Resource) error
+
+type node struct {
+	resource     Resource
+	dependencies []*node
+	done         chan struct{}
+	err          error
+}
+
+// Executor reconciles a resource tree as a dependency graph: a resource depends on its
+// sub resources, independent subtrees run concurrently up to the workers limit
+type Executor struct {
+	workers int
+	journal *Journal
+
+	lock   sync.Mutex
+	errors ExecutionErrors
+}
+
+func NewExecutor(workers int, journal *Journal) *Executor {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Executor{
+		workers: workers,
+		journal: journal,
+	}
+}
+
+func buildGraph(r Resource, nodes map[Resource]*node) *node {
+	if n, ok := nodes[r]; ok {
+		return n
+	}
+	n := &node{
+		resource: r,
+		done:     make(chan struct{}),
+	}
+	nodes[r] = n
+	for _, subresource := range r.SubResources() {
+		n.dependencies = append(n.dependencies, buildGraph(subresource, nodes))
+	}
+	return n
+}
+
+func (e *Executor) addError(r Resource, err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.errors = append(e.errors, ResourceError{
+		Type: ResourceType(r),
+		Name: r.ResourceName(),
+		Err:  err,
+	})
+}
+
+func (e *Executor) run(root Resource, action resourceAction) error {
+	nodes := map[Resource]*node{}
+	buildGraph(root, nodes)
+
+	sem := semaphore.NewWeighted(int64(e.workers))
+	var wg sync.WaitGroup
+	wg.Add(len(nodes))
+	for _, n := range nodes {
+		go func(n *node) {
+			defer wg.Done()
+			defer close(n.done)
+
+			for _, dependency := range n.dependencies {
+				<-dependency.done
+				if dependency.err != nil {
+					n.err = ErrDependencyFailed
+				}
+			}
+			if n.err != nil {
+				log.Debug().Msgf("skipping %s %s, dependency failed", ResourceType(n.resource), n.resource.ResourceName())
+				return
+			}
+
+			_ = sem.Acquire(context.Background(), 1)
+			defer sem.Release(1)
+			n.err = action(n.resource)
+			if n.err != nil {
+				log.Error().Err(n.err).Msgf("%s %s failed", ResourceType(n.resource), n.resource.ResourceName())
+				e.addError(n.resource, n.err)
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	if len(e.errors) != 0 {
+		return e.errors
+	}
+	return nil
+}
+
+func (e *Executor) ensure(r Resource) error {
+	resourceType := ResourceType(r)
+
+	err := r.Fetch()
+	if err != nil {
+		return err
+	}
+
+	if r.DeployedVersion() == "" {
+		log.Info().Msgf("creating resource %s %s ...", resourceType, r.ResourceName())
+		err = r.Create()
+		if err != nil {
+			return err
+		}
+		e.journal.RecordResource(r)
+		return nil
+	}
+
+	if r.DeployedVersion() != r.TargetVersion() {
+		log.Info().Msgf("updating resource %s %s ...", resourceType, r.ResourceName())
+		return r.Update()
+	}
+
+	log.Debug().Msgf("resource %s %s exists and updated", resourceType, r.ResourceName())
+	return nil
+}
+
+// Ensure creates or updates every resource of the tree, after its sub resources were ensured
+func (e *Executor) Ensure(r Resource) error {
+	return e.run(r, e.ensure)
+}
+
+// Destroy deletes every resource of the tree, after its sub resources were deleted
+func (e *Executor) Destroy(r Resource) error {
+	return e.run(r, func(r Resource) error {
+		return r.Delete()
+	})
+}
@@ -0,0 +1,140 @@
+package cluster
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testResource struct {
+	name         string
+	subResources []Resource
+	createErr    error
+
+	running    *int32
+	maxRunning *int32
+	lock       *sync.Mutex
+	created    *[]string
+}
+
+func (t *testResource) ResourceName() string     { return t.name }
+func (t *testResource) SubResources() []Resource { return t.subResources }
+func (t *testResource) Tags() Tags               { return nil }
+func (t *testResource) Fetch() error             { return nil }
+func (t *testResource) DeployedVersion() string  { return "" }
+func (t *testResource) TargetVersion() string    { return "v1" }
+func (t *testResource) Delete() error            { return nil }
+func (t *testResource) Update() error            { return nil }
+func (t *testResource) Init()                    {}
+
+func (t *testResource) Status() (ResourceStatus, error) {
+	return ResourceStatus{Id: t.name}, nil
+}
+
+func (t *testResource) Create() error {
+	running := atomic.AddInt32(t.running, 1)
+	defer atomic.AddInt32(t.running, -1)
+	for {
+		max := atomic.LoadInt32(t.maxRunning)
+		if running <= max || atomic.CompareAndSwapInt32(t.maxRunning, max, running) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if t.createErr != nil {
+		return t.createErr
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	*t.created = append(*t.created, t.name)
+	return nil
+}
+
+type testTree struct {
+	running    int32
+	maxRunning int32
+	lock       sync.Mutex
+	created    []string
+}
+
+func (tt *testTree) resource(name string, subResources ...Resource) *testResource {
+	return &testResource{
+		name:         name,
+		subResources: subResources,
+		running:      &tt.running,
+		maxRunning:   &tt.maxRunning,
+		lock:         &tt.lock,
+		created:      &tt.created,
+	}
+}
+
+func (tt *testTree) index(name string) int {
+	for i, created := range tt.created {
+		if created == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestExecutorEnsureOrderAndConcurrency(t *testing.T) {
+	tt := &testTree{}
+	var lambdas []Resource
+	for _, name := range []string{"fetch", "scale", "terminate", "transient"} {
+		lambdas = append(lambdas, tt.resource(name))
+	}
+	root := tt.resource("cluster",
+		tt.resource("db", tt.resource("kms")),
+		tt.resource("machine", lambdas...),
+	)
+
+	journal := &Journal{}
+	err := NewExecutor(2, journal).Ensure(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tt.created) != 8 || journal.Len() != 8 {
+		t.Fatalf("expected 8 created resources, got %v", tt.created)
+	}
+	if tt.maxRunning > 2 {
+		t.Errorf("workers limit exceeded: %d", tt.maxRunning)
+	}
+	if tt.maxRunning < 2 {
+		t.Errorf("independent resources were not reconciled concurrently")
+	}
+	if tt.index("kms") > tt.index("db") || tt.index("db") > tt.index("cluster") || tt.index("machine") > tt.index("cluster") {
+		t.Errorf("resource created before its sub resources: %v", tt.created)
+	}
+	for _, lambda := range []string{"fetch", "scale", "terminate", "transient"} {
+		if tt.index(lambda) > tt.index("machine") {
+			t.Errorf("%s created after machine: %v", lambda, tt.created)
+		}
+	}
+}
+
+func TestExecutorEnsureErrors(t *testing.T) {
+	tt := &testTree{}
+	failing := tt.resource("scale")
+	failing.createErr = errors.New("boom")
+	root := tt.resource("cluster",
+		tt.resource("db"),
+		tt.resource("machine", tt.resource("fetch"), failing),
+	)
+
+	err := NewExecutor(4, nil).Ensure(root)
+	var executionErrors ExecutionErrors
+	if !errors.As(err, &executionErrors) {
+		t.Fatalf("expected execution errors, got %v", err)
+	}
+	if len(executionErrors) != 1 || executionErrors[0].Name != "scale" || executionErrors[0].Type != "testResource" {
+		t.Fatalf("unexpected errors: %v", executionErrors)
+	}
+	if tt.index("db") == -1 || tt.index("fetch") == -1 {
+		t.Errorf("independent resources were not created: %v", tt.created)
+	}
+	if tt.index("machine") != -1 || tt.index("cluster") != -1 {
+		t.Errorf("resources depending on a failed resource were created: %v", tt.created)
+	}
+}
@@ -0,0 +1,71 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"wekactl/internal/logging"
+)
+
+type JournalEntry struct {
+	Description string
+	Undo        func() error
+}
+
+// Journal records every change an operation made, so it can be undone if the operation fails halfway
+type Journal struct {
+	sync.Mutex
+	entries []JournalEntry
+}
+
+func (j *Journal) Record(description string, undo func() error) {
+	if j == nil {
+		return
+	}
+	j.Lock()
+	defer j.Unlock()
+	log.Debug().Msgf("journal: recording %s", description)
+	j.entries = append(j.entries, JournalEntry{
+		Description: description,
+		Undo:        undo,
+	})
+}
+
+func (j *Journal) RecordResource(r Resource) {
+	j.Record(fmt.Sprintf("created %s %s", ResourceType(r), r.ResourceName()), r.Delete)
+}
+
+func (j *Journal) Entries() []JournalEntry {
+	if j == nil {
+		return nil
+	}
+	j.Lock()
+	defer j.Unlock()
+	return append([]JournalEntry{}, j.entries...)
+}
+
+func (j *Journal) Len() int {
+	return len(j.Entries())
+}
+
+// Rollback undoes the recorded changes in reverse order. It continues past failures and
+// returns every error it encountered
+func (j *Journal) Rollback() (errs []error) {
+	entries := j.Entries()
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		logging.UserProgress("rolling back: %s ...", entry.Description)
+		err := entry.Undo()
+		if err != nil {
+			log.Error().Err(err).Msgf("failed rolling back: %s", entry.Description)
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Description, err))
+		}
+	}
+
+	if j != nil {
+		j.Lock()
+		j.entries = nil
+		j.Unlock()
+	}
+	return
+}
@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type PlanAction string
+
+const (
+	PlanCreate PlanAction = "create"
+	PlanUpdate PlanAction = "update"
+	PlanNone   PlanAction = "none"
+)
+
+type ResourcePlan struct {
+	Type            string
+	Name            string
+	Action          PlanAction
+	DeployedVersion string
+	TargetVersion   string
+	SubResources    []ResourcePlan
+}
+
+// PlanResource walks the resource tree the same way EnsureResource does, but only fetches
+// the deployed state, so nothing in the account is changed
+func PlanResource(r Resource) (plan ResourcePlan, err error) {
+	for _, subresource := range r.SubResources() {
+		subPlan, err := PlanResource(subresource)
+		if err != nil {
+			return plan, err
+		}
+		plan.SubResources = append(plan.SubResources, subPlan)
+	}
+
+	err = r.Fetch()
+	if err != nil {
+		return
+	}
+
+	plan.Type = ResourceType(r)
+	plan.Name = r.ResourceName()
+	plan.DeployedVersion = r.DeployedVersion()
+	plan.TargetVersion = r.TargetVersion()
+	plan.Action = planAction(r)
+	return
+}
+
+func planAction(r Resource) PlanAction {
+	if r.DeployedVersion() == "" {
+		return PlanCreate
+	}
+	if r.DeployedVersion() != r.TargetVersion() {
+		return PlanUpdate
+	}
+	return PlanNone
+}
+
+func (p ResourcePlan) Count(action PlanAction) (count int) {
+	if p.Action == action {
+		count++
+	}
+	for _, subPlan := range p.SubResources {
+		count += subPlan.Count(action)
+	}
+	return
+}
+
+func planVersion(version string) string {
+	if version == "" {
+		return "-"
+	}
+	return version
+}
+
+func (p ResourcePlan) render(w io.Writer, prefix, childPrefix string) {
+	_, _ = fmt.Fprintf(w, "%s%s %s [%s] (deployed: %s, target: %s)\n",
+		prefix, p.Type, p.Name, p.Action, planVersion(p.DeployedVersion), planVersion(p.TargetVersion))
+	for i, subPlan := range p.SubResources {
+		if i == len(p.SubResources)-1 {
+			subPlan.render(w, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			subPlan.render(w, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
+
+// Render prints the plan as a tree followed by a summary line
+func (p ResourcePlan) Render(w io.Writer) {
+	p.render(w, "", "")
+	summary := []string{
+		fmt.Sprintf("%d to create", p.Count(PlanCreate)),
+		fmt.Sprintf("%d to update", p.Count(PlanUpdate)),
+		fmt.Sprintf("%d unchanged", p.Count(PlanNone)),
+	}
+	_, _ = fmt.Fprintf(w, "\nPlan: %s\n", strings.Join(summary, ", "))
+}
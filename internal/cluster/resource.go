@@ -1,14 +1,11 @@
 package cluster
 
 import (
-	"github.com/rs/zerolog/log"
 	"reflect"
-	"strings"
 )
 
 /*
 
-- consider import/create recovery
 - consider destroy
 - consider multiple clouds [OPTIONAL]
 
@@ -19,6 +16,8 @@ type Resource interface {
 	SubResources() []Resource
 	Tags() Tags
 	Fetch() error
+	// Status describes the fetched resource, it is called only for deployed resources
+	Status() (ResourceStatus, error)
 	DeployedVersion() string
 	TargetVersion() string
 	Delete() error
@@ -27,40 +26,20 @@ type Resource interface {
 	Init()
 }
 
-func EnsureResource(r Resource) error {
-	for _, subresource := range r.SubResources() {
-		if err := EnsureResource(subresource); err != nil {
-			return err
-		}
-	}
-
-	resourceType := strings.TrimLeft(reflect.TypeOf(r).String(), "*cluster.")
-
-	err := r.Fetch()
-	if err != nil {
-		return err
-	}
-
-	if r.DeployedVersion() == "" {
-		log.Info().Msgf("creating resource %s %s ...", resourceType, r.ResourceName())
-		return r.Create()
-	}
-
-	if r.DeployedVersion() != r.TargetVersion() {
-		log.Info().Msgf("updating resource %s %s ...", resourceType, r.ResourceName())
-		return r.Update()
+func ResourceType(r Resource) string {
+	t := reflect.TypeOf(r)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
 	}
+	return t.Name()
+}
 
-	log.Debug().Msgf("resource %s %s exists and updated", resourceType, r.ResourceName())
-	return nil
+// EnsureResource creates or updates the resource tree, recording every created resource in
+// the journal (which may be nil)
+func EnsureResource(r Resource, journal *Journal) error {
+	return NewExecutor(Workers, journal).Ensure(r)
 }
 
 func DestroyResource(r Resource) error {
-	for _, subresource := range r.SubResources() {
-		if err := DestroyResource(subresource); err != nil {
-			return err
-		}
-	}
-
-	return r.Delete()
+	return NewExecutor(Workers, nil).Destroy(r)
 }
@@ -0,0 +1,109 @@
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const minLaunchGracePeriod = 5 * time.Minute
+
+// ScalingPolicy tunes the decisions the scale lambdas of a host group make
+type ScalingPolicy struct {
+	// UnhealthyDeactivateTimeout is how long a host may be down before it is deactivated as unhealthy
+	UnhealthyDeactivateTimeout time.Duration `json:"unhealthy_deactivate_timeout"`
+	// BackendCleanupDelay is how long an inactive backend of another host group is left for its own host group to remove
+	BackendCleanupDelay time.Duration `json:"backend_cleanup_delay"`
+	// DownKickOutTimeout is how long a down backend of another host group may stay active
+	DownKickOutTimeout time.Duration `json:"down_kick_out_timeout"`
+	// MaxUnhealthyDeactivating caps how many hosts are deactivated at once for being unhealthy
+	MaxUnhealthyDeactivating int `json:"max_unhealthy_deactivating"`
+	// LaunchGracePeriod is how long a new instance has to join the cluster before it is terminated
+	LaunchGracePeriod time.Duration `json:"launch_grace_period"`
+}
+
+func DefaultScalingPolicy() ScalingPolicy {
+	return ScalingPolicy{
+		UnhealthyDeactivateTimeout: 120 * time.Minute,
+		BackendCleanupDelay:        5 * time.Minute,
+		DownKickOutTimeout:         3 * time.Hour,
+		MaxUnhealthyDeactivating:   2,
+		LaunchGracePeriod:          30 * time.Minute,
+	}
+}
+
+func (p ScalingPolicy) Validate() error {
+	if p.UnhealthyDeactivateTimeout <= 0 {
+		return errors.New("unhealthy deactivate timeout must be positive")
+	}
+	if p.BackendCleanupDelay < 0 {
+		return errors.New("backend cleanup delay can't be negative")
+	}
+	if p.DownKickOutTimeout <= 0 {
+		return errors.New("down kick out timeout must be positive")
+	}
+	if p.MaxUnhealthyDeactivating < 1 {
+		return errors.New("at least 1 unhealthy host must be allowed to deactivate")
+	}
+	if p.LaunchGracePeriod < minLaunchGracePeriod {
+		return errors.New(fmt.Sprintf("launch grace period must be at least %s, for new instances to join the cluster", minLaunchGracePeriod))
+	}
+	return nil
+}
+
+type ScaleHistoryHost struct {
+	HostId     string
+	InstanceId string
+	State      string
+	ScaleState string
+	// Reason is why the host was deactivated or removed, empty for hosts that were left as is
+	Reason string
+}
+
+// ScaleHistoryRecord holds the decisions of a single run of a host group scale state machine
+type ScaleHistoryRecord struct {
+	Time            time.Time
+	Hosts           []ScaleHistoryHost
+	Deactivated     []ScaleHistoryHost
+	RemovedInactive []ScaleHistoryHost
+	Terminated      []string
+	ScaleDownLimit  string
+	Paused          string
+	TransientErrors []string
+	Error           string
+}
+
+// Changed returns whether the run deactivated, removed or terminated anything
+func (r ScaleHistoryRecord) Changed() bool {
+	return len(r.Deactivated) != 0 || len(r.RemovedInactive) != 0 || len(r.Terminated) != 0
+}
+
+func (r ScaleHistoryRecord) Failed() bool {
+	return len(r.TransientErrors) != 0 || r.Error != ""
+}
+
+// ScalingPause stops the scale lambdas of every cluster host group from changing the cluster, e.g. during maintenance
+type ScalingPause struct {
+	PausedBy string    `json:"paused_by"`
+	PausedAt time.Time `json:"paused_at"`
+	// Until is when scaling resumes by itself, zero for when it is resumed
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason"`
+}
+
+func (p ScalingPause) Active(now time.Time) bool {
+	return !p.PausedAt.IsZero() && (p.Until.IsZero() || now.Before(p.Until))
+}
+
+func (p ScalingPause) String() string {
+	description := fmt.Sprintf("paused by %s at %s", p.PausedBy, p.PausedAt.Format(time.RFC3339))
+	if p.Until.IsZero() {
+		description += " until resumed"
+	} else {
+		description += fmt.Sprintf(" until %s", p.Until.Format(time.RFC3339))
+	}
+	if p.Reason != "" {
+		description += fmt.Sprintf(" (%s)", p.Reason)
+	}
+	return description
+}
@@ -0,0 +1,31 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScalingPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		update  func(p *ScalingPolicy)
+		wantErr bool
+	}{
+		{"default", func(p *ScalingPolicy) {}, false},
+		{"noCleanupDelay", func(p *ScalingPolicy) { p.BackendCleanupDelay = 0 }, false},
+		{"noUnhealthyTimeout", func(p *ScalingPolicy) { p.UnhealthyDeactivateTimeout = 0 }, true},
+		{"negativeCleanupDelay", func(p *ScalingPolicy) { p.BackendCleanupDelay = -time.Minute }, true},
+		{"noKickOutTimeout", func(p *ScalingPolicy) { p.DownKickOutTimeout = 0 }, true},
+		{"noUnhealthyDeactivating", func(p *ScalingPolicy) { p.MaxUnhealthyDeactivating = 0 }, true},
+		{"shortLaunchGrace", func(p *ScalingPolicy) { p.LaunchGracePeriod = time.Minute }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := DefaultScalingPolicy()
+			tt.update(&policy)
+			if err := policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package cluster
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type StatusDetail struct {
+	Key   string
+	Value string
+}
+
+// ResourceStatus is what a resource reports about itself once fetched: its physical ID/ARN and
+// resource specific details, e.g. an auto scaling group capacity
+type ResourceStatus struct {
+	Id      string
+	Details []StatusDetail
+}
+
+func (s *ResourceStatus) AddDetail(key, value string) {
+	s.Details = append(s.Details, StatusDetail{Key: key, Value: value})
+}
+
+type ResourceStatusTree struct {
+	Type            string
+	Name            string
+	Id              string
+	DeployedVersion string
+	TargetVersion   string
+	Missing         bool
+	Details         []StatusDetail
+	SubResources    []ResourceStatusTree
+}
+
+// StatusResource walks the resource tree, fetching every resource and asking it for its status
+func StatusResource(r Resource) (tree ResourceStatusTree, err error) {
+	for _, subresource := range r.SubResources() {
+		subTree, err := StatusResource(subresource)
+		if err != nil {
+			return tree, err
+		}
+		tree.SubResources = append(tree.SubResources, subTree)
+	}
+
+	err = r.Fetch()
+	if err != nil {
+		return
+	}
+
+	tree.Type = ResourceType(r)
+	tree.Name = r.ResourceName()
+	tree.DeployedVersion = r.DeployedVersion()
+	tree.TargetVersion = r.TargetVersion()
+	tree.Missing = r.DeployedVersion() == "" && r.TargetVersion() != ""
+	if tree.Missing {
+		return
+	}
+
+	status, err := r.Status()
+	if err != nil {
+		return tree, fmt.Errorf("%s %s: %w", tree.Type, tree.Name, err)
+	}
+	tree.Id = status.Id
+	tree.Details = status.Details
+	return
+}
+
+// CountMissing returns how many resources of the tree are missing
+func (t ResourceStatusTree) CountMissing() (count int) {
+	if t.Missing {
+		count++
+	}
+	for _, subTree := range t.SubResources {
+		count += subTree.CountMissing()
+	}
+	return
+}
+
+func (t ResourceStatusTree) render(w io.Writer, prefix, childPrefix string) {
+	line := fmt.Sprintf("%s%s %s", prefix, t.Type, t.Name)
+	if t.Missing {
+		line += " [MISSING]"
+	} else {
+		if t.Id != "" && t.Id != t.Name {
+			line += fmt.Sprintf(" (%s)", t.Id)
+		}
+		if t.TargetVersion != "" {
+			line += fmt.Sprintf(" deployed: %s, target: %s", planVersion(t.DeployedVersion), planVersion(t.TargetVersion))
+		}
+	}
+	_, _ = fmt.Fprintln(w, line)
+
+	detailPrefix := childPrefix + "│ "
+	if len(t.SubResources) == 0 {
+		detailPrefix = childPrefix + "  "
+	}
+	var details []string
+	for _, detail := range t.Details {
+		details = append(details, fmt.Sprintf("%s: %s", detail.Key, detail.Value))
+	}
+	if len(details) != 0 {
+		_, _ = fmt.Fprintf(w, "%s%s\n", detailPrefix, strings.Join(details, ", "))
+	}
+
+	for i, subTree := range t.SubResources {
+		if i == len(t.SubResources)-1 {
+			subTree.render(w, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			subTree.render(w, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
+
+// Render prints the status tree followed by a summary line
+func (t ResourceStatusTree) Render(w io.Writer) {
+	t.render(w, "", "")
+	_, _ = fmt.Fprintf(w, "\n%d resources missing\n", t.CountMissing())
+}
@@ -0,0 +1,32 @@
+package cluster
+
+import (
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"wekactl/internal/logging"
+)
+
+// TaggedResource is a resource found by its wekactl.io/cluster_name tag, rather than by the cluster resource tree
+type TaggedResource struct {
+	Type   string
+	Name   string
+	delete func() error
+}
+
+func NewTaggedResource(resourceType, name string, delete func() error) TaggedResource {
+	return TaggedResource{Type: resourceType, Name: name, delete: delete}
+}
+
+// DestroyTaggedResources deletes the resources in the given order, it continues past failures
+// and returns every error it encountered
+func DestroyTaggedResources(resources []TaggedResource) (errs []error) {
+	for _, resource := range resources {
+		logging.UserProgress("deleting %s %s ...", resource.Type, resource.Name)
+		err := resource.delete()
+		if err != nil {
+			log.Error().Err(err).Msgf("failed deleting %s %s", resource.Type, resource.Name)
+			errs = append(errs, fmt.Errorf("%s %s: %w", resource.Type, resource.Name, err))
+		}
+	}
+	return
+}
@@ -5,47 +5,66 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/apigateway"
+	"github.com/aws/aws-sdk-go/service/apigateway/apigatewayiface"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/autoscaling/autoscalingiface"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
 	"github.com/aws/aws-sdk-go/service/cloudwatchevents"
+	"github.com/aws/aws-sdk-go/service/cloudwatchevents/cloudwatcheventsiface"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
 	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
 	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
 	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
 	"github.com/aws/aws-sdk-go/service/lambda"
+	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
 	"github.com/aws/aws-sdk-go/service/sfn"
+	"github.com/aws/aws-sdk-go/service/sfn/sfniface"
 	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
 	"sync"
 	"wekactl/internal/env"
 )
 
 type SAwsSession struct {
 	sync.RWMutex
+	initialized      bool
 	Session          *session.Session
-	CF               *cloudformation.CloudFormation
-	EC2              *ec2.EC2
-	ASG              *autoscaling.AutoScaling
-	KMS              *kms.KMS
-	DynamoDB         *dynamodb.DynamoDB
-	IAM              *iam.IAM
-	Lambda           *lambda.Lambda
-	ApiGateway       *apigateway.APIGateway
-	STS              *sts.STS
-	SFN              *sfn.SFN
-	CloudWatchEvents *cloudwatchevents.CloudWatchEvents
-	ELB              *elb.ELB
+	CF               cloudformationiface.CloudFormationAPI
+	EC2              ec2iface.EC2API
+	ASG              autoscalingiface.AutoScalingAPI
+	KMS              kmsiface.KMSAPI
+	DynamoDB         dynamodbiface.DynamoDBAPI
+	IAM              iamiface.IAMAPI
+	Lambda           lambdaiface.LambdaAPI
+	ApiGateway       apigatewayiface.APIGatewayAPI
+	STS              stsiface.STSAPI
+	SFN              sfniface.SFNAPI
+	CloudWatchEvents cloudwatcheventsiface.CloudWatchEventsAPI
+	CloudWatch       cloudwatchiface.CloudWatchAPI
+	ELB              elbiface.ELBAPI
 }
 
 var awsSession SAwsSession
 
 func GetAWSSession() *SAwsSession {
-	if awsSession.Session != nil {
+	awsSession.RLock()
+	initialized := awsSession.initialized
+	awsSession.RUnlock()
+	if initialized {
 		return &awsSession
 	}
 	awsSession.Lock()
 	defer awsSession.Unlock()
-	if awsSession.Session == nil {
+	if !awsSession.initialized {
 		awsSession.Session = newSession(env.Config.Region)
 		awsSession.CF = cloudformation.New(awsSession.Session)
 		awsSession.EC2 = ec2.New(awsSession.Session)
@@ -58,11 +77,22 @@ func GetAWSSession() *SAwsSession {
 		awsSession.STS = sts.New(awsSession.Session)
 		awsSession.SFN = sfn.New(awsSession.Session)
 		awsSession.CloudWatchEvents = cloudwatchevents.New(awsSession.Session)
+		awsSession.CloudWatch = cloudwatch.New(awsSession.Session)
 		awsSession.ELB = elb.New(awsSession.Session)
+		awsSession.initialized = true
 	}
 	return &awsSession
 }
 
+// SetAWSSession sets the session clients instead of creating them on first use,
+// so tests can replace them with in-memory fakes
+func SetAWSSession(set func(s *SAwsSession)) {
+	awsSession.Lock()
+	defer awsSession.Unlock()
+	set(&awsSession)
+	awsSession.initialized = true
+}
+
 func newSession(region string) *session.Session {
 	// TODO: Double-check if works profile
 	config := aws.NewConfig()
@@ -0,0 +1,130 @@
+package jrpc_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+	"wekactl/internal/lib/jrpc"
+	"wekactl/internal/lib/weka"
+	"wekactl/internal/lib/weka/fake"
+)
+
+var discardLogger = log.New(ioutil.Discard, "", 0)
+
+func newClient(ctx context.Context, address, username, password string) *jrpc.BaseClient {
+	opt := jrpc.ClientOptions{}
+	opt.AuthenticatedClient(username, password, "")
+	opt.RequestTimeout(3 * time.Second)
+	return jrpc.NewClient(ctx, discardLogger, &url.URL{Scheme: "http", Host: address, Path: "/api/v1"}, &http.Transport{}, &opt)
+}
+
+func listen(t *testing.T, cluster *fake.Cluster) *fake.Server {
+	t.Helper()
+	server, err := cluster.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+	return server
+}
+
+func TestBaseClientAuth(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster := fake.NewCluster("admin", "password")
+	cluster.AddHost("backend", "i-0", "10.0.0.0", 1)
+	server := listen(t, cluster)
+
+	client := newClient(ctx, server.Addr(), "admin", "password")
+	defer client.Close()
+	hosts := weka.HostListResponse{}
+	err := client.Call(ctx, string(weka.JrpcHostList), struct{}{}, &hosts)
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if len(hosts) != 1 {
+		t.Errorf("Call() returned %d hosts, want 1", len(hosts))
+	}
+	err = client.Call(ctx, string(weka.JrpcStatus), struct{}{}, &weka.StatusResponse{})
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if logins := cluster.CalledMethods()["user_login"]; logins != 1 {
+		t.Errorf("client logged in %d times, want 1", logins)
+	}
+
+	badClient := newClient(ctx, server.Addr(), "admin", "wrong")
+	defer badClient.Close()
+	err = badClient.Call(ctx, string(weka.JrpcHostList), struct{}{}, &hosts)
+	if err == nil {
+		t.Error("Call() with wrong credentials succeeded")
+	}
+	if calls := cluster.CalledMethods()[string(weka.JrpcHostList)]; calls != 1 {
+		t.Errorf("cluster served %d %s calls, want 1", calls, weka.JrpcHostList)
+	}
+}
+
+func TestBaseClientRefreshToken(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster := fake.NewCluster("admin", "password")
+	// oauth2 refreshes tokens that expire within 10 seconds, so every call refreshes the token
+	cluster.TokenLifetime = 5 * time.Second
+	server := listen(t, cluster)
+
+	client := newClient(ctx, server.Addr(), "admin", "password")
+	defer client.Close()
+	for i := 0; i < 3; i++ {
+		err := client.Call(ctx, string(weka.JrpcStatus), struct{}{}, &weka.StatusResponse{})
+		if err != nil {
+			t.Fatalf("Call() error = %v", err)
+		}
+	}
+	methods := cluster.CalledMethods()
+	if methods["user_login"] != 1 || methods["user_refresh_token"] != 2 {
+		t.Errorf("client logged in %d times and refreshed %d times, want 1 and 2", methods["user_login"], methods["user_refresh_token"])
+	}
+}
+
+func TestPoolFailover(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster := fake.NewCluster("admin", "password")
+	down := listen(t, cluster)
+	up := listen(t, cluster)
+	_ = down.Close()
+
+	pool := &jrpc.Pool{
+		Ips:     []string{down.Addr(), up.Addr()},
+		Clients: map[string]*jrpc.BaseClient{},
+		Builder: func(ip string) *jrpc.BaseClient {
+			return newClient(ctx, ip, "admin", "password")
+		},
+		Ctx: ctx,
+	}
+	status := weka.StatusResponse{}
+	err := pool.Call(weka.JrpcStatus, struct{}{}, &status)
+	if err != nil {
+		t.Fatalf("Pool.Call() error = %v", err)
+	}
+	if status.IoStatus != "STARTED" {
+		t.Errorf("Pool.Call() io status = %s, want STARTED", status.IoStatus)
+	}
+	if pool.Active != up.Addr() || len(pool.Ips) != 1 {
+		t.Errorf("Pool.Call() active = %s, ips = %v, want only %s", pool.Active, pool.Ips, up.Addr())
+	}
+
+	cluster.FailMethod(weka.JrpcDeactivateHosts, errors.New("host is busy"))
+	err = pool.Call(weka.JrpcDeactivateHosts, struct{}{}, nil)
+	if err == nil {
+		t.Error("Pool.Call() of a failing method succeeded")
+	}
+	if len(pool.Ips) != 1 {
+		t.Errorf("Pool.Call() of a failing method dropped the host, ips = %v", pool.Ips)
+	}
+}
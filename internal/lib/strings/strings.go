@@ -25,6 +25,15 @@ func AnyOfSubstr(testString string, variants ...string) bool {
 	return false
 }
 
+func AnyOfPrefix(testString string, prefixes ...string) bool {
+	for _, s := range prefixes {
+		if strings.HasPrefix(testString, s) {
+			return true
+		}
+	}
+	return false
+}
+
 func ListToRefList(ss []string) (ret []*string) {
 	for i := range ss {
 		ret = append(ret, &ss[i])
@@ -32,6 +41,15 @@ func ListToRefList(ss []string) (ret []*string) {
 	return
 }
 
+func RefListToList(ss []*string) (ret []string) {
+	for _, s := range ss {
+		if s != nil {
+			ret = append(ret, *s)
+		}
+	}
+	return
+}
+
 var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
 
 func RandSeq(n int) string {
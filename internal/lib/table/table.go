@@ -0,0 +1,14 @@
+package table
+
+import (
+	"github.com/olekukonko/tablewriter"
+	"os"
+)
+
+func Render(fields []string, data [][]string) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(fields)
+	table.SetRowLine(true)
+	table.AppendBulk(data)
+	table.Render()
+}
@@ -16,15 +16,73 @@ const (
 	JrpcDeactivateDrives JrpcMethod = "cluster_deactivate_drives"
 	JrpcDeactivateHosts  JrpcMethod = "cluster_deactivate_hosts"
 	JrpcStatus           JrpcMethod = "status"
+	JrpcMountsList       JrpcMethod = "mounts_list"
+	JrpcStatsRealtime    JrpcMethod = "stats_get_realtime"
 )
 
 type HostListResponse map[HostId]Host
 type DriveListResponse map[DriveId]Drive
 type NodeListResponse map[NodeId]Node
+type MountsListResponse map[HostId][]Mount
+type StatsRealtimeResponse map[NodeId]NodeStats
+
+type Capacity struct {
+	TotalBytes         int64 `json:"total_bytes"`
+	UnprovisionedBytes int64 `json:"unprovisioned_bytes"`
+}
+
+// ProtectionState is how much data lost how many of its protection drives, data with no failures is fully protected
+type ProtectionState struct {
+	MiB         int64   `json:"MiB"`
+	NumFailures int     `json:"numFailures"`
+	Percent     float64 `json:"percent"`
+}
+
+// Rebuild is the cluster data protection state, weka rebuilds the data of failed drives and failure domains
+type Rebuild struct {
+	ProgressPercent int               `json:"progressPercent"`
+	ProtectionState []ProtectionState `json:"protectionState"`
+	UnavailableMiB  int64             `json:"unavailableMiB"`
+}
+
+// Count is how many of the cluster drives or hosts are active
+type Count struct {
+	Active int `json:"active"`
+	Total  int `json:"total"`
+}
+
+// Unavailable returns how many are failed or otherwise not active
+func (c Count) Unavailable() int {
+	return c.Total - c.Active
+}
 
 type StatusResponse struct {
-	IoStatus string `json:"io_status"`
-	Upgrade  string `json:"upgrade"`
+	IoStatus string   `json:"io_status"`
+	Upgrade  string   `json:"upgrade"`
+	Capacity Capacity `json:"capacity"`
+	// StripeProtectionDrives is the protection level, the number of failure domains that may fail at once
+	StripeProtectionDrives int     `json:"stripe_protection_drives"`
+	Rebuild                Rebuild `json:"rebuild"`
+	Drives                 Count   `json:"drives"`
+	Hosts                  struct {
+		Backends Count `json:"backends"`
+		Clients  Count `json:"clients"`
+	} `json:"hosts"`
+}
+
+// Rebuilding tells whether weka is rebuilding data of failed drives
+func (s StatusResponse) Rebuilding() bool {
+	return s.Rebuild.ProgressPercent > 0 && s.Rebuild.ProgressPercent < 100
+}
+
+// DegradedMiB returns how much data lost some of its protection or is unavailable
+func (s StatusResponse) DegradedMiB() (mib int64) {
+	for _, state := range s.Rebuild.ProtectionState {
+		if state.NumFailures > 0 {
+			mib += state.MiB
+		}
+	}
+	return mib + s.Rebuild.UnavailableMiB
 }
 
 type Host struct {
@@ -33,8 +91,13 @@ type Host struct {
 	State            string    `json:"state"`
 	Status           string    `json:"status"`
 	HostIp           string    `json:"host_ip"`
-	Aws              struct {
-		InstanceId string `json:"instance_id"`
+	Mode             string    `json:"mode"`
+	// FailureDomain is the host failure domain name, every host has its own when FailureDomainType is AUTO
+	FailureDomain     string `json:"failure_domain"`
+	FailureDomainType string `json:"failure_domain_type"`
+	Aws               struct {
+		InstanceId       string `json:"instance_id"`
+		AvailabilityZone string `json:"availability_zone"`
 	} `json:"aws"`
 }
 
@@ -43,6 +106,23 @@ type Drive struct {
 	Status         string    `json:"status"`
 	Uuid           uuid.UUID `json:"uuid"`
 	ShouldBeActive bool      `json:"should_be_active"`
+	SizeBytes      int64     `json:"size_bytes"`
+}
+
+// Mount is a filesystem mounted by a host frontend
+type Mount struct {
+	Filesystem string `json:"filesystem"`
+	MountPoint string `json:"mount_point"`
+}
+
+// NodeStats are the realtime stats of a node, Ops is how many operations per second it serves
+type NodeStats struct {
+	Ops float64 `json:"ops"`
+	// ReadBytes and WriteBytes are how many bytes per second it reads and writes
+	ReadBytes  float64 `json:"read_bytes"`
+	WriteBytes float64 `json:"write_bytes"`
+	// Cpu is the node cpu utilization percentage
+	Cpu float64 `json:"cpu"`
 }
 
 type Node struct {
@@ -1,3 +1,6 @@
 package weka
 
 const ManagementJrpcPort = 14000
+
+// MinBackends is the least number of backends a weka cluster runs with
+const MinBackends = 6
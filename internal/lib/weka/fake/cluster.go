@@ -0,0 +1,487 @@
+package fake
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"sync"
+	"time"
+	"wekactl/internal/lib/weka"
+)
+
+const (
+	// PhaseOutDuration is how long a deactivated drive takes to become inactive
+	PhaseOutDuration = time.Minute
+	// DeactivateDuration is how long a deactivated host takes to become inactive
+	DeactivateDuration = time.Minute
+	// DriveSizeBytes is the size of every drive the fake cluster adds
+	DriveSizeBytes = 1000
+)
+
+type host struct {
+	id               int
+	mode             string
+	instanceId       string
+	ip               string
+	addedTime        time.Time
+	state            string
+	stateChangedTime time.Time
+	status           string
+	lastFencingTime  *time.Time
+	upSince          time.Time
+	failureDomain    string
+	zone             string
+	// inactiveAt is when a deactivating host becomes inactive
+	inactiveAt time.Time
+	// frontendStats are the realtime stats of the host frontend node, hosts without stats have no frontend node
+	frontendStats *weka.NodeStats
+}
+
+type drive struct {
+	id             int
+	hostId         int
+	uuid           uuid.UUID
+	status         string
+	shouldBeActive bool
+	// inactiveAt is when a phasing out drive becomes inactive
+	inactiveAt time.Time
+}
+
+// Call is a weka api call the fake cluster served
+type Call struct {
+	Method string
+	Params string
+}
+
+// Cluster is a scriptable weka cluster model, its hosts and drives move between states as the cluster clock advances
+type Cluster struct {
+	lock sync.Mutex
+
+	Username string
+	Password string
+	// TokenLifetime is how long access tokens are valid by the cluster clock
+	TokenLifetime time.Duration
+
+	now        time.Time
+	ioStatus   string
+	protection int
+	upgrade    string
+	rebuild    weka.Rebuild
+	hosts      map[int]*host
+	drives     map[int]*drive
+	nextId     int
+
+	tokens        map[string]time.Time
+	refreshTokens map[string]bool
+	calls         []Call
+	failures      map[string]error
+}
+
+// NewCluster returns an empty cluster with io started, its clock starts at the current time
+func NewCluster(username, password string) *Cluster {
+	return &Cluster{
+		Username:      username,
+		Password:      password,
+		TokenLifetime: time.Hour,
+		now:           time.Now().UTC(),
+		ioStatus:      "STARTED",
+		protection:    2,
+		hosts:         map[int]*host{},
+		drives:        map[int]*drive{},
+		tokens:        map[string]time.Time{},
+		refreshTokens: map[string]bool{},
+		failures:      map[string]error{},
+	}
+}
+
+func (c *Cluster) newId() int {
+	id := c.nextId
+	c.nextId++
+	return id
+}
+
+func hostId(id int) string {
+	return fmt.Sprintf("HostId<%d>", id)
+}
+
+func driveId(id int) string {
+	return fmt.Sprintf("DiskId<%d>", id)
+}
+
+// managementNodeId is a management node id, as weka.NodeId.IsManagement recognizes them
+func managementNodeId(hostId int) string {
+	return fmt.Sprintf("NodeId<%d>", hostId*20)
+}
+
+func frontendNodeId(hostId int) string {
+	return fmt.Sprintf("NodeId<%d>", hostId*20+1)
+}
+
+// AddHost adds an active and up host with the given number of active drives, and returns its id
+func (c *Cluster) AddHost(mode, instanceId, ip string, drives int) weka.HostId {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	h := &host{
+		id:               c.newId(),
+		mode:             mode,
+		instanceId:       instanceId,
+		ip:               ip,
+		addedTime:        c.now,
+		state:            "ACTIVE",
+		stateChangedTime: c.now,
+		status:           "UP",
+		upSince:          c.now,
+	}
+	c.hosts[h.id] = h
+	for i := 0; i < drives; i++ {
+		d := &drive{id: c.newId(), hostId: h.id, uuid: uuid.New(), status: "ACTIVE", shouldBeActive: true}
+		c.drives[d.id] = d
+	}
+	id := weka.HostId{}
+	_ = id.UnmarshalText([]byte(hostId(h.id)))
+	return id
+}
+
+func (c *Cluster) getHost(id weka.HostId) (*host, error) {
+	h, ok := c.hosts[id.Int()]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("host %s not found", id))
+	}
+	return h, nil
+}
+
+// SetHostDown marks the host and its management node down, as if it was fenced at the given time
+func (c *Cluster) SetHostDown(id weka.HostId, since time.Time) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	h, err := c.getHost(id)
+	if err != nil {
+		return err
+	}
+	h.status = "DOWN"
+	h.lastFencingTime = &since
+	return nil
+}
+
+// SetHostLocation sets the host failure domain and availability zone, hosts without a failure domain get their own
+func (c *Cluster) SetHostLocation(id weka.HostId, failureDomain, zone string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	h, err := c.getHost(id)
+	if err != nil {
+		return err
+	}
+	h.failureDomain = failureDomain
+	h.zone = zone
+	return nil
+}
+
+// SetHostDrivesStatus sets the status of all the host drives, e.g. INACTIVE for failed drives
+func (c *Cluster) SetHostDrivesStatus(id weka.HostId, status string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	h, err := c.getHost(id)
+	if err != nil {
+		return err
+	}
+	for _, d := range c.drives {
+		if d.hostId == h.id {
+			d.status = status
+		}
+	}
+	return nil
+}
+
+// SetHostStats gives the host a frontend node with the given realtime stats
+func (c *Cluster) SetHostStats(id weka.HostId, stats weka.NodeStats) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	h, err := c.getHost(id)
+	if err != nil {
+		return err
+	}
+	h.frontendStats = &stats
+	return nil
+}
+
+// SetIoStatus sets the status response io_status and upgrade, scale runs only when io is STARTED and no upgrade runs
+func (c *Cluster) SetIoStatus(ioStatus, upgrade string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.ioStatus = ioStatus
+	c.upgrade = upgrade
+}
+
+// SetRebuild sets the status response rebuild progress and how much data lost one protection drive,
+// the scale lambda doesn't start new deactivations while either is set
+func (c *Cluster) SetRebuild(progressPercent int, degradedMiB int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.rebuild = weka.Rebuild{ProgressPercent: progressPercent}
+	if degradedMiB > 0 {
+		c.rebuild.ProtectionState = []weka.ProtectionState{{MiB: degradedMiB, NumFailures: 1}}
+	}
+}
+
+// FailMethod makes every call of the method fail with err, until it is called again with a nil err
+func (c *Cluster) FailMethod(method weka.JrpcMethod, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err == nil {
+		delete(c.failures, string(method))
+		return
+	}
+	c.failures[string(method)] = err
+}
+
+// Advance moves the cluster clock, phasing out drives and deactivating hosts whose time has come
+func (c *Cluster) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+	for _, dr := range c.drives {
+		if dr.status == "PHASING_OUT" && !c.now.Before(dr.inactiveAt) {
+			dr.status = "INACTIVE"
+		}
+	}
+	for _, h := range c.hosts {
+		if h.state == "DEACTIVATING" && !c.now.Before(h.inactiveAt) {
+			h.state = "INACTIVE"
+			h.stateChangedTime = c.now
+		}
+	}
+}
+
+// Calls returns the calls the cluster served, in order
+func (c *Cluster) Calls() []Call {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]Call(nil), c.calls...)
+}
+
+// CalledMethods returns how many times each method was called
+func (c *Cluster) CalledMethods() map[string]int {
+	methods := map[string]int{}
+	for _, call := range c.Calls() {
+		methods[call.Method]++
+	}
+	return methods
+}
+
+// HostStates returns the state of every host by its instance id
+func (c *Cluster) HostStates() map[string]string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	states := map[string]string{}
+	for _, h := range c.hosts {
+		states[h.instanceId] = h.state
+	}
+	return states
+}
+
+type hostResponse struct {
+	Mode              string    `json:"mode"`
+	AddedTime         time.Time `json:"added_time"`
+	StateChangedTime  time.Time `json:"state_changed_time"`
+	State             string    `json:"state"`
+	Status            string    `json:"status"`
+	HostIp            string    `json:"host_ip"`
+	FailureDomain     string    `json:"failure_domain"`
+	FailureDomainType string    `json:"failure_domain_type"`
+	Aws               struct {
+		InstanceId       string `json:"instance_id"`
+		AvailabilityZone string `json:"availability_zone"`
+	} `json:"aws"`
+}
+
+type driveResponse struct {
+	HostId         string    `json:"host_id"`
+	Status         string    `json:"status"`
+	Uuid           uuid.UUID `json:"uuid"`
+	ShouldBeActive bool      `json:"should_be_active"`
+	SizeBytes      int64     `json:"size_bytes"`
+}
+
+type nodeResponse struct {
+	HostId          string     `json:"host_id"`
+	Status          string     `json:"status"`
+	LastFencingTime *time.Time `json:"last_fencing_time"`
+	UpSince         *time.Time `json:"up_since"`
+}
+
+func (c *Cluster) status() weka.StatusResponse {
+	var totalBytes int64
+	var drives weka.Count
+	for _, d := range c.drives {
+		if d.shouldBeActive {
+			totalBytes += DriveSizeBytes
+		}
+		drives.Total++
+		if d.status == "ACTIVE" {
+			drives.Active++
+		}
+	}
+	status := weka.StatusResponse{
+		IoStatus: c.ioStatus,
+		Upgrade:  c.upgrade,
+		Capacity: weka.Capacity{TotalBytes: totalBytes, UnprovisionedBytes: totalBytes},
+
+		StripeProtectionDrives: c.protection,
+		Rebuild:                c.rebuild,
+		Drives:                 drives,
+	}
+	for _, h := range c.hosts {
+		count := &status.Hosts.Clients
+		if h.mode == "backend" {
+			count = &status.Hosts.Backends
+		}
+		count.Total++
+		if h.state == "ACTIVE" && h.status == "UP" {
+			count.Active++
+		}
+	}
+	return status
+}
+
+func (c *Cluster) hostsList() map[string]hostResponse {
+	hosts := map[string]hostResponse{}
+	for _, h := range c.hosts {
+		response := hostResponse{
+			Mode:             h.mode,
+			AddedTime:        h.addedTime,
+			StateChangedTime: h.stateChangedTime,
+			State:            h.state,
+			Status:           h.status,
+			HostIp:           h.ip,
+		}
+		response.FailureDomain, response.FailureDomainType = h.failureDomain, "USER"
+		if h.failureDomain == "" {
+			response.FailureDomain, response.FailureDomainType = fmt.Sprintf("DOM-%03d", h.id), "AUTO"
+		}
+		response.Aws.InstanceId = h.instanceId
+		response.Aws.AvailabilityZone = h.zone
+		hosts[hostId(h.id)] = response
+	}
+	return hosts
+}
+
+func (c *Cluster) drivesList() map[string]driveResponse {
+	drives := map[string]driveResponse{}
+	for _, d := range c.drives {
+		owner := "HostId<INVALID>"
+		if d.hostId >= 0 {
+			owner = hostId(d.hostId)
+		}
+		drives[driveId(d.id)] = driveResponse{
+			HostId:         owner,
+			Status:         d.status,
+			Uuid:           d.uuid,
+			ShouldBeActive: d.shouldBeActive,
+			SizeBytes:      DriveSizeBytes,
+		}
+	}
+	return drives
+}
+
+func (c *Cluster) nodesList() map[string]nodeResponse {
+	nodes := map[string]nodeResponse{}
+	for _, h := range c.hosts {
+		upSince := h.upSince
+		node := nodeResponse{HostId: hostId(h.id), Status: "UP", UpSince: &upSince}
+		if h.status == "DOWN" {
+			node.Status = "DOWN"
+			node.UpSince = nil
+			node.LastFencingTime = h.lastFencingTime
+		}
+		nodes[managementNodeId(h.id)] = node
+		if h.frontendStats != nil {
+			nodes[frontendNodeId(h.id)] = node
+		}
+	}
+	return nodes
+}
+
+func (c *Cluster) statsRealtime() map[string]weka.NodeStats {
+	stats := map[string]weka.NodeStats{}
+	for _, h := range c.hosts {
+		if h.frontendStats != nil {
+			stats[frontendNodeId(h.id)] = *h.frontendStats
+		}
+	}
+	return stats
+}
+
+func (c *Cluster) driveByUuid(driveUuid uuid.UUID) (*drive, error) {
+	for _, d := range c.drives {
+		if d.uuid == driveUuid {
+			return d, nil
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("drive %s not found", driveUuid))
+}
+
+func (c *Cluster) deactivateDrives(driveUuids []uuid.UUID) error {
+	for _, driveUuid := range driveUuids {
+		d, err := c.driveByUuid(driveUuid)
+		if err != nil {
+			return err
+		}
+		d.shouldBeActive = false
+		if d.status != "INACTIVE" {
+			d.status = "PHASING_OUT"
+			d.inactiveAt = c.now.Add(PhaseOutDuration)
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) deactivateHosts(hostIds []weka.HostId) error {
+	for _, id := range hostIds {
+		h, err := c.getHost(id)
+		if err != nil {
+			return err
+		}
+		for _, d := range c.drives {
+			if d.hostId == h.id && d.status != "INACTIVE" {
+				return errors.New(fmt.Sprintf("host %s has drives that are not inactive", id))
+			}
+		}
+		if h.state == "ACTIVE" {
+			h.state = "DEACTIVATING"
+			h.stateChangedTime = c.now
+			h.inactiveAt = c.now.Add(DeactivateDuration)
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) removeHost(id int) error {
+	h, ok := c.hosts[id]
+	if !ok {
+		return errors.New(fmt.Sprintf("host %s not found", hostId(id)))
+	}
+	if h.state != "INACTIVE" {
+		return errors.New(fmt.Sprintf("host %s is %s, only inactive hosts can be removed", hostId(id), h.state))
+	}
+	delete(c.hosts, id)
+	for _, d := range c.drives {
+		if d.hostId == id {
+			d.hostId = -1
+		}
+	}
+	return nil
+}
+
+func (c *Cluster) removeDrives(driveUuids []uuid.UUID) error {
+	for _, driveUuid := range driveUuids {
+		d, err := c.driveByUuid(driveUuid)
+		if err != nil {
+			return err
+		}
+		if d.status != "INACTIVE" {
+			return errors.New(fmt.Sprintf("drive %s is %s, only inactive drives can be removed", driveUuid, d.status))
+		}
+		delete(c.drives, d.id)
+	}
+	return nil
+}
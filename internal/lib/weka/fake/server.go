@@ -0,0 +1,201 @@
+// Package fake is an in-process weka management api, it serves the json-rpc methods wekactl calls over real
+// http so the jrpc client, its authentication and pool failover run as they do against a weka cluster
+package fake
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"wekactl/internal/lib/jsonrpc2"
+	"wekactl/internal/lib/weka"
+)
+
+const (
+	methodLogin        = "user_login"
+	methodRefreshToken = "user_refresh_token"
+)
+
+// Server serves the cluster api on a single address, like the management process of a single weka host
+type Server struct {
+	cluster  *Cluster
+	listener net.Listener
+	server   *http.Server
+}
+
+// Listen serves the cluster api on the address, e.g. "127.0.0.1:0" for any free port
+func (c *Cluster) Listen(address string) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{cluster: c, listener: listener}
+	s.server = &http.Server{Handler: s}
+	go func() {
+		_ = s.server.Serve(listener)
+	}()
+	return s, nil
+}
+
+// ListenManagement serves the cluster api on the weka management port of the ip, where the scale lambdas look for it
+func (c *Cluster) ListenManagement(ip string) (*Server, error) {
+	return c.Listen(net.JoinHostPort(ip, strconv.Itoa(weka.ManagementJrpcPort)))
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the server, its clients get connection refused like from a host that went down
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	request := jsonrpc2.WireRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var params []byte
+	if request.Params != nil {
+		params = *request.Params
+	}
+
+	c := s.cluster
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.calls = append(c.calls, Call{Method: request.Method, Params: string(params)})
+
+	if request.Method != methodLogin && request.Method != methodRefreshToken {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		expiry, ok := c.tokens[token]
+		if !ok || !c.now.Before(expiry) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	result, err := c.handle(request.Method, params)
+	if err == errUnauthorized {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	response := jsonrpc2.WireResponse{ID: request.ID}
+	if err != nil {
+		code := int64(jsonrpc2.CodeUnknownError)
+		if err == errMethodNotFound {
+			code = jsonrpc2.CodeMethodNotFound
+		}
+		response.Error = &jsonrpc2.Error{Code: code, Message: err.Error()}
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		raw := json.RawMessage(data)
+		response.Result = &raw
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+var errUnauthorized = errors.New("unauthorized")
+var errMethodNotFound = errors.New("Method not found")
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresInSec int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+}
+
+func (c *Cluster) newToken() tokenResponse {
+	token := tokenResponse{
+		AccessToken:  uuid.New().String(),
+		RefreshToken: uuid.New().String(),
+		ExpiresInSec: int(c.TokenLifetime / time.Second),
+		TokenType:    "Bearer",
+	}
+	c.tokens[token.AccessToken] = c.now.Add(c.TokenLifetime)
+	c.refreshTokens[token.RefreshToken] = true
+	return token
+}
+
+func (c *Cluster) handle(method string, params []byte) (interface{}, error) {
+	if err, ok := c.failures[method]; ok {
+		return nil, err
+	}
+	switch method {
+	case methodLogin:
+		var credentials []string
+		if json.Unmarshal(params, &credentials) != nil || len(credentials) != 2 ||
+			credentials[0] != c.Username || credentials[1] != c.Password {
+			return nil, errUnauthorized
+		}
+		return c.newToken(), nil
+	case methodRefreshToken:
+		var refreshToken []string
+		if json.Unmarshal(params, &refreshToken) != nil || len(refreshToken) != 1 || !c.refreshTokens[refreshToken[0]] {
+			return nil, errUnauthorized
+		}
+		delete(c.refreshTokens, refreshToken[0])
+		return c.newToken(), nil
+	case string(weka.JrpcStatus):
+		return c.status(), nil
+	case string(weka.JrpcHostList):
+		return c.hostsList(), nil
+	case string(weka.JrpcDrivesList):
+		return c.drivesList(), nil
+	case string(weka.JrpcNodeList):
+		return c.nodesList(), nil
+	case string(weka.JrpcStatsRealtime):
+		return c.statsRealtime(), nil
+	case string(weka.JrpcDeactivateDrives), string(weka.JrpcRemoveDrive):
+		var p struct {
+			DriveUuids []uuid.UUID `json:"drive_uuids"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		if method == string(weka.JrpcRemoveDrive) {
+			return nil, c.removeDrives(p.DriveUuids)
+		}
+		return nil, c.deactivateDrives(p.DriveUuids)
+	case string(weka.JrpcDeactivateHosts):
+		var p struct {
+			HostIds []weka.HostId `json:"host_ids"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		return nil, c.deactivateHosts(p.HostIds)
+	case string(weka.JrpcRemoveHost):
+		var p struct {
+			HostId int `json:"host_id"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		return nil, c.removeHost(p.HostId)
+	default:
+		return nil, errMethodNotFound
+	}
+}
@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"strings"
@@ -54,3 +55,14 @@ func UserError(msg string, format ...interface{}) {
 	fmt.Println(Colorize(ColorError, msg))
 	os.Exit(2)
 }
+
+// UserConfirm prints a colorized question and returns true only if the user answered yes
+func UserConfirm(msg string, format ...interface{}) bool {
+	msg = fmt.Sprintf(msg+" [y/N]: ", format...)
+	fmt.Print(Colorize(ColorWarning, msg))
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(answer), "y") || strings.EqualFold(strings.TrimSpace(answer), "yes")
+}
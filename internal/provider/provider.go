@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"wekactl/internal/cluster"
+	"wekactl/internal/env"
+)
+
+type ClusterInfo struct {
+	Name         string
+	CreationTime string
+}
+
+type HostGroupInfo struct {
+	Name      string
+	Role      string
+	Desired   int64
+	Instances int64
+}
+
+// HostGroupProgress is how far a host group got towards its desired capacity, counted in capacity units, which are
+// instances unless the host group has weighted instance types
+type HostGroupProgress struct {
+	Desired   int64
+	Instances int64
+	// Active is how many of the host group instances weka reports as active hosts
+	Active int64
+}
+
+// InstanceTypeWeight is an instance type a host group launches, Weight is the capacity units an instance of it
+// counts for in the host group desired capacity
+type InstanceTypeWeight struct {
+	InstanceType string
+	Weight       int64
+}
+
+// Metrics an autoscaled client host group can track, averaged over the frontends of its active hosts
+const (
+	// MetricThroughput is in megabytes per second
+	MetricThroughput = "throughput"
+	MetricIops       = "iops"
+	// MetricCpu is a utilization percentage
+	MetricCpu = "cpu"
+)
+
+// Autoscale makes a client host group keep its Metric at Target, with MinSize to MaxSize instances
+type Autoscale struct {
+	Metric  string
+	Target  float64
+	MinSize int64
+	MaxSize int64
+}
+
+// Provider implements the cluster and host group operations of a single cloud provider,
+// the cli commands dispatch through it instead of calling a provider package directly
+type Provider interface {
+	ListClusters() ([]ClusterInfo, error)
+	ImportCluster(name, username, password string, journal *cluster.Journal) error
+	PlanImportCluster(name string) (cluster.ResourcePlan, error)
+	// RollbackImport undoes the changes a failed import recorded in the journal
+	RollbackImport(journal *cluster.Journal) []error
+	UpdateCluster(name string) error
+	PlanUpdateCluster(name string) (cluster.ResourcePlan, error)
+	DiffCluster(name string) (cluster.ResourceDiff, error)
+	StatusCluster(name string) (cluster.ResourceStatusTree, error)
+	ReconcileInventory(name string) (recorded, removed int, err error)
+	DestroyCluster(name string, keepInstances bool) error
+	// DiscoverTaggedResources finds every resource tagged with the cluster name, in the order they are
+	// safe to delete, keepInstances is honored when deleting instance groups
+	DiscoverTaggedResources(name string, keepInstances bool) ([]cluster.TaggedResource, error)
+	ChangeCredentials(name, username, password string) error
+	ListHostGroups(name string) ([]HostGroupInfo, error)
+	GetScalingPolicy(name, hostGroup string) (cluster.ScalingPolicy, error)
+	// SetScalingPolicy validates the policy and saves it, the host group lambdas use it on their next run
+	SetScalingPolicy(name, hostGroup string, policy cluster.ScalingPolicy) error
+	// ScaleHistory returns the recorded scale decisions of the host group since the given time, most recent first,
+	// limit 0 returns all of them
+	ScaleHistory(name, hostGroup string, since time.Time, limit int) ([]cluster.ScaleHistoryRecord, error)
+	// ScaleHostGroup validates and sets the host group desired capacity, a desired capacity above the max size
+	// fails unless raiseMaxSize is set
+	ScaleHostGroup(name, hostGroup string, desired int64, raiseMaxSize bool) error
+	// SetHostGroupSpot sets the share of spot instances a client host group launches above its on-demand base,
+	// a spot percentage of 0 runs on-demand instances only
+	SetHostGroupSpot(name, hostGroup string, onDemandBase, spotPercentage int64) error
+	// SetHostGroupInstanceTypes sets the instance types a host group launches, in priority order, and how the type of
+	// on-demand instances is picked. No instance types launch the launch template instance type only
+	SetHostGroupInstanceTypes(name, hostGroup string, instanceTypes []InstanceTypeWeight, allocationStrategy string) error
+	// EnableHostGroupAutoscale makes a client host group scale to keep a metric of its frontends at a target value,
+	// the cluster must have been updated to have the metrics lambda
+	EnableHostGroupAutoscale(name, hostGroup string, autoscale Autoscale) error
+	// DisableHostGroupAutoscale leaves the host group desired capacity as is, to be set by hand again
+	DisableHostGroupAutoscale(name, hostGroup string) error
+	// HostGroupProgress returns the host group desired capacity, its instances and how many of them weka reports
+	// as active hosts, listing the weka hosts needs network access to the backends
+	HostGroupProgress(name, hostGroup string) (HostGroupProgress, error)
+	// PauseScaling stops the scale lambdas of every host group from changing the cluster, until it is resumed,
+	// or until the given time when it isn't zero
+	PauseScaling(name string, until time.Time, reason string) error
+	ResumeScaling(name string) error
+	// SetBackendCoreCounts overrides the cores weka runs on backends of the instance type that join the cluster,
+	// instead of the ones computed from the instance type
+	SetBackendCoreCounts(name, instanceType string, counts cluster.CoreCounts) error
+	ResetBackendCoreCounts(name, instanceType string) error
+}
+
+var lock sync.RWMutex
+var providers = map[string]Provider{}
+
+// Register makes a provider available by name, it is meant to be called from the provider package init
+func Register(name string, provider Provider) {
+	lock.Lock()
+	defer lock.Unlock()
+	if _, ok := providers[name]; ok {
+		panic(fmt.Sprintf("cloud provider %s is already registered", name))
+	}
+	providers[name] = provider
+}
+
+func Get(name string) (Provider, error) {
+	lock.RLock()
+	defer lock.RUnlock()
+	provider, ok := providers[name]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("Cloud provider '%s' is not supported with this action", name))
+	}
+	return provider, nil
+}
+
+// Current returns the provider selected with the --provider flag
+func Current() (Provider, error) {
+	return Get(env.Config.Provider)
+}